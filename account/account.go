@@ -1,12 +1,34 @@
 package account
 
 import (
+	"errors"
 	"time"
 
+	"github.com/arthit666/make_app/config"
 	"github.com/arthit666/make_app/pocket"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrInsufficientBalance   = errors.New("insufficient balance in source account")
+	ErrNotReversible         = errors.New("only completed transfers can be reversed")
+	ErrAlreadyReversed       = errors.New("transfer already fully reversed")
+	ErrReversalExceedsAmount = errors.New("reversal amount exceeds the unreversed amount")
+	ErrReversalNotPending    = errors.New("reversal is not pending")
+	ErrAccountNotFound       = errors.New("account not found")
+	ErrAccountClosed         = errors.New("account is closed")
+	ErrAccountFrozen         = errors.New("account is frozen")
+	ErrAccountNotEmpty       = errors.New("account still holds funds")
+	ErrTransferLimitExceeded = errors.New("daily transfer limit exceeded")
+	ErrAliasTaken            = errors.New("alias is already taken")
+	ErrSenderNotFound        = errors.New("from account not found")
+	ErrRecipientNotFound     = errors.New("target account not found")
+	ErrRecipientClosed       = errors.New("target account is closed")
+	ErrSelfTransfer          = errors.New("cannot transfer to the same account")
+	ErrTransferNotFound      = errors.New("transfer not found")
+	ErrInvalidCredentials    = errors.New("invalid email or password")
+)
+
 type Account struct {
 	ID            uint            `gorm:"primarykey" json:"id"`
 	CreatedAt     time.Time       `json:"create_at"`
@@ -16,14 +38,32 @@ type Account struct {
 	Password      string          `json:"password" validate:"required"`
 	Balance       float64         `json:"balance"`
 	AccountNumber string          `json:"account_number"`
+	Alias         *string         `json:"alias" gorm:"uniqueIndex"`
+	Role          string          `json:"role" gorm:"default:user"`
+	Status        string          `json:"status" gorm:"default:active"`
+	TransferLimit float64         `json:"transfer_limit"`
 	PocketList    []pocket.Pocket `gorm:"ForeignKey:AccountID"`
 }
 
+const (
+	RoleUser    = "user"
+	RoleAdmin   = "admin"
+	RoleAuditor = "auditor"
+
+	StatusActive = "active"
+	StatusClosed = "closed"
+	// StatusFrozen accounts can still receive money but cannot send any.
+	StatusFrozen = "frozen"
+)
+
 type AccountResponse struct {
 	ID            uint            `json:"id"`
 	Email         string          `json:"email"`
 	AccountNumber string          `json:"account_number"`
+	Alias         *string         `json:"alias"`
 	Balance       float64         `json:"balance"`
+	Status        string          `json:"status"`
+	TransferLimit float64         `json:"transfer_limit"`
 	PocketList    []pocket.Pocket `json:"pocket_list"`
 }
 
@@ -41,34 +81,146 @@ type AccountRequest struct {
 	Balance  float64 `json:"balance"`
 }
 
+type AliasRequest struct {
+	Alias string `json:"alias" validate:"required,min=3,max=64"`
+}
+
 type AccountTransfer struct {
-	ID        uint      `gorm:"primarykey" json:"id"`
-	CreatedAt time.Time `json:"create_at"`
-	From      string    `json:"from"`
-	To        string    `json:"to" validate:"required"`
-	Amount    float64   `json:"amount" validate:"required,numeric,gt=0"`
+	ID             uint      `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time `json:"create_at"`
+	Type           string    `json:"type" gorm:"default:transfer"`
+	Status         string    `json:"status" gorm:"default:completed"`
+	From           string    `json:"from"`
+	To             string    `json:"to" validate:"required"`
+	Amount         float64   `json:"amount" validate:"required,numeric,gt=0"`
+	ReversedAmount float64   `json:"reversed_amount"`
+	ReversalOf     *uint     `json:"reversal_of,omitempty" gorm:"index"`
+	ReasonCode     string    `json:"reason_code,omitempty"`
+	Memo           string    `json:"memo,omitempty"`
+	Reference      string    `json:"reference,omitempty"`
+	Category       string    `json:"category,omitempty" gorm:"index"`
+	Tags           []string  `json:"tags,omitempty" gorm:"serializer:json"`
 }
 
+const (
+	TransferTypeTransfer       = "transfer"
+	TransferTypeReversal       = "reversal"
+	TransferTypeAdjustment     = "adjustment"
+	TransferTypeInterest       = "interest"
+	TransferTypeWithholdingTax = "withholding_tax"
+	// TransferTypeDeposit is money brought into the bank from outside, such
+	// as the opening balance of an account.
+	TransferTypeDeposit = "deposit"
+
+	TransferStatusCompleted = "completed"
+	TransferStatusPending   = "pending"
+
+	CategoryUncategorized = "uncategorized"
+
+	SuggestionHistory      = "history"
+	SuggestionCounterparty = "counterparty"
+	SuggestionMemo         = "memo"
+)
+
 type AccountTransferRequest struct {
 	To     string  `json:"to" validate:"required"`
 	Amount float64 `json:"amount" validate:"required,numeric,gt=0"`
+	Memo   string  `json:"memo" validate:"max=140"`
+	// Reference is the end-to-end reference of the payer, such as an
+	// invoice number, passed on unchanged to statements.
+	Reference string   `json:"reference" validate:"max=35"`
+	Category  string   `json:"category" validate:"max=32"`
+	Tags      []string `json:"tags" validate:"max=10,dive,required,max=32"`
+}
+
+type CategorySuggestion struct {
+	Category string `json:"category"`
+	Source   string `json:"source"`
+}
+
+type Insights struct {
+	Period            string              `json:"period"`
+	PreviousPeriod    string              `json:"previous_period"`
+	TotalSpent        float64             `json:"total_spent"`
+	PreviousSpent     float64             `json:"previous_spent"`
+	Change            *float64            `json:"change"`
+	Categories        []CategorySpend     `json:"categories"`
+	TopCounterparties []CounterpartySpend `json:"top_counterparties"`
+}
+
+type CategorySpend struct {
+	Category string   `json:"category"`
+	Amount   float64  `json:"amount"`
+	Count    int      `json:"count"`
+	Share    float64  `json:"share"`
+	Previous float64  `json:"previous"`
+	Change   *float64 `json:"change"`
+}
+
+type CounterpartySpend struct {
+	AccountNumber string  `json:"account_number"`
+	Amount        float64 `json:"amount"`
+	Count         int     `json:"count"`
+}
+
+type ReversalRequest struct {
+	Amount     float64 `json:"amount" validate:"omitempty,numeric,gt=0"`
+	ReasonCode string  `json:"reason_code" validate:"required,oneof=DUPLICATE ERRONEOUS FRAUD CUSTOMER_REQUEST REFUND"`
+}
+
+type AdjustmentRequest struct {
+	Amount     float64 `json:"amount" validate:"required,numeric,ne=0"`
+	ReasonCode string  `json:"reason_code" validate:"required,oneof=CORRECTION FEE GOODWILL REFUND"`
+	Memo       string  `json:"memo" validate:"max=140"`
 }
 
+type TransferLimitRequest struct {
+	TransferLimit float64 `json:"transfer_limit" validate:"numeric,gte=0"`
+}
+
+type ReversalAction struct {
+	TransferID uint `json:"transfer_id"`
+	ReversalRequest
+}
+
+type AdjustmentAction struct {
+	AccountID uint `json:"account_id"`
+	AdjustmentRequest
+}
+
+type TransferLimitAction struct {
+	AccountID uint `json:"account_id"`
+	TransferLimitRequest
+}
+
+type CloseAction struct {
+	AccountID uint `json:"account_id"`
+}
+
+const (
+	ActionReverseTransfer = "transfer.reverse"
+	ActionAdjustBalance   = "account.adjust_balance"
+	ActionIncreaseLimit   = "account.increase_limit"
+	ActionCloseAccount    = "account.close"
+)
+
 type Login struct {
 	Email    string `json:"email" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
 
 type handler struct {
-	DB *gorm.DB
-}
-
-func New(db *gorm.DB) *handler {
-	return &handler{db}
+	// DB is only for submitting actions for approval. Accounts and
+	// transfers are read and changed through the services.
+	DB        *gorm.DB
+	cfg       *config.Config
+	accounts  *AccountService
+	transfers *TransferService
 }
 
-type Err struct {
-	Massage string `json:"message"`
+func New(db *gorm.DB, cfg *config.Config) *handler {
+	store := NewStore(db)
+	return &handler{DB: db, cfg: cfg, accounts: NewAccountService(store), transfers: NewTransferService(store)}
 }
 
 type SuccessResponse struct {
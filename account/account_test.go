@@ -2,13 +2,20 @@ package account
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/approval"
+	"github.com/arthit666/make_app/config"
+	"github.com/arthit666/make_app/event"
 	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/rule"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
@@ -16,18 +23,24 @@ import (
 	"gorm.io/gorm"
 )
 
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.JWT.Secret = "secret"
+	return cfg
+}
+
 func TestCreateAccount(t *testing.T) {
 	//Arrange
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 	assert.NoError(t, err)
-	err = db.AutoMigrate(&Account{})
+	err = db.AutoMigrate(&Account{}, &AccountTransfer{})
 	assert.NoError(t, err)
 
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	app := fiber.New()
-	handler := New(tx)
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	handler := New(tx, testConfig())
 	app.Post("/accounts", handler.CreateAccount)
 
 	reqBody := AccountRequest{
@@ -61,6 +74,10 @@ func TestCreateAccount(t *testing.T) {
 	err = bcrypt.CompareHashAndPassword([]byte(createdAccount.Password), []byte(reqBody.Password))
 	assert.NoError(t, err)
 
+	var opening AccountTransfer
+	tx.Where(&AccountTransfer{To: createdAccount.AccountNumber}).First(&opening)
+	assert.Equal(t, TransferTypeDeposit, opening.Type)
+	assert.Equal(t, reqBody.Balance, opening.Amount)
 }
 
 func TestGetAllAccounts(t *testing.T) {
@@ -73,8 +90,8 @@ func TestGetAllAccounts(t *testing.T) {
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	app := fiber.New()
-	handler := New(tx)
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	handler := New(tx, testConfig())
 	app.Get("/accounts", handler.GetAllAccounts)
 
 	accounts := []Account{
@@ -130,8 +147,12 @@ func TestGetAccountById(t *testing.T) {
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	app := fiber.New()
-	handler := New(tx)
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", 1)
+		return c.Next()
+	})
+	handler := New(tx, testConfig())
 	app.Get("/accounts/:id", handler.GetAccountDetail)
 
 	account := Account{
@@ -183,8 +204,8 @@ func TestLogin(t *testing.T) {
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	app := fiber.New()
-	handler := New(tx)
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	handler := New(tx, testConfig())
 	app.Post("/login", handler.Login)
 
 	password := "password123"
@@ -222,15 +243,15 @@ func TestAccountTransfer(t *testing.T) {
 	// Arrange
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 	assert.NoError(t, err)
-	err = db.AutoMigrate(&Account{}, &AccountTransfer{}, &pocket.Pocket{})
+	err = db.AutoMigrate(&Account{}, &AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{}, &rule.Rule{}, &rule.RuleExecution{})
 	assert.NoError(t, err)
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
 	app.Use(func(c *fiber.Ctx) error {
 		c.Locals("account_id", 1)
 		return c.Next()
 	})
-	handler := New(db)
+	handler := New(db, testConfig())
 	app.Config()
 	app.Post("/accounts/transfer", handler.Transfer)
 
@@ -282,3 +303,739 @@ func TestAccountTransfer(t *testing.T) {
 	assert.Equal(t, 500.0, transferRecord.Amount)
 
 }
+
+func TestRefreshAccessToken(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &pocket.Pocket{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	cfg := testConfig()
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Get("/refresh", New(tx, cfg).RefreshAccessToken)
+
+	active := &Account{Email: "active@test.com", AccountNumber: "1000000001"}
+	closed := &Account{Email: "closed@test.com", AccountNumber: "1000000002", Status: StatusClosed}
+	tx.Create(active)
+	tx.Create(closed)
+	refresh := func(acc *Account) *http.Response {
+		tokens, err := IssueTokens(cfg.JWT, acc)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/refresh", nil)
+		req.Header.Set("X-Refresh-Token", "Bearer "+tokens.RefreshToken)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Act
+	activeResp := refresh(active)
+	closedResp := refresh(closed)
+
+	// Assert
+	assert.Equal(t, fiber.StatusOK, activeResp.StatusCode)
+	assert.Equal(t, fiber.StatusUnauthorized, closedResp.StatusCode)
+}
+
+func TestReverseTransfer(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &AccountTransfer{}, &pocket.Pocket{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	sender := Account{Email: "sender@example.com", AccountNumber: "1111111111", Balance: 500}
+	tx.Create(&sender)
+	recipient := Account{Email: "recipient@example.com", AccountNumber: "2222222222", Balance: 300}
+	tx.Create(&recipient)
+
+	transfer := AccountTransfer{
+		Type:   TransferTypeTransfer,
+		Status: TransferStatusCompleted,
+		From:   sender.AccountNumber,
+		To:     recipient.AccountNumber,
+		Amount: 500,
+	}
+	tx.Create(&transfer)
+
+	// Act & Assert: partial reversal
+	partial, err := Reverse(tx, testConfig().Policy, transfer.ID, &ReversalRequest{Amount: 200, ReasonCode: "ERRONEOUS"})
+	assert.NoError(t, err)
+	assert.Equal(t, TransferTypeReversal, partial.Type)
+	assert.Equal(t, TransferStatusCompleted, partial.Status)
+	assert.Equal(t, recipient.AccountNumber, partial.From)
+	assert.Equal(t, sender.AccountNumber, partial.To)
+	assert.Equal(t, 200.0, partial.Amount)
+	assert.Equal(t, transfer.ID, *partial.ReversalOf)
+
+	var updatedSender, updatedRecipient Account
+	tx.First(&updatedSender, sender.ID)
+	assert.Equal(t, 700.0, updatedSender.Balance)
+	tx.First(&updatedRecipient, recipient.ID)
+	assert.Equal(t, 100.0, updatedRecipient.Balance)
+
+	// Act & Assert: more than what is left to reverse
+	_, err = Reverse(tx, testConfig().Policy, transfer.ID, &ReversalRequest{Amount: 400, ReasonCode: "ERRONEOUS"})
+	assert.ErrorIs(t, err, ErrReversalExceedsAmount)
+
+	// Act & Assert: recipient can no longer cover the rest, so it is left pending
+	pending, err := Reverse(tx, testConfig().Policy, transfer.ID, &ReversalRequest{ReasonCode: "FRAUD"})
+	assert.NoError(t, err)
+	assert.Equal(t, TransferStatusPending, pending.Status)
+	assert.Equal(t, 300.0, pending.Amount)
+
+	tx.First(&updatedRecipient, recipient.ID)
+	assert.Equal(t, 100.0, updatedRecipient.Balance)
+
+	// Act & Assert: double reversal
+	_, err = Reverse(tx, testConfig().Policy, transfer.ID, &ReversalRequest{ReasonCode: "FRAUD"})
+	assert.ErrorIs(t, err, ErrAlreadyReversed)
+
+	// Act & Assert: a reversal cannot itself be reversed
+	_, err = Reverse(tx, testConfig().Policy, partial.ID, &ReversalRequest{ReasonCode: "ERRONEOUS"})
+	assert.ErrorIs(t, err, ErrNotReversible)
+
+	// Act & Assert: settle once the recipient has the funds again
+	_, err = Settle(tx, pending.ID)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	tx.Model(&Account{}).Where("id = ?", recipient.ID).Update("balance", 300)
+	settled, err := Settle(tx, pending.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, TransferStatusCompleted, settled.Status)
+
+	tx.First(&updatedSender, sender.ID)
+	assert.Equal(t, 1000.0, updatedSender.Balance)
+	tx.First(&updatedRecipient, recipient.ID)
+	assert.Equal(t, 0.0, updatedRecipient.Balance)
+}
+
+func TestReverseTransferNeedsApproval(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &AccountTransfer{}, &pocket.Pocket{}, &approval.PendingAction{})
+	assert.NoError(t, err)
+	approval.Register(ActionReverseTransfer, ExecuteReversal(testConfig().Policy))
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", 1)
+		return c.Next()
+	})
+	handler := New(tx, testConfig())
+	app.Post("/admin/transfers/:id/reverse", handler.ReverseTransfer)
+
+	sender := Account{Email: "sender@example.com", AccountNumber: "1111111111", Balance: 500}
+	tx.Create(&sender)
+	recipient := Account{Email: "recipient@example.com", AccountNumber: "2222222222", Balance: 300}
+	tx.Create(&recipient)
+
+	transfer := AccountTransfer{
+		Type:   TransferTypeTransfer,
+		Status: TransferStatusCompleted,
+		From:   sender.AccountNumber,
+		To:     recipient.AccountNumber,
+		Amount: 100,
+	}
+	tx.Create(&transfer)
+
+	reverse := func(body ReversalRequest) *http.Response {
+		reqBodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/transfers/%d/reverse", transfer.ID), bytes.NewReader(reqBodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Act & Assert: reason code is mandatory
+	resp := reverse(ReversalRequest{Amount: 50})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	// Act
+	resp = reverse(ReversalRequest{Amount: 50, ReasonCode: "DUPLICATE"})
+
+	// Assert
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+
+	var pending approval.PendingAction
+	err = json.NewDecoder(resp.Body).Decode(&pending)
+	assert.NoError(t, err)
+	assert.Equal(t, ActionReverseTransfer, pending.Action)
+	assert.Equal(t, approval.StatusPending, pending.Status)
+	assert.Equal(t, uint(1), pending.MakerID)
+
+	var updatedRecipient Account
+	tx.First(&updatedRecipient, recipient.ID)
+	assert.Equal(t, 300.0, updatedRecipient.Balance)
+}
+
+func TestSetAlias(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &pocket.Pocket{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	taken := "taken"
+	other := Account{Email: "other@example.com", AccountNumber: "2222222222", Alias: &taken}
+	tx.Create(&other)
+	account := Account{Email: "alias@example.com", AccountNumber: "1111111111"}
+	tx.Create(&account)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(account.ID))
+		return c.Next()
+	})
+	handler := New(tx, testConfig())
+	app.Put("/account/alias", handler.SetAlias)
+
+	put := func(alias string) *http.Response {
+		reqBodyBytes, _ := json.Marshal(AliasRequest{Alias: alias})
+		req := httptest.NewRequest(http.MethodPut, "/account/alias", bytes.NewReader(reqBodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Act & Assert
+	resp := put("taken")
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+	resp = put("mine")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var updated Account
+	tx.First(&updated, account.ID)
+	assert.Equal(t, "mine", *updated.Alias)
+}
+
+func TestSuggestCategory(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&AccountTransfer{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	tx.Create(&[]AccountTransfer{
+		{Type: TransferTypeTransfer, From: "1000000001", To: "2000000001", Amount: 10, Category: "food"},
+		{Type: TransferTypeTransfer, From: "1000000001", To: "2000000001", Amount: 10, Category: "groceries"},
+		{Type: TransferTypeTransfer, From: "1000000001", To: "2000000001", Amount: 10, Category: "groceries"},
+		{Type: TransferTypeTransfer, From: "1000000002", To: "2000000002", Amount: 10, Category: "housing"},
+		{Type: TransferTypeTransfer, From: "1000000001", To: "2000000003", Amount: 10, Category: CategoryUncategorized},
+	})
+
+	cases := []struct {
+		to, memo string
+		want     CategorySuggestion
+	}{
+		{"2000000001", "lunch", CategorySuggestion{Category: "groceries", Source: SuggestionHistory}},
+		{"2000000002", "", CategorySuggestion{Category: "housing", Source: SuggestionCounterparty}},
+		{"2000000003", "Taxi to the airport", CategorySuggestion{Category: "transport", Source: SuggestionMemo}},
+		{"2000000004", "for you", CategorySuggestion{Category: CategoryUncategorized}},
+	}
+
+	for _, c := range cases {
+		// Act
+		got, err := suggest(context.Background(), NewStore(tx).Transfers(), "1000000001", c.to, c.memo)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, got, c.to)
+	}
+}
+
+func TestInsights(t *testing.T) {
+	// Arrange
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	prevStart := start.AddDate(0, -1, 0)
+	feb := prevStart.AddDate(0, 0, 10)
+	mar := start.AddDate(0, 0, 10)
+
+	transfers := []AccountTransfer{
+		{CreatedAt: feb, To: "2000000001", Amount: 400, Category: "food"},
+		{CreatedAt: feb, To: "2000000002", Amount: 5000, Category: "housing"},
+		{CreatedAt: mar, To: "2000000001", Amount: 300, Category: "food"},
+		{CreatedAt: mar, To: "2000000003", Amount: 300, Category: "food"},
+		{CreatedAt: mar, To: "2000000002", Amount: 5000, Category: "housing"},
+		{CreatedAt: mar, To: "2000000004", Amount: 1000, ReversedAmount: 600},
+		{CreatedAt: mar, To: "2000000005", Amount: 100, ReversedAmount: 100, Category: "shopping"},
+	}
+
+	// Act
+	res := insights(transfers, prevStart, start)
+
+	// Assert
+	assert.Equal(t, "2026-03", res.Period)
+	assert.Equal(t, "2026-02", res.PreviousPeriod)
+	assert.Equal(t, 6000.0, res.TotalSpent)
+	assert.Equal(t, 5400.0, res.PreviousSpent)
+	assert.Equal(t, 11.11, *res.Change)
+
+	assert.Equal(t, 3, len(res.Categories))
+	assert.Equal(t, "housing", res.Categories[0].Category)
+	assert.Equal(t, 0.0, *res.Categories[0].Change)
+	assert.Equal(t, "food", res.Categories[1].Category)
+	assert.Equal(t, 600.0, res.Categories[1].Amount)
+	assert.Equal(t, 2, res.Categories[1].Count)
+	assert.Equal(t, 10.0, res.Categories[1].Share)
+	assert.Equal(t, 400.0, res.Categories[1].Previous)
+	assert.Equal(t, 50.0, *res.Categories[1].Change)
+	assert.Equal(t, CategoryUncategorized, res.Categories[2].Category)
+	assert.Nil(t, res.Categories[2].Change)
+
+	assert.Equal(t, 4, len(res.TopCounterparties))
+	assert.Equal(t, "2000000002", res.TopCounterparties[0].AccountNumber)
+	assert.Equal(t, "2000000004", res.TopCounterparties[1].AccountNumber)
+	assert.Equal(t, 400.0, res.TopCounterparties[1].Amount)
+}
+
+// fakeStore keeps accounts and transfers in memory. A transaction works on a
+// copy that replaces the store when it commits.
+type fakeStore struct {
+	accounts  map[uint]Account
+	transfers []AccountTransfer
+}
+
+func newFakeStore(accounts ...Account) *fakeStore {
+	s := &fakeStore{accounts: map[uint]Account{}}
+	for _, a := range accounts {
+		s.accounts[a.ID] = a
+	}
+	return s
+}
+
+func (s *fakeStore) Accounts() AccountRepository   { return fakeAccounts{s} }
+func (s *fakeStore) Transfers() TransferRepository { return fakeTransfers{s} }
+
+func (s *fakeStore) Transaction(ctx context.Context, fn func(Store) error) error {
+	tx := newFakeStore()
+	for id, a := range s.accounts {
+		tx.accounts[id] = a
+	}
+	tx.transfers = append(tx.transfers, s.transfers...)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	*s = *tx
+	return nil
+}
+
+type fakeAccounts struct{ *fakeStore }
+
+func (r fakeAccounts) Get(ctx context.Context, id uint) (*Account, error) {
+	a, ok := r.accounts[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	return &a, nil
+}
+
+func (r fakeAccounts) GetByNumber(ctx context.Context, number string) (*Account, error) {
+	for _, a := range r.accounts {
+		if a.AccountNumber == number {
+			return &a, nil
+		}
+	}
+	return nil, ErrAccountNotFound
+}
+
+func (r fakeAccounts) GetByEmail(ctx context.Context, email string) (*Account, error) {
+	for _, a := range r.accounts {
+		if a.Email == email {
+			return &a, nil
+		}
+	}
+	return nil, ErrAccountNotFound
+}
+
+func (r fakeAccounts) List(ctx context.Context, offset, limit int) ([]Account, int64, error) {
+	accounts := []Account{}
+	for id := uint(1); len(accounts) < len(r.accounts); id++ {
+		if a, ok := r.accounts[id]; ok {
+			accounts = append(accounts, a)
+		}
+	}
+	total := int64(len(accounts))
+	if offset > len(accounts) {
+		offset = len(accounts)
+	}
+	accounts = accounts[offset:]
+	if limit < len(accounts) {
+		accounts = accounts[:limit]
+	}
+	return accounts, total, nil
+}
+
+func (r fakeAccounts) Create(ctx context.Context, a *Account) error {
+	a.ID = uint(len(r.accounts) + 1)
+	r.accounts[a.ID] = *a
+	return nil
+}
+
+func (r fakeAccounts) UpdateBalance(ctx context.Context, a *Account) error {
+	stored := r.accounts[a.ID]
+	stored.Balance = a.Balance
+	r.accounts[a.ID] = stored
+	return nil
+}
+
+func (r fakeAccounts) Lock(ctx context.Context, ids ...uint) (map[uint]*Account, error) {
+	locked := map[uint]*Account{}
+	for _, id := range ids {
+		a, ok := r.accounts[id]
+		if !ok {
+			return nil, ErrAccountNotFound
+		}
+		locked[id] = &a
+	}
+	return locked, nil
+}
+
+func (r fakeAccounts) UpdateStatus(ctx context.Context, a *Account) error {
+	stored := r.accounts[a.ID]
+	stored.Status = a.Status
+	r.accounts[a.ID] = stored
+	return nil
+}
+
+func (r fakeAccounts) UpdateLimit(ctx context.Context, a *Account) error {
+	stored := r.accounts[a.ID]
+	stored.TransferLimit = a.TransferLimit
+	r.accounts[a.ID] = stored
+	return nil
+}
+
+func (r fakeAccounts) UpdateAlias(ctx context.Context, a *Account) error {
+	stored := r.accounts[a.ID]
+	stored.Alias = a.Alias
+	r.accounts[a.ID] = stored
+	return nil
+}
+
+func (r fakeAccounts) AliasTaken(ctx context.Context, alias string, id uint) (bool, error) {
+	for _, a := range r.accounts {
+		if a.ID != id && a.Alias != nil && *a.Alias == alias {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeTransfers struct{ *fakeStore }
+
+func (r fakeTransfers) Get(ctx context.Context, id uint) (*AccountTransfer, error) {
+	if id == 0 || int(id) > len(r.transfers) {
+		return nil, ErrTransferNotFound
+	}
+	t := r.transfers[id-1]
+	return &t, nil
+}
+
+func (r fakeTransfers) Lock(ctx context.Context, id uint) (*AccountTransfer, error) {
+	return r.Get(ctx, id)
+}
+
+func (r fakeTransfers) Create(ctx context.Context, t *AccountTransfer) error {
+	t.ID = uint(len(r.transfers) + 1)
+	t.CreatedAt = time.Now()
+	r.transfers = append(r.transfers, *t)
+	return nil
+}
+
+func (r fakeTransfers) UpdateStatus(ctx context.Context, t *AccountTransfer) error {
+	r.transfers[t.ID-1].Status = t.Status
+	return nil
+}
+
+func (r fakeTransfers) UpdateReversed(ctx context.Context, t *AccountTransfer) error {
+	r.transfers[t.ID-1].ReversedAmount = t.ReversedAmount
+	return nil
+}
+
+func (r fakeTransfers) Sent(ctx context.Context, number string, since, until time.Time) ([]AccountTransfer, error) {
+	sent := []AccountTransfer{}
+	for _, t := range r.transfers {
+		if t.From == number && t.Type == TransferTypeTransfer && !t.CreatedAt.Before(since) && t.CreatedAt.Before(until) {
+			sent = append(sent, t)
+		}
+	}
+	return sent, nil
+}
+
+func (r fakeTransfers) SentSince(ctx context.Context, number string, since time.Time) (float64, error) {
+	sent := 0.0
+	for _, t := range r.transfers {
+		if t.From == number && t.Type == TransferTypeTransfer && !t.CreatedAt.Before(since) {
+			sent += t.Amount
+		}
+	}
+	return sent, nil
+}
+
+func (r fakeTransfers) MostUsedCategory(ctx context.Context, cond *AccountTransfer) (string, error) {
+	counts := map[string]int{}
+	best := ""
+	for _, t := range r.transfers {
+		if (cond.From != "" && t.From != cond.From) || t.To != cond.To || t.Category == CategoryUncategorized {
+			continue
+		}
+		counts[t.Category]++
+		if n := counts[t.Category]; n > counts[best] || (n == counts[best] && t.Category < best) {
+			best = t.Category
+		}
+	}
+	return best, nil
+}
+
+func (r fakeTransfers) ApplyRules(ctx context.Context, t *AccountTransfer, fromID, toID uint) error {
+	return nil
+}
+
+func TestAccountService(t *testing.T) {
+	// Arrange
+	store := newFakeStore()
+	s := NewAccountService(store)
+	ctx := context.Background()
+
+	// Act
+	a, err := s.Create(ctx, &AccountRequest{Email: "new@example.com", Password: "secret", Balance: 100})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, RoleUser, a.Role)
+	assert.Len(t, a.AccountNumber, 10)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(a.Password), []byte("secret")))
+
+	got, err := s.Get(ctx, a.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", got.Email)
+
+	_, err = s.Get(ctx, 99)
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+
+	list, total, err := s.List(ctx, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, list, 1)
+
+	logged, err := s.Login(ctx, "new@example.com", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, a.ID, logged.ID)
+
+	_, err = s.Login(ctx, "new@example.com", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestAccountServiceChanges(t *testing.T) {
+	// Arrange
+	alias := "alice"
+	store := newFakeStore(
+		Account{ID: 1, AccountNumber: "1000000001", Balance: 10, Status: StatusActive},
+		Account{ID: 2, AccountNumber: "1000000002", Status: StatusActive, Alias: &alias},
+	)
+	s := NewAccountService(store)
+	ctx := context.Background()
+
+	// Act
+	named, aliasErr := s.SetAlias(ctx, 1, "bob")
+	_, takenErr := s.SetAlias(ctx, 1, "alice")
+	_, freezeErr := s.Freeze(ctx, 1)
+	_, limitErr := s.SetLimit(ctx, 1, 250)
+	_, notEmptyErr := s.Close(ctx, 1)
+	closed, closeErr := s.Close(ctx, 2)
+	_, frozenClosedErr := s.Freeze(ctx, 2)
+
+	// Assert
+	assert.NoError(t, aliasErr)
+	assert.Equal(t, "bob", *named.Alias)
+	assert.ErrorIs(t, takenErr, ErrAliasTaken)
+	assert.Equal(t, "bob", *store.accounts[1].Alias)
+	assert.NoError(t, freezeErr)
+	assert.NoError(t, limitErr)
+	assert.Equal(t, StatusFrozen, store.accounts[1].Status)
+	assert.Equal(t, 250.0, store.accounts[1].TransferLimit)
+	assert.ErrorIs(t, notEmptyErr, ErrAccountNotEmpty)
+	assert.NoError(t, closeErr)
+	assert.Equal(t, StatusClosed, closed.Status)
+	assert.Equal(t, StatusClosed, store.accounts[2].Status)
+	assert.ErrorIs(t, frozenClosedErr, ErrAccountClosed)
+}
+
+func TestTransferService(t *testing.T) {
+	ctx := context.Background()
+	accounts := func() *fakeStore {
+		return newFakeStore(
+			Account{ID: 1, AccountNumber: "1000000001", Balance: 500, Status: StatusActive, TransferLimit: 400},
+			Account{ID: 2, AccountNumber: "1000000002", Balance: 0, Status: StatusActive},
+			Account{ID: 3, AccountNumber: "1000000003", Status: StatusClosed},
+		)
+	}
+
+	t.Run("send", func(t *testing.T) {
+		// Arrange
+		store := accounts()
+		var sent []event.Event
+		unsubscribe := event.Subscribe(func(e event.Event) {
+			if e.Type == event.TransferSent {
+				sent = append(sent, e)
+			}
+		})
+		defer unsubscribe()
+
+		// Act
+		tr, err := NewTransferService(store).Send(ctx, 1, &AccountTransferRequest{To: "1000000002", Amount: 120.5, Memo: " rent "})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "1000000001", tr.From)
+		assert.Equal(t, "rent", tr.Memo)
+		assert.Equal(t, "housing", tr.Category)
+		assert.Equal(t, 379.5, store.accounts[1].Balance)
+		assert.Equal(t, 120.5, store.accounts[2].Balance)
+		assert.Len(t, store.transfers, 1)
+		assert.Len(t, sent, 1)
+	})
+
+	t.Run("send held", func(t *testing.T) {
+		// Arrange
+		store := accounts()
+		var sent []event.Event
+		unsubscribe := event.Subscribe(func(e event.Event) {
+			if e.Type == event.TransferSent {
+				sent = append(sent, e)
+			}
+		})
+		defer unsubscribe()
+		held, publish := event.Hold(ctx)
+
+		// Act
+		_, err := NewTransferService(store).SendAll(held, 1, []*AccountTransferRequest{{To: "1000000002", Amount: 10}, {To: "1000000002", Amount: 20}})
+		before := len(sent)
+		publish()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0, before)
+		assert.Len(t, sent, 2)
+	})
+
+	errs := []struct {
+		name string
+		from uint
+		req  AccountTransferRequest
+		want error
+	}{
+		{"unknown sender", 9, AccountTransferRequest{To: "1000000002", Amount: 10}, ErrSenderNotFound},
+		{"unknown recipient", 1, AccountTransferRequest{To: "1999999999", Amount: 10}, ErrRecipientNotFound},
+		{"closed recipient", 1, AccountTransferRequest{To: "1000000003", Amount: 10}, ErrRecipientClosed},
+		{"closed sender", 3, AccountTransferRequest{To: "1000000002", Amount: 10}, ErrAccountClosed},
+		{"over the limit", 1, AccountTransferRequest{To: "1000000002", Amount: 450}, ErrTransferLimitExceeded},
+		{"insufficient balance", 2, AccountTransferRequest{To: "1000000001", Amount: 10}, ErrInsufficientBalance},
+		{"to itself", 1, AccountTransferRequest{To: "1000000001", Amount: 10}, ErrSelfTransfer},
+	}
+	for _, tc := range errs {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			store := accounts()
+
+			// Act
+			_, err := NewTransferService(store).Send(ctx, tc.from, &tc.req)
+
+			// Assert
+			assert.ErrorIs(t, err, tc.want)
+			assert.Empty(t, store.transfers)
+			assert.Equal(t, 500.0, store.accounts[1].Balance)
+		})
+	}
+
+	t.Run("send all or nothing", func(t *testing.T) {
+		// Arrange
+		store := accounts()
+		reqs := []*AccountTransferRequest{
+			{To: "1000000002", Amount: 100},
+			{To: "1000000003", Amount: 100},
+		}
+
+		// Act
+		_, err := NewTransferService(store).SendAll(ctx, 1, reqs)
+
+		// Assert
+		var batchErr *BatchError
+		assert.ErrorAs(t, err, &batchErr)
+		assert.Equal(t, 1, batchErr.Index)
+		assert.ErrorIs(t, err, ErrRecipientClosed)
+		assert.Equal(t, 500.0, store.accounts[1].Balance)
+		assert.Empty(t, store.transfers)
+	})
+
+	t.Run("reverse into the negative", func(t *testing.T) {
+		// Arrange
+		store := accounts()
+		s := NewTransferService(store)
+		policy := testConfig().Policy
+		policy.ReversalShortfall = "negative"
+		sent, err := s.Send(ctx, 1, &AccountTransferRequest{To: "1000000002", Amount: 100})
+		assert.NoError(t, err)
+		_, err = s.Adjust(ctx, 2, &AdjustmentRequest{Amount: -40, ReasonCode: "FEE"})
+		assert.NoError(t, err)
+
+		// Act
+		r, err := s.Reverse(ctx, policy, sent.ID, &ReversalRequest{ReasonCode: "FRAUD"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, TransferStatusCompleted, r.Status)
+		assert.Equal(t, -40.0, store.accounts[2].Balance)
+	})
+
+	t.Run("reverse and settle", func(t *testing.T) {
+		// Arrange
+		store := accounts()
+		s := NewTransferService(store)
+		sent, err := s.Send(ctx, 1, &AccountTransferRequest{To: "1000000002", Amount: 100})
+		assert.NoError(t, err)
+		_, err = s.Adjust(ctx, 2, &AdjustmentRequest{Amount: -40, ReasonCode: "FEE"})
+		assert.NoError(t, err)
+
+		// Act
+		pending, reverseErr := s.Reverse(ctx, testConfig().Policy, sent.ID, &ReversalRequest{ReasonCode: "FRAUD"})
+		_, shortErr := s.Settle(ctx, pending.ID)
+		_, creditErr := s.Adjust(ctx, 2, &AdjustmentRequest{Amount: 40, ReasonCode: "GOODWILL"})
+		settled, settleErr := s.Settle(ctx, pending.ID)
+		_, againErr := s.Reverse(ctx, testConfig().Policy, sent.ID, &ReversalRequest{ReasonCode: "FRAUD"})
+		_, missingErr := s.Reverse(ctx, testConfig().Policy, 99, &ReversalRequest{ReasonCode: "FRAUD"})
+
+		// Assert
+		assert.NoError(t, reverseErr)
+		assert.Equal(t, TransferStatusPending, pending.Status)
+		assert.ErrorIs(t, shortErr, ErrInsufficientBalance)
+		assert.NoError(t, creditErr)
+		assert.NoError(t, settleErr)
+		assert.Equal(t, TransferStatusCompleted, settled.Status)
+		assert.ErrorIs(t, againErr, ErrAlreadyReversed)
+		assert.ErrorIs(t, missingErr, ErrTransferNotFound)
+		assert.Equal(t, 500.0, store.accounts[1].Balance)
+		assert.Equal(t, 0.0, store.accounts[2].Balance)
+		assert.Equal(t, 100.0, store.transfers[sent.ID-1].ReversedAmount)
+	})
+}
@@ -0,0 +1,349 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/approval"
+	"github.com/arthit666/make_app/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// @Summary Adjust an account balance
+// @Description Submit a manual credit (positive amount) or debit (negative amount) for approval
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Account ID"
+// @Param adjustment body account.AdjustmentRequest true "AdjustmentRequest data"
+// @Success 202 {object} approval.PendingAction
+// @Security  Bearer
+// @Router /admin/accounts/{id}/adjust [post]
+func (h *handler) AdjustBalance(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	req := &AdjustmentRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	acc, err := h.accounts.Get(c.UserContext(), uint(id))
+	if err != nil {
+		return adminError(err, "account not found")
+	}
+
+	maker := uint(c.Locals("account_id").(int))
+	p, err := approval.Submit(h.DB, h.cfg.Policy, ActionAdjustBalance, maker, AdjustmentAction{AccountID: acc.ID, AdjustmentRequest: *req})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(p)
+}
+
+// @Summary Set the daily transfer limit
+// @Description Lower the daily transfer limit right away, or submit an increase for approval. 0 means no limit.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Account ID"
+// @Param limit body account.TransferLimitRequest true "TransferLimitRequest data"
+// @Success 200 {object} account.AccountResponse
+// @Success 202 {object} approval.PendingAction
+// @Security  Bearer
+// @Router /admin/accounts/{id}/limit [post]
+func (h *handler) SetTransferLimit(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	req := &TransferLimitRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	acc, err := h.accounts.Get(c.UserContext(), uint(id))
+	if err != nil {
+		return adminError(err, "account not found")
+	}
+
+	if isLimitIncrease(acc.TransferLimit, req.TransferLimit) {
+		maker := uint(c.Locals("account_id").(int))
+		p, err := approval.Submit(h.DB, h.cfg.Policy, ActionIncreaseLimit, maker, TransferLimitAction{AccountID: acc.ID, TransferLimitRequest: *req})
+		if err != nil {
+			return apperr.Internal(err)
+		}
+		return c.Status(fiber.StatusAccepted).JSON(p)
+	}
+
+	acc, err = h.accounts.SetLimit(c.UserContext(), acc.ID, req.TransferLimit)
+	if err != nil {
+		return adminError(err, "account not found")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(AccountResponse{
+		ID:            acc.ID,
+		Email:         acc.Email,
+		AccountNumber: acc.AccountNumber,
+		Alias:         acc.Alias,
+		PocketList:    acc.PocketList,
+		Balance:       acc.Balance,
+		Status:        acc.Status,
+		TransferLimit: acc.TransferLimit,
+	})
+}
+
+// @Summary Close an account
+// @Description Submit the closure of an empty account for approval
+// @Tags admin
+// @Produce json
+// @Param id path int true "Account ID"
+// @Success 202 {object} approval.PendingAction
+// @Security  Bearer
+// @Router /admin/accounts/{id}/close [post]
+func (h *handler) CloseAccount(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	acc, err := h.accounts.Get(c.UserContext(), uint(id))
+	if err != nil {
+		return adminError(err, "account not found")
+	}
+	if acc.Status == StatusClosed {
+		return adminError(ErrAccountClosed, "account not found")
+	}
+
+	maker := uint(c.Locals("account_id").(int))
+	p, err := approval.Submit(h.DB, h.cfg.Policy, ActionCloseAccount, maker, CloseAction{AccountID: acc.ID})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(p)
+}
+
+// isLimitIncrease reports whether changing the limit from cur to next lets
+// the account send more per day. A limit of 0 means unlimited.
+func isLimitIncrease(cur, next float64) bool {
+	if next == 0 {
+		return cur != 0
+	}
+	return cur != 0 && next > cur
+}
+
+// RegisterActions registers the executors of the actions this package
+// submits for approval, reversing transfers under policy. Like
+// approval.Register, it is meant to be called before anything is submitted.
+func RegisterActions(policy config.Policy) {
+	approval.Register(ActionReverseTransfer, ExecuteReversal(policy))
+	approval.Register(ActionAdjustBalance, ExecuteAdjustment)
+	approval.Register(ActionIncreaseLimit, ExecuteLimitIncrease)
+	approval.Register(ActionCloseAccount, ExecuteClose)
+}
+
+// ExecuteAdjustment is the approval.Executor for ActionAdjustBalance.
+func ExecuteAdjustment(tx *gorm.DB, payload []byte) (interface{}, error) {
+	a := &AdjustmentAction{}
+	if err := json.Unmarshal(payload, a); err != nil {
+		return nil, err
+	}
+	t, err := Adjust(tx, a.AccountID, &a.AdjustmentRequest)
+	if err != nil {
+		return nil, adminError(err, "account not found")
+	}
+	return t, nil
+}
+
+// ExecuteLimitIncrease is the approval.Executor for ActionIncreaseLimit.
+func ExecuteLimitIncrease(tx *gorm.DB, payload []byte) (interface{}, error) {
+	a := &TransferLimitAction{}
+	if err := json.Unmarshal(payload, a); err != nil {
+		return nil, err
+	}
+	acc, err := SetLimit(tx, a.AccountID, a.TransferLimit)
+	if err != nil {
+		return nil, adminError(err, "account not found")
+	}
+	return acc, nil
+}
+
+// ExecuteClose is the approval.Executor for ActionCloseAccount.
+func ExecuteClose(tx *gorm.DB, payload []byte) (interface{}, error) {
+	a := &CloseAction{}
+	if err := json.Unmarshal(payload, a); err != nil {
+		return nil, err
+	}
+	acc, err := Close(tx, a.AccountID)
+	if err != nil {
+		return nil, adminError(err, "account not found")
+	}
+	return acc, nil
+}
+
+// Adjust adjusts the balance of account id through the TransferService of
+// db.
+func Adjust(db *gorm.DB, id uint, req *AdjustmentRequest) (*AccountTransfer, error) {
+	return NewTransferService(NewStore(db)).Adjust(context.Background(), id, req)
+}
+
+// SetLimit sets the daily transfer limit of account id through the
+// AccountService of db.
+func SetLimit(db *gorm.DB, id uint, limit float64) (*Account, error) {
+	return NewAccountService(NewStore(db)).SetLimit(context.Background(), id, limit)
+}
+
+// Freeze freezes account id through the AccountService of db.
+func Freeze(db *gorm.DB, id uint) (*Account, error) {
+	return NewAccountService(NewStore(db)).Freeze(context.Background(), id)
+}
+
+// Unfreeze unfreezes account id through the AccountService of db.
+func Unfreeze(db *gorm.DB, id uint) (*Account, error) {
+	return NewAccountService(NewStore(db)).Unfreeze(context.Background(), id)
+}
+
+// Close closes account id through the AccountService of db.
+func Close(db *gorm.DB, id uint) (*Account, error) {
+	return NewAccountService(NewStore(db)).Close(context.Background(), id)
+}
+
+// Adjust credits or debits account id by req.Amount and records the
+// movement as an adjustment transfer. A debit may not overdraw the account.
+func (s *TransferService) Adjust(ctx context.Context, id uint, req *AdjustmentRequest) (*AccountTransfer, error) {
+	t := &AccountTransfer{}
+	err := s.store.Transaction(ctx, func(store Store) error {
+		locked, err := store.Accounts().Lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		acc := locked[id]
+		if acc.Status == StatusClosed {
+			return ErrAccountClosed
+		}
+
+		amountDec := decimal.NewFromFloat(req.Amount)
+		bl := decimal.NewFromFloat(acc.Balance).Add(amountDec)
+		if bl.IsNegative() {
+			return ErrInsufficientBalance
+		}
+
+		acc.Balance, _ = bl.Float64()
+		if err := store.Accounts().UpdateBalance(ctx, acc); err != nil {
+			return err
+		}
+
+		t = &AccountTransfer{
+			Type:       TransferTypeAdjustment,
+			Status:     TransferStatusCompleted,
+			ReasonCode: req.ReasonCode,
+			Memo:       strings.TrimSpace(req.Memo),
+		}
+		t.Amount, _ = amountDec.Abs().Float64()
+		if amountDec.IsPositive() {
+			t.To = acc.AccountNumber
+		} else {
+			t.From = acc.AccountNumber
+		}
+
+		return store.Transfers().Create(ctx, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SetLimit sets the daily transfer limit of account id.
+func (s *AccountService) SetLimit(ctx context.Context, id uint, limit float64) (*Account, error) {
+	acc, err := s.store.Accounts().Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	acc.TransferLimit = limit
+	if err := s.store.Accounts().UpdateLimit(ctx, acc); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// Freeze stops account id from sending money until it is unfrozen. It can
+// still receive money.
+func (s *AccountService) Freeze(ctx context.Context, id uint) (*Account, error) {
+	return s.setStatus(ctx, id, StatusFrozen)
+}
+
+// Unfreeze lets frozen account id send money again.
+func (s *AccountService) Unfreeze(ctx context.Context, id uint) (*Account, error) {
+	return s.setStatus(ctx, id, StatusActive)
+}
+
+func (s *AccountService) setStatus(ctx context.Context, id uint, status string) (*Account, error) {
+	acc, err := s.store.Accounts().Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if acc.Status == StatusClosed {
+		return nil, ErrAccountClosed
+	}
+
+	acc.Status = status
+	if err := s.store.Accounts().UpdateStatus(ctx, acc); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// Close closes account id. The account and its pockets must be empty. The
+// account row is locked first, so no money can come in while it closes.
+func (s *AccountService) Close(ctx context.Context, id uint) (*Account, error) {
+	var acc *Account
+	err := s.store.Transaction(ctx, func(store Store) error {
+		accounts := store.Accounts()
+		if _, err := accounts.Lock(ctx, id); err != nil {
+			return err
+		}
+		var err error
+		if acc, err = accounts.Get(ctx, id); err != nil {
+			return err
+		}
+		if acc.Status == StatusClosed {
+			return ErrAccountClosed
+		}
+
+		if acc.Balance != 0 {
+			return ErrAccountNotEmpty
+		}
+		for _, p := range acc.PocketList {
+			if p.Balance != 0 {
+				return ErrAccountNotEmpty
+			}
+		}
+
+		acc.Status = StatusClosed
+		return accounts.UpdateStatus(ctx, acc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
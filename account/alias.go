@@ -0,0 +1,79 @@
+package account
+
+import (
+	"context"
+	"errors"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Set account alias
+// @Description Set the alias other accounts can use instead of the account number
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param alias body account.AliasRequest true "AliasRequest data"
+// @Success 200 {object} account.AccountResponse
+// @Security  Bearer
+// @Router /account/alias [put]
+func (h *handler) SetAlias(c *fiber.Ctx) error {
+	req := &AliasRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	id := c.Locals("account_id").(int)
+	acc, err := h.accounts.SetAlias(c.UserContext(), uint(id), req.Alias)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAccountNotFound):
+			return apperr.NotFound(apperr.CodeAccountNotFound, "account not found")
+		case errors.Is(err, ErrAliasTaken):
+			return apperr.Conflict(apperr.CodeAlreadyExists, err.Error())
+		}
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(AccountResponse{
+		ID:            acc.ID,
+		Email:         acc.Email,
+		AccountNumber: acc.AccountNumber,
+		Alias:         acc.Alias,
+		PocketList:    acc.PocketList,
+		Balance:       acc.Balance,
+		Status:        acc.Status,
+		TransferLimit: acc.TransferLimit,
+	})
+}
+
+// SetAlias lets other accounts reach account id by alias instead of its
+// account number.
+func (s *AccountService) SetAlias(ctx context.Context, id uint, alias string) (*Account, error) {
+	var acc *Account
+	err := s.store.Transaction(ctx, func(store Store) error {
+		accounts := store.Accounts()
+		var err error
+		if acc, err = accounts.Get(ctx, id); err != nil {
+			return err
+		}
+		taken, err := accounts.AliasTaken(ctx, alias, id)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrAliasTaken
+		}
+
+		acc.Alias = &alias
+		return accounts.UpdateAlias(ctx, acc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
@@ -0,0 +1,58 @@
+package account
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+)
+
+// memoRules suggest a category from the words of a memo, for counterparties
+// nobody has categorized yet. The first rule with a matching word wins.
+var memoRules = []struct {
+	category string
+	words    []string
+}{
+	{"housing", []string{"rent", "condo", "mortgage", "dorm"}},
+	{"bills", []string{"bill", "bills", "electric", "electricity", "water", "internet", "phone", "mobile"}},
+	{"transport", []string{"taxi", "grab", "bolt", "bts", "mrt", "fuel", "gas", "parking", "toll"}},
+	{"groceries", []string{"grocery", "groceries", "market", "supermarket"}},
+	{"food", []string{"food", "lunch", "dinner", "breakfast", "coffee", "cafe", "restaurant"}},
+	{"health", []string{"hospital", "clinic", "pharmacy", "doctor", "dentist"}},
+	{"insurance", []string{"insurance", "premium"}},
+	{"education", []string{"school", "tuition", "course", "books"}},
+	{"travel", []string{"hotel", "flight", "trip", "airline"}},
+	{"entertainment", []string{"movie", "cinema", "concert", "netflix", "spotify", "game"}},
+	{"shopping", []string{"shop", "shopping", "clothes", "shoes"}},
+}
+
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// @Summary Suggest a transfer category
+// @Description Suggest a category for a transfer to an account, from past transfers to it or the memo
+// @Tags accounts
+// @Produce json
+// @Param to query string true "Recipient account number"
+// @Param memo query string false "Transfer memo"
+// @Success 200 {object} account.CategorySuggestion
+// @Security  Bearer
+// @Router /account/categories/suggest [get]
+func (h *handler) SuggestCategory(c *fiber.Ctx) error {
+	to := c.Query("to")
+	if to == "" {
+		return apperr.BadRequest("payload invalid: to is required")
+	}
+
+	id := c.Locals("account_id").(int)
+	s, err := h.transfers.SuggestCategory(c.UserContext(), uint(id), to, c.Query("memo"))
+	if err != nil {
+		if errors.Is(err, ErrAccountNotFound) {
+			return apperr.NotFound(apperr.CodeAccountNotFound, err.Error())
+		}
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(s)
+}
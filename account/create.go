@@ -1,14 +1,12 @@
 package account
 
 import (
-	"fmt"
 	"math/rand"
 	"strconv"
 	"time"
 
-	"github.com/go-playground/validator"
+	"github.com/arthit666/make_app/apperr"
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // @Summary Create a new account
@@ -24,25 +22,16 @@ func (h *handler) CreateAccount(c *fiber.Ctx) error {
 	a := &Account{}
 
 	if err := c.BodyParser(a); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.ErrBadRequest)
+		return apperr.BadRequest("invalid request body")
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(a); err != nil {
-
-		return c.Status(fiber.StatusBadRequest).JSON(Err{Massage: "payload invalid: " + err.Error()})
-	}
-	a.AccountNumber = randomAccountNumber()
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(a.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
+	if err := apperr.Validate(a); err != nil {
+		return err
 	}
-	a.Password = string(hashedPassword)
 
-	_, err = create(h, a)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
+	req := &AccountRequest{Email: a.Email, Password: a.Password, Balance: a.Balance}
+	if _, err := h.accounts.Create(c.UserContext(), req); err != nil {
+		return apperr.Internal(err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{Message: "create account success"})
@@ -56,11 +45,3 @@ func randomAccountNumber() string {
 	}
 	return id
 }
-
-func create(h *handler, a *Account) (*Account, error) {
-	tx := h.DB.Create(a)
-	if tx.Error != nil {
-		return nil, fmt.Errorf(tx.Error.Error())
-	}
-	return a, nil
-}
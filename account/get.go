@@ -5,8 +5,8 @@ import (
 	"math"
 	"strconv"
 
+	"github.com/arthit666/make_app/apperr"
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 // @Summary Get all accounts
@@ -20,23 +20,17 @@ import (
 func (h *handler) GetAllAccounts(c *fiber.Ctx) error {
 	page, err := strconv.Atoi(c.Query("page", "1"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(Err{Massage: "invalid page parameter"})
+		return apperr.BadRequest("invalid page parameter")
 	}
 	limit, err := strconv.Atoi(c.Query("count", "10"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(Err{Massage: "invalid page_size parameter"})
+		return apperr.BadRequest("invalid page_size parameter")
 	}
 
-	offset := (page - 1) * limit
-
-	acc := []Account{}
-	tx := h.DB.Offset(offset).Limit(limit).Preload("PocketList").Find(&acc)
-	if tx.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + tx.Error.Error()})
+	acc, totalCount, err := h.accounts.List(c.UserContext(), page, limit)
+	if err != nil {
+		return apperr.Internal(err)
 	}
-
-	var totalCount int64
-	h.DB.Model(&Account{}).Count(&totalCount)
 	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
 
 	accr := []AccountResponse{}
@@ -46,8 +40,11 @@ func (h *handler) GetAllAccounts(c *fiber.Ctx) error {
 			ID:            v.ID,
 			Email:         v.Email,
 			AccountNumber: v.AccountNumber,
+			Alias:         v.Alias,
 			PocketList:    v.PocketList,
 			Balance:       v.Balance,
+			Status:        v.Status,
+			TransferLimit: v.TransferLimit,
 		})
 	}
 
@@ -71,38 +68,23 @@ func (h *handler) GetAllAccounts(c *fiber.Ctx) error {
 // @Router /account/ [get]
 func (h *handler) GetAccountDetail(c *fiber.Ctx) error {
 	id := c.Locals("account_id").(int)
-	acc, err := getById(strconv.Itoa(id), h)
+	acc, err := h.accounts.Get(c.UserContext(), uint(id))
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(Err{Massage: "account not found"})
+		if errors.Is(err, ErrAccountNotFound) {
+			return apperr.NotFound(apperr.CodeAccountNotFound, "account not found")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
+		return apperr.Internal(err)
 	}
 	res := AccountResponse{
 		ID:            acc.ID,
 		Email:         acc.Email,
 		AccountNumber: acc.AccountNumber,
+		Alias:         acc.Alias,
 		PocketList:    acc.PocketList,
 		Balance:       acc.Balance,
+		Status:        acc.Status,
+		TransferLimit: acc.TransferLimit,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(res)
 }
-
-func getById(id string, h *handler) (*Account, error) {
-	p := &Account{}
-	tx := h.DB.Preload("PocketList").First(p, id)
-	if tx.Error != nil {
-		return nil, tx.Error
-	}
-	return p, nil
-}
-
-func getByAccountNumber(an string, h *handler) (*Account, error) {
-	p := &Account{}
-	tx := h.DB.Preload("PocketList").First(p, "account_number", an)
-	if tx.Error != nil {
-		return nil, tx.Error
-	}
-	return p, nil
-}
@@ -0,0 +1,162 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	periodLayout      = "2006-01"
+	topCounterparties = 5
+)
+
+// @Summary Get spending insights
+// @Description Get spend per category, top counterparties and the change from the month before
+// @Tags accounts
+// @Produce json
+// @Param period query string false "Month as YYYY-MM, the current month by default"
+// @Success 200 {object} account.Insights
+// @Security  Bearer
+// @Router /account/insights [get]
+func (h *handler) GetInsights(c *fiber.Ctx) error {
+	start := time.Now()
+	if p := c.Query("period"); p != "" {
+		var err error
+		start, err = time.ParseInLocation(periodLayout, p, time.Local)
+		if err != nil {
+			return apperr.BadRequest("payload invalid: period must be YYYY-MM")
+		}
+	}
+	start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	id := c.Locals("account_id").(int)
+	res, err := h.transfers.Insights(c.UserContext(), uint(id), start)
+	if err != nil {
+		if errors.Is(err, ErrAccountNotFound) {
+			return apperr.NotFound(apperr.CodeAccountNotFound, "account not found")
+		}
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+// Insights sums what account id spent in the month starting at start and
+// the one before.
+func (s *TransferService) Insights(ctx context.Context, id uint, start time.Time) (Insights, error) {
+	acc, err := s.store.Accounts().Get(ctx, id)
+	if err != nil {
+		return Insights{}, err
+	}
+
+	prevStart := start.AddDate(0, -1, 0)
+	transfers, err := s.store.Transfers().Sent(ctx, acc.AccountNumber, prevStart, start.AddDate(0, 1, 0))
+	if err != nil {
+		return Insights{}, err
+	}
+	return insights(transfers, prevStart, start), nil
+}
+
+// insights sums transfers sent from prevStart on into the month starting at
+// start and the one before. Reversed amounts do not count as spent.
+func insights(transfers []AccountTransfer, prevStart, start time.Time) Insights {
+	type total struct {
+		amount, previous decimal.Decimal
+		count            int
+	}
+	var spent, prevSpent decimal.Decimal
+	categories := map[string]*total{}
+	counterparties := map[string]*total{}
+
+	for _, t := range transfers {
+		net := decimal.NewFromFloat(t.Amount).Sub(decimal.NewFromFloat(t.ReversedAmount))
+		if !net.IsPositive() {
+			continue
+		}
+
+		category := t.Category
+		if category == "" {
+			category = CategoryUncategorized
+		}
+		if categories[category] == nil {
+			categories[category] = &total{}
+		}
+
+		if t.CreatedAt.Before(start) {
+			prevSpent = prevSpent.Add(net)
+			categories[category].previous = categories[category].previous.Add(net)
+			continue
+		}
+
+		spent = spent.Add(net)
+		categories[category].amount = categories[category].amount.Add(net)
+		categories[category].count++
+
+		if counterparties[t.To] == nil {
+			counterparties[t.To] = &total{}
+		}
+		counterparties[t.To].amount = counterparties[t.To].amount.Add(net)
+		counterparties[t.To].count++
+	}
+
+	res := Insights{
+		Period:            start.Format(periodLayout),
+		PreviousPeriod:    prevStart.Format(periodLayout),
+		Change:            change(spent, prevSpent),
+		Categories:        []CategorySpend{},
+		TopCounterparties: []CounterpartySpend{},
+	}
+	res.TotalSpent, _ = spent.Float64()
+	res.PreviousSpent, _ = prevSpent.Float64()
+
+	for name, t := range categories {
+		cs := CategorySpend{Category: name, Count: t.count, Change: change(t.amount, t.previous)}
+		cs.Amount, _ = t.amount.Float64()
+		cs.Previous, _ = t.previous.Float64()
+		if spent.IsPositive() {
+			cs.Share, _ = t.amount.Div(spent).Mul(decimal.NewFromInt(100)).Round(2).Float64()
+		}
+		res.Categories = append(res.Categories, cs)
+	}
+	sort.Slice(res.Categories, func(i, j int) bool {
+		a, b := res.Categories[i], res.Categories[j]
+		if a.Amount != b.Amount {
+			return a.Amount > b.Amount
+		}
+		return a.Category < b.Category
+	})
+
+	for number, t := range counterparties {
+		cp := CounterpartySpend{AccountNumber: number, Count: t.count}
+		cp.Amount, _ = t.amount.Float64()
+		res.TopCounterparties = append(res.TopCounterparties, cp)
+	}
+	sort.Slice(res.TopCounterparties, func(i, j int) bool {
+		a, b := res.TopCounterparties[i], res.TopCounterparties[j]
+		if a.Amount != b.Amount {
+			return a.Amount > b.Amount
+		}
+		return a.AccountNumber < b.AccountNumber
+	})
+	if len(res.TopCounterparties) > topCounterparties {
+		res.TopCounterparties = res.TopCounterparties[:topCounterparties]
+	}
+
+	return res
+}
+
+// change is the percentage change from previous to current, or nil when
+// there was nothing to compare with.
+func change(current, previous decimal.Decimal) *float64 {
+	if previous.IsZero() {
+		return nil
+	}
+	c, _ := current.Sub(previous).Div(previous).Mul(decimal.NewFromInt(100)).Round(2).Float64()
+	return &c
+}
@@ -1,11 +1,10 @@
 package account
 
 import (
-	"time"
+	"errors"
 
-	"github.com/go-playground/validator"
+	"github.com/arthit666/make_app/apperr"
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // @Summary Login account
@@ -18,38 +17,31 @@ import (
 // @Router /login/ [post]
 func (h *handler) Login(c *fiber.Ctx) error {
 	req := &Login{}
-	acc := &Account{}
 
 	if err := c.BodyParser(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.ErrBadRequest)
+		return apperr.BadRequest("invalid request body")
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(Err{Massage: "payload invalid: " + err.Error()})
+	if err := apperr.Validate(req); err != nil {
+		return err
 	}
 
-	tx := h.DB.Where("email = ?", req.Email).First(acc)
-	if tx.Error != nil {
-		return c.Status(fiber.StatusNotFound).JSON(Err{Massage: tx.Error.Error()})
+	acc, err := h.accounts.Login(c.UserContext(), req.Email, req.Password)
+	switch {
+	case errors.Is(err, ErrAccountNotFound):
+		return apperr.NotFound(apperr.CodeAccountNotFound, err.Error())
+	case errors.Is(err, ErrInvalidCredentials):
+		return apperr.New(fiber.StatusUnauthorized, apperr.CodeInvalidCredentials, err.Error())
+	case errors.Is(err, ErrAccountClosed):
+		return apperr.New(fiber.StatusForbidden, apperr.CodeAccountClosed, err.Error())
+	case err != nil:
+		return apperr.Internal(err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(acc.Password), []byte(req.Password)); err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.ErrUnauthorized)
-	}
-
-	act, err := GenerateToken(acc.ID, time.Now().Add(time.Minute*15).Unix())
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
-	}
-
-	rft, err := GenerateToken(acc.ID, time.Now().Add(time.Hour*24).Unix())
+	tokens, err := IssueTokens(h.cfg.JWT, acc)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
+		return apperr.Internal(err)
 	}
 
-	return c.JSON(TokenResponse{
-		AccessToken:  *act,
-		RefreshToken: *rft,
-	})
+	return c.JSON(tokens)
 }
@@ -0,0 +1,234 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arthit666/make_app/rule"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AccountRepository stores accounts. A missing account is ErrAccountNotFound.
+type AccountRepository interface {
+	Get(ctx context.Context, id uint) (*Account, error)
+	GetByNumber(ctx context.Context, number string) (*Account, error)
+	GetByEmail(ctx context.Context, email string) (*Account, error)
+	List(ctx context.Context, offset, limit int) ([]Account, int64, error)
+	Create(ctx context.Context, a *Account) error
+	UpdateBalance(ctx context.Context, a *Account) error
+	UpdateStatus(ctx context.Context, a *Account) error
+	UpdateLimit(ctx context.Context, a *Account) error
+	UpdateAlias(ctx context.Context, a *Account) error
+	// AliasTaken reports whether an account other than id goes by alias.
+	AliasTaken(ctx context.Context, alias string, id uint) (bool, error)
+	// Lock loads accounts ids and locks their rows until the transaction
+	// ends. Rows are locked in ID order, so two transactions locking the
+	// same accounts cannot deadlock.
+	Lock(ctx context.Context, ids ...uint) (map[uint]*Account, error)
+}
+
+// TransferRepository stores transfers between accounts. A missing transfer
+// is ErrTransferNotFound.
+type TransferRepository interface {
+	Get(ctx context.Context, id uint) (*AccountTransfer, error)
+	// Lock loads transfer id and locks its row until the transaction ends.
+	Lock(ctx context.Context, id uint) (*AccountTransfer, error)
+	Create(ctx context.Context, t *AccountTransfer) error
+	UpdateStatus(ctx context.Context, t *AccountTransfer) error
+	UpdateReversed(ctx context.Context, t *AccountTransfer) error
+	// Sent lists the transfers the account numbered number sent from since
+	// until before until.
+	Sent(ctx context.Context, number string, since, until time.Time) ([]AccountTransfer, error)
+	// SentSince sums the transfers the account numbered number sent from
+	// since on.
+	SentSince(ctx context.Context, number string, since time.Time) (float64, error)
+	// MostUsedCategory is the category given most to the transfers matching
+	// cond, or "" when none has one.
+	MostUsedCategory(ctx context.Context, cond *AccountTransfer) (string, error)
+	// ApplyRules runs the automation rules of both sides of t.
+	ApplyRules(ctx context.Context, t *AccountTransfer, fromID, toID uint) error
+}
+
+// Store hands out the repositories of this package. Transaction runs fn
+// with a Store whose repositories share one transaction, committed when fn
+// returns nil.
+type Store interface {
+	Accounts() AccountRepository
+	Transfers() TransferRepository
+	Transaction(ctx context.Context, fn func(Store) error) error
+}
+
+// NewStore is the Store of db.
+func NewStore(db *gorm.DB) Store {
+	return gormStore{db}
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func (s gormStore) Accounts() AccountRepository   { return gormAccounts(s) }
+func (s gormStore) Transfers() TransferRepository { return gormTransfers(s) }
+
+func (s gormStore) Transaction(ctx context.Context, fn func(Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(gormStore{tx})
+	})
+}
+
+type gormAccounts struct {
+	db *gorm.DB
+}
+
+func (r gormAccounts) Get(ctx context.Context, id uint) (*Account, error) {
+	a := &Account{}
+	err := r.db.WithContext(ctx).Preload("PocketList").First(a, id).Error
+	return a, notFound(err, ErrAccountNotFound)
+}
+
+func (r gormAccounts) GetByNumber(ctx context.Context, number string) (*Account, error) {
+	a := &Account{}
+	err := r.db.WithContext(ctx).Preload("PocketList").First(a, "account_number", number).Error
+	return a, notFound(err, ErrAccountNotFound)
+}
+
+func (r gormAccounts) GetByEmail(ctx context.Context, email string) (*Account, error) {
+	a := &Account{}
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(a).Error
+	return a, notFound(err, ErrAccountNotFound)
+}
+
+func (r gormAccounts) List(ctx context.Context, offset, limit int) ([]Account, int64, error) {
+	accounts := []Account{}
+	db := r.db.WithContext(ctx)
+	if err := db.Offset(offset).Limit(limit).Preload("PocketList").Find(&accounts).Error; err != nil {
+		return nil, 0, err
+	}
+	var total int64
+	err := db.Model(&Account{}).Count(&total).Error
+	return accounts, total, err
+}
+
+func (r gormAccounts) Create(ctx context.Context, a *Account) error {
+	return r.db.WithContext(ctx).Create(a).Error
+}
+
+func (r gormAccounts) UpdateBalance(ctx context.Context, a *Account) error {
+	return r.db.WithContext(ctx).Model(a).Update("balance", a.Balance).Error
+}
+
+func (r gormAccounts) UpdateStatus(ctx context.Context, a *Account) error {
+	return r.db.WithContext(ctx).Model(a).Update("status", a.Status).Error
+}
+
+func (r gormAccounts) UpdateLimit(ctx context.Context, a *Account) error {
+	return r.db.WithContext(ctx).Model(a).Update("transfer_limit", a.TransferLimit).Error
+}
+
+func (r gormAccounts) UpdateAlias(ctx context.Context, a *Account) error {
+	return r.db.WithContext(ctx).Model(a).Update("alias", a.Alias).Error
+}
+
+func (r gormAccounts) AliasTaken(ctx context.Context, alias string, id uint) (bool, error) {
+	var taken int64
+	err := r.db.WithContext(ctx).Model(&Account{}).Where("alias = ? AND id <> ?", alias, id).Count(&taken).Error
+	return taken > 0, err
+}
+
+func (r gormAccounts) Lock(ctx context.Context, ids ...uint) (map[uint]*Account, error) {
+	accounts := []Account{}
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Order("id").Find(&accounts, ids).Error
+	if err != nil {
+		return nil, err
+	}
+	locked := map[uint]*Account{}
+	for i := range accounts {
+		locked[accounts[i].ID] = &accounts[i]
+	}
+	for _, id := range ids {
+		if locked[id] == nil {
+			return nil, ErrAccountNotFound
+		}
+	}
+	return locked, nil
+}
+
+type gormTransfers struct {
+	db *gorm.DB
+}
+
+func (r gormTransfers) Get(ctx context.Context, id uint) (*AccountTransfer, error) {
+	t := &AccountTransfer{}
+	err := r.db.WithContext(ctx).First(t, id).Error
+	return t, notFound(err, ErrTransferNotFound)
+}
+
+func (r gormTransfers) Lock(ctx context.Context, id uint) (*AccountTransfer, error) {
+	t := &AccountTransfer{}
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(t, id).Error
+	return t, notFound(err, ErrTransferNotFound)
+}
+
+func (r gormTransfers) Create(ctx context.Context, t *AccountTransfer) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r gormTransfers) UpdateStatus(ctx context.Context, t *AccountTransfer) error {
+	return r.db.WithContext(ctx).Model(t).Update("status", t.Status).Error
+}
+
+func (r gormTransfers) UpdateReversed(ctx context.Context, t *AccountTransfer) error {
+	return r.db.WithContext(ctx).Model(t).Update("reversed_amount", t.ReversedAmount).Error
+}
+
+func (r gormTransfers) Sent(ctx context.Context, number string, since, until time.Time) ([]AccountTransfer, error) {
+	transfers := []AccountTransfer{}
+	err := r.db.WithContext(ctx).Where(&AccountTransfer{From: number, Type: TransferTypeTransfer}).
+		Where("created_at >= ? AND created_at < ?", since, until).
+		Find(&transfers).Error
+	return transfers, err
+}
+
+func (r gormTransfers) SentSince(ctx context.Context, number string, since time.Time) (float64, error) {
+	var sent float64
+	err := r.db.WithContext(ctx).Model(&AccountTransfer{}).
+		Where(&AccountTransfer{From: number, Type: TransferTypeTransfer}).
+		Where("created_at >= ?", since).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sent).Error
+	return sent, err
+}
+
+func (r gormTransfers) MostUsedCategory(ctx context.Context, cond *AccountTransfer) (string, error) {
+	var row struct {
+		Category string
+		N        int
+	}
+	err := r.db.WithContext(ctx).Model(&AccountTransfer{}).
+		Select("category, COUNT(*) AS n").
+		Where(cond).
+		Where("category <> '' AND category <> ?", CategoryUncategorized).
+		Group("category").
+		Order("n DESC, category").
+		Limit(1).
+		Scan(&row).Error
+	return row.Category, err
+}
+
+func (r gormTransfers) ApplyRules(ctx context.Context, t *AccountTransfer, fromID, toID uint) error {
+	db := r.db.WithContext(ctx)
+	if err := rule.Apply(db, rule.Outgoing, fromID, t.ID, t.Amount); err != nil {
+		return err
+	}
+	return rule.Apply(db, rule.Incoming, toID, t.ID, t.Amount)
+}
+
+// notFound turns gorm.ErrRecordNotFound into err.
+func notFound(dbErr, err error) error {
+	if errors.Is(dbErr, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return dbErr
+}
@@ -0,0 +1,248 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/approval"
+	"github.com/arthit666/make_app/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// @Summary Reverse a transfer
+// @Description Submit a compensating transfer for all or part of a completed transfer for approval
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Param reversal body account.ReversalRequest true "ReversalRequest data"
+// @Success 202 {object} approval.PendingAction
+// @Security  Bearer
+// @Router /admin/transfers/{id}/reverse [post]
+func (h *handler) ReverseTransfer(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	req := &ReversalRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	t, err := h.transfers.Get(c.UserContext(), uint(id))
+	if err != nil {
+		return adminError(err, "transfer not found")
+	}
+
+	maker := uint(c.Locals("account_id").(int))
+	p, err := approval.Submit(h.DB, h.cfg.Policy, ActionReverseTransfer, maker, ReversalAction{TransferID: t.ID, ReversalRequest: *req})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(p)
+}
+
+// @Summary Settle a pending reversal
+// @Description Retry a reversal that was left pending because the recipient was short of funds
+// @Tags admin
+// @Produce json
+// @Param id path int true "Reversal transfer ID"
+// @Success 200 {object} account.AccountTransfer
+// @Security  Bearer
+// @Router /admin/transfers/{id}/settle [post]
+func (h *handler) SettleReversal(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	r, err := h.transfers.Settle(c.UserContext(), uint(id))
+	if err != nil {
+		return adminError(err, "transfer not found")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(r)
+}
+
+func adminError(err error, notFound string) error {
+	switch {
+	case errors.Is(err, ErrTransferNotFound), errors.Is(err, ErrAccountNotFound):
+		return apperr.NotFound(apperr.CodeNotFound, notFound)
+	case errors.Is(err, ErrReversalNotPending):
+		return apperr.Conflict(apperr.CodeNotPending, err.Error())
+	case errors.Is(err, ErrInsufficientBalance):
+		return apperr.Conflict(apperr.CodeInsufficientFunds, err.Error())
+	case errors.Is(err, ErrAccountClosed):
+		return apperr.Conflict(apperr.CodeAccountClosed, err.Error())
+	case errors.Is(err, ErrNotReversible),
+		errors.Is(err, ErrAlreadyReversed),
+		errors.Is(err, ErrReversalExceedsAmount),
+		errors.Is(err, ErrAccountNotEmpty):
+		return apperr.Conflict(apperr.CodeConflict, err.Error())
+	}
+	return apperr.Internal(err)
+}
+
+// ExecuteReversal is the approval.Executor for ActionReverseTransfer,
+// reversing under policy.
+func ExecuteReversal(policy config.Policy) approval.Executor {
+	return func(tx *gorm.DB, payload []byte) (interface{}, error) {
+		a := &ReversalAction{}
+		if err := json.Unmarshal(payload, a); err != nil {
+			return nil, err
+		}
+		t, err := Reverse(tx, policy, a.TransferID, &a.ReversalRequest)
+		if err != nil {
+			return nil, adminError(err, "transfer not found")
+		}
+		return t, nil
+	}
+}
+
+// Reverse reverses transfer id through the TransferService of db.
+func Reverse(db *gorm.DB, policy config.Policy, id uint, req *ReversalRequest) (*AccountTransfer, error) {
+	return NewTransferService(NewStore(db)).Reverse(context.Background(), policy, id, req)
+}
+
+// Settle settles reversal id through the TransferService of db.
+func Settle(db *gorm.DB, id uint) (*AccountTransfer, error) {
+	return NewTransferService(NewStore(db)).Settle(context.Background(), id)
+}
+
+// Reverse moves req.Amount of transfer id, or whatever has not been reversed
+// yet when no amount is given, back to the sender. policy says what happens
+// when the original recipient cannot cover it: by default the reversal is
+// recorded as pending, to be settled later.
+func (s *TransferService) Reverse(ctx context.Context, policy config.Policy, id uint, req *ReversalRequest) (*AccountTransfer, error) {
+	r := &AccountTransfer{}
+	err := s.store.Transaction(ctx, func(store Store) error {
+		orig, err := store.Transfers().Lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if orig.Type != TransferTypeTransfer || orig.Status != TransferStatusCompleted {
+			return ErrNotReversible
+		}
+
+		reversed := decimal.NewFromFloat(orig.ReversedAmount)
+		remaining := decimal.NewFromFloat(orig.Amount).Sub(reversed)
+		if !remaining.IsPositive() {
+			return ErrAlreadyReversed
+		}
+
+		amount := remaining
+		if req.Amount > 0 {
+			amount = decimal.NewFromFloat(req.Amount)
+		}
+		if amount.GreaterThan(remaining) {
+			return ErrReversalExceedsAmount
+		}
+
+		orig.ReversedAmount, _ = reversed.Add(amount).Float64()
+		if err := store.Transfers().UpdateReversed(ctx, orig); err != nil {
+			return err
+		}
+
+		r = &AccountTransfer{
+			Type:       TransferTypeReversal,
+			Status:     TransferStatusCompleted,
+			From:       orig.To,
+			To:         orig.From,
+			ReversalOf: &orig.ID,
+			ReasonCode: req.ReasonCode,
+		}
+		r.Amount, _ = amount.Float64()
+
+		moved, err := moveReversal(ctx, store, r, policy.ReversalShortfall == "negative")
+		if err != nil {
+			return err
+		}
+		if !moved {
+			r.Status = TransferStatusPending
+		}
+
+		return store.Transfers().Create(ctx, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Settle completes reversal id if it was left pending for lack of funds.
+func (s *TransferService) Settle(ctx context.Context, id uint) (*AccountTransfer, error) {
+	var r *AccountTransfer
+	err := s.store.Transaction(ctx, func(store Store) error {
+		var err error
+		if r, err = store.Transfers().Lock(ctx, id); err != nil {
+			return err
+		}
+		if r.Type != TransferTypeReversal || r.Status != TransferStatusPending {
+			return ErrReversalNotPending
+		}
+
+		moved, err := moveReversal(ctx, store, r, false)
+		if err != nil {
+			return err
+		}
+		if !moved {
+			return ErrInsufficientBalance
+		}
+
+		r.Status = TransferStatusCompleted
+		return store.Transfers().UpdateStatus(ctx, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// moveReversal moves r.Amount from r.From back to r.To. It returns false
+// without touching either balance when r.From is short and allowNegative is
+// not set.
+func moveReversal(ctx context.Context, store Store, r *AccountTransfer, allowNegative bool) (bool, error) {
+	accounts := store.Accounts()
+	from, err := accounts.GetByNumber(ctx, r.From)
+	if err != nil {
+		return false, err
+	}
+	to, err := accounts.GetByNumber(ctx, r.To)
+	if err != nil {
+		return false, err
+	}
+	locked, err := accounts.Lock(ctx, from.ID, to.ID)
+	if err != nil {
+		return false, err
+	}
+	from, to = locked[from.ID], locked[to.ID]
+
+	amountDec := decimal.NewFromFloat(r.Amount)
+	bl := decimal.NewFromFloat(from.Balance)
+	if bl.LessThan(amountDec) && !allowNegative {
+		return false, nil
+	}
+
+	from.Balance, _ = bl.Sub(amountDec).Float64()
+	if err := accounts.UpdateBalance(ctx, from); err != nil {
+		return false, err
+	}
+
+	to.Balance, _ = decimal.NewFromFloat(to.Balance).Add(amountDec).Float64()
+	if err := accounts.UpdateBalance(ctx, to); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
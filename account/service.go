@@ -0,0 +1,290 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/arthit666/make_app/event"
+	"github.com/shopspring/decimal"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountService opens, looks up, changes and closes accounts.
+type AccountService struct {
+	store Store
+}
+
+func NewAccountService(store Store) *AccountService {
+	return &AccountService{store}
+}
+
+// Create opens a user account for req with a new account number. An
+// opening balance is recorded as a deposit, so that the balance can always
+// be worked out from the transfers.
+func (s *AccountService) Create(ctx context.Context, req *AccountRequest) (*Account, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Account{
+		Email:         req.Email,
+		Password:      string(hashed),
+		Balance:       req.Balance,
+		AccountNumber: randomAccountNumber(),
+		Role:          RoleUser,
+	}
+	err = s.store.Transaction(ctx, func(store Store) error {
+		if err := store.Accounts().Create(ctx, a); err != nil {
+			return err
+		}
+		if a.Balance <= 0 {
+			return nil
+		}
+		return store.Transfers().Create(ctx, &AccountTransfer{
+			Type:   TransferTypeDeposit,
+			Status: TransferStatusCompleted,
+			To:     a.AccountNumber,
+			Amount: a.Balance,
+			Memo:   "opening balance",
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *AccountService) Get(ctx context.Context, id uint) (*Account, error) {
+	return s.store.Accounts().Get(ctx, id)
+}
+
+// Login is the account email belongs to when password is its password.
+// Closed accounts cannot log in.
+func (s *AccountService) Login(ctx context.Context, email, password string) (*Account, error) {
+	a, err := s.store.Accounts().GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if a.Status == StatusClosed {
+		return nil, ErrAccountClosed
+	}
+	return a, nil
+}
+
+// List returns page page of count accounts, and how many there are.
+func (s *AccountService) List(ctx context.Context, page, count int) ([]Account, int64, error) {
+	return s.store.Accounts().List(ctx, (page-1)*count, count)
+}
+
+// TransferService makes transfers between accounts.
+type TransferService struct {
+	store Store
+}
+
+func NewTransferService(store Store) *TransferService {
+	return &TransferService{store}
+}
+
+// Send transfers tr.Amount from account fromID to the account numbered tr.To,
+// the way every transfer between accounts is made: closed accounts and the
+// daily limit are checked, a category is suggested when none is given, the
+// automation rules run and event.TransferSent is published, or held back
+// when ctx is held by event.Hold.
+func (s *TransferService) Send(ctx context.Context, fromID uint, tr *AccountTransferRequest) (*AccountTransfer, error) {
+	var t *AccountTransfer
+	err := s.store.Transaction(ctx, func(store Store) error {
+		var err error
+		t, err = send(ctx, store, fromID, tr)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	event.PublishContext(ctx, event.Event{Type: event.TransferSent, AccountID: fromID, Payload: *t})
+	return t, nil
+}
+
+// SendAll makes every transfer in trs from account fromID in one
+// transaction, so either all of them are made or none is. The error of a
+// failed transfer is a *BatchError telling which one failed.
+func (s *TransferService) SendAll(ctx context.Context, fromID uint, trs []*AccountTransferRequest) ([]*AccountTransfer, error) {
+	sent := []*AccountTransfer{}
+	err := s.store.Transaction(ctx, func(store Store) error {
+		for i, tr := range trs {
+			t, err := send(ctx, store, fromID, tr)
+			if err != nil {
+				return &BatchError{Index: i, Err: err}
+			}
+			sent = append(sent, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range sent {
+		event.PublishContext(ctx, event.Event{Type: event.TransferSent, AccountID: fromID, Payload: *t})
+	}
+	return sent, nil
+}
+
+// Get is transfer id.
+func (s *TransferService) Get(ctx context.Context, id uint) (*AccountTransfer, error) {
+	return s.store.Transfers().Get(ctx, id)
+}
+
+// SuggestCategory suggests a category for a transfer from account id to the
+// account numbered to.
+func (s *TransferService) SuggestCategory(ctx context.Context, id uint, to, memo string) (CategorySuggestion, error) {
+	acc, err := s.store.Accounts().Get(ctx, id)
+	if err != nil {
+		return CategorySuggestion{}, err
+	}
+	return suggest(ctx, s.store.Transfers(), acc.AccountNumber, to, memo)
+}
+
+func send(ctx context.Context, store Store, fromID uint, tr *AccountTransferRequest) (*AccountTransfer, error) {
+	t := &AccountTransfer{
+		Type:      TransferTypeTransfer,
+		Status:    TransferStatusCompleted,
+		To:        tr.To,
+		Amount:    tr.Amount,
+		Memo:      strings.TrimSpace(tr.Memo),
+		Reference: strings.TrimSpace(tr.Reference),
+		Category:  normalizeCategory(tr.Category),
+	}
+	for _, tag := range tr.Tags {
+		t.Tags = append(t.Tags, strings.TrimSpace(tag))
+	}
+
+	from, err := store.Accounts().Get(ctx, fromID)
+	if errors.Is(err, ErrAccountNotFound) {
+		return nil, ErrSenderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if from.Status == StatusClosed {
+		return nil, ErrAccountClosed
+	}
+	if from.Status == StatusFrozen {
+		return nil, ErrAccountFrozen
+	}
+	t.From = from.AccountNumber
+
+	to, err := store.Accounts().GetByNumber(ctx, t.To)
+	if errors.Is(err, ErrAccountNotFound) {
+		return nil, ErrRecipientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if to.ID == from.ID {
+		return nil, ErrSelfTransfer
+	}
+
+	// Both balances are re-read with their rows locked, so that transfers
+	// made at the same time cannot spend the same money twice.
+	locked, err := store.Accounts().Lock(ctx, from.ID, to.ID)
+	if err != nil {
+		return nil, err
+	}
+	from, to = locked[from.ID], locked[to.ID]
+	if to.Status == StatusClosed {
+		return nil, ErrRecipientClosed
+	}
+
+	if t.Category == "" {
+		s, err := suggest(ctx, store.Transfers(), t.From, t.To, t.Memo)
+		if err != nil {
+			return nil, err
+		}
+		t.Category = s.Category
+	}
+
+	if err := checkTransferLimit(ctx, store.Transfers(), from, t.Amount); err != nil {
+		return nil, err
+	}
+
+	amount := decimal.NewFromFloat(t.Amount)
+	bl := decimal.NewFromFloat(from.Balance)
+	if bl.LessThan(amount) {
+		return nil, ErrInsufficientBalance
+	}
+	from.Balance, _ = bl.Sub(amount).Float64()
+	if err := store.Accounts().UpdateBalance(ctx, from); err != nil {
+		return nil, err
+	}
+	to.Balance, _ = decimal.NewFromFloat(to.Balance).Add(amount).Float64()
+	if err := store.Accounts().UpdateBalance(ctx, to); err != nil {
+		return nil, err
+	}
+
+	if err := store.Transfers().Create(ctx, t); err != nil {
+		return nil, err
+	}
+	if err := store.Transfers().ApplyRules(ctx, t, from.ID, to.ID); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// checkTransferLimit makes sure sending amount keeps from within its daily
+// transfer limit, counting every transfer it sent since midnight.
+func checkTransferLimit(ctx context.Context, transfers TransferRepository, from *Account, amount float64) error {
+	if from.TransferLimit <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	sent, err := transfers.SentSince(ctx, from.AccountNumber, midnight)
+	if err != nil {
+		return err
+	}
+
+	total := decimal.NewFromFloat(sent).Add(decimal.NewFromFloat(amount))
+	if total.GreaterThan(decimal.NewFromFloat(from.TransferLimit)) {
+		return ErrTransferLimitExceeded
+	}
+	return nil
+}
+
+// suggest picks a category for a transfer from account from to account to.
+// It prefers the category from used most for to before, then the one
+// everybody else used most for to, then the memo rules.
+func suggest(ctx context.Context, transfers TransferRepository, from, to, memo string) (CategorySuggestion, error) {
+	c, err := transfers.MostUsedCategory(ctx, &AccountTransfer{From: from, To: to, Type: TransferTypeTransfer})
+	if err != nil || c != "" {
+		return CategorySuggestion{Category: c, Source: SuggestionHistory}, err
+	}
+
+	c, err = transfers.MostUsedCategory(ctx, &AccountTransfer{To: to, Type: TransferTypeTransfer})
+	if err != nil || c != "" {
+		return CategorySuggestion{Category: c, Source: SuggestionCounterparty}, err
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(memo), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, r := range memoRules {
+		for _, w := range words {
+			for _, k := range r.words {
+				if w == k {
+					return CategorySuggestion{Category: r.category, Source: SuggestionMemo}, nil
+				}
+			}
+		}
+	}
+
+	return CategorySuggestion{Category: CategoryUncategorized}, nil
+}
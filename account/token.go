@@ -1,10 +1,13 @@
 package account
 
 import (
-	"os"
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 	"time"
 
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/config"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -20,57 +23,112 @@ import (
 func (h *handler) RefreshAccessToken(c *fiber.Ctx) error {
 	refreshTokenStr := c.Get("X-Refresh-Token")
 	if refreshTokenStr == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(Err{Massage: "missing refresh token"})
+		return apperr.Unauthenticated("missing refresh token")
 	}
 
 	refreshTokenStr = strings.TrimPrefix(refreshTokenStr, "Bearer ")
 
-	refreshToken, err := jwt.Parse(refreshTokenStr, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	refreshToken, err := jwt.Parse(refreshTokenStr, Keyfunc(h.cfg.JWT))
 	if err != nil || !refreshToken.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(Err{Massage: "invalid refresh token"})
+		return apperr.Unauthenticated("invalid refresh token")
 	}
 
 	claims, ok := refreshToken.Claims.(jwt.MapClaims)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(Err{Massage: "invalid refresh token"})
+		return apperr.Unauthenticated("invalid refresh token")
 	}
 
 	exp, ok := claims["exp"].(float64)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(Err{Massage: "invalid refresh token"})
+		return apperr.Unauthenticated("invalid refresh token")
 	}
 
 	expTime := time.Unix(int64(exp), 0)
 	if time.Now().After(expTime) {
-		return c.Status(fiber.StatusUnauthorized).JSON(Err{Massage: "refresh token expired"})
+		return apperr.Unauthenticated("refresh token expired")
 	}
 
-	act, err := GenerateToken(uint(claims["account_id"].(float64)), time.Now().Add(time.Minute*15).Unix())
+	accId, ok := claims["account_id"].(float64)
+	if !ok {
+		return apperr.Unauthenticated("invalid refresh token")
+	}
+
+	acc, err := h.accounts.Get(c.UserContext(), uint(accId))
+	if err != nil {
+		return apperr.Unauthenticated("invalid refresh token")
+	}
+	if acc.Status == StatusClosed {
+		return apperr.Unauthenticated(ErrAccountClosed.Error())
+	}
+
+	tokens, err := IssueTokens(h.cfg.JWT, acc)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.JSON(tokens)
+}
+
+// IssueTokens signs a new access and refresh token for acc, valid for as
+// long as jwt says.
+func IssueTokens(jwt config.JWT, acc *Account) (*TokenResponse, error) {
+	now := time.Now()
+	act, err := GenerateToken(jwt.Secret, acc.ID, acc.Role, now.Add(jwt.AccessTTL).Unix())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
+		return nil, err
 	}
 
-	rft, err := GenerateToken(uint(claims["account_id"].(float64)), time.Now().Add(time.Hour*24).Unix())
+	rft, err := GenerateToken(jwt.Secret, acc.ID, acc.Role, now.Add(jwt.RefreshTTL).Unix())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
+		return nil, err
 	}
 
-	return c.JSON(TokenResponse{
-		AccessToken:  *act,
-		RefreshToken: *rft,
-	})
+	return &TokenResponse{AccessToken: *act, RefreshToken: *rft}, nil
 }
 
-func GenerateToken(accId uint, exp int64) (*string, error) {
+func GenerateToken(secret string, accId uint, role string, exp int64) (*string, error) {
 	tk := jwt.New(jwt.SigningMethodHS256)
+	tk.Header["kid"] = KeyID(secret)
 	cl := tk.Claims.(jwt.MapClaims)
 	cl["account_id"] = accId
+	cl["role"] = role
 	cl["exp"] = exp
-	tkStr, err := tk.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tkStr, err := tk.SignedString([]byte(secret))
 	if err != nil {
 		return nil, err
 	}
 	return &tkStr, nil
 }
+
+// KeyID names secret in the kid header of the tokens it signs, without
+// giving it away.
+func KeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:4])
+}
+
+// SigningKeys are the keys tokens are verified with by key ID: the current
+// secret and the ones rotated out.
+func SigningKeys(cfg config.JWT) map[string]interface{} {
+	keys := map[string]interface{}{KeyID(cfg.Secret): []byte(cfg.Secret)}
+	for _, secret := range cfg.PreviousSecrets {
+		keys[KeyID(secret)] = []byte(secret)
+	}
+	return keys
+}
+
+// Keyfunc verifies a token with the key of SigningKeys its kid header names.
+func Keyfunc(cfg config.JWT) jwt.Keyfunc {
+	keys := SigningKeys(cfg)
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys[kid]
+		if !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return key, nil
+	}
+}
@@ -3,11 +3,9 @@ package account
 import (
 	"errors"
 	"fmt"
-	"strconv"
 
-	"github.com/go-playground/validator"
+	"github.com/arthit666/make_app/apperr"
 	"github.com/gofiber/fiber/v2"
-	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -24,90 +22,64 @@ func (h *handler) Transfer(c *fiber.Ctx) error {
 	tr := &AccountTransferRequest{}
 
 	if err := c.BodyParser(tr); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.ErrBadRequest)
+		return apperr.BadRequest("invalid request body")
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(tr); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(Err{Massage: "payload invalid: " + err.Error()})
-	}
-
-	t := &AccountTransfer{
-		To:     tr.To,
-		Amount: tr.Amount,
+	if err := apperr.Validate(tr); err != nil {
+		return err
 	}
 
 	acc := c.Locals("account_id").(int)
-
-	accStr := strconv.Itoa(acc)
-
-	fpock, err := getById(accStr, h)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(Err{Massage: "from account not found"})
-		}
-
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
-	}
-
-	t.From = fpock.AccountNumber
-
-	tpock, err := getByAccountNumber(t.To, h)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(Err{Massage: "target account not found"})
-		}
-
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
-	}
-
-	if err = transferBalance(h, fpock, tpock, t.Amount); err != nil {
-		fmt.Println(err)
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + err.Error()})
-	}
-
-	tx := h.DB.Create(t)
-	if tx.Error != nil {
-
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Massage: "error: " + tx.Error.Error()})
+	if _, err := h.transfers.Send(c.UserContext(), uint(acc), tr); err != nil {
+		return transferError(err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(SuccessResponse{Message: "transfer success"})
 }
 
-func transferBalance(h *handler, from, to *Account, amount float64) error {
-	amountDec := decimal.NewFromFloat(amount)
-
-	bl := decimal.NewFromFloat(from.Balance)
-	if bl.LessThan(amountDec) {
-		return fmt.Errorf("insufficient balance in source account")
+func transferError(err error) error {
+	switch {
+	case errors.Is(err, ErrSenderNotFound), errors.Is(err, ErrRecipientNotFound):
+		return apperr.NotFound(apperr.CodeAccountNotFound, err.Error())
+	case errors.Is(err, ErrAccountClosed):
+		return apperr.New(fiber.StatusForbidden, apperr.CodeAccountClosed, err.Error())
+	case errors.Is(err, ErrAccountFrozen):
+		return apperr.New(fiber.StatusForbidden, apperr.CodeAccountFrozen, err.Error())
+	case errors.Is(err, ErrSelfTransfer):
+		return apperr.BadRequest(err.Error())
+	case errors.Is(err, ErrRecipientClosed):
+		return apperr.Unprocessable(apperr.CodeAccountClosed, err.Error())
+	case errors.Is(err, ErrTransferLimitExceeded):
+		return apperr.Unprocessable(apperr.CodeLimitExceeded, err.Error())
+	case errors.Is(err, ErrInsufficientBalance):
+		return apperr.Unprocessable(apperr.CodeInsufficientFunds, err.Error())
 	}
+	return apperr.Internal(err)
+}
 
-	tx := h.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+// Send makes a transfer through the TransferService of db, with the context
+// of db. Inside a transaction, that context should be held by event.Hold, so
+// that the transfer is only published once the transaction is committed.
+func Send(db *gorm.DB, fromID uint, tr *AccountTransferRequest) (*AccountTransfer, error) {
+	return NewTransferService(NewStore(db)).Send(db.Statement.Context, fromID, tr)
+}
 
-	if err := tx.Error; err != nil {
-		return err
-	}
+// SendAll makes transfers all at once through the TransferService of db,
+// with the context of db like Send.
+func SendAll(db *gorm.DB, fromID uint, trs []*AccountTransferRequest) ([]*AccountTransfer, error) {
+	return NewTransferService(NewStore(db)).SendAll(db.Statement.Context, fromID, trs)
+}
 
-	bl = bl.Sub(amountDec)
-	from.Balance, _ = bl.Float64()
-	if err := tx.Save(from).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+// BatchError is the error of the transfer at Index when SendAll fails.
+type BatchError struct {
+	Index int
+	Err   error
+}
 
-	bl = decimal.NewFromFloat(to.Balance)
-	bl = bl.Add(amountDec)
-	to.Balance, _ = bl.Float64()
-	if err := tx.Save(to).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("transfer %d: %s", e.Index+1, e.Err)
+}
 
-	return tx.Commit().Error
+func (e *BatchError) Unwrap() error {
+	return e.Err
 }
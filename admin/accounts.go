@@ -0,0 +1,311 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/approval"
+	"github.com/arthit666/make_app/audit"
+	"github.com/arthit666/make_app/ledger"
+	"gorm.io/gorm"
+)
+
+// notFound tells which account could not be found, which gorm does not.
+func notFound(err error, id uint) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, account.ErrAccountNotFound) {
+		return fmt.Errorf("account %d not found", id)
+	}
+	return err
+}
+
+func accountView(acc *account.Account) view {
+	pockets := 0.0
+	for _, p := range acc.PocketList {
+		pockets += p.Balance
+	}
+	return view{
+		v: account.AccountResponse{
+			ID:            acc.ID,
+			Email:         acc.Email,
+			AccountNumber: acc.AccountNumber,
+			Alias:         acc.Alias,
+			PocketList:    acc.PocketList,
+			Balance:       acc.Balance,
+			Status:        acc.Status,
+			TransferLimit: acc.TransferLimit,
+		},
+		header: []string{"ID", "EMAIL", "NUMBER", "ROLE", "STATUS", "BALANCE", "POCKETS", "LIMIT"},
+		rows: [][]string{{
+			strconv.FormatUint(uint64(acc.ID), 10), acc.Email, acc.AccountNumber, acc.Role, acc.Status,
+			money(acc.Balance), money(pockets), money(acc.TransferLimit),
+		}},
+	}
+}
+
+func (r *runner) createAccount(args []string) error {
+	fs := r.flags("account create")
+	req := &account.AccountRequest{}
+	fs.StringVar(&req.Email, "email", "", "email of the account")
+	fs.StringVar(&req.Password, "password", "", "password of the account")
+	fs.Float64Var(&req.Balance, "balance", 0, "opening balance")
+	role := fs.String("role", account.RoleUser, "user, admin or auditor")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return ErrUsage
+	}
+	if err := validate(req); err != nil {
+		return err
+	}
+	if req.Balance < 0 {
+		return errors.New("balance must be at least 0")
+	}
+	if *role != account.RoleUser && *role != account.RoleAdmin && *role != account.RoleAuditor {
+		return errors.New("role must be user, admin or auditor")
+	}
+
+	return r.change(func(tx *gorm.DB) (view, error) {
+		acc, err := account.NewAccountService(account.NewStore(tx)).Create(context.Background(), req)
+		if err != nil {
+			return view{}, err
+		}
+		if *role != account.RoleUser {
+			acc.Role = *role
+			if err := tx.Model(acc).Update("role", acc.Role).Error; err != nil {
+				return view{}, err
+			}
+		}
+		target := fmt.Sprintf("accounts:%d", acc.ID)
+		if err := record(tx, "admin.account.create", target, nil, audit.AccountSnapshot(tx, "id = ?", acc.ID)); err != nil {
+			return view{}, err
+		}
+		return accountView(acc), nil
+	})
+}
+
+func (r *runner) showAccount(args []string) error {
+	rest, err := parse(r.flags("account show"), args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(rest)
+	if err != nil {
+		return err
+	}
+
+	acc := &account.Account{}
+	if err := r.db.Preload("PocketList").First(acc, id).Error; err != nil {
+		return notFound(err, id)
+	}
+	return r.show(accountView(acc))
+}
+
+func (r *runner) freezeAccount(args []string) error {
+	return r.setStatus("account freeze", "admin.account.freeze", account.Freeze, args)
+}
+
+func (r *runner) unfreezeAccount(args []string) error {
+	return r.setStatus("account unfreeze", "admin.account.unfreeze", account.Unfreeze, args)
+}
+
+func (r *runner) setStatus(name, action string, set func(db *gorm.DB, id uint) (*account.Account, error), args []string) error {
+	rest, err := parse(r.flags(name), args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(rest)
+	if err != nil {
+		return err
+	}
+
+	return r.change(func(tx *gorm.DB) (view, error) {
+		before := audit.AccountSnapshot(tx, "id = ?", id)
+		acc, err := set(tx, id)
+		if err != nil {
+			return view{}, notFound(err, id)
+		}
+		if err := record(tx, action, fmt.Sprintf("accounts:%d", id), before, audit.AccountSnapshot(tx, "id = ?", id)); err != nil {
+			return view{}, err
+		}
+		return accountView(acc), nil
+	})
+}
+
+func (r *runner) adjustBalance(args []string) error {
+	fs := r.flags("balance adjust")
+	req := &account.AdjustmentRequest{}
+	fs.Float64Var(&req.Amount, "amount", 0, "amount to credit, or debit when negative")
+	fs.StringVar(&req.ReasonCode, "reason", "", "CORRECTION, FEE, GOODWILL or REFUND")
+	fs.StringVar(&req.Memo, "memo", "", "what the adjustment is for")
+	maker := fs.Uint("maker", 0, "ID of the admin submitting the adjustment")
+	force := fs.Bool("force", false, "adjust right away without a second admin, in an emergency")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(rest)
+	if err != nil {
+		return err
+	}
+	if err := validate(req); err != nil {
+		return err
+	}
+	if *maker == 0 && !*force {
+		return errors.New("maker is required unless the adjustment is forced")
+	}
+
+	// Like on the API, an adjustment waits for a second admin. Forcing it is
+	// the break-glass way around that, and is audited as such.
+	if *force {
+		return r.change(func(tx *gorm.DB) (view, error) {
+			before := audit.AccountSnapshot(tx, "id = ?", id)
+			t, err := account.Adjust(tx, id, req)
+			if err != nil {
+				return view{}, notFound(err, id)
+			}
+			if err := record(tx, "admin.account.adjust.force", fmt.Sprintf("accounts:%d", id), before, audit.AccountSnapshot(tx, "id = ?", id)); err != nil {
+				return view{}, err
+			}
+			return transfersView([]account.AccountTransfer{*t}), nil
+		})
+	}
+
+	return r.change(func(tx *gorm.DB) (view, error) {
+		by := &account.Account{}
+		if err := tx.First(by, *maker).Error; err != nil {
+			return view{}, notFound(err, *maker)
+		}
+		if by.Role != account.RoleAdmin || by.Status == account.StatusClosed {
+			return view{}, fmt.Errorf("account %d is not an admin", *maker)
+		}
+		if err := tx.First(&account.Account{}, id).Error; err != nil {
+			return view{}, notFound(err, id)
+		}
+
+		p, err := approval.Submit(tx, r.cfg.Policy, account.ActionAdjustBalance, by.ID, account.AdjustmentAction{AccountID: id, AdjustmentRequest: *req})
+		if err != nil {
+			return view{}, err
+		}
+		if err := record(tx, "admin.account.adjust", fmt.Sprintf("accounts:%d", id), nil, p); err != nil {
+			return view{}, err
+		}
+		return pendingView(p), nil
+	})
+}
+
+func pendingView(p *approval.PendingAction) view {
+	return view{
+		v:      p,
+		header: []string{"ID", "ACTION", "MAKER", "STATUS", "EXPIRES"},
+		rows: [][]string{{
+			strconv.FormatUint(uint64(p.ID), 10), p.Action, strconv.FormatUint(uint64(p.MakerID), 10), p.Status,
+			p.ExpiresAt.Format(time.RFC3339),
+		}},
+	}
+}
+
+func (r *runner) rebuildBalances(args []string) error {
+	rest, err := parse(r.flags("balance rebuild"), args)
+	if err != nil {
+		return err
+	}
+	var id uint
+	if len(rest) > 0 {
+		if id, err = parseID(rest); err != nil {
+			return err
+		}
+	}
+
+	return r.change(func(tx *gorm.DB) (view, error) {
+		changed, err := ledger.Rebuild(tx, id)
+		if err != nil {
+			return view{}, notFound(err, id)
+		}
+		if len(changed) > 0 {
+			target := "accounts"
+			if id != 0 {
+				target = fmt.Sprintf("accounts:%d", id)
+			}
+			if err := record(tx, "admin.balance.rebuild", target, nil, changed); err != nil {
+				return view{}, err
+			}
+		}
+		return balancesView(changed), nil
+	})
+}
+
+// snapshot is how many balances balance snapshot kept at the end of a day.
+type snapshot struct {
+	Day      string    `json:"day"`
+	At       time.Time `json:"at"`
+	Balances int       `json:"balances"`
+}
+
+func (r *runner) snapshotBalances(args []string) error {
+	fs := r.flags("balance snapshot")
+	first := fs.String("from", "", "first day (YYYY-MM-DD), the last by default")
+	last := fs.String("to", "", "last day (YYYY-MM-DD), yesterday by default")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return ErrUsage
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location())
+	if *last != "" {
+		if to, err = time.ParseInLocation("2006-01-02", *last, now.Location()); err != nil {
+			return errors.New("to must be YYYY-MM-DD")
+		}
+	}
+	from := to
+	if *first != "" {
+		if from, err = time.ParseInLocation("2006-01-02", *first, now.Location()); err != nil {
+			return errors.New("from must be YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return errors.New("from must not be after to")
+	}
+	if ledger.EndOfDay(to).After(now) {
+		return errors.New("the snapshot of a day can only be taken once the day is over")
+	}
+
+	return r.change(func(tx *gorm.DB) (view, error) {
+		taken := []snapshot{}
+		v := view{header: []string{"DAY", "AT", "BALANCES"}}
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			n, err := ledger.Snapshot(tx, day)
+			if err != nil {
+				return view{}, err
+			}
+			s := snapshot{Day: day.Format("2006-01-02"), At: ledger.EndOfDay(day), Balances: n}
+			taken = append(taken, s)
+			v.rows = append(v.rows, []string{s.Day, s.At.Format(time.RFC3339), strconv.Itoa(n)})
+		}
+		if err := record(tx, "admin.balance.snapshot", "balance_snapshots", nil, taken); err != nil {
+			return view{}, err
+		}
+		v.v = taken
+		return v, nil
+	})
+}
+
+func balancesView(balances []ledger.Balance) view {
+	v := view{v: balances, header: []string{"KIND", "ID", "ACCOUNT", "NAME", "RECORDED", "EXPECTED"}}
+	for _, b := range balances {
+		v.rows = append(v.rows, []string{
+			b.Kind, strconv.FormatUint(uint64(b.ID), 10), strconv.FormatUint(uint64(b.AccountID), 10),
+			b.Name, money(b.Recorded), money(b.Expected),
+		})
+	}
+	return v
+}
@@ -0,0 +1,241 @@
+// Package admin is the command line the bank is operated with. It works on
+// the database through the same services as the APIs rather than through
+// HTTP, and records every change it makes in the audit log.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/audit"
+	"github.com/arthit666/make_app/config"
+	"github.com/arthit666/make_app/migrate"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Usage explains the arguments of Run.
+const Usage = `usage: admin [-output table|json] [-dry-run] <command>
+
+  account create -email <email> -password <password> [-balance <amount>] [-role user|admin|auditor]
+  account show <id>
+  account freeze <id>
+  account unfreeze <id>
+  balance adjust <id> -amount <amount> -reason CORRECTION|FEE|GOODWILL|REFUND [-memo <text>] -maker <admin id> | -force
+  balance rebuild [<id>]
+  balance snapshot [-from YYYY-MM-DD] [-to YYYY-MM-DD]
+  transfer show <id>
+  transfer list -account <number> [-limit <n>]
+  statement export <id> -format mt940|ofx|camt053 [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-file <path>]
+  jwt rotate
+  reconcile run
+  reconcile show <id>
+  reconcile list [-failed] [-limit <n>]
+  migrate up|down|status|to <version>
+
+With -dry-run, changes are shown and then rolled back. A balance adjustment
+waits for a second admin to approve it, unless it is forced.`
+
+var ErrUsage = errors.New(Usage)
+
+// userAgent is what the audit records of the command line are made with.
+const userAgent = "admin-cli"
+
+// errDryRun rolls back the transaction of a change on a dry run.
+var errDryRun = errors.New("dry run")
+
+var commands = map[string]func(r *runner, args []string) error{
+	"account create":   (*runner).createAccount,
+	"account show":     (*runner).showAccount,
+	"account freeze":   (*runner).freezeAccount,
+	"account unfreeze": (*runner).unfreezeAccount,
+	"balance adjust":   (*runner).adjustBalance,
+	"balance rebuild":  (*runner).rebuildBalances,
+	"balance snapshot": (*runner).snapshotBalances,
+	"transfer show":    (*runner).showTransfer,
+	"transfer list":    (*runner).listTransfers,
+	"statement export": (*runner).exportStatement,
+	"jwt rotate":       (*runner).rotateKeys,
+	"reconcile run":    (*runner).runReconciliation,
+	"reconcile show":   (*runner).showReconciliation,
+	"reconcile list":   (*runner).listReconciliations,
+}
+
+type runner struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	w      io.Writer
+	output string
+	dryRun bool
+}
+
+// Run runs the admin command given by args against db, and writes what it
+// did to w. The actions it submits for approval are registered first.
+func Run(db *gorm.DB, cfg *config.Config, args []string, w io.Writer) error {
+	account.RegisterActions(cfg.Policy)
+	r := &runner{db: db, cfg: cfg, w: w}
+	fs := r.flags("admin")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%v\n%w", err, ErrUsage)
+	}
+	args = fs.Args()
+
+	if len(args) > 0 && args[0] == "migrate" {
+		return migrate.Command(db, args[1:], w)
+	}
+	if len(args) < 2 || commands[args[0]+" "+args[1]] == nil {
+		return ErrUsage
+	}
+	return commands[args[0]+" "+args[1]](r, args[2:])
+}
+
+// flags is the flag set of command name, with the -output and -dry-run
+// flags every command takes.
+func (r *runner) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Func("output", "table or json", func(value string) error {
+		if value != "table" && value != "json" {
+			return errors.New("output must be table or json")
+		}
+		r.output = value
+		return nil
+	})
+	fs.BoolVar(&r.dryRun, "dry-run", r.dryRun, "show what a change would do and roll it back")
+	return fs
+}
+
+// parse parses the flags of fs in args, wherever they are, and returns the
+// arguments that are not flags.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	rest := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%v\n%w", err, ErrUsage)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// parseID reads the one ID args should hold.
+func parseID(args []string) (uint, error) {
+	if len(args) != 1 {
+		return 0, ErrUsage
+	}
+	n, err := strconv.ParseUint(args[0], 10, 0)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("%q is not an ID", args[0])
+	}
+	return uint(n), nil
+}
+
+// view is what a command shows: v as JSON, or a table of header and rows,
+// followed by the table of details when there is one.
+type view struct {
+	v       interface{}
+	header  []string
+	rows    [][]string
+	details *view
+}
+
+func (r *runner) show(v view) error {
+	if r.output == "json" {
+		enc := json.NewEncoder(r.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v.v)
+	}
+	tw := tabwriter.NewWriter(r.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(v.header, "\t"))
+	for _, row := range v.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if v.details == nil {
+		return nil
+	}
+	fmt.Fprintln(r.w)
+	return r.show(*v.details)
+}
+
+// change runs fn in a transaction and shows what it returns. On a dry run
+// the transaction is rolled back after fn.
+func (r *runner) change(fn func(tx *gorm.DB) (view, error)) error {
+	var v view
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if v, err = fn(tx); err != nil {
+			return err
+		}
+		if r.dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return err
+	}
+	if err := r.show(v); err != nil {
+		return err
+	}
+	if r.dryRun && r.output != "json" {
+		fmt.Fprintln(r.w, "dry run: nothing was changed")
+	}
+	return nil
+}
+
+// record appends what a command changed on target to the audit log.
+func record(tx *gorm.DB, action, target string, before, after interface{}) error {
+	return audit.Append(tx, &audit.Record{
+		UserAgent: userAgent,
+		Action:    action,
+		Target:    target,
+		Status:    fiber.StatusOK,
+		Before:    encode(before),
+		After:     encode(after),
+	})
+}
+
+func encode(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// validate checks v the way the APIs check requests, telling every field
+// that is wrong.
+func validate(v interface{}) error {
+	err := apperr.Validate(v)
+	var ae *apperr.Error
+	if !errors.As(err, &ae) || len(ae.Fields) == 0 {
+		return err
+	}
+	msgs := []string{}
+	for _, f := range ae.Fields {
+		msgs = append(msgs, f.Field+" "+f.Message)
+	}
+	return errors.New(strings.Join(msgs, ", "))
+}
+
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,308 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/approval"
+	"github.com/arthit666/make_app/audit"
+	"github.com/arthit666/make_app/config"
+	"github.com/arthit666/make_app/event"
+	"github.com/arthit666/make_app/ledger"
+	"github.com/arthit666/make_app/migrate"
+	"github.com/arthit666/make_app/notification"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/reconciliation"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) (*gorm.DB, *config.Config) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "bank.db")), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, migrate.Command(db, []string{"up"}, &bytes.Buffer{}))
+	cfg := config.Default()
+	cfg.JWT.Secret = "secret"
+	return db, cfg
+}
+
+func TestAccount(t *testing.T) {
+	// Arrange
+	db, cfg := setup(t)
+	out := &bytes.Buffer{}
+	created := account.AccountResponse{}
+	db.Create(&account.Account{Email: "b@test.com", AccountNumber: "1000000002"})
+
+	// Act
+	createErr := Run(db, cfg, []string{"-output", "json", "account", "create", "-email", "ops@test.com", "-password", "secret", "-balance", "100"}, out)
+	json.Unmarshal(out.Bytes(), &created)
+	dryErr := Run(db, cfg, []string{"account", "freeze", "-dry-run", "2"}, &bytes.Buffer{})
+	_, dryTransferErr := account.Send(db, created.ID, &account.AccountTransferRequest{To: "1000000002", Amount: 1})
+	freezeErr := Run(db, cfg, []string{"account", "freeze", "2"}, &bytes.Buffer{})
+	_, frozenErr := account.Send(db, created.ID, &account.AccountTransferRequest{To: "1000000002", Amount: 1})
+	out.Reset()
+	unfreezeErr := Run(db, cfg, []string{"account", "unfreeze", "2"}, out)
+	missingErr := Run(db, cfg, []string{"account", "freeze", "7"}, &bytes.Buffer{})
+	invalidErr := Run(db, cfg, []string{"account", "create", "-email", "ops@test.com"}, &bytes.Buffer{})
+
+	// Assert
+	assert.NoError(t, createErr)
+	assert.Equal(t, "ops@test.com", created.Email)
+	assert.Equal(t, 100.0, created.Balance)
+	assert.NoError(t, dryErr)
+	assert.NoError(t, dryTransferErr)
+	assert.NoError(t, freezeErr)
+	assert.ErrorIs(t, frozenErr, account.ErrAccountFrozen)
+	assert.NoError(t, unfreezeErr)
+	assert.Contains(t, out.String(), "ops@test.com")
+	assert.Contains(t, out.String(), account.StatusActive)
+	assert.EqualError(t, missingErr, "account 7 not found")
+	assert.EqualError(t, invalidErr, "password is required")
+
+	actions := []string{}
+	db.Model(&audit.Record{}).Order("id").Pluck("action", &actions)
+	assert.Equal(t, []string{"admin.account.create", "admin.account.freeze", "admin.account.unfreeze"}, actions)
+}
+
+func TestBalance(t *testing.T) {
+	// Arrange
+	db, cfg := setup(t)
+	acc := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 100}
+	db.Create(acc)
+	db.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: acc.AccountNumber, Amount: 100})
+	p := &pocket.Pocket{Title: "Holiday", Balance: 5, AccountID: acc.ID}
+	db.Create(p)
+	db.Create(&account.Account{Email: "ops@test.com", AccountNumber: "1000000009", Role: account.RoleAdmin})
+	out := &bytes.Buffer{}
+
+	// Act
+	submitErr := Run(db, cfg, []string{"-output", "json", "balance", "adjust", "1", "-amount", "-20", "-reason", "FEE", "-maker", "2"}, out)
+	submitted := approval.PendingAction{}
+	json.Unmarshal(out.Bytes(), &submitted)
+	submittedBalance := account.Account{}
+	db.First(&submittedBalance, acc.ID)
+	notAdminErr := Run(db, cfg, []string{"balance", "adjust", "1", "-amount", "-20", "-reason", "FEE", "-maker", "1"}, &bytes.Buffer{})
+	noMakerErr := Run(db, cfg, []string{"balance", "adjust", "1", "-amount", "-20", "-reason", "FEE"}, &bytes.Buffer{})
+	out.Reset()
+	adjustErr := Run(db, cfg, []string{"balance", "adjust", "1", "-amount", "-20", "-reason", "FEE", "-memo", "card fee", "-force"}, out)
+	adjusted := out.String()
+	forced := audit.Record{}
+	db.Where("action = ?", "admin.account.adjust.force").First(&forced)
+	badReasonErr := Run(db, cfg, []string{"balance", "adjust", "1", "-amount", "5", "-reason", "GIFT", "-force"}, &bytes.Buffer{})
+	out.Reset()
+	dryErr := Run(db, cfg, []string{"-dry-run", "balance", "rebuild"}, out)
+	dry := out.String()
+	dryPocket := pocket.Pocket{}
+	db.First(&dryPocket, p.ID)
+	out.Reset()
+	rebuildErr := Run(db, cfg, []string{"-output", "json", "balance", "rebuild", "1"}, out)
+	rebuilt := pocket.Pocket{}
+	db.First(&rebuilt, p.ID)
+	after := account.Account{}
+	db.First(&after, acc.ID)
+
+	// Assert
+	assert.NoError(t, submitErr)
+	assert.Equal(t, account.ActionAdjustBalance, submitted.Action)
+	assert.Equal(t, approval.StatusPending, submitted.Status)
+	assert.Equal(t, uint(2), submitted.MakerID)
+	assert.Equal(t, 100.0, submittedBalance.Balance)
+	assert.EqualError(t, notAdminErr, "account 1 is not an admin")
+	assert.EqualError(t, noMakerErr, "maker is required unless the adjustment is forced")
+	assert.NoError(t, adjustErr)
+	assert.Equal(t, "accounts:1", forced.Target)
+	assert.Contains(t, adjusted, "adjustment")
+	assert.Contains(t, adjusted, "card fee")
+	assert.EqualError(t, badReasonErr, "reason_code must be one of CORRECTION, FEE, GOODWILL, REFUND")
+	assert.NoError(t, dryErr)
+	assert.Contains(t, dry, "pocket  1   1        Holiday  5.00      0.00")
+	assert.Contains(t, dry, "dry run: nothing was changed")
+	assert.Equal(t, 5.0, dryPocket.Balance)
+	assert.NoError(t, rebuildErr)
+	assert.Contains(t, out.String(), `"expected": 0`)
+	assert.Equal(t, 0.0, rebuilt.Balance)
+	assert.Equal(t, 80.0, after.Balance)
+}
+
+func TestSnapshot(t *testing.T) {
+	// Arrange
+	db, cfg := setup(t)
+	acc := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 100, CreatedAt: time.Date(2026, 9, 1, 9, 0, 0, 0, time.Local)}
+	db.Create(acc)
+	db.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: acc.AccountNumber, Amount: 100, CreatedAt: time.Date(2026, 9, 1, 10, 0, 0, 0, time.Local)})
+	out := &bytes.Buffer{}
+
+	// Act
+	err := Run(db, cfg, []string{"balance", "snapshot", "-from", "2026-09-01", "-to", "2026-09-03"}, out)
+	var taken int64
+	db.Model(&ledger.BalanceSnapshot{}).Where("balance = ?", 100).Count(&taken)
+	orderErr := Run(db, cfg, []string{"balance", "snapshot", "-from", "2026-09-03", "-to", "2026-09-01"}, &bytes.Buffer{})
+	futureErr := Run(db, cfg, []string{"balance", "snapshot", "-to", "2999-01-01"}, &bytes.Buffer{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "2026-09-03")
+	assert.Equal(t, 4, strings.Count(out.String(), "\n"))
+	assert.Equal(t, int64(3), taken)
+	assert.EqualError(t, orderErr, "from must not be after to")
+	assert.EqualError(t, futureErr, "the snapshot of a day can only be taken once the day is over")
+}
+
+func TestTransfer(t *testing.T) {
+	// Arrange
+	db, cfg := setup(t)
+	db.Create(&account.AccountTransfer{Type: account.TransferTypeTransfer, From: "1000000001", To: "1000000002", Amount: 12.5, Memo: "lunch"})
+	db.Create(&account.AccountTransfer{Type: account.TransferTypeTransfer, From: "1000000003", To: "1000000004", Amount: 3})
+	show := &bytes.Buffer{}
+	list := &bytes.Buffer{}
+
+	// Act
+	showErr := Run(db, cfg, []string{"transfer", "show", "1"}, show)
+	listErr := Run(db, cfg, []string{"transfer", "list", "-account", "1000000002"}, list)
+	missingErr := Run(db, cfg, []string{"transfer", "show", "9"}, &bytes.Buffer{})
+
+	// Assert
+	assert.NoError(t, showErr)
+	assert.Contains(t, show.String(), "12.50")
+	assert.Contains(t, show.String(), "lunch")
+	assert.NoError(t, listErr)
+	assert.Equal(t, 2, strings.Count(list.String(), "\n"))
+	assert.EqualError(t, missingErr, "transfer 9 not found")
+}
+
+func TestStatementExport(t *testing.T) {
+	// Arrange
+	db, cfg := setup(t)
+	acc := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 100}
+	db.Create(acc)
+	db.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: acc.AccountNumber, Amount: 100})
+	file := filepath.Join(t.TempDir(), "statement.sta")
+	out := &bytes.Buffer{}
+
+	// Act
+	err := Run(db, cfg, []string{"statement", "export", "1", "-format", "mt940", "-file", file}, out)
+	b, _ := os.ReadFile(file)
+	formatErr := Run(db, cfg, []string{"statement", "export", "1", "-format", "pdf"}, &bytes.Buffer{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), file)
+	assert.Contains(t, string(b), ":25:1000000001")
+	assert.EqualError(t, formatErr, "format must be mt940, ofx or camt053")
+}
+
+func TestRotateKeys(t *testing.T) {
+	// Arrange
+	db, cfg := setup(t)
+	cfg.File = filepath.Join(t.TempDir(), "app.env")
+	assert.NoError(t, os.WriteFile(cfg.File, []byte("JWT_SECRET=secret\nJWT_PREVIOUS_SECRETS=older\n"), 0o600))
+	cfg.JWT.PreviousSecrets = []string{"older"}
+	unfiled := *cfg
+	unfiled.File = ""
+	out := &bytes.Buffer{}
+	old, err := account.GenerateToken(cfg.JWT.Secret, 1, account.RoleUser, 4102444800)
+	assert.NoError(t, err)
+
+	// Act
+	dryErr := Run(db, cfg, []string{"jwt", "rotate", "-dry-run"}, &bytes.Buffer{})
+	dry, _ := os.ReadFile(cfg.File)
+	rotateErr := Run(db, cfg, []string{"jwt", "rotate"}, &bytes.Buffer{})
+	rotated, _, loadErr := config.Load([]string{"-config", cfg.File})
+	_, oldErr := jwt.Parse(*old, account.Keyfunc(rotated.JWT))
+	fresh, _ := account.GenerateToken(rotated.JWT.Secret, 1, account.RoleUser, 4102444800)
+	_, freshErr := jwt.Parse(*fresh, account.Keyfunc(rotated.JWT))
+	_, staleErr := jwt.Parse(*fresh, account.Keyfunc(cfg.JWT))
+	printErr := Run(db, &unfiled, []string{"jwt", "rotate"}, out)
+
+	// Assert
+	assert.NoError(t, dryErr)
+	assert.Equal(t, "JWT_SECRET=secret\nJWT_PREVIOUS_SECRETS=older\n", string(dry))
+	assert.NoError(t, rotateErr)
+	assert.NoError(t, loadErr)
+	assert.NotEqual(t, "secret", rotated.JWT.Secret)
+	assert.Equal(t, []string{"secret", "older"}, rotated.JWT.PreviousSecrets)
+	assert.NoError(t, oldErr)
+	assert.NoError(t, freshErr)
+	assert.Error(t, staleErr)
+	assert.NoError(t, printErr)
+	assert.Contains(t, out.String(), "JWT_SECRET=")
+	assert.NotContains(t, out.String(), "=secret")
+}
+
+func TestReconcile(t *testing.T) {
+	// Arrange
+	db, cfg := setup(t)
+	db.Create(&account.Account{Email: "ops@test.com", AccountNumber: "1000000009", Role: account.RoleAdmin})
+	acc := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 100}
+	db.Create(acc)
+	db.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: acc.AccountNumber, Amount: 100})
+	out := &bytes.Buffer{}
+
+	// Act
+	dryErr := Run(db, cfg, []string{"reconcile", "run", "-dry-run"}, out)
+	dry := out.String()
+	var dryReports int64
+	db.Model(&reconciliation.ReconciliationReport{}).Count(&dryReports)
+	cleanErr := Run(db, cfg, []string{"reconcile", "run"}, &bytes.Buffer{})
+	db.Model(acc).Update("balance", 90)
+	out.Reset()
+	offErr := Run(db, cfg, []string{"reconcile", "run"}, out)
+	off := out.String()
+	out.Reset()
+	showErr := Run(db, cfg, []string{"-output", "json", "reconcile", "show", "2"}, out)
+	shown := reconciliation.ReconciliationReport{}
+	json.Unmarshal(out.Bytes(), &shown)
+	list := &bytes.Buffer{}
+	listErr := Run(db, cfg, []string{"reconcile", "list", "-failed"}, list)
+	missingErr := Run(db, cfg, []string{"reconcile", "show", "9"}, &bytes.Buffer{})
+
+	// Assert
+	assert.NoError(t, dryErr)
+	assert.Contains(t, dry, "dry run: nothing was changed")
+	assert.Zero(t, dryReports)
+	assert.NoError(t, cleanErr)
+	assert.NoError(t, offErr)
+	assert.Contains(t, off, "CHECK")
+	assert.Contains(t, off, "balance  accounts:2  100.00    90.00")
+	assert.NoError(t, showErr)
+	assert.Equal(t, reconciliation.TriggerCLI, shown.Trigger)
+	assert.False(t, shown.OK)
+	assert.Len(t, shown.Discrepancies, 2)
+	assert.NoError(t, listErr)
+	assert.Equal(t, 2, strings.Count(list.String(), "\n"))
+	assert.EqualError(t, missingErr, "report 9 not found")
+
+	actions := []string{}
+	db.Model(&audit.Record{}).Order("id").Pluck("action", &actions)
+	assert.Equal(t, []string{"admin.reconciliation.run", "admin.reconciliation.run"}, actions)
+	var notified int64
+	db.Model(&notification.Notification{}).Where("type = ?", event.DiscrepanciesFound).Count(&notified)
+	assert.Equal(t, int64(1), notified)
+}
+
+func TestUsage(t *testing.T) {
+	// Arrange
+	db, cfg := setup(t)
+	out := &bytes.Buffer{}
+
+	// Act
+	unknownErr := Run(db, cfg, []string{"account", "delete", "1"}, out)
+	flagErr := Run(db, cfg, []string{"account", "show", "-colour", "1"}, out)
+	outputErr := Run(db, cfg, []string{"-output", "yaml", "account", "show", "1"}, out)
+	migrateErr := Run(db, cfg, []string{"migrate", "status"}, out)
+
+	// Assert
+	assert.ErrorIs(t, unknownErr, ErrUsage)
+	assert.ErrorIs(t, flagErr, ErrUsage)
+	assert.ErrorIs(t, outputErr, ErrUsage)
+	assert.NoError(t, migrateErr)
+	assert.Contains(t, out.String(), "0001     init")
+}
@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/config"
+	"gorm.io/gorm"
+)
+
+// rotation is a new JWT secret. Secret is only given when it was not
+// written to the config file, for it to be set wherever the settings are.
+type rotation struct {
+	KeyID         string `json:"key_id"`
+	PreviousKeyID string `json:"previous_key_id"`
+	File          string `json:"file,omitempty"`
+	Secret        string `json:"secret,omitempty"`
+}
+
+// rotateKeys makes a new JWT secret to sign tokens with, and adds the
+// current one to the previous secrets, to verify the tokens it signed until
+// they expire. Secrets older than the refresh tokens can be removed from
+// JWT_PREVIOUS_SECRETS by hand.
+func (r *runner) rotateKeys(args []string) error {
+	rest, err := parse(r.flags("jwt rotate"), args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return ErrUsage
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	previous := []string{r.cfg.JWT.Secret}
+	for _, s := range r.cfg.JWT.PreviousSecrets {
+		if s != r.cfg.JWT.Secret {
+			previous = append(previous, s)
+		}
+	}
+	values := map[string]string{"JWT_SECRET": secret, "JWT_PREVIOUS_SECRETS": strings.Join(previous, ",")}
+	rot := rotation{KeyID: account.KeyID(secret), PreviousKeyID: account.KeyID(r.cfg.JWT.Secret), File: r.cfg.File}
+
+	return r.change(func(tx *gorm.DB) (view, error) {
+		before := map[string]string{"key_id": rot.PreviousKeyID}
+		after := map[string]string{"key_id": rot.KeyID}
+		if err := record(tx, "admin.jwt.rotate", "config:"+r.cfg.File, before, after); err != nil {
+			return view{}, err
+		}
+		if r.dryRun {
+			return rotationView(rot), nil
+		}
+		if rot.File == "" {
+			// Without a config file, the operator sets the new secret
+			// wherever the others come from. The current one is theirs to
+			// move, so it is not printed.
+			rot.Secret = secret
+			v := rotationView(rot)
+			v.rows = append(v.rows, []string{}, []string{"JWT_SECRET=" + secret},
+				[]string{"move the current JWT_SECRET to the front of JWT_PREVIOUS_SECRETS"})
+			return v, nil
+		}
+		return rotationView(rot), config.WriteFile(rot.File, values)
+	})
+}
+
+func rotationView(rot rotation) view {
+	file := rot.File
+	if file == "" {
+		file = "-"
+	}
+	return view{
+		v:      rot,
+		header: []string{"KEY ID", "PREVIOUS KEY ID", "FILE"},
+		rows:   [][]string{{rot.KeyID, rot.PreviousKeyID, file}},
+	}
+}
@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/arthit666/make_app/reconciliation"
+	"gorm.io/gorm"
+)
+
+func reportsView(reports []reconciliation.ReconciliationReport) view {
+	v := view{v: reports, header: []string{"ID", "TIME", "TRIGGER", "ACCOUNTS", "POCKETS", "TRANSFERS", "HOLDINGS", "INFLOW", "DISCREPANCIES"}}
+	for _, rep := range reports {
+		v.rows = append(v.rows, []string{
+			strconv.FormatUint(uint64(rep.ID), 10), rep.CreatedAt.Format(time.RFC3339), rep.Trigger,
+			strconv.Itoa(rep.Accounts), strconv.Itoa(rep.Pockets), strconv.FormatInt(rep.Transfers, 10),
+			money(rep.Holdings), money(rep.Inflow), strconv.Itoa(len(rep.Discrepancies)),
+		})
+	}
+	return v
+}
+
+// reportView shows one report followed by its discrepancies.
+func reportView(rep *reconciliation.ReconciliationReport) view {
+	v := reportsView([]reconciliation.ReconciliationReport{*rep})
+	v.v = rep
+	if len(rep.Discrepancies) == 0 {
+		return v
+	}
+	details := view{header: []string{"CHECK", "TARGET", "EXPECTED", "ACTUAL", "DETAIL"}}
+	for _, d := range rep.Discrepancies {
+		details.rows = append(details.rows, []string{d.Check, d.Target, money(d.Expected), money(d.Actual), d.Detail})
+	}
+	v.details = &details
+	return v
+}
+
+func (r *runner) runReconciliation(args []string) error {
+	rest, err := parse(r.flags("reconcile run"), args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return ErrUsage
+	}
+
+	return r.change(func(tx *gorm.DB) (view, error) {
+		rep, err := reconciliation.Run(tx, reconciliation.TriggerCLI)
+		if err != nil {
+			return view{}, err
+		}
+		target := fmt.Sprintf("reconciliation_reports:%d", rep.ID)
+		if err := record(tx, "admin.reconciliation.run", target, nil, rep); err != nil {
+			return view{}, err
+		}
+		return reportView(rep), nil
+	})
+}
+
+func (r *runner) showReconciliation(args []string) error {
+	rest, err := parse(r.flags("reconcile show"), args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(rest)
+	if err != nil {
+		return err
+	}
+
+	rep := &reconciliation.ReconciliationReport{}
+	if err := r.db.Preload("Discrepancies").First(rep, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("report %d not found", id)
+		}
+		return err
+	}
+	return r.show(reportView(rep))
+}
+
+func (r *runner) listReconciliations(args []string) error {
+	fs := r.flags("reconcile list")
+	failed := fs.Bool("failed", false, "only reports that found discrepancies")
+	limit := fs.Int("limit", 20, "how many of the latest reports to list")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 || *limit <= 0 {
+		return ErrUsage
+	}
+
+	reports := []reconciliation.ReconciliationReport{}
+	q := r.db.Preload("Discrepancies").Order("id desc").Limit(*limit)
+	if *failed {
+		q = q.Where("ok = ?", false)
+	}
+	if err := q.Find(&reports).Error; err != nil {
+		return err
+	}
+	return r.show(reportsView(reports))
+}
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/arthit666/make_app/statement"
+)
+
+// export is where statement export wrote a statement.
+type export struct {
+	File   string `json:"file"`
+	Format string `json:"format"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+func (r *runner) exportStatement(args []string) error {
+	fs := r.flags("statement export")
+	format := fs.String("format", "", "mt940, ofx or camt053")
+	first := fs.String("from", "", "first day (YYYY-MM-DD), 29 days before the last by default")
+	last := fs.String("to", "", "last day (YYYY-MM-DD), today by default")
+	file := fs.String("file", "", "file to write the statement to instead of the output")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(rest)
+	if err != nil {
+		return err
+	}
+	if *format != statement.FormatMT940 && *format != statement.FormatOFX && *format != statement.FormatCamt053 {
+		return errors.New("format must be mt940, ofx or camt053")
+	}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if *last != "" {
+		if to, err = time.ParseInLocation("2006-01-02", *last, now.Location()); err != nil {
+			return errors.New("to must be YYYY-MM-DD")
+		}
+	}
+	from := to.AddDate(0, 0, -29)
+	if *first != "" {
+		if from, err = time.ParseInLocation("2006-01-02", *first, now.Location()); err != nil {
+			return errors.New("from must be YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return errors.New("from must not be after to")
+	}
+
+	s, err := statement.Build(r.db, id, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return notFound(err, id)
+	}
+	var out []byte
+	switch *format {
+	case statement.FormatMT940:
+		out = s.MT940()
+	case statement.FormatOFX:
+		out, err = s.OFX(now)
+	case statement.FormatCamt053:
+		out, err = s.Camt053(now)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		_, err := r.w.Write(out)
+		return err
+	}
+	if err := os.WriteFile(*file, out, 0o600); err != nil {
+		return err
+	}
+	e := export{File: *file, Format: *format, From: from.Format("2006-01-02"), To: to.Format("2006-01-02")}
+	return r.show(view{v: e, header: []string{"FILE", "FORMAT", "FROM", "TO"}, rows: [][]string{{e.File, e.Format, e.From, e.To}}})
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"gorm.io/gorm"
+)
+
+func transfersView(transfers []account.AccountTransfer) view {
+	v := view{v: transfers, header: []string{"ID", "TIME", "TYPE", "STATUS", "FROM", "TO", "AMOUNT", "REASON", "MEMO"}}
+	for _, t := range transfers {
+		v.rows = append(v.rows, []string{
+			strconv.FormatUint(uint64(t.ID), 10), t.CreatedAt.Format(time.RFC3339), t.Type, t.Status,
+			t.From, t.To, money(t.Amount), t.ReasonCode, t.Memo,
+		})
+	}
+	return v
+}
+
+func (r *runner) showTransfer(args []string) error {
+	rest, err := parse(r.flags("transfer show"), args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(rest)
+	if err != nil {
+		return err
+	}
+
+	t := account.AccountTransfer{}
+	if err := r.db.First(&t, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("transfer %d not found", id)
+		}
+		return err
+	}
+	return r.show(transfersView([]account.AccountTransfer{t}))
+}
+
+func (r *runner) listTransfers(args []string) error {
+	fs := r.flags("transfer list")
+	number := fs.String("account", "", "account number the transfers are from or to")
+	limit := fs.Int("limit", 20, "how many of the latest transfers to list")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 || *number == "" || *limit <= 0 {
+		return ErrUsage
+	}
+
+	transfers := []account.AccountTransfer{}
+	err = r.db.Where(r.db.Where(&account.AccountTransfer{From: *number}).Or(&account.AccountTransfer{To: *number})).
+		Order("id desc").Limit(*limit).Find(&transfers).Error
+	if err != nil {
+		return err
+	}
+	return r.show(transfersView(transfers))
+}
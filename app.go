@@ -1,33 +1,61 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/admin"
+	"github.com/arthit666/make_app/audit"
+	"github.com/arthit666/make_app/batch"
+	"github.com/arthit666/make_app/budget"
+	"github.com/arthit666/make_app/config"
+	"github.com/arthit666/make_app/event"
+	"github.com/arthit666/make_app/interest"
+	"github.com/arthit666/make_app/job"
+	"github.com/arthit666/make_app/ledger"
+	"github.com/arthit666/make_app/migrate"
+	"github.com/arthit666/make_app/notification"
 	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/reconciliation"
 	"github.com/arthit666/make_app/routes"
+	"github.com/arthit666/make_app/rpc"
+	"github.com/arthit666/make_app/rule"
+	"github.com/arthit666/make_app/stream"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func InitDb() (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"))
+// logLevels are the gorm log levels of config.LogLevels.
+var logLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
+func InitDb(cfg *config.Config) (*gorm.DB, error) {
 	newLogger := logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags),
 		logger.Config{
-			SlowThreshold: time.Second,
-			LogLevel:      logger.Info,
+			SlowThreshold: cfg.DB.SlowThreshold,
+			LogLevel:      logLevels[cfg.LogLevel],
 			Colorful:      true,
 		},
 	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	dialector := postgres.Open(cfg.DB.DSN())
+	if cfg.DB.Driver == "sqlite" {
+		dialector = sqlite.Open(cfg.DB.Name)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: newLogger,
 	})
 	if err != nil {
@@ -43,33 +71,126 @@ func InitDb() (*gorm.DB, error) {
 // @name Authorization
 
 func main() {
-	db, err := InitDb()
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("config: %s", err)
+	}
+
+	db, err := InitDb(cfg)
 	if err != nil {
 		panic("filed to connect to database")
 	}
 
-	db.AutoMigrate(
-		&account.Account{},
-		&account.AccountTransfer{},
-		&pocket.Pocket{},
-		&pocket.PocketTransfer{},
-	)
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := migrate.Command(db, args[1:], os.Stdout); err != nil {
+			log.Fatalf("migrate: %s", err)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "admin" {
+		if err := admin.Run(db, cfg, args[1:], os.Stdout); err != nil {
+			log.Fatalf("admin: %s", err)
+		}
+		return
+	}
+
+	if cfg.DB.MigrateOnStart {
+		m, err := migrate.New(db)
+		if err != nil {
+			log.Fatalf("migrate: %s", err)
+		}
+		if _, err := m.Up(); err != nil {
+			log.Fatalf("migrate: %s", err)
+		}
+	}
+
+	if len(args) > 0 && args[0] == "audit-verify" {
+		checked, head, err := audit.Verify(db)
+		if err != nil {
+			log.Fatalf("audit log verification failed after %d records: %s", checked, err)
+		}
+		log.Printf("audit log ok: %d records verified, head hash %s", checked, head)
+		return
+	}
+
+	app := routes.RegRoute(db, cfg)
 
-	app := routes.RegRoute(db)
+	stopNotifications := notification.Listen(db, event.BudgetThresholdReached, event.PocketGoalReached,
+		event.PaymentRequested, event.PaymentRequestPaid)
+	defer stopNotifications()
+	stopBudgets := budget.Track(db)
+	defer stopBudgets()
+	stopStreams := stream.Listen(db)
+	defer stopStreams()
+
+	ctx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go func() {
+		if err := batch.Resume(db); err != nil {
+			log.Printf("batch: resuming pending batches: %s", err)
+		}
+	}()
+
+	go job.Daily(ctx, db, "sweep", 23*time.Hour+30*time.Minute, func(day time.Time) error {
+		return rule.Sweep(db, day)
+	})
+	go job.Daily(ctx, db, "maturity", 5*time.Minute, func(day time.Time) error {
+		return pocket.Mature(db, day)
+	})
+	go job.Daily(ctx, db, "interest", 2*time.Minute, func(day time.Time) error {
+		return interest.RunDaily(db, day)
+	})
+	go job.Daily(ctx, db, "snapshot", time.Minute, func(day time.Time) error {
+		return ledger.RunDaily(db, day)
+	})
+	go job.Daily(ctx, db, "reconcile", 30*time.Minute, func(day time.Time) error {
+		return reconciliation.RunDaily(db, day)
+	})
 
 	go func() {
-		if err := app.Listen(":8000"); err != nil {
+		if err := app.Listen(cfg.Addr()); err != nil {
 			log.Fatalf("listen: %s\n", err)
 		}
 	}()
 
-	log.Println("Server started on port 8000")
+	log.Printf("Server started on port %d", cfg.Port)
+
+	lis, err := net.Listen("tcp", cfg.GRPCAddr())
+	if err != nil {
+		log.Fatalf("grpc listen: %s\n", err)
+	}
+	grpcServer := rpc.New(db, cfg)
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("grpc serve: %s\n", err)
+		}
+	}()
+
+	log.Printf("gRPC server started on %s", cfg.GRPCAddr())
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	<-quit
 	log.Println("Shutting down server...")
+	// Streams never end on their own, so they are closed first, and gRPC
+	// streams are cut if they hold up the shutdown.
+	stream.Close()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(cfg.ShutdownTimeout):
+		grpcServer.Stop()
+	}
 	if err := app.Shutdown(); err != nil {
 		log.Fatalf("Server shutdown failed: %s", err)
 	}
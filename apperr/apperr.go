@@ -0,0 +1,179 @@
+// Package apperr holds the errors the API answers with. Each carries the
+// HTTP status, a stable code clients can branch on and a detail for people,
+// and Handler renders them as RFC 7807 problem details.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator"
+)
+
+const (
+	CodeBadRequest         = "BAD_REQUEST"
+	CodeValidationFailed   = "VALIDATION_FAILED"
+	CodeUnauthenticated    = "UNAUTHENTICATED"
+	CodeInvalidCredentials = "INVALID_CREDENTIALS"
+	CodeForbidden          = "FORBIDDEN"
+	CodeNotFound           = "NOT_FOUND"
+	CodeAccountNotFound    = "ACCOUNT_NOT_FOUND"
+	CodePocketNotFound     = "POCKET_NOT_FOUND"
+	CodeAccountClosed      = "ACCOUNT_CLOSED"
+	CodeAccountFrozen      = "ACCOUNT_FROZEN"
+	CodeConflict           = "CONFLICT"
+	CodeAlreadyExists      = "ALREADY_EXISTS"
+	CodeNotPending         = "NOT_PENDING"
+	CodeGone               = "GONE"
+	CodeInsufficientFunds  = "INSUFFICIENT_FUNDS"
+	CodeLimitExceeded      = "LIMIT_EXCEEDED"
+	CodePocketLocked       = "POCKET_LOCKED"
+	CodeUnprocessable      = "UNPROCESSABLE"
+	CodeUpgradeRequired    = "UPGRADE_REQUIRED"
+	CodeInternal           = "INTERNAL"
+)
+
+// Error is an error the API answers with. Err is what caused it, kept for
+// the logs and never sent to clients.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+// FieldError tells which field of a request failed which rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+func BadRequest(detail string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, detail)
+}
+
+func Unauthenticated(detail string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthenticated, detail)
+}
+
+func Forbidden(detail string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, detail)
+}
+
+func NotFound(code, detail string) *Error {
+	return New(http.StatusNotFound, code, detail)
+}
+
+func Conflict(code, detail string) *Error {
+	return New(http.StatusConflict, code, detail)
+}
+
+// Unprocessable is a well-formed request that breaks a business rule.
+func Unprocessable(code, detail string) *Error {
+	return New(http.StatusUnprocessableEntity, code, detail)
+}
+
+// Internal hides err from the client behind a generic detail.
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: "internal server error", Err: err}
+}
+
+var validate = validator.New()
+
+func init() {
+	// Fields are named as clients send them.
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return f.Name
+		}
+		return name
+	})
+}
+
+// Validate checks v against its validate tags, and is an Error listing
+// every field that fails one.
+func Validate(v interface{}) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return Internal(err)
+	}
+
+	e := &Error{Status: http.StatusBadRequest, Code: CodeValidationFailed, Detail: "payload invalid", Err: err}
+	for _, fe := range verrs {
+		e.Fields = append(e.Fields, FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Message: message(fe)})
+	}
+	return e
+}
+
+// fieldPath is the path of the field in the request, without the name of
+// the request type.
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return "is required when " + fe.Param() + " is not given"
+	case "email":
+		return "must be an email address"
+	case "numeric":
+		return "must be a number"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	case "min":
+		return "must be at least " + fe.Param() + unit(fe)
+	case "max":
+		return "must be at most " + fe.Param() + unit(fe)
+	case "len":
+		return "must be " + fe.Param() + unit(fe)
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	}
+	return "failed the " + fe.Tag() + " rule"
+}
+
+// unit is what the length rules of fe count.
+func unit(fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return " items"
+	}
+	return ""
+}
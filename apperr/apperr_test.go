@@ -0,0 +1,90 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/stretchr/testify/assert"
+)
+
+type transferRequest struct {
+	To     string  `json:"to" validate:"required,len=10"`
+	Amount float64 `json:"amount" validate:"gt=0"`
+	Memo   string  `validate:"max=5"`
+}
+
+func TestValidate(t *testing.T) {
+	// Act
+	err := Validate(&transferRequest{To: "123", Memo: "too long"})
+
+	// Assert
+	e := &Error{}
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusBadRequest, e.Status)
+	assert.Equal(t, CodeValidationFailed, e.Code)
+	assert.Equal(t, []FieldError{
+		{Field: "to", Rule: "len", Message: "must be 10 characters"},
+		{Field: "amount", Rule: "gt", Message: "must be greater than 0"},
+		{Field: "Memo", Rule: "max", Message: "must be at most 5 characters"},
+	}, e.Fields)
+	assert.NoError(t, Validate(&transferRequest{To: "1234567890", Amount: 1}))
+}
+
+func TestHandler(t *testing.T) {
+	// Arrange
+	app := fiber.New(fiber.Config{ErrorHandler: Handler})
+	app.Use(requestid.New())
+	app.Get("/funds", func(c *fiber.Ctx) error {
+		return Unprocessable(CodeInsufficientFunds, "insufficient balance")
+	})
+	app.Get("/db", func(c *fiber.Ctx) error {
+		return errors.New("record not found")
+	})
+	app.Get("/fiber", func(c *fiber.Ctx) error {
+		return fiber.ErrUnauthorized
+	})
+	app.Post("/validate", func(c *fiber.Ctx) error {
+		return Validate(&transferRequest{})
+	})
+
+	get := func(method, url string) (*http.Response, Problem) {
+		resp, err := app.Test(httptest.NewRequest(method, url, nil))
+		assert.NoError(t, err)
+		p := Problem{}
+		json.NewDecoder(resp.Body).Decode(&p)
+		return resp, p
+	}
+
+	// Act
+	funds, fundsProblem := get(http.MethodGet, "/funds?x=1")
+	_, dbProblem := get(http.MethodGet, "/db")
+	_, fiberProblem := get(http.MethodGet, "/fiber")
+	_, validateProblem := get(http.MethodPost, "/validate")
+
+	// Assert
+	assert.Equal(t, http.StatusUnprocessableEntity, funds.StatusCode)
+	assert.Equal(t, "application/problem+json", funds.Header.Get("Content-Type"))
+	assert.Equal(t, "about:blank", fundsProblem.Type)
+	assert.Equal(t, "Unprocessable Entity", fundsProblem.Title)
+	assert.Equal(t, CodeInsufficientFunds, fundsProblem.Code)
+	assert.Equal(t, "insufficient balance", fundsProblem.Detail)
+	assert.Equal(t, "/funds?x=1", fundsProblem.Instance)
+	assert.Equal(t, funds.Header.Get(fiber.HeaderXRequestID), fundsProblem.RequestID)
+	assert.NotEmpty(t, fundsProblem.RequestID)
+
+	assert.Equal(t, http.StatusInternalServerError, dbProblem.Status)
+	assert.Equal(t, CodeInternal, dbProblem.Code)
+	assert.Equal(t, "internal server error", dbProblem.Detail)
+
+	assert.Equal(t, http.StatusUnauthorized, fiberProblem.Status)
+	assert.Equal(t, CodeUnauthenticated, fiberProblem.Code)
+
+	assert.Equal(t, CodeValidationFailed, validateProblem.Code)
+	assert.Len(t, validateProblem.Errors, 2)
+	assert.Equal(t, "to", validateProblem.Errors[0].Field)
+}
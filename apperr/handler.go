@@ -0,0 +1,77 @@
+package apperr
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Problem is an error response, as RFC 7807 problem details.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// codes are the codes of the errors Fiber and its middleware return on
+// their own.
+var codes = map[int]string{
+	http.StatusBadRequest:          CodeBadRequest,
+	http.StatusUnauthorized:        CodeUnauthenticated,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusConflict:            CodeConflict,
+	http.StatusGone:                CodeGone,
+	http.StatusUnprocessableEntity: CodeUnprocessable,
+	http.StatusUpgradeRequired:     CodeUpgradeRequired,
+}
+
+// Handler is the Fiber error handler rendering every error a route returns
+// as application/problem+json. Errors that are not an Error or a
+// *fiber.Error are logged and answered with a generic 500, so what went
+// wrong inside, such as a database error, never reaches the client.
+func Handler(c *fiber.Ctx, err error) error {
+	var e *Error
+	var fe *fiber.Error
+	switch {
+	case errors.As(err, &e):
+	case errors.As(err, &fe):
+		code, ok := codes[fe.Code]
+		if !ok {
+			code = CodeInternal
+			if fe.Code < 500 {
+				code = CodeBadRequest
+			}
+		}
+		e = New(fe.Code, code, fe.Message)
+	default:
+		e = Internal(err)
+	}
+
+	requestID, _ := c.Locals("requestid").(string)
+	if e.Status >= 500 {
+		cause := err
+		if e.Err != nil {
+			cause = e.Err
+		}
+		log.Printf("request %s %s %s: %v", requestID, c.Method(), c.Path(), cause)
+	}
+
+	return c.Status(e.Status).JSON(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Detail,
+		Instance:  c.OriginalURL(),
+		Code:      e.Code,
+		RequestID: requestID,
+		Errors:    e.Fields,
+	}, "application/problem+json")
+}
@@ -0,0 +1,96 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/arthit666/make_app/config"
+	"gorm.io/gorm"
+)
+
+type PendingAction struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"create_at"`
+	UpdatedAt time.Time  `json:"update_at"`
+	Action    string     `json:"action" gorm:"index"`
+	Payload   string     `json:"payload"`
+	MakerID   uint       `json:"maker_id"`
+	CheckerID *uint      `json:"checker_id,omitempty"`
+	Status    string     `json:"status" gorm:"index"`
+	Reason    string     `json:"reason,omitempty"`
+	Result    string     `json:"result,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusExpired  = "expired"
+	StatusFailed   = "failed"
+)
+
+type RejectRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Executor carries out an approved action. It runs inside the transaction
+// that marks the action approved, so an error leaves no partial changes.
+// Errors the checker should see are returned as an *apperr.Error; any other
+// error is logged and answered as an internal error.
+type Executor func(tx *gorm.DB, payload []byte) (interface{}, error)
+
+var executors = map[string]Executor{}
+
+// Register makes action available to Submit. It is meant to be called while
+// wiring up routes, before any request is served.
+func Register(action string, fn Executor) {
+	executors[action] = fn
+}
+
+// Submit records action with payload as waiting for a second admin, for as
+// long as policy allows. Nothing is executed until a different admin
+// approves it.
+func Submit(db *gorm.DB, policy config.Policy, action string, makerID uint, payload interface{}) (*PendingAction, error) {
+	if _, ok := executors[action]; !ok {
+		return nil, fmt.Errorf("unknown action %q", action)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PendingAction{
+		Action:    action,
+		Payload:   string(b),
+		MakerID:   makerID,
+		Status:    StatusPending,
+		ExpiresAt: time.Now().Add(policy.ApprovalTTL),
+	}
+	if err := db.Create(p).Error; err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ExpireOverdue marks every pending action past its expiry as expired.
+func ExpireOverdue(db *gorm.DB) error {
+	return db.Model(&PendingAction{}).
+		Where("status = ? AND expires_at <= ?", StatusPending, time.Now()).
+		Update("status", StatusExpired).Error
+}
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,214 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/config"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Account struct {
+	ID      uint
+	Balance float64
+}
+
+type creditPayload struct {
+	AccountID uint    `json:"account_id"`
+	Amount    float64 `json:"amount"`
+}
+
+func credit(tx *gorm.DB, payload []byte) (interface{}, error) {
+	p := &creditPayload{}
+	if err := json.Unmarshal(payload, p); err != nil {
+		return nil, err
+	}
+	if p.Amount < 0 {
+		return nil, apperr.Unprocessable(apperr.CodeLimitExceeded, "negative credit")
+	}
+	acc := &Account{}
+	if err := tx.First(acc, p.AccountID).Error; err != nil {
+		return nil, err
+	}
+	acc.Balance += p.Amount
+	return acc, tx.Save(acc).Error
+}
+
+func newApp(db *gorm.DB, checker int) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", checker)
+		return c.Next()
+	})
+	handler := New(db)
+	app.Get("/admin/approvals", handler.GetAllPendingActions)
+	app.Post("/admin/approvals/:id/approve", handler.Approve)
+	app.Post("/admin/approvals/:id/reject", handler.Reject)
+	return app
+}
+
+func TestApprove(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &PendingAction{})
+	assert.NoError(t, err)
+	Register("test.credit", credit)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	account := Account{Balance: 100}
+	tx.Create(&account)
+
+	p, err := Submit(tx, config.Default().Policy, "test.credit", 1, creditPayload{AccountID: account.ID, Amount: 50})
+	assert.NoError(t, err)
+
+	// Act: the maker tries to approve their own request
+	resp, err := newApp(tx, 1).Test(httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/approvals/%d/approve", p.ID), nil))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+	var unchanged Account
+	tx.First(&unchanged, account.ID)
+	assert.Equal(t, 100.0, unchanged.Balance)
+
+	// Act: a second admin approves
+	resp, err = newApp(tx, 2).Test(httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/approvals/%d/approve", p.ID), nil))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var responseBody PendingAction
+	err = json.NewDecoder(resp.Body).Decode(&responseBody)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusApproved, responseBody.Status)
+	assert.Equal(t, uint(2), *responseBody.CheckerID)
+	assert.NotEmpty(t, responseBody.Result)
+
+	var credited Account
+	tx.First(&credited, account.ID)
+	assert.Equal(t, 150.0, credited.Balance)
+
+	// Act: approving twice
+	resp, err = newApp(tx, 3).Test(httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/approvals/%d/approve", p.ID), nil))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+}
+
+func TestApproveFailedAction(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &PendingAction{})
+	assert.NoError(t, err)
+	Register("test.credit", credit)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	p, err := Submit(tx, config.Default().Policy, "test.credit", 1, creditPayload{AccountID: 1, Amount: -10})
+	assert.NoError(t, err)
+	missing, err := Submit(tx, config.Default().Policy, "test.credit", 1, creditPayload{AccountID: 99, Amount: 10})
+	assert.NoError(t, err)
+
+	// Act
+	resp, err := newApp(tx, 2).Test(httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/approvals/%d/approve", p.ID), nil))
+	problem := apperr.Problem{}
+	json.NewDecoder(resp.Body).Decode(&problem)
+	internalResp, internalErr := newApp(tx, 2).Test(httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/approvals/%d/approve", missing.ID), nil))
+	internal := apperr.Problem{}
+	json.NewDecoder(internalResp.Body).Decode(&internal)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, apperr.CodeLimitExceeded, problem.Code)
+	assert.Equal(t, "negative credit", problem.Detail)
+
+	var failed PendingAction
+	tx.First(&failed, p.ID)
+	assert.Equal(t, StatusFailed, failed.Status)
+	assert.Equal(t, "action failed: negative credit", failed.Result)
+
+	assert.NoError(t, internalErr)
+	assert.Equal(t, fiber.StatusInternalServerError, internalResp.StatusCode)
+	assert.Equal(t, apperr.CodeInternal, internal.Code)
+	assert.NotContains(t, internal.Detail, "record not found")
+	var broken PendingAction
+	tx.First(&broken, missing.ID)
+	assert.Equal(t, StatusFailed, broken.Status)
+	assert.Equal(t, "action failed: internal error", broken.Result)
+}
+
+func TestReject(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &PendingAction{})
+	assert.NoError(t, err)
+	Register("test.credit", credit)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	p, err := Submit(tx, config.Default().Policy, "test.credit", 1, creditPayload{AccountID: 1, Amount: 10})
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/approvals/%d/reject", p.ID), strings.NewReader(`{"reason":"not justified"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	// Act
+	resp, err := newApp(tx, 2).Test(req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var responseBody PendingAction
+	err = json.NewDecoder(resp.Body).Decode(&responseBody)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusRejected, responseBody.Status)
+	assert.Equal(t, "not justified", responseBody.Reason)
+}
+
+func TestApproveExpired(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &PendingAction{})
+	assert.NoError(t, err)
+	Register("test.credit", credit)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	p, err := Submit(tx, config.Default().Policy, "test.credit", 1, creditPayload{AccountID: 1, Amount: 10})
+	assert.NoError(t, err)
+	tx.Model(p).Update("expires_at", time.Now().Add(-time.Minute))
+
+	// Act
+	resp, err := newApp(tx, 2).Test(httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/approvals/%d/approve", p.ID), nil))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusGone, resp.StatusCode)
+
+	var expired PendingAction
+	tx.First(&expired, p.ID)
+	assert.Equal(t, StatusExpired, expired.Status)
+}
@@ -0,0 +1,212 @@
+package approval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSelfApproval    = errors.New("maker cannot approve their own request")
+	ErrNotPending      = errors.New("action is no longer pending")
+	ErrExpired         = errors.New("action has expired")
+	ErrExecutionFailed = errors.New("action failed")
+)
+
+// @Summary Get approval requests
+// @Description Get actions submitted for approval, optionally filtered by status
+// @Tags admin
+// @Produce json
+// @Param status query string false "pending, approved, rejected, expired or failed"
+// @Success 200 {array} approval.PendingAction
+// @Security  Bearer
+// @Router /admin/approvals/ [get]
+func (h *handler) GetAllPendingActions(c *fiber.Ctx) error {
+	if err := ExpireOverdue(h.DB); err != nil {
+		return apperr.Internal(err)
+	}
+
+	p := []PendingAction{}
+	tx := h.DB.Order("id desc")
+	if status := c.Query("status"); status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if err := tx.Find(&p).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(p)
+}
+
+// @Summary Approve an action
+// @Description Approve an action submitted by another admin and execute it
+// @Tags admin
+// @Produce json
+// @Param id path int true "Pending action ID"
+// @Success 200 {object} approval.PendingAction
+// @Security  Bearer
+// @Router /admin/approvals/{id}/approve [post]
+func (h *handler) Approve(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+	checker := uint(c.Locals("account_id").(int))
+
+	p, err := approve(h.DB, uint(id), checker)
+	if err != nil {
+		return decisionError(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(p)
+}
+
+// @Summary Reject an action
+// @Description Reject an action waiting for approval
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Pending action ID"
+// @Param reject body approval.RejectRequest false "RejectRequest data"
+// @Success 200 {object} approval.PendingAction
+// @Security  Bearer
+// @Router /admin/approvals/{id}/reject [post]
+func (h *handler) Reject(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	req := &RejectRequest{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			return apperr.BadRequest("invalid request body")
+		}
+	}
+	checker := uint(c.Locals("account_id").(int))
+
+	p, err := reject(h.DB, uint(id), checker, req.Reason)
+	if err != nil {
+		return decisionError(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(p)
+}
+
+func decisionError(err error) error {
+	switch {
+	case errors.Is(err, ErrExecutionFailed):
+		// What the executor ran into, not the action, decides the answer.
+		var e *apperr.Error
+		if errors.As(err, &e) {
+			return e
+		}
+		return apperr.Internal(err)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperr.NotFound(apperr.CodeNotFound, "action not found")
+	case errors.Is(err, ErrSelfApproval):
+		return apperr.Forbidden(err.Error())
+	case errors.Is(err, ErrNotPending):
+		return apperr.Conflict(apperr.CodeNotPending, err.Error())
+	case errors.Is(err, ErrExpired):
+		return apperr.New(fiber.StatusGone, apperr.CodeGone, err.Error())
+	}
+	return apperr.Internal(err)
+}
+
+// getPending loads action id and makes sure it can still be decided on.
+func getPending(db *gorm.DB, id uint) (*PendingAction, error) {
+	p := &PendingAction{}
+	if err := db.First(p, id).Error; err != nil {
+		return nil, err
+	}
+	if p.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+	if !time.Now().Before(p.ExpiresAt) {
+		if err := db.Model(p).Update("status", StatusExpired).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrExpired
+	}
+	return p, nil
+}
+
+func approve(db *gorm.DB, id, checker uint) (*PendingAction, error) {
+	p, err := getPending(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.MakerID == checker {
+		return nil, ErrSelfApproval
+	}
+
+	fn, ok := executors[p.Action]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown action %q", ErrExecutionFailed, p.Action)
+	}
+
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&PendingAction{}).
+			Where("id = ? AND status = ?", p.ID, StatusPending).
+			Updates(map[string]interface{}{"status": StatusApproved, "checker_id": checker, "decided_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotPending
+		}
+
+		out, err := fn(tx, []byte(p.Payload))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrExecutionFailed, err)
+		}
+
+		b, err := json.Marshal(out)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&PendingAction{}).Where("id = ?", p.ID).Update("result", string(b)).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrExecutionFailed) {
+			// The approval itself stands; keep a record of why it did not
+			// go through, without what went wrong inside.
+			reason := ErrExecutionFailed.Error() + ": internal error"
+			var e *apperr.Error
+			if errors.As(err, &e) && e.Status < 500 {
+				reason = fmt.Sprintf("%s: %s", ErrExecutionFailed, e.Detail)
+			}
+			db.Model(&PendingAction{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+				"status": StatusFailed, "checker_id": checker, "decided_at": now, "result": reason,
+			})
+		}
+		return nil, err
+	}
+
+	return p, db.First(p, p.ID).Error
+}
+
+func reject(db *gorm.DB, id, checker uint, reason string) (*PendingAction, error) {
+	p, err := getPending(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	res := db.Model(&PendingAction{}).
+		Where("id = ? AND status = ?", p.ID, StatusPending).
+		Updates(map[string]interface{}{"status": StatusRejected, "checker_id": checker, "decided_at": now, "reason": reason})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotPending
+	}
+
+	return p, db.First(p, p.ID).Error
+}
@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Account struct {
+	ID       uint
+	Email    string
+	Password string
+	Balance  float64
+}
+
+type Pocket struct {
+	gorm.Model
+	Title     string
+	Balance   float64
+	AccountID uint
+}
+
+func TestVerify(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Record{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	for i := 1; i <= 3; i++ {
+		err = Append(tx, &Record{Action: "pocket.update", Target: fmt.Sprintf("pockets:%d", i), Status: fiber.StatusOK})
+		assert.NoError(t, err)
+	}
+
+	// Act
+	checked, head, err := Verify(tx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 3, checked)
+	assert.NotEmpty(t, head)
+
+	var records []Record
+	tx.Order("id").Find(&records)
+	assert.Equal(t, "", records[0].PrevHash)
+	assert.Equal(t, records[0].Hash, records[1].PrevHash)
+	assert.Equal(t, records[1].Hash, records[2].PrevHash)
+	assert.Equal(t, records[2].Hash, head)
+
+	// Act: edit a record
+	tx.Model(&Record{}).Where("id = ?", records[1].ID).Update("target", "pockets:99")
+	_, _, err = Verify(tx)
+
+	// Assert
+	var ce *ChainError
+	assert.ErrorAs(t, err, &ce)
+	assert.Equal(t, records[1].ID, ce.ID)
+
+	// Act: delete it instead
+	tx.Delete(&Record{}, records[1].ID)
+	_, _, err = Verify(tx)
+
+	// Assert
+	assert.ErrorAs(t, err, &ce)
+	assert.Equal(t, records[2].ID, ce.ID)
+}
+
+func TestLog(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &Pocket{}, &Record{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	account := Account{Email: "test@example.com", Password: "secret", Balance: 1000}
+	tx.Create(&account)
+	pocket := Pocket{Title: "Pocket 1", Balance: 100, AccountID: account.ID}
+	tx.Create(&pocket)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(account.ID))
+		return c.Next()
+	})
+	app.Put("/pockets/:id", Log(tx, "pocket.update", Row("pockets", "id")), func(c *fiber.Ctx) error {
+		tx.Model(&Pocket{}).Where("id = ?", c.Params("id")).Update("title", "Renamed")
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Post("/accounts/transfer", Log(tx, "account.transfer", Caller), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusBadRequest)
+	})
+
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/pockets/%d", pocket.ID), nil)
+	req.Header.Set("User-Agent", "audit-test")
+
+	// Act
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodPost, "/accounts/transfer", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	// Assert
+	var records []Record
+	tx.Order("id").Find(&records)
+	assert.Equal(t, 2, len(records))
+
+	update := records[0]
+	assert.Equal(t, "pocket.update", update.Action)
+	assert.Equal(t, fmt.Sprintf("pockets:%d", pocket.ID), update.Target)
+	assert.Equal(t, account.ID, *update.ActorID)
+	assert.Equal(t, "audit-test", update.UserAgent)
+	assert.Equal(t, fiber.StatusOK, update.Status)
+
+	var before, after map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(update.Before), &before))
+	assert.NoError(t, json.Unmarshal([]byte(update.After), &after))
+	assert.Equal(t, "Pocket 1", before["title"])
+	assert.Equal(t, "Renamed", after["title"])
+
+	transfer := records[1]
+	assert.Equal(t, fmt.Sprintf("accounts:%d", account.ID), transfer.Target)
+	assert.Equal(t, fiber.StatusBadRequest, transfer.Status)
+	assert.NotContains(t, transfer.Before, "secret")
+	assert.Contains(t, transfer.Before, "Renamed")
+
+	_, _, err = Verify(tx)
+	assert.NoError(t, err)
+}
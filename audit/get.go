@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Get audit records
+// @Description Query the audit log, newest first
+// @Tags audit
+// @Produce json
+// @Param actor_id query int false "Acting account ID"
+// @Param action query string false "Action, e.g. pocket.update"
+// @Param target query string false "Target, e.g. pockets:3"
+// @Param from query string false "RFC 3339 lower bound"
+// @Param to query string false "RFC 3339 upper bound"
+// @Param page query int false "Page"
+// @Param count query int false "Records per page"
+// @Success 200 {object} audit.RecordList
+// @Security  Bearer
+// @Router /audit/ [get]
+func (h *handler) GetAllRecords(c *fiber.Ctx) error {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return apperr.BadRequest("invalid page parameter")
+	}
+	limit, err := strconv.Atoi(c.Query("count", "50"))
+	if err != nil || limit < 1 {
+		return apperr.BadRequest("invalid count parameter")
+	}
+
+	tx := h.DB.Model(&Record{})
+	if v := c.Query("actor_id"); v != "" {
+		tx = tx.Where("actor_id = ?", v)
+	}
+	if v := c.Query("action"); v != "" {
+		tx = tx.Where("action = ?", v)
+	}
+	if v := c.Query("target"); v != "" {
+		tx = tx.Where("target = ?", v)
+	}
+	for param, cond := range map[string]string{"from": "created_at >= ?", "to": "created_at <= ?"} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return apperr.BadRequest("invalid " + param + " parameter")
+		}
+		tx = tx.Where(cond, t.UTC())
+	}
+
+	var totalCount int64
+	if err := tx.Count(&totalCount).Error; err != nil {
+		return apperr.Internal(err)
+	}
+
+	records := []Record{}
+	if err := tx.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&records).Error; err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(RecordList{
+		Records:    records,
+		Page:       page,
+		Count:      limit,
+		TotalPage:  int(math.Ceil(float64(totalCount) / float64(limit))),
+		TotalCount: totalCount,
+	})
+}
+
+// @Summary Verify the audit log
+// @Description Recompute the hash chain of the whole audit log
+// @Tags audit
+// @Produce json
+// @Success 200 {object} audit.VerifyResponse
+// @Security  Bearer
+// @Router /audit/verify [get]
+func (h *handler) VerifyLog(c *fiber.Ctx) error {
+	checked, head, err := Verify(h.DB)
+	res := VerifyResponse{OK: err == nil, Checked: checked, HeadHash: head}
+
+	var ce *ChainError
+	if errors.As(err, &ce) {
+		res.BrokenID = ce.ID
+		res.Message = ce.Reason
+	} else if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Target names what a request changes and returns a snapshot of it. It is
+// called once before and once after the handler runs.
+type Target func(c *fiber.Ctx, db *gorm.DB) (string, interface{})
+
+// Log writes an audit record for every request through the route it is
+// mounted on, whatever the outcome.
+func Log(db *gorm.DB, action string, target Target) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		_, before := target(c, db)
+
+		err := c.Next()
+
+		name, after := target(c, db)
+
+		status := c.Response().StatusCode()
+		var ae *apperr.Error
+		var fe *fiber.Error
+		switch {
+		case errors.As(err, &ae):
+			status = ae.Status
+		case errors.As(err, &fe):
+			status = fe.Code
+		case err != nil:
+			status = fiber.StatusInternalServerError
+		}
+
+		r := &Record{
+			IP:        c.IP(),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+			Action:    action,
+			Target:    name,
+			Status:    status,
+			Before:    encode(before),
+			After:     encode(after),
+		}
+		if id, ok := c.Locals("account_id").(int); ok {
+			actor := uint(id)
+			r.ActorID = &actor
+		}
+
+		if aerr := Append(db, r); aerr != nil {
+			log.Printf("audit: failed to record %s on %s: %s", action, name, aerr)
+		}
+		return err
+	}
+}
+
+func encode(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Row snapshots the row of table whose id is in route parameter param.
+func Row(table, param string) Target {
+	return func(c *fiber.Ctx, db *gorm.DB) (string, interface{}) {
+		id := c.Params(param)
+		return table + ":" + id, row(db, table, "id = ?", id)
+	}
+}
+
+// Named records target as is, for routes that do not change a single row.
+func Named(target string) Target {
+	return func(c *fiber.Ctx, db *gorm.DB) (string, interface{}) {
+		return target, nil
+	}
+}
+
+// Caller snapshots the authenticated account together with its pockets.
+func Caller(c *fiber.Ctx, db *gorm.DB) (string, interface{}) {
+	id, ok := c.Locals("account_id").(int)
+	if !ok {
+		return "accounts:", nil
+	}
+	return fmt.Sprintf("accounts:%d", id), AccountSnapshot(db, "id = ?", id)
+}
+
+// AccountByEmail snapshots the account named by the email in the request
+// body, for the routes that run before the caller is authenticated.
+func AccountByEmail(c *fiber.Ctx, db *gorm.DB) (string, interface{}) {
+	body := struct {
+		Email string `json:"email"`
+	}{}
+	if err := json.Unmarshal(c.Body(), &body); err != nil || body.Email == "" {
+		return "accounts:", nil
+	}
+
+	snap := AccountSnapshot(db, "email = ?", body.Email)
+	if snap == nil {
+		return "accounts:", nil
+	}
+	return fmt.Sprintf("accounts:%v", snap["id"]), snap
+}
+
+// AccountSnapshot snapshots the account matching query together with its
+// pockets, or is nil when there is none.
+func AccountSnapshot(db *gorm.DB, query string, args ...interface{}) map[string]interface{} {
+	acc := row(db, "accounts", query, args...)
+	if acc == nil {
+		return nil
+	}
+
+	pockets := []map[string]interface{}{}
+	db.Table("pockets").Where("account_id = ? AND deleted_at IS NULL", acc["id"]).Order("id").Find(&pockets)
+	for _, p := range pockets {
+		delete(p, "deleted_at")
+	}
+	acc["pockets"] = pockets
+	return acc
+}
+
+func row(db *gorm.DB, table, query string, args ...interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	tx := db.Table(table).Where(query, args...).Limit(1).Find(&m)
+	if tx.Error != nil || len(m) == 0 {
+		return nil
+	}
+	if _, ok := m["deleted_at"]; ok {
+		if m["deleted_at"] != nil {
+			return nil
+		}
+		delete(m, "deleted_at")
+	}
+	delete(m, "password")
+	return m
+}
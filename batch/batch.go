@@ -0,0 +1,112 @@
+package batch
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Batch is a list of transfers from one account uploaded at once and made
+// in the background.
+type Batch struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"create_at"`
+	UpdatedAt time.Time `json:"update_at"`
+	AccountID uint      `json:"-" gorm:"index"`
+	Mode      string    `json:"mode"`
+	// MessageID is the GrpHdr/MsgId of a pain.001 upload, which may only be
+	// uploaded once.
+	MessageID   string     `json:"message_id,omitempty" gorm:"index"`
+	Status      string     `json:"status" gorm:"index"`
+	Total       int        `json:"total"`
+	Succeeded   int        `json:"succeeded"`
+	Failed      int        `json:"failed"`
+	TotalAmount float64    `json:"total_amount"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Rows        []Row      `json:"rows,omitempty"`
+}
+
+// Row is one transfer of a batch. Line is where it was in the upload.
+type Row struct {
+	ID         uint      `gorm:"primarykey" json:"-"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+	BatchID    uint      `json:"-" gorm:"index"`
+	Line       int       `json:"line"`
+	Recipient  string    `json:"recipient"`
+	To         string    `json:"to,omitempty"`
+	Amount     float64   `json:"amount"`
+	Memo       string    `json:"memo,omitempty"`
+	Reference  string    `json:"reference,omitempty"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	TransferID *uint     `json:"transfer_id,omitempty"`
+}
+
+func (Row) TableName() string {
+	return "batch_rows"
+}
+
+// BatchRequest is the JSON form of an upload. A CSV upload has a header
+// row naming the recipient, amount and memo columns, and the mode in the
+// query string, like an ISO 20022 pain.001 upload.
+type BatchRequest struct {
+	Mode string       `json:"mode" validate:"omitempty,oneof=all_or_nothing best_effort"`
+	Rows []RowRequest `json:"rows" validate:"required,min=1,max=1000"`
+}
+
+// RowRequest pays Amount to Recipient, an account number or alias.
+type RowRequest struct {
+	Recipient string  `json:"recipient" validate:"required,max=64"`
+	Amount    float64 `json:"amount" validate:"required,gt=0"`
+	Memo      string  `json:"memo" validate:"max=140"`
+	Reference string  `json:"reference" validate:"max=35"`
+}
+
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ValidationResponse struct {
+	Message string     `json:"message"`
+	Errors  []RowError `json:"errors"`
+}
+
+const (
+	// ModeAllOrNothing makes no transfer at all when a row is invalid or
+	// fails, ModeBestEffort makes every transfer it can.
+	ModeAllOrNothing = "all_or_nothing"
+	ModeBestEffort   = "best_effort"
+
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusPartial    = "partially_completed"
+	StatusFailed     = "failed"
+
+	RowPending   = "pending"
+	RowSucceeded = "succeeded"
+	RowFailed    = "failed"
+	RowSkipped   = "skipped"
+
+	maxRows = 1000
+)
+
+type handler struct {
+	DB *gorm.DB
+	// process runs a stored batch. It runs in the background unless a test
+	// says otherwise.
+	process func(id uint)
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{DB: db, process: func(id uint) {
+		go func() {
+			if err := Process(db, id); err != nil {
+				log.Printf("batch %d: %s", id, err)
+			}
+		}()
+	}}
+}
@@ -0,0 +1,388 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/rule"
+	"github.com/arthit666/make_app/statement"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fixture struct {
+	tx       *gorm.DB
+	app      *fiber.App
+	sender   *account.Account
+	alice    *account.Account
+	bob      *account.Account
+	rollback func()
+}
+
+func setup(t *testing.T, limit float64) *fixture {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{},
+		&rule.Rule{}, &rule.RuleExecution{}, &Batch{}, &Row{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	alias := "alice"
+	f := &fixture{
+		tx:       tx,
+		sender:   &account.Account{Email: "payroll@test.com", AccountNumber: "5000000001", Balance: 1000, TransferLimit: limit},
+		alice:    &account.Account{Email: "alice@test.com", AccountNumber: "5000000002", Alias: &alias},
+		bob:      &account.Account{Email: "bob@test.com", AccountNumber: "5000000003"},
+		rollback: func() { tx.Rollback() },
+	}
+	tx.Create(f.sender)
+	tx.Create(f.alice)
+	tx.Create(f.bob)
+
+	h := New(tx)
+	h.process = func(id uint) {
+		assert.NoError(t, Process(tx, id))
+	}
+
+	f.app = fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	f.app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(f.sender.ID))
+		return c.Next()
+	})
+	f.app.Post("/accounts/transfers/batch", h.CreateBatch)
+	f.app.Get("/accounts/transfers/batch/:id", h.GetBatch)
+	f.app.Get("/accounts/transfers/batch/:id/result", h.GetResult)
+	return f
+}
+
+func (f *fixture) do(t *testing.T, req *http.Request, out interface{}) (int, []byte) {
+	resp, err := f.app.Test(req)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	if out != nil {
+		json.Unmarshal(body, out)
+	}
+	return resp.StatusCode, body
+}
+
+func (f *fixture) balance(acc *account.Account) float64 {
+	var a account.Account
+	f.tx.First(&a, acc.ID)
+	return a.Balance
+}
+
+func jsonRequest(body interface{}) *http.Request {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/accounts/transfers/batch", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestBatchAllOrNothing(t *testing.T) {
+	// Arrange
+	f := setup(t, 0)
+	defer f.rollback()
+
+	// Act
+	b := Batch{}
+	status, _ := f.do(t, jsonRequest(BatchRequest{Rows: []RowRequest{
+		{Recipient: "alice", Amount: 300, Memo: "salary"},
+		{Recipient: f.bob.AccountNumber, Amount: 200.5},
+	}}), &b)
+
+	// Assert
+	assert.Equal(t, fiber.StatusAccepted, status)
+	assert.Equal(t, ModeAllOrNothing, b.Mode)
+	assert.Equal(t, 2, b.Total)
+	assert.Equal(t, 500.5, b.TotalAmount)
+
+	url := "/accounts/transfers/batch/" + strconv.Itoa(int(b.ID))
+	f.do(t, httptest.NewRequest(http.MethodGet, url, nil), &b)
+	assert.Equal(t, StatusCompleted, b.Status)
+	assert.Equal(t, 2, b.Succeeded)
+	assert.Equal(t, 2, len(b.Rows))
+	assert.Equal(t, f.alice.AccountNumber, b.Rows[0].To)
+	assert.NotNil(t, b.Rows[1].TransferID)
+
+	assert.Equal(t, 499.5, f.balance(f.sender))
+	assert.Equal(t, 300.0, f.balance(f.alice))
+
+	status, body := f.do(t, httptest.NewRequest(http.MethodGet, url+"/result", nil), nil)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Contains(t, string(body), "line,recipient,amount,memo,reference,status,error,transfer_id\n1,alice,300.00,salary,,succeeded,,")
+}
+
+func TestBatchValidation(t *testing.T) {
+	// Arrange
+	f := setup(t, 0)
+	defer f.rollback()
+
+	rows := []RowRequest{
+		{Recipient: "alice", Amount: 100},
+		{Recipient: "nobody", Amount: 100},
+		{Recipient: f.sender.AccountNumber, Amount: 100},
+		{Recipient: f.bob.AccountNumber, Amount: 0},
+		{Recipient: f.bob.AccountNumber, Amount: 1.005},
+	}
+
+	// Act
+	res := ValidationResponse{}
+	status, _ := f.do(t, jsonRequest(BatchRequest{Rows: rows}), &res)
+
+	// Assert
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, 4, len(res.Errors))
+	assert.Equal(t, RowError{Line: 2, Error: account.ErrRecipientNotFound.Error()}, res.Errors[0])
+	assert.Equal(t, 3, res.Errors[1].Line)
+	assert.Equal(t, 4, res.Errors[2].Line)
+	assert.Equal(t, 5, res.Errors[3].Line)
+
+	var count int64
+	f.tx.Model(&Batch{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	status, _ = f.do(t, jsonRequest(BatchRequest{Rows: []RowRequest{{Recipient: "alice", Amount: 5000}}}), &res)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	assert.Equal(t, account.ErrInsufficientBalance.Error(), res.Message)
+}
+
+func TestBatchRollback(t *testing.T) {
+	// Arrange
+	f := setup(t, 250)
+	defer f.rollback()
+
+	// Act
+	b := Batch{}
+	status, _ := f.do(t, jsonRequest(BatchRequest{Rows: []RowRequest{
+		{Recipient: "alice", Amount: 200},
+		{Recipient: f.bob.AccountNumber, Amount: 100},
+	}}), &b)
+
+	// Assert
+	assert.Equal(t, fiber.StatusAccepted, status)
+	f.do(t, httptest.NewRequest(http.MethodGet, "/accounts/transfers/batch/"+strconv.Itoa(int(b.ID)), nil), &b)
+	assert.Equal(t, StatusFailed, b.Status)
+	assert.Equal(t, RowSkipped, b.Rows[0].Status)
+	assert.Equal(t, RowFailed, b.Rows[1].Status)
+	assert.Equal(t, account.ErrTransferLimitExceeded.Error(), b.Rows[1].Error)
+
+	assert.Equal(t, 1000.0, f.balance(f.sender))
+	assert.Equal(t, 0.0, f.balance(f.alice))
+
+	var count int64
+	f.tx.Model(&account.AccountTransfer{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestResume(t *testing.T) {
+	// Arrange
+	f := setup(t, 0)
+	defer f.rollback()
+
+	// A batch stopped after its first transfer, and one that never started.
+	sent := &account.AccountTransfer{Type: account.TransferTypeTransfer, From: f.sender.AccountNumber, To: f.alice.AccountNumber, Amount: 100}
+	f.tx.Create(sent)
+	stopped := &Batch{AccountID: f.sender.ID, Mode: ModeBestEffort, Status: StatusProcessing, Total: 3, Rows: []Row{
+		{Line: 1, To: f.alice.AccountNumber, Amount: 100, Status: RowSucceeded, TransferID: &sent.ID},
+		{Line: 2, To: f.alice.AccountNumber, Amount: 100, Status: RowPending, TransferID: &sent.ID},
+		{Line: 3, To: f.bob.AccountNumber, Amount: 50, Status: RowPending},
+	}}
+	f.tx.Create(stopped)
+	waiting := &Batch{AccountID: f.sender.ID, Mode: ModeAllOrNothing, Status: StatusPending, Total: 1, Rows: []Row{
+		{Line: 1, To: f.bob.AccountNumber, Amount: 25, Status: RowPending},
+	}}
+	f.tx.Create(waiting)
+
+	// Act
+	err := Resume(f.tx)
+
+	// Assert
+	assert.NoError(t, err)
+	f.tx.Preload("Rows").First(stopped, stopped.ID)
+	assert.Equal(t, StatusCompleted, stopped.Status)
+	assert.Equal(t, 3, stopped.Succeeded)
+	assert.Equal(t, sent.ID, *stopped.Rows[1].TransferID)
+	assert.Equal(t, RowSucceeded, stopped.Rows[2].Status)
+	f.tx.First(waiting, waiting.ID)
+	assert.Equal(t, StatusCompleted, waiting.Status)
+
+	assert.Equal(t, 925.0, f.balance(f.sender))
+	assert.Equal(t, 75.0, f.balance(f.bob))
+	var count int64
+	f.tx.Model(&account.AccountTransfer{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestBatchBestEffortCSV(t *testing.T) {
+	// Arrange
+	f := setup(t, 250)
+	defer f.rollback()
+
+	csv := "Recipient,Amount,Memo\n" +
+		"alice,200,invoice 1\n" +
+		"nobody,10,invoice 2\n" +
+		f.bob.AccountNumber + ",100,\"invoice 3, final\"\n" +
+		f.bob.AccountNumber + ",50,invoice 4\n"
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, _ := w.CreateFormFile("file", "payouts.csv")
+	part.Write([]byte(csv))
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/accounts/transfers/batch?mode=best_effort", &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	// Act
+	b := Batch{}
+	status, _ := f.do(t, req, &b)
+
+	// Assert
+	assert.Equal(t, fiber.StatusAccepted, status)
+	assert.Equal(t, ModeBestEffort, b.Mode)
+	assert.Equal(t, 4, b.Total)
+	assert.Equal(t, 1, b.Failed)
+
+	f.do(t, httptest.NewRequest(http.MethodGet, "/accounts/transfers/batch/"+strconv.Itoa(int(b.ID)), nil), &b)
+	assert.Equal(t, StatusPartial, b.Status)
+	assert.Equal(t, 2, b.Succeeded)
+	assert.Equal(t, 2, b.Failed)
+
+	assert.Equal(t, 2, b.Rows[0].Line)
+	assert.Equal(t, RowSucceeded, b.Rows[0].Status)
+	assert.Equal(t, RowFailed, b.Rows[1].Status)
+	assert.Equal(t, account.ErrTransferLimitExceeded.Error(), b.Rows[2].Error)
+	assert.Equal(t, "invoice 3, final", b.Rows[2].Memo)
+	assert.Equal(t, RowSucceeded, b.Rows[3].Status)
+
+	assert.Equal(t, 750.0, f.balance(f.sender))
+	assert.Equal(t, 50.0, f.balance(f.bob))
+
+	req = httptest.NewRequest(http.MethodPost, "/accounts/transfers/batch", bytes.NewReader([]byte("name,value\nalice,1\n")))
+	req.Header.Set("Content-Type", "text/csv")
+	status, _ = f.do(t, req, nil)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+}
+
+func TestBatchPain001(t *testing.T) {
+	// Arrange
+	f := setup(t, 0)
+	defer f.rollback()
+
+	sample, err := os.ReadFile("testdata/pain001.xml")
+	assert.NoError(t, err)
+
+	xmlRequest := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/accounts/transfers/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/xml")
+		return req
+	}
+
+	// Act
+	b := Batch{}
+	status, _ := f.do(t, xmlRequest(string(sample)), &b)
+
+	// Assert
+	assert.Equal(t, fiber.StatusAccepted, status)
+	assert.Equal(t, "PAYROLL-2026-01", b.MessageID)
+	assert.Equal(t, 3, b.Total)
+	assert.Equal(t, 650.75, b.TotalAmount)
+
+	f.do(t, httptest.NewRequest(http.MethodGet, "/accounts/transfers/batch/"+strconv.Itoa(int(b.ID)), nil), &b)
+	assert.Equal(t, StatusCompleted, b.Status)
+	assert.Equal(t, "SAL-0001", b.Rows[0].Reference)
+	assert.Equal(t, f.alice.AccountNumber, b.Rows[0].To)
+	assert.Equal(t, "Salary January", b.Rows[0].Memo)
+	assert.Equal(t, "INV-7731", b.Rows[2].Reference)
+
+	tr := account.AccountTransfer{}
+	f.tx.First(&tr, *b.Rows[1].TransferID)
+	assert.Equal(t, "SAL-0002", tr.Reference)
+	assert.Equal(t, 200.75, tr.Amount)
+	assert.Equal(t, 349.25, f.balance(f.sender))
+
+	status, _ = f.do(t, xmlRequest(string(sample)), nil)
+	assert.Equal(t, fiber.StatusConflict, status)
+
+	invalid := map[string]string{
+		"control sum": strings.Replace(string(sample), "<CtrlSum>650.75</CtrlSum>", "<CtrlSum>650.00</CtrlSum>", 1),
+		"count":       strings.Replace(string(sample), "<NbOfTxs>2</NbOfTxs>", "<NbOfTxs>3</NbOfTxs>", 1),
+		"debtor":      strings.Replace(string(sample), "<Id>5000000001</Id>", "<Id>5000000002</Id>", 1),
+		"currency":    strings.Replace(string(sample), `Ccy="THB">150.00`, `Ccy="USD">150.00`, 1),
+		"namespace":   strings.Replace(string(sample), "pain.001.001.03", "pain.008.001.02", 1),
+		"end-to-end":  strings.Replace(string(sample), "<EndToEndId>INV-7731</EndToEndId>", "", 1),
+		"message id":  strings.Replace(string(sample), "PAYROLL-2026-01", "", 1),
+		"malformed":   string(sample[:200]),
+	}
+	for name, body := range invalid {
+		status, _ = f.do(t, xmlRequest(body), nil)
+		assert.Equal(t, fiber.StatusBadRequest, status, name)
+	}
+}
+
+// TestPain001RoundTrip uploads the sample pain.001 and checks that the
+// camt.053 statement of the day has every transfer it asked for.
+func TestPain001RoundTrip(t *testing.T) {
+	// Arrange
+	f := setup(t, 0)
+	defer f.rollback()
+
+	sample, err := os.ReadFile("testdata/pain001.xml")
+	assert.NoError(t, err)
+	pain := Pain001{}
+	assert.NoError(t, xml.Unmarshal(sample, &pain))
+
+	start := time.Now().Add(-time.Second)
+	req := httptest.NewRequest(http.MethodPost, "/accounts/transfers/batch", bytes.NewReader(sample))
+	req.Header.Set("Content-Type", "application/xml")
+	status, _ := f.do(t, req, nil)
+	assert.Equal(t, fiber.StatusAccepted, status)
+
+	// Act
+	s, err := statement.Build(f.tx, f.sender.ID, start, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	out, err := s.Camt053(time.Now())
+	assert.NoError(t, err)
+
+	// Assert
+	doc := statement.Camt053{}
+	assert.NoError(t, xml.Unmarshal(out, &doc))
+	assert.Equal(t, statement.Camt053Namespace, doc.Xmlns)
+
+	stmt := doc.Stmts[0]
+	assert.Equal(t, f.sender.AccountNumber, stmt.Acct.ID)
+	assert.Equal(t, "1000.00", stmt.Bals[0].Amt.Value)
+	assert.Equal(t, "349.25", stmt.Bals[1].Amt.Value)
+	assert.Equal(t, pain.GrpHdr.CtrlSum, stmt.TxsSummry.DbtSum)
+	assert.Equal(t, pain.GrpHdr.NbOfTxs, strconv.Itoa(stmt.TxsSummry.DbtNbOfNtries))
+
+	i := 0
+	for _, p := range pain.PmtInfs {
+		for _, want := range p.Txs {
+			got := stmt.Ntries[i]
+			assert.Equal(t, want.EndToEndID, got.TxDtls.EndToEndID)
+			assert.Equal(t, want.InstdAmt.Value, got.Amt.Value)
+			assert.Equal(t, want.InstdAmt.Ccy, got.Amt.Ccy)
+			assert.Equal(t, "DBIT", got.CdtDbtInd)
+			if want.Ustrd != "" {
+				assert.Equal(t, want.Ustrd, got.TxDtls.RmtInf.Ustrd)
+			}
+			i++
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/go-playground/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// @Summary Upload a batch of transfers
+// @Description Upload up to 1000 transfers as JSON, as CSV (text/csv or a multipart "file") with recipient, amount and memo columns, or as an ISO 20022 pain.001 credit transfer initiation (application/xml or a multipart "file"), with the mode in the query. Every row is validated first; an all_or_nothing batch (the default) with an invalid row is refused, a best_effort batch skips them. The transfers are then made in the background
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param batch body batch.BatchRequest true "BatchRequest data"
+// @Param mode query string false "all_or_nothing or best_effort"
+// @Success 202 {object} batch.Batch
+// @Failure 422 {object} batch.ValidationResponse
+// @Security  Bearer
+// @Router /accounts/transfers/batch [post]
+func (h *handler) CreateBatch(c *fiber.Ctx) error {
+	sender := &account.Account{}
+	if err := h.DB.First(sender, c.Locals("account_id").(int)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodeAccountNotFound, "account not found")
+		}
+		return apperr.Internal(err)
+	}
+	if sender.Status == account.StatusClosed {
+		return apperr.New(fiber.StatusForbidden, apperr.CodeAccountClosed, account.ErrAccountClosed.Error())
+	}
+	if sender.Status == account.StatusFrozen {
+		return apperr.New(fiber.StatusForbidden, apperr.CodeAccountFrozen, account.ErrAccountFrozen.Error())
+	}
+
+	req, lines, msgID, err := parse(c, sender.AccountNumber)
+	if err != nil {
+		return apperr.BadRequest("payload invalid: " + err.Error())
+	}
+	if req.Mode == "" {
+		req.Mode = ModeAllOrNothing
+	}
+	if req.Mode != ModeAllOrNothing && req.Mode != ModeBestEffort {
+		return apperr.BadRequest("payload invalid: mode must be all_or_nothing or best_effort")
+	}
+	if len(req.Rows) == 0 || len(req.Rows) > maxRows {
+		return apperr.BadRequest(fmt.Sprintf("payload invalid: a batch has 1 to %d rows", maxRows))
+	}
+
+	if msgID != "" {
+		var seen int64
+		if err := h.DB.Model(&Batch{}).Where("account_id = ? AND message_id = ?", sender.ID, msgID).Count(&seen).Error; err != nil {
+			return apperr.Internal(err)
+		}
+		if seen > 0 {
+			return apperr.Conflict(apperr.CodeAlreadyExists, "message "+msgID+" was already uploaded")
+		}
+	}
+
+	b := &Batch{AccountID: sender.ID, Mode: req.Mode, MessageID: msgID, Status: StatusPending, Total: len(req.Rows)}
+	rows, errs, err := validate(h.DB, sender, req.Rows, lines)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	total := decimal.Zero
+	valid := 0
+	for _, r := range rows {
+		if r.Status == RowPending {
+			total = total.Add(decimal.NewFromFloat(r.Amount))
+			valid++
+		}
+	}
+	b.TotalAmount, _ = total.Float64()
+
+	if len(errs) > 0 && (b.Mode == ModeAllOrNothing || valid == 0) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(ValidationResponse{Message: "batch has invalid rows", Errors: errs})
+	}
+	if b.Mode == ModeAllOrNothing && total.GreaterThan(decimal.NewFromFloat(sender.Balance)) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(ValidationResponse{Message: account.ErrInsufficientBalance.Error(), Errors: []RowError{}})
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(b).Error; err != nil {
+			return err
+		}
+		for i := range rows {
+			rows[i].BatchID = b.ID
+		}
+		return tx.CreateInBatches(rows, 100).Error
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	b.Failed = len(errs)
+	h.process(b.ID)
+
+	return c.Status(fiber.StatusAccepted).JSON(b)
+}
+
+// parse reads the rows of a JSON, CSV or pain.001 upload from account, along
+// with the line each one came from and the message ID of a pain.001.
+func parse(c *fiber.Ctx, account string) (*BatchRequest, []int, string, error) {
+	req := &BatchRequest{Mode: c.Query("mode")}
+
+	var data []byte
+	if fh, err := c.FormFile("file"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			return nil, nil, "", err
+		}
+		defer f.Close()
+		if data, err = io.ReadAll(f); err != nil {
+			return nil, nil, "", err
+		}
+	} else {
+		data = c.Body()
+		contentType := c.Get(fiber.HeaderContentType)
+		if !strings.HasPrefix(contentType, "text/csv") && !strings.Contains(contentType, "xml") {
+			if err := json.Unmarshal(data, req); err != nil {
+				return nil, nil, "", err
+			}
+			lines := []int{}
+			for i := range req.Rows {
+				lines = append(lines, i+1)
+			}
+			return req, lines, "", nil
+		}
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		pain, lines, msgID, err := parsePain001(data, account)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		pain.Mode = req.Mode
+		return pain, lines, msgID, nil
+	}
+
+	rows, lines, err := parseCSV(data)
+	req.Rows = rows
+	return req, lines, "", err
+}
+
+// parseCSV reads rows of a CSV file whose header names a recipient (or to),
+// an amount and optionally a memo and a reference column.
+func parseCSV(data []byte) ([]RowRequest, []int, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "to" {
+			name = "recipient"
+		}
+		cols[name] = i
+	}
+	for _, name := range []string{"recipient", "amount"} {
+		if _, ok := cols[name]; !ok {
+			return nil, nil, fmt.Errorf("CSV header has no %s column", name)
+		}
+	}
+
+	rows, lines := []RowRequest{}, []int{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := r.FieldPos(0)
+		field := func(name string) string {
+			i, ok := cols[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := RowRequest{Recipient: field("recipient"), Memo: field("memo"), Reference: field("reference")}
+		if amount := field("amount"); amount != "" {
+			// An amount that is not a number fails validation as zero.
+			row.Amount, _ = strconv.ParseFloat(amount, 64)
+		}
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+	return rows, lines, nil
+}
+
+// validate checks every row and resolves its recipient. Invalid rows come
+// back failed, with their errors listed by line.
+func validate(db *gorm.DB, sender *account.Account, reqs []RowRequest, lines []int) ([]Row, []RowError, error) {
+	names := []string{}
+	for _, r := range reqs {
+		names = append(names, strings.TrimSpace(r.Recipient))
+	}
+	accounts := []account.Account{}
+	if err := db.Where("account_number IN ? OR alias IN ?", names, names).Find(&accounts).Error; err != nil {
+		return nil, nil, err
+	}
+	byName := map[string]*account.Account{}
+	for i := range accounts {
+		byName[accounts[i].AccountNumber] = &accounts[i]
+		if accounts[i].Alias != nil {
+			byName[*accounts[i].Alias] = &accounts[i]
+		}
+	}
+
+	v := validator.New()
+	rows, errs := []Row{}, []RowError{}
+	for i, req := range reqs {
+		row := Row{
+			Line:      lines[i],
+			Recipient: strings.TrimSpace(req.Recipient),
+			Amount:    req.Amount,
+			Memo:      strings.TrimSpace(req.Memo),
+			Reference: strings.TrimSpace(req.Reference),
+			Status:    RowPending,
+		}
+
+		var msg string
+		if err := v.Struct(req); err != nil {
+			msg = "row invalid: " + err.Error()
+		} else if !decimal.NewFromFloat(req.Amount).Equal(decimal.NewFromFloat(req.Amount).Round(2)) {
+			msg = "amount has more than 2 decimals"
+		} else if acc, ok := byName[row.Recipient]; !ok {
+			msg = account.ErrRecipientNotFound.Error()
+		} else if acc.Status == account.StatusClosed {
+			msg = account.ErrRecipientClosed.Error()
+		} else if acc.ID == sender.ID {
+			msg = "cannot transfer to the sending account"
+		} else {
+			row.To = acc.AccountNumber
+		}
+
+		if msg != "" {
+			row.Status, row.Error = RowFailed, msg
+			errs = append(errs, RowError{Line: row.Line, Error: msg})
+		}
+		rows = append(rows, row)
+	}
+	return rows, errs, nil
+}
+
+// @Summary Get a batch of transfers
+// @Description Get the status of a batch of transfers and how each row went
+// @Tags accounts
+// @Produce json
+// @Param id path int true "Batch ID"
+// @Success 200 {object} batch.Batch
+// @Security  Bearer
+// @Router /accounts/transfers/batch/{id} [get]
+func (h *handler) GetBatch(c *fiber.Ctx) error {
+	b, err := h.batch(c)
+	if err != nil {
+		return batchError(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(b)
+}
+
+// @Summary Download the result of a batch
+// @Description Download how each row of a batch of transfers went as a CSV file
+// @Tags accounts
+// @Produce text/csv
+// @Param id path int true "Batch ID"
+// @Success 200 {file} file
+// @Security  Bearer
+// @Router /accounts/transfers/batch/{id}/result [get]
+func (h *handler) GetResult(c *fiber.Ctx) error {
+	b, err := h.batch(c)
+	if err != nil {
+		return batchError(err)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"line", "recipient", "amount", "memo", "reference", "status", "error", "transfer_id"})
+	for _, r := range b.Rows {
+		transfer := ""
+		if r.TransferID != nil {
+			transfer = strconv.FormatUint(uint64(*r.TransferID), 10)
+		}
+		w.Write([]string{strconv.Itoa(r.Line), r.Recipient, decimal.NewFromFloat(r.Amount).StringFixed(2), r.Memo, r.Reference, r.Status, r.Error, transfer})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="batch-%d.csv"`, b.ID))
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+func (h *handler) batch(c *fiber.Ctx) (*Batch, error) {
+	b := &Batch{}
+	err := h.DB.Where("account_id = ?", c.Locals("account_id").(int)).
+		Preload("Rows", func(db *gorm.DB) *gorm.DB { return db.Order("line") }).
+		First(b, c.Params("id")).Error
+	return b, err
+}
+
+func batchError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.NotFound(apperr.CodeNotFound, "batch not found")
+	}
+	return apperr.Internal(err)
+}
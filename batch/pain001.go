@@ -0,0 +1,136 @@
+package batch
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// pain.001 versions a batch can be uploaded in.
+var painNamespaces = map[string]bool{
+	"urn:iso:std:iso:20022:tech:xsd:pain.001.001.03": true,
+	"urn:iso:std:iso:20022:tech:xsd:pain.001.001.09": true,
+}
+
+// Pain001 is the subset of an ISO 20022 pain.001 customer credit transfer
+// initiation a batch is read from. Accounts are identified by account
+// number or alias as other identification.
+type Pain001 struct {
+	XMLName xml.Name     `xml:"Document"`
+	GrpHdr  PainGrpHdr   `xml:"CstmrCdtTrfInitn>GrpHdr"`
+	PmtInfs []PainPmtInf `xml:"CstmrCdtTrfInitn>PmtInf"`
+}
+
+type PainGrpHdr struct {
+	MsgID    string `xml:"MsgId"`
+	CreDtTm  string `xml:"CreDtTm"`
+	NbOfTxs  string `xml:"NbOfTxs"`
+	CtrlSum  string `xml:"CtrlSum"`
+	InitgPty string `xml:"InitgPty>Nm"`
+}
+
+type PainPmtInf struct {
+	PmtInfID string         `xml:"PmtInfId"`
+	PmtMtd   string         `xml:"PmtMtd"`
+	NbOfTxs  string         `xml:"NbOfTxs"`
+	CtrlSum  string         `xml:"CtrlSum"`
+	DbtrAcct string         `xml:"DbtrAcct>Id>Othr>Id"`
+	Txs      []PainCdtTrfTx `xml:"CdtTrfTxInf"`
+}
+
+type PainCdtTrfTx struct {
+	EndToEndID string  `xml:"PmtId>EndToEndId"`
+	InstdAmt   PainAmt `xml:"Amt>InstdAmt"`
+	Cdtr       string  `xml:"Cdtr>Nm"`
+	CdtrAcct   string  `xml:"CdtrAcct>Id>Othr>Id"`
+	Ustrd      string  `xml:"RmtInf>Ustrd"`
+}
+
+type PainAmt struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+// parsePain001 reads the rows of a pain.001 upload. Message-level problems
+// are errors; problems with a transaction are left for validate to report
+// on its row, numbered in the order transactions appear.
+func parsePain001(data []byte, account string) (*BatchRequest, []int, string, error) {
+	doc := &Pain001{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, nil, "", fmt.Errorf("reading pain.001: %w", err)
+	}
+	if !painNamespaces[doc.XMLName.Space] {
+		return nil, nil, "", fmt.Errorf("unsupported document %q, expected pain.001.001.03 or pain.001.001.09", doc.XMLName.Space)
+	}
+
+	h := doc.GrpHdr
+	if h.MsgID == "" || len(h.MsgID) > 35 {
+		return nil, nil, "", fmt.Errorf("GrpHdr/MsgId must have 1 to 35 characters")
+	}
+	if h.CreDtTm == "" {
+		return nil, nil, "", fmt.Errorf("GrpHdr/CreDtTm is missing")
+	}
+	if len(doc.PmtInfs) == 0 {
+		return nil, nil, "", fmt.Errorf("no PmtInf")
+	}
+
+	req := &BatchRequest{}
+	lines := []int{}
+	total := decimal.Zero
+	for _, p := range doc.PmtInfs {
+		if p.PmtMtd != "TRF" {
+			return nil, nil, "", fmt.Errorf("PmtInf %s: PmtMtd must be TRF", p.PmtInfID)
+		}
+		if p.DbtrAcct != account {
+			return nil, nil, "", fmt.Errorf("PmtInf %s: DbtrAcct is not the account of the caller", p.PmtInfID)
+		}
+		sum := decimal.Zero
+		for _, tx := range p.Txs {
+			amount, err := decimal.NewFromString(strings.TrimSpace(tx.InstdAmt.Value))
+			if err != nil {
+				return nil, nil, "", fmt.Errorf("PmtInf %s: InstdAmt %q is not an amount", p.PmtInfID, tx.InstdAmt.Value)
+			}
+			if tx.InstdAmt.Ccy != "THB" {
+				return nil, nil, "", fmt.Errorf("PmtInf %s: InstdAmt must be in THB", p.PmtInfID)
+			}
+			if tx.EndToEndID == "" || len(tx.EndToEndID) > 35 {
+				return nil, nil, "", fmt.Errorf("PmtInf %s: EndToEndId must have 1 to 35 characters", p.PmtInfID)
+			}
+			sum = sum.Add(amount)
+
+			row := RowRequest{
+				Recipient: strings.TrimSpace(tx.CdtrAcct),
+				Memo:      strings.TrimSpace(tx.Ustrd),
+				Reference: strings.TrimSpace(tx.EndToEndID),
+			}
+			row.Amount, _ = amount.Float64()
+			req.Rows = append(req.Rows, row)
+			lines = append(lines, len(req.Rows))
+		}
+		if err := checkTotals(p.NbOfTxs, p.CtrlSum, len(p.Txs), sum); err != nil {
+			return nil, nil, "", fmt.Errorf("PmtInf %s: %w", p.PmtInfID, err)
+		}
+		total = total.Add(sum)
+	}
+	if err := checkTotals(h.NbOfTxs, h.CtrlSum, len(req.Rows), total); err != nil {
+		return nil, nil, "", fmt.Errorf("GrpHdr: %w", err)
+	}
+	return req, lines, h.MsgID, nil
+}
+
+// checkTotals checks the optional transaction count and control sum of a
+// pain.001 block against what it holds.
+func checkTotals(nbOfTxs, ctrlSum string, n int, sum decimal.Decimal) error {
+	if nbOfTxs != "" && nbOfTxs != fmt.Sprint(n) {
+		return fmt.Errorf("NbOfTxs is %s but there are %d transactions", nbOfTxs, n)
+	}
+	if ctrlSum != "" {
+		c, err := decimal.NewFromString(ctrlSum)
+		if err != nil || !c.Equal(sum) {
+			return fmt.Errorf("CtrlSum is %s but the transactions add up to %s", ctrlSum, sum.StringFixed(2))
+		}
+	}
+	return nil
+}
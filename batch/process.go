@@ -0,0 +1,209 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/event"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Process makes the transfers of a pending batch through account.Send, or
+// account.SendAll for an all-or-nothing batch, and records how each row went.
+// A batch another process already took is left alone.
+func Process(db *gorm.DB, id uint) error {
+	res := db.Model(&Batch{}).Where("id = ? AND status = ?", id, StatusPending).Update("status", StatusProcessing)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+	return run(db, id)
+}
+
+// run makes the transfers of the rows of batch id that are still pending.
+// Each row is recorded in the transaction that makes its transfer, so a
+// batch stopped halfway can be run again without sending any row twice.
+func run(db *gorm.DB, id uint) error {
+	b := &Batch{}
+	if err := db.First(b, id).Error; err != nil {
+		return err
+	}
+	// A row whose transfer was made is done, whatever its status says.
+	err := db.Model(&Row{}).Where(&Row{BatchID: b.ID, Status: RowPending}).Where("transfer_id IS NOT NULL").
+		Update("status", RowSucceeded).Error
+	if err != nil {
+		return err
+	}
+	rows := []Row{}
+	err = db.Where(&Row{BatchID: b.ID, Status: RowPending}).Where("transfer_id IS NULL").Order("line").Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	if b.Mode == ModeAllOrNothing {
+		if err := sendAll(db, b, rows); err != nil {
+			return err
+		}
+	} else {
+		for i := range rows {
+			if err := send(db, b, &rows[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return finish(db, b)
+}
+
+// send makes the transfer of row r and records how it went, unless another
+// process got to the row first. The transfer is published once the row is
+// committed with it.
+func send(db *gorm.DB, b *Batch, r *Row) error {
+	ctx, publish := event.Hold(context.Background())
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if claimed, err := claim(tx, r.ID); err != nil || !claimed {
+			return err
+		}
+
+		t, err := account.Send(tx, b.AccountID, request(r))
+		if err != nil {
+			r.Status, r.Error = RowFailed, err.Error()
+		} else {
+			r.Status, r.TransferID = RowSucceeded, &t.ID
+		}
+		return save(tx, r)
+	})
+	if err == nil {
+		publish()
+	}
+	return err
+}
+
+// sendAll makes the transfers of rows all at once and records how each of
+// them went in the same transaction, publishing the transfers once it is
+// committed.
+func sendAll(db *gorm.DB, b *Batch, rows []Row) error {
+	ctx, publish := event.Hold(context.Background())
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range rows {
+			if claimed, err := claim(tx, rows[i].ID); err != nil || !claimed {
+				return err
+			}
+		}
+
+		trs := []*account.AccountTransferRequest{}
+		for i := range rows {
+			trs = append(trs, request(&rows[i]))
+		}
+
+		sent, err := account.SendAll(tx, b.AccountID, trs)
+		if err == nil {
+			for i := range rows {
+				rows[i].Status, rows[i].TransferID = RowSucceeded, &sent[i].ID
+			}
+		} else {
+			failed := -1
+			var berr *account.BatchError
+			if errors.As(err, &berr) {
+				failed = berr.Index
+				err = berr.Err
+			}
+			for i := range rows {
+				if i == failed || failed < 0 {
+					rows[i].Status, rows[i].Error = RowFailed, err.Error()
+				} else {
+					rows[i].Status, rows[i].Error = RowSkipped, "not sent because another transfer of the batch failed"
+				}
+			}
+		}
+
+		for i := range rows {
+			if err := save(tx, &rows[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		publish()
+	}
+	return err
+}
+
+// claim locks row id until tx ends, and reports whether it is still
+// pending without a transfer.
+func claim(tx *gorm.DB, id uint) (bool, error) {
+	r := &Row{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(r, id).Error; err != nil {
+		return false, err
+	}
+	return r.Status == RowPending && r.TransferID == nil, nil
+}
+
+func save(tx *gorm.DB, r *Row) error {
+	return tx.Model(r).Select("status", "error", "transfer_id").Updates(r).Error
+}
+
+func request(r *Row) *account.AccountTransferRequest {
+	return &account.AccountTransferRequest{To: r.To, Amount: r.Amount, Memo: r.Memo, Reference: r.Reference}
+}
+
+// finish counts the rows of b and sets its final status.
+func finish(db *gorm.DB, b *Batch) error {
+	var counts []struct {
+		Status string
+		Count  int
+	}
+	err := db.Model(&Row{}).Where(&Row{BatchID: b.ID}).
+		Select("status, COUNT(*) AS count").Group("status").Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	b.Succeeded, b.Failed = 0, 0
+	for _, c := range counts {
+		if c.Status == RowSucceeded {
+			b.Succeeded += c.Count
+		} else {
+			b.Failed += c.Count
+		}
+	}
+
+	switch {
+	case b.Failed == 0:
+		b.Status = StatusCompleted
+	case b.Succeeded == 0:
+		b.Status = StatusFailed
+	default:
+		b.Status = StatusPartial
+	}
+	now := time.Now()
+	b.FinishedAt = &now
+	return db.Model(b).Select("status", "succeeded", "failed", "finished_at").Updates(b).Error
+}
+
+// Resume processes the batches that were accepted but never started or
+// never finished, for instance because the server stopped. The rows of a
+// batch that already have a transfer are not sent again.
+func Resume(db *gorm.DB) error {
+	batches := []Batch{}
+	err := db.Where("status IN ?", []string{StatusPending, StatusProcessing}).Order("id").Find(&batches).Error
+	if err != nil {
+		return err
+	}
+	for _, b := range batches {
+		if b.Status == StatusPending {
+			err = Process(db, b.ID)
+		} else {
+			err = run(db, b.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package budget
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Budget caps what an account spends in a month, either on one transfer
+// category or out of one pocket.
+type Budget struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"create_at"`
+	UpdatedAt time.Time `json:"update_at"`
+	AccountID uint      `json:"-" gorm:"index"`
+	Category  string    `json:"category,omitempty"`
+	PocketID  *uint     `json:"pocket_id,omitempty"`
+	Amount    float64   `json:"amount"`
+	Carryover bool      `json:"carryover"`
+}
+
+// BudgetPeriod is one month of a budget. Carried is what was left of the
+// month before when the budget carries over.
+type BudgetPeriod struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"create_at"`
+	UpdatedAt time.Time `json:"update_at"`
+	BudgetID  uint      `json:"budget_id" gorm:"uniqueIndex:idx_budget_period"`
+	Period    string    `json:"period" gorm:"uniqueIndex:idx_budget_period"`
+	Amount    float64   `json:"amount"`
+	Carried   float64   `json:"carried"`
+	Spent     float64   `json:"spent"`
+	Notified  int       `json:"notified"`
+}
+
+type BudgetStatus struct {
+	Budget
+	Period    string  `json:"period"`
+	Carried   float64 `json:"carried"`
+	Available float64 `json:"available"`
+	Spent     float64 `json:"spent"`
+	Remaining float64 `json:"remaining"`
+	Progress  float64 `json:"progress"`
+}
+
+// ThresholdReached is the payload of event.BudgetThresholdReached.
+type ThresholdReached struct {
+	Threshold int `json:"threshold"`
+	BudgetStatus
+}
+
+func (t ThresholdReached) Message() string {
+	name := t.Category
+	if t.PocketID != nil {
+		name = fmt.Sprintf("pocket %d", *t.PocketID)
+	}
+	return fmt.Sprintf("You have spent %d%% of your %s budget for %s: %.2f of %.2f.",
+		t.Threshold, name, t.Period, t.Spent, t.Available)
+}
+
+type BudgetRequest struct {
+	Category  string  `json:"category" validate:"required_without=PocketID,max=32"`
+	PocketID  *uint   `json:"pocket_id" validate:"required_without=Category"`
+	Amount    float64 `json:"amount" validate:"required,gt=0"`
+	Carryover bool    `json:"carryover"`
+}
+
+type BudgetUpdate struct {
+	Amount    *float64 `json:"amount" validate:"omitempty,gt=0"`
+	Carryover *bool    `json:"carryover"`
+}
+
+// Thresholds are the percentages of a budget at which the owner is told.
+var Thresholds = []int{80, 100}
+
+const periodLayout = "2006-01"
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
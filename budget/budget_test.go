@@ -0,0 +1,181 @@
+package budget
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/event"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSpend(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Budget{}, &BudgetPeriod{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	b := Budget{CreatedAt: jan, AccountID: 7, Category: "food", Amount: 1000, Carryover: true}
+	tx.Create(&b)
+
+	reached := []ThresholdReached{}
+	unsubscribe := event.Subscribe(func(e event.Event) {
+		if e.Type == event.BudgetThresholdReached {
+			reached = append(reached, e.Payload.(ThresholdReached))
+		}
+	})
+	defer unsubscribe()
+
+	food := tx.Where("category = ?", "food")
+
+	// Act & Assert
+	err = Spend(tx, food, 500, jan.AddDate(0, 0, 9))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(reached))
+
+	err = Spend(tx, food, 300, jan.AddDate(0, 0, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(reached))
+	assert.Equal(t, 80, reached[0].Threshold)
+	assert.Equal(t, 800.0, reached[0].Spent)
+	assert.Equal(t, "You have spent 80% of your food budget for 2026-01: 800.00 of 1000.00.", reached[0].Message())
+
+	err = Spend(tx, food, 300, jan.AddDate(0, 0, 11))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(reached))
+	assert.Equal(t, 100, reached[1].Threshold)
+	assert.Equal(t, -100.0, reached[1].Remaining)
+
+	err = Spend(tx, food, 50, jan.AddDate(0, 0, 12))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(reached))
+
+	// Nothing is left of January and nothing was spent in February, so all
+	// of February carries over into March.
+	err = Spend(tx, food, 100, jan.AddDate(0, 2, 3))
+	assert.NoError(t, err)
+
+	var periods []BudgetPeriod
+	tx.Where(&BudgetPeriod{BudgetID: b.ID}).Order("period").Find(&periods)
+	assert.Equal(t, 3, len(periods))
+	assert.Equal(t, "2026-02", periods[1].Period)
+	assert.Equal(t, 0.0, periods[1].Carried)
+	assert.Equal(t, "2026-03", periods[2].Period)
+	assert.Equal(t, 1000.0, periods[2].Carried)
+	assert.Equal(t, 100.0, periods[2].Spent)
+	assert.Equal(t, 2, len(reached))
+}
+
+func TestTrack(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Budget{}, &BudgetPeriod{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	pocketID := uint(3)
+	transport := Budget{AccountID: 8, Category: "transport", Amount: 500}
+	tx.Create(&transport)
+	holiday := Budget{AccountID: 8, PocketID: &pocketID, Amount: 2000}
+	tx.Create(&holiday)
+
+	stop := Track(tx)
+	defer stop()
+
+	now := time.Now()
+
+	// Act
+	event.Publish(event.Event{Type: event.TransferSent, AccountID: 8,
+		Payload: account.AccountTransfer{CreatedAt: now, Amount: 120, Category: "transport"}})
+	event.Publish(event.Event{Type: event.TransferSent, AccountID: 9,
+		Payload: account.AccountTransfer{CreatedAt: now, Amount: 999, Category: "transport"}})
+	event.Publish(event.Event{Type: event.TransferSent, AccountID: 8,
+		Payload: account.AccountTransfer{CreatedAt: now, Amount: 50, Category: "food"}})
+	event.Publish(event.Event{Type: event.PocketWithdrawn, AccountID: 8,
+		Payload: pocket.PocketTransfer{CreatedAt: now, From: pocketID, Amount: 700}})
+
+	// Assert
+	var transportPeriod, holidayPeriod BudgetPeriod
+	tx.Where(&BudgetPeriod{BudgetID: transport.ID}).First(&transportPeriod)
+	assert.Equal(t, 120.0, transportPeriod.Spent)
+	tx.Where(&BudgetPeriod{BudgetID: holiday.ID}).First(&holidayPeriod)
+	assert.Equal(t, 700.0, holidayPeriod.Spent)
+}
+
+func TestCreateBudget(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &Budget{}, &BudgetPeriod{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	acc := account.Account{Email: "budget@example.com", AccountNumber: "4444444444"}
+	tx.Create(&acc)
+	tx.Create(&[]account.AccountTransfer{
+		{Type: account.TransferTypeTransfer, From: "4444444444", To: "1", Amount: 450, Category: "food"},
+		{Type: account.TransferTypeTransfer, From: "4444444444", To: "1", Amount: 60, Category: "bills"},
+		{Type: account.TransferTypeTransfer, From: "4444444444", To: "1", Amount: 70, Category: "food",
+			CreatedAt: time.Now().AddDate(0, -1, 0)},
+	})
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(acc.ID))
+		return c.Next()
+	})
+	handler := New(tx)
+	app.Post("/budgets", handler.CreateBudget)
+	app.Get("/budgets", handler.GetAllBudgets)
+
+	post := func(body BudgetRequest) *http.Response {
+		reqBodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/budgets", bytes.NewReader(reqBodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Act
+	resp := post(BudgetRequest{Category: "Food", Amount: 500})
+
+	// Assert
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = post(BudgetRequest{Category: "food", Amount: 600})
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	resp = post(BudgetRequest{Amount: 600})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	req := httptest.NewRequest(http.MethodGet, "/budgets", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+
+	var budgets []BudgetStatus
+	err = json.NewDecoder(resp.Body).Decode(&budgets)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(budgets))
+	assert.Equal(t, "food", budgets[0].Category)
+	assert.Equal(t, 450.0, budgets[0].Spent)
+	assert.Equal(t, 90.0, budgets[0].Progress)
+	assert.Equal(t, time.Now().Format(periodLayout), budgets[0].Period)
+}
@@ -0,0 +1,237 @@
+package budget
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// @Summary Create a budget
+// @Description Create a monthly budget for a transfer category or a pocket
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param budget body budget.BudgetRequest true "BudgetRequest data"
+// @Success 201 {object} budget.BudgetStatus
+// @Security  Bearer
+// @Router /budgets/ [post]
+func (h *handler) CreateBudget(c *fiber.Ctx) error {
+	req := &BudgetRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	acc := uint(c.Locals("account_id").(int))
+	b := &Budget{AccountID: acc, Amount: req.Amount, Carryover: req.Carryover}
+	q := h.DB.Model(&Budget{}).Where("account_id = ?", acc)
+	if req.PocketID != nil {
+		p := &pocket.Pocket{}
+		if err := h.DB.Where("account_id = ?", acc).First(p, *req.PocketID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.NotFound(apperr.CodePocketNotFound, "pocket not found")
+			}
+			return apperr.Internal(err)
+		}
+		b.PocketID = &p.ID
+		q = q.Where("pocket_id = ?", p.ID)
+	} else {
+		b.Category = strings.ToLower(strings.TrimSpace(req.Category))
+		q = q.Where("category = ? AND pocket_id IS NULL", b.Category)
+	}
+
+	var exists int64
+	if err := q.Count(&exists).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	if exists > 0 {
+		return apperr.Conflict(apperr.CodeAlreadyExists, "budget already exists")
+	}
+
+	var s BudgetStatus
+	var reached []ThresholdReached
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(b).Error; err != nil {
+			return err
+		}
+
+		// Spending before the budget existed still counts for this month.
+		now := time.Now()
+		spent, err := spentSince(tx, b, monthStart(now))
+		if err != nil {
+			return err
+		}
+		p, err := period(tx, b, now)
+		if err != nil {
+			return err
+		}
+		p.Spent = spent
+		if err := tx.Model(p).Update("spent", spent).Error; err != nil {
+			return err
+		}
+		reached, err = check(tx, b, p)
+		s = status(b, p)
+		return err
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	notify(b, reached)
+
+	return c.Status(fiber.StatusCreated).JSON(s)
+}
+
+// spentSince sums what b would have counted from since on.
+func spentSince(tx *gorm.DB, b *Budget, since time.Time) (float64, error) {
+	var sum float64
+	if b.PocketID != nil {
+		err := tx.Model(&pocket.PocketTransfer{}).
+			Where(&pocket.PocketTransfer{From: *b.PocketID}).
+			Where("type IN ? AND created_at >= ?", []string{pocket.TransferTypeWithdrawal, pocket.TransferTypeTransfer}, since).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&sum).Error
+		return sum, err
+	}
+
+	acc := &account.Account{}
+	if err := tx.First(acc, b.AccountID).Error; err != nil {
+		return 0, err
+	}
+	transfers := []account.AccountTransfer{}
+	err := tx.Where(&account.AccountTransfer{From: acc.AccountNumber, Type: account.TransferTypeTransfer, Category: b.Category}).
+		Where("created_at >= ?", since).
+		Find(&transfers).Error
+	total := decimal.Zero
+	for _, t := range transfers {
+		total = total.Add(decimal.NewFromFloat(t.Amount))
+	}
+	sum, _ = total.Float64()
+	return sum, err
+}
+
+// @Summary Get all budgets
+// @Description Get every budget of the caller with how much of it was spent this month
+// @Tags budgets
+// @Produce json
+// @Success 200 {array} budget.BudgetStatus
+// @Security  Bearer
+// @Router /budgets/ [get]
+func (h *handler) GetAllBudgets(c *fiber.Ctx) error {
+	acc := c.Locals("account_id").(int)
+	budgets := []Budget{}
+	if err := h.DB.Where("account_id = ?", acc).Order("id").Find(&budgets).Error; err != nil {
+		return apperr.Internal(err)
+	}
+
+	res := []BudgetStatus{}
+	now := time.Now()
+	for i := range budgets {
+		p, err := period(h.DB, &budgets[i], now)
+		if err != nil {
+			return apperr.Internal(err)
+		}
+		res = append(res, status(&budgets[i], p))
+	}
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+// @Summary Update a budget
+// @Description Change the monthly amount of a budget or whether it carries over. The current month changes too
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param id path int true "Budget ID"
+// @Param budget body budget.BudgetUpdate true "BudgetUpdate data"
+// @Success 200 {object} budget.BudgetStatus
+// @Security  Bearer
+// @Router /budgets/{id} [put]
+func (h *handler) UpdateBudget(c *fiber.Ctx) error {
+	req := &BudgetUpdate{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	acc := c.Locals("account_id").(int)
+	b := &Budget{}
+	if err := h.DB.Where("account_id = ?", acc).First(b, c.Params("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodeNotFound, "budget not found")
+		}
+		return apperr.Internal(err)
+	}
+
+	if req.Amount != nil {
+		b.Amount = *req.Amount
+	}
+	if req.Carryover != nil {
+		b.Carryover = *req.Carryover
+	}
+
+	var s BudgetStatus
+	var reached []ThresholdReached
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(b).Select("amount", "carryover").Updates(b).Error; err != nil {
+			return err
+		}
+		p, err := period(tx, b, time.Now())
+		if err != nil {
+			return err
+		}
+		p.Amount = b.Amount
+		if err := tx.Model(p).Update("amount", b.Amount).Error; err != nil {
+			return err
+		}
+		reached, err = check(tx, b, p)
+		s = status(b, p)
+		return err
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	notify(b, reached)
+
+	return c.Status(fiber.StatusOK).JSON(s)
+}
+
+// @Summary Delete a budget
+// @Description Delete a budget and its history
+// @Tags budgets
+// @Param id path int true "Budget ID"
+// @Success 200 {object} budget.SuccessResponse
+// @Security  Bearer
+// @Router /budgets/{id} [delete]
+func (h *handler) DeleteBudget(c *fiber.Ctx) error {
+	acc := c.Locals("account_id").(int)
+	b := &Budget{}
+	if err := h.DB.Where("account_id = ?", acc).First(b, c.Params("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodeNotFound, "budget not found")
+		}
+		return apperr.Internal(err)
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&BudgetPeriod{BudgetID: b.ID}).Delete(&BudgetPeriod{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(b).Error
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(SuccessResponse{Message: "delete budget success"})
+}
@@ -0,0 +1,152 @@
+package budget
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/event"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Track keeps budgets up to date with the transfers and pocket withdrawals
+// published on the event bus, until the returned function is called.
+func Track(db *gorm.DB) func() {
+	return event.Subscribe(func(e event.Event) {
+		var err error
+		switch t := e.Payload.(type) {
+		case account.AccountTransfer:
+			if e.Type != event.TransferSent {
+				return
+			}
+			err = Spend(db, db.Where("account_id = ? AND category = ? AND pocket_id IS NULL", e.AccountID, t.Category), t.Amount, t.CreatedAt)
+		case pocket.PocketTransfer:
+			if e.Type != event.PocketWithdrawn {
+				return
+			}
+			err = Spend(db, db.Where("pocket_id = ?", t.From), t.Amount, t.CreatedAt)
+		}
+		if err != nil {
+			log.Printf("budget: %s for account %d: %s", e.Type, e.AccountID, err)
+		}
+	})
+}
+
+// Spend adds amount spent at at to every budget matched by scope and tells
+// their owners about each threshold crossed.
+func Spend(db *gorm.DB, scope *gorm.DB, amount float64, at time.Time) error {
+	budgets := []Budget{}
+	if err := scope.Order("id").Find(&budgets).Error; err != nil {
+		return err
+	}
+
+	for i := range budgets {
+		b := &budgets[i]
+		var reached []ThresholdReached
+		err := db.Transaction(func(tx *gorm.DB) error {
+			p, err := period(tx, b, at)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(p).Update("spent", gorm.Expr("spent + ?", amount)).Error; err != nil {
+				return err
+			}
+			if err := tx.First(p, p.ID).Error; err != nil {
+				return err
+			}
+			reached, err = check(tx, b, p)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		notify(b, reached)
+	}
+	return nil
+}
+
+// period returns the period of b that at falls in, creating it if needed.
+// A budget that carries over starts the month with what was left of the
+// month before, so missing earlier periods are created on the way.
+func period(tx *gorm.DB, b *Budget, at time.Time) (*BudgetPeriod, error) {
+	start := monthStart(at)
+	p := &BudgetPeriod{}
+	err := tx.Where(&BudgetPeriod{BudgetID: b.ID, Period: start.Format(periodLayout)}).First(p).Error
+	if err == nil {
+		return p, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	p = &BudgetPeriod{BudgetID: b.ID, Period: start.Format(periodLayout), Amount: b.Amount}
+	prevStart := start.AddDate(0, -1, 0)
+	if b.Carryover && !prevStart.Before(monthStart(b.CreatedAt)) {
+		prev, err := period(tx, b, prevStart)
+		if err != nil {
+			return nil, err
+		}
+		left := decimal.NewFromFloat(prev.Amount).Add(decimal.NewFromFloat(prev.Carried)).Sub(decimal.NewFromFloat(prev.Spent))
+		if left.IsPositive() {
+			p.Carried, _ = left.Float64()
+		}
+	}
+
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(p)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		p = &BudgetPeriod{}
+		err = tx.Where(&BudgetPeriod{BudgetID: b.ID, Period: start.Format(periodLayout)}).First(p).Error
+		return p, err
+	}
+	return p, nil
+}
+
+// check marks every threshold p has reached and not been told about yet.
+func check(tx *gorm.DB, b *Budget, p *BudgetPeriod) ([]ThresholdReached, error) {
+	s := status(b, p)
+	reached := []ThresholdReached{}
+	for _, th := range Thresholds {
+		if s.Progress < float64(th) || p.Notified >= th {
+			continue
+		}
+		res := tx.Model(&BudgetPeriod{}).Where("id = ? AND notified < ?", p.ID, th).Update("notified", th)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected > 0 {
+			p.Notified = th
+			reached = append(reached, ThresholdReached{Threshold: th, BudgetStatus: s})
+		}
+	}
+	return reached, nil
+}
+
+func notify(b *Budget, reached []ThresholdReached) {
+	for _, r := range reached {
+		event.Publish(event.Event{Type: event.BudgetThresholdReached, AccountID: b.AccountID, Payload: r})
+	}
+}
+
+func status(b *Budget, p *BudgetPeriod) BudgetStatus {
+	available := decimal.NewFromFloat(p.Amount).Add(decimal.NewFromFloat(p.Carried))
+	spent := decimal.NewFromFloat(p.Spent)
+
+	s := BudgetStatus{Budget: *b, Period: p.Period, Carried: p.Carried, Spent: p.Spent}
+	s.Available, _ = available.Float64()
+	s.Remaining, _ = available.Sub(spent).Float64()
+	if available.IsPositive() {
+		s.Progress, _ = spent.Div(available).Mul(decimal.NewFromInt(100)).Round(2).Float64()
+	}
+	return s
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,421 @@
+// Package config loads the settings of the server. Every setting has a
+// default, overridden in turn by a config file, the environment and command
+// line flags, and the result is checked before anything starts.
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// File is the config file the settings were read from, if any.
+	File            string
+	Port            int
+	GRPCPort        int
+	ShutdownTimeout time.Duration
+	LogLevel        string
+	DB              DB
+	JWT             JWT
+	CORS            CORS
+	Policy          Policy
+}
+
+type DB struct {
+	// Driver is postgres or sqlite, where Name is the path of the file.
+	Driver   string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+	// SlowThreshold is how long a query takes before it is logged as slow.
+	SlowThreshold time.Duration
+	// MigrateOnStart has the server apply pending migrations before it
+	// starts.
+	MigrateOnStart bool
+}
+
+type JWT struct {
+	Secret string
+	// PreviousSecrets are secrets rotated out, that tokens signed before
+	// the rotation are still verified with.
+	PreviousSecrets []string
+	AccessTTL       time.Duration
+	RefreshTTL      time.Duration
+}
+
+type CORS struct {
+	AllowOrigins string
+	AllowHeaders string
+}
+
+// Policy is how the bank treats the money of its customers.
+type Policy struct {
+	// ApprovalTTL is how long an action waits for a second admin before it
+	// expires.
+	ApprovalTTL time.Duration
+	// ReversalShortfall is what happens to a reversal the recipient cannot
+	// cover: pending leaves it to be settled later, negative takes the
+	// recipient below zero.
+	ReversalShortfall string
+	// TimeDepositBonusRate and EarlyWithdrawalPenaltyRate, in percent, are
+	// fixed on a locked pocket when it is opened.
+	TimeDepositBonusRate       float64
+	EarlyWithdrawalPenaltyRate float64
+}
+
+// ReversalShortfalls are what ReversalShortfall can be.
+var ReversalShortfalls = []string{"pending", "negative"}
+
+// LogLevels are the levels LogLevel can be, from the quietest.
+var LogLevels = []string{"silent", "error", "warn", "info"}
+
+// Default is the configuration before anything overrides it. It has no JWT
+// secret, so it does not pass Validate on its own.
+func Default() *Config {
+	return &Config{
+		Port:            8000,
+		GRPCPort:        9000,
+		ShutdownTimeout: 5 * time.Second,
+		LogLevel:        "info",
+		DB: DB{
+			Driver:         "postgres",
+			Host:           "localhost",
+			Port:           5432,
+			SSLMode:        "disable",
+			SlowThreshold:  time.Second,
+			MigrateOnStart: true,
+		},
+		JWT: JWT{
+			AccessTTL:  15 * time.Minute,
+			RefreshTTL: 24 * time.Hour,
+		},
+		CORS: CORS{
+			AllowOrigins: "*",
+			AllowHeaders: "Origin, Content-Type, Accept",
+		},
+		Policy: Policy{
+			ApprovalTTL:                24 * time.Hour,
+			ReversalShortfall:          "pending",
+			TimeDepositBonusRate:       1,
+			EarlyWithdrawalPenaltyRate: 2,
+		},
+	}
+}
+
+// Addr is the address the HTTP API listens on.
+func (c *Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Port)
+}
+
+// GRPCAddr is the address the gRPC API listens on.
+func (c *Config) GRPCAddr() string {
+	return fmt.Sprintf(":%d", c.GRPCPort)
+}
+
+func (d DB) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
+}
+
+// setting is one setting, named key in the environment and the config file
+// and flag on the command line.
+type setting struct {
+	key   string
+	flag  string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"PORT", "port", "port of the HTTP API", integer(func(c *Config) *int { return &c.Port })},
+	{"GRPC_PORT", "grpc-port", "port of the gRPC API", integer(func(c *Config) *int { return &c.GRPCPort })},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long open streams may hold up a shutdown", duration(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+	{"LOG_LEVEL", "log-level", "level of the database log: silent, error, warn or info", text(func(c *Config) *string { return &c.LogLevel })},
+	{"DB_DRIVER", "db-driver", "database driver: postgres or sqlite", text(func(c *Config) *string { return &c.DB.Driver })},
+	{"DB_HOST", "db-host", "database host", text(func(c *Config) *string { return &c.DB.Host })},
+	{"DB_PORT", "db-port", "database port", integer(func(c *Config) *int { return &c.DB.Port })},
+	{"DB_USER", "db-user", "database user", text(func(c *Config) *string { return &c.DB.User })},
+	{"DB_PASSWORD", "db-password", "database password", text(func(c *Config) *string { return &c.DB.Password })},
+	{"DB_NAME", "db-name", "database name", text(func(c *Config) *string { return &c.DB.Name })},
+	{"DB_SSLMODE", "db-sslmode", "database sslmode", text(func(c *Config) *string { return &c.DB.SSLMode })},
+	{"DB_SLOW_THRESHOLD", "db-slow-threshold", "how long a query takes before it is logged as slow", duration(func(c *Config) *time.Duration { return &c.DB.SlowThreshold })},
+	{"MIGRATE_ON_START", "migrate-on-start", "apply pending migrations before the server starts", boolean(func(c *Config) *bool { return &c.DB.MigrateOnStart })},
+	{"JWT_SECRET", "jwt-secret", "secret signing the access and refresh tokens", text(func(c *Config) *string { return &c.JWT.Secret })},
+	{"JWT_PREVIOUS_SECRETS", "jwt-previous-secrets", "comma-separated secrets that tokens signed before key rotations are verified with, newest first", list(func(c *Config) *[]string { return &c.JWT.PreviousSecrets })},
+	{"ACCESS_TOKEN_TTL", "access-token-ttl", "how long an access token is valid", duration(func(c *Config) *time.Duration { return &c.JWT.AccessTTL })},
+	{"REFRESH_TOKEN_TTL", "refresh-token-ttl", "how long a refresh token is valid", duration(func(c *Config) *time.Duration { return &c.JWT.RefreshTTL })},
+	{"CORS_ALLOW_ORIGINS", "cors-allow-origins", "origins allowed to call the HTTP API", text(func(c *Config) *string { return &c.CORS.AllowOrigins })},
+	{"CORS_ALLOW_HEADERS", "cors-allow-headers", "headers cross-origin requests may send", text(func(c *Config) *string { return &c.CORS.AllowHeaders })},
+	{"APPROVAL_TTL", "approval-ttl", "how long an admin action waits for approval", duration(func(c *Config) *time.Duration { return &c.Policy.ApprovalTTL })},
+	{"REVERSAL_SHORTFALL_POLICY", "reversal-shortfall-policy", "what a reversal the recipient cannot cover does: pending or negative", text(func(c *Config) *string { return &c.Policy.ReversalShortfall })},
+	{"TIME_DEPOSIT_BONUS_RATE", "time-deposit-bonus-rate", "yearly bonus of new time deposits, in percent", number(func(c *Config) *float64 { return &c.Policy.TimeDepositBonusRate })},
+	{"EARLY_WITHDRAWAL_PENALTY_RATE", "early-withdrawal-penalty-rate", "penalty of new time deposits on early withdrawals, in percent", number(func(c *Config) *float64 { return &c.Policy.EarlyWithdrawalPenaltyRate })},
+}
+
+func text(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func list(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		values := []string{}
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		*field(c) = values
+		return nil
+	}
+}
+
+func integer(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func number(field func(c *Config) *float64) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+func boolean(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func duration(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 15m or 24h", value)
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// Load builds the configuration from the defaults, the config file named by
+// the -config flag or CONFIG_FILE, the environment and the flags in args,
+// each overriding the one before, and validates it. It also returns what
+// is left of args after the flags, such as a subcommand.
+func Load(args []string) (*Config, []string, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(key string) (string, bool)) (*Config, []string, error) {
+	fs := flag.NewFlagSet("bank", flag.ContinueOnError)
+	file := fs.String("config", "", "file of KEY=VALUE settings")
+	flags := map[string]string{}
+	for _, s := range settings {
+		key := s.key
+		fs.Func(s.flag, s.usage+" ("+key+")", func(value string) error {
+			flags[key] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *file == "" {
+		*file, _ = lookupEnv("CONFIG_FILE")
+	}
+	values := map[string]string{}
+	if *file != "" {
+		var err error
+		if values, err = readFile(*file); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	c := Default()
+	c.File = *file
+	var errs []error
+	for _, s := range settings {
+		value, ok := flags[s.key]
+		if !ok {
+			// Variables set to nothing, as compose files often leave them,
+			// count as unset.
+			value, ok = lookupEnv(s.key)
+			ok = ok && value != ""
+		}
+		if !ok {
+			value, ok = values[s.key]
+		}
+		if !ok {
+			continue
+		}
+		if err := s.set(c, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.key, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return c, fs.Args(), nil
+}
+
+// readFile reads a file of KEY=VALUE lines, such as a .env file. Blank
+// lines and lines starting with # are skipped, and values may be quoted.
+func readFile(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", name, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, scanner.Err()
+}
+
+// WriteFile sets the settings in values in config file name, keeping its
+// other lines as they are and adding the settings it does not have yet.
+func WriteFile(name string, values map[string]string) error {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	done := map[string]bool{}
+	for i, line := range lines {
+		text := strings.TrimSpace(line)
+		key, _, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		key = strings.TrimSpace(key)
+		value, set := values[key]
+		if !ok || strings.HasPrefix(text, "#") || !set {
+			continue
+		}
+		prefix := ""
+		if strings.HasPrefix(text, "export ") {
+			prefix = "export "
+		}
+		lines[i] = prefix + key + "=" + value
+		done[key] = true
+	}
+	for _, s := range settings {
+		if value, set := values[s.key]; set && !done[s.key] {
+			lines = append(lines, s.key+"="+value)
+		}
+	}
+	return os.WriteFile(name, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+}
+
+// Validate reports every setting that is missing or out of range.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("JWT_SECRET is required"))
+	}
+	ports := []struct {
+		key  string
+		port int
+	}{{"PORT", c.Port}, {"GRPC_PORT", c.GRPCPort}, {"DB_PORT", c.DB.Port}}
+	for _, p := range ports {
+		if p.port < 1 || p.port > 65535 {
+			errs = append(errs, fmt.Errorf("%s must be between 1 and 65535", p.key))
+		}
+	}
+	if c.Port == c.GRPCPort {
+		errs = append(errs, errors.New("PORT and GRPC_PORT must differ"))
+	}
+	if c.JWT.AccessTTL <= 0 {
+		errs = append(errs, errors.New("ACCESS_TOKEN_TTL must be positive"))
+	}
+	if c.JWT.RefreshTTL <= c.JWT.AccessTTL {
+		errs = append(errs, errors.New("REFRESH_TOKEN_TTL must be longer than ACCESS_TOKEN_TTL"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.DB.SlowThreshold <= 0 {
+		errs = append(errs, errors.New("DB_SLOW_THRESHOLD must be positive"))
+	}
+	if c.DB.Driver != "postgres" && c.DB.Driver != "sqlite" {
+		errs = append(errs, errors.New("DB_DRIVER must be postgres or sqlite"))
+	}
+	if c.DB.Driver == "sqlite" && c.DB.Name == "" {
+		errs = append(errs, errors.New("DB_NAME, the path of the database file, is required with sqlite"))
+	}
+	if !contains(LogLevels, c.LogLevel) {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be one of %s", strings.Join(LogLevels, ", ")))
+	}
+	if c.CORS.AllowOrigins == "" {
+		errs = append(errs, errors.New("CORS_ALLOW_ORIGINS is required"))
+	}
+	if c.Policy.ApprovalTTL <= 0 {
+		errs = append(errs, errors.New("APPROVAL_TTL must be positive"))
+	}
+	if !contains(ReversalShortfalls, c.Policy.ReversalShortfall) {
+		errs = append(errs, fmt.Errorf("REVERSAL_SHORTFALL_POLICY must be one of %s", strings.Join(ReversalShortfalls, ", ")))
+	}
+	rates := []struct {
+		key  string
+		rate float64
+	}{{"TIME_DEPOSIT_BONUS_RATE", c.Policy.TimeDepositBonusRate}, {"EARLY_WITHDRAWAL_PENALTY_RATE", c.Policy.EarlyWithdrawalPenaltyRate}}
+	for _, r := range rates {
+		if r.rate < 0 || r.rate > 100 {
+			errs = append(errs, fmt.Errorf("%s must be between 0 and 100", r.key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func env(values map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func TestLoad(t *testing.T) {
+	// Arrange
+	file := filepath.Join(t.TempDir(), "app.env")
+	err := os.WriteFile(file, []byte(`# settings of the test server
+JWT_SECRET="from file"
+PORT=8100
+GRPC_PORT=9100
+export ACCESS_TOKEN_TTL=5m
+LOG_LEVEL=warn
+JWT_PREVIOUS_SECRETS=old, older
+TIME_DEPOSIT_BONUS_RATE=1.5
+`), 0o600)
+	assert.NoError(t, err)
+
+	// Act
+	cfg, args, err := load([]string{"-config", file, "-port", "8200", "-refresh-token-ttl", "2h", "audit-verify"},
+		env(map[string]string{"PORT": "8300", "GRPC_PORT": "9300", "LOG_LEVEL": "", "DB_HOST": "postgres", "REVERSAL_SHORTFALL_POLICY": "negative"}))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"audit-verify"}, args)
+	assert.Equal(t, file, cfg.File)
+	assert.Equal(t, "from file", cfg.JWT.Secret)
+	assert.Equal(t, []string{"old", "older"}, cfg.JWT.PreviousSecrets)
+	assert.Equal(t, 8200, cfg.Port)
+	assert.Equal(t, ":9300", cfg.GRPCAddr())
+	assert.Equal(t, 5*time.Minute, cfg.JWT.AccessTTL)
+	assert.Equal(t, 2*time.Hour, cfg.JWT.RefreshTTL)
+	assert.Equal(t, "warn", cfg.LogLevel)
+	assert.Equal(t, "postgres", cfg.DB.Host)
+	assert.Equal(t, 5*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, "*", cfg.CORS.AllowOrigins)
+	assert.Equal(t, 24*time.Hour, cfg.Policy.ApprovalTTL)
+	assert.Equal(t, "negative", cfg.Policy.ReversalShortfall)
+	assert.Equal(t, 1.5, cfg.Policy.TimeDepositBonusRate)
+	assert.Equal(t, 2.0, cfg.Policy.EarlyWithdrawalPenaltyRate)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	// Act
+	_, _, missing := load(nil, env(nil))
+	_, _, malformed := load([]string{"-jwt-secret", "s", "-access-token-ttl", "soon", "-port", "http"}, env(nil))
+	_, _, invalid := load([]string{"-jwt-secret", "s", "-access-token-ttl", "48h", "-grpc-port", "8000"},
+		env(map[string]string{"LOG_LEVEL": "debug"}))
+	_, _, policy := load([]string{"-jwt-secret", "s", "-approval-ttl", "0s", "-early-withdrawal-penalty-rate", "150"},
+		env(map[string]string{"REVERSAL_SHORTFALL_POLICY": "always"}))
+	_, _, unreadable := load([]string{"-config", filepath.Join(t.TempDir(), "missing.env")}, env(nil))
+
+	// Assert
+	assert.EqualError(t, missing, "JWT_SECRET is required")
+	assert.EqualError(t, malformed, "PORT: \"http\" is not a number\nACCESS_TOKEN_TTL: \"soon\" is not a duration such as 15m or 24h")
+	assert.EqualError(t, invalid, "PORT and GRPC_PORT must differ\nREFRESH_TOKEN_TTL must be longer than ACCESS_TOKEN_TTL\nLOG_LEVEL must be one of silent, error, warn, info")
+	assert.EqualError(t, policy, "APPROVAL_TTL must be positive\nREVERSAL_SHORTFALL_POLICY must be one of pending, negative\nEARLY_WITHDRAWAL_PENALTY_RATE must be between 0 and 100")
+	assert.Error(t, unreadable)
+}
+
+func TestWriteFile(t *testing.T) {
+	// Arrange
+	file := filepath.Join(t.TempDir(), "app.env")
+	err := os.WriteFile(file, []byte("# keys\nexport JWT_SECRET=old\nPORT=8100\n"), 0o600)
+	assert.NoError(t, err)
+
+	// Act
+	err = WriteFile(file, map[string]string{"JWT_SECRET": "new", "JWT_PREVIOUS_SECRETS": "old"})
+	b, _ := os.ReadFile(file)
+	cfg, _, loadErr := load([]string{"-config", file}, env(nil))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "# keys\nexport JWT_SECRET=new\nPORT=8100\nJWT_PREVIOUS_SECRETS=old\n", string(b))
+	assert.NoError(t, loadErr)
+	assert.Equal(t, "new", cfg.JWT.Secret)
+	assert.Equal(t, []string{"old"}, cfg.JWT.PreviousSecrets)
+}
@@ -43,14 +43,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/accounts/": {
-            "get": {
+        "/account/alias": {
+            "put": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Get details of all accounts",
+                "description": "Set the alias other accounts can use instead of the account number",
                 "consumes": [
                     "application/json"
                 ],
@@ -60,138 +60,302 @@ const docTemplate = `{
                 "tags": [
                     "accounts"
                 ],
-                "summary": "Get all accounts",
+                "summary": "Set account alias",
+                "parameters": [
+                    {
+                        "description": "AliasRequest data",
+                        "name": "alias",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.AliasRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/account.AccountResponseList"
-                            }
+                            "$ref": "#/definitions/account.AccountResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/account/balance": {
+            "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Create a new account with the input payload",
-                "consumes": [
+                "description": "Get the balance of the caller's account and pockets at a point in time, now by default, worked out from the last end-of-day snapshot before it and the movements made after the snapshot",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Get a past balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, e.g. 2026-09-30T23:59:59Z",
+                        "name": "at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ledger.HistoricalBalance"
+                        }
+                    }
+                }
+            }
+        },
+        "/account/categories/suggest": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Suggest a category for a transfer to an account, from past transfers to it or the memo",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "accounts"
                 ],
-                "summary": "Create a new account",
+                "summary": "Suggest a transfer category",
                 "parameters": [
                     {
-                        "description": "Create account",
-                        "name": "account",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Recipient account number",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transfer memo",
+                        "name": "memo",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/account.AccountRequest"
+                            "$ref": "#/definitions/account.CategorySuggestion"
                         }
                     }
+                }
+            }
+        },
+        "/account/export": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Export the history of the caller's account and pockets between two days (the last 30 by default, up to a year) for accounting software, as SWIFT MT940 or OFX 2.1.1. Pockets are sub-accounts: separate MT940 statements, or OFX savings accounts",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Export the account history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "mt940 or ofx",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day (YYYY-MM-DD), today by default",
+                        "name": "to",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/account.SuccessResponse"
+                            "type": "file"
                         }
                     }
                 }
             }
         },
-        "/accounts/transfer/": {
-            "post": {
+        "/account/insights": {
+            "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Transfer funds from one accounts to another with account number",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get spend per category, top counterparties and the change from the month before",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "accounts"
                 ],
-                "summary": "Transfer funds between accounts",
+                "summary": "Get spending insights",
                 "parameters": [
                     {
-                        "description": "AccountTransferRequest data",
-                        "name": "transfer",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Month as YYYY-MM, the current month by default",
+                        "name": "period",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/account.AccountTransferRequest"
+                            "$ref": "#/definitions/account.Insights"
                         }
                     }
+                }
+            }
+        },
+        "/account/statement": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the ISO 20022 camt.053 statement of the caller's account and pockets for a day, yesterday by default. Pockets are statements of their own, with the account number and pocket ID as account",
+                "produces": [
+                    "text/xml"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Get an end-of-day statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Day of the statement (YYYY-MM-DD)",
+                        "name": "date",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/account.SuccessResponse"
+                            "type": "file"
                         }
                     }
                 }
             }
         },
-        "/login/": {
-            "post": {
-                "description": "Authenticate account and obtain access and refresh tokens",
-                "consumes": [
-                    "application/json"
+        "/account/stream": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
                 ],
+                "description": "Server-sent events of the caller's transfers in and out, pocket updates and the balance after each of them. Every event has an ID; reconnecting with it as Last-Event-ID replays the ones missed, as far back as the last 100.",
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "auth"
+                    "accounts"
                 ],
-                "summary": "Login account",
+                "summary": "Stream account updates",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "account",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "ID of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event stream",
                         "schema": {
-                            "$ref": "#/definitions/account.Login"
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/apperr.Problem"
                         }
                     }
+                }
+            }
+        },
+        "/account/stream/ws": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "The WebSocket equivalent of /account/stream. Every update is a JSON message with its ID, type, data and time; reconnecting with last_event_id (or a Last-Event-ID header) replays the ones missed, as far back as the last 100.",
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Stream account updates over a WebSocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the last message received",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "101": {
+                        "description": "Switching Protocols",
                         "schema": {
-                            "$ref": "#/definitions/account.TokenResponse"
+                            "$ref": "#/definitions/stream.SocketMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/apperr.Problem"
+                        }
+                    },
+                    "426": {
+                        "description": "Upgrade Required",
+                        "schema": {
+                            "$ref": "#/definitions/apperr.Problem"
                         }
                     }
                 }
             }
         },
-        "/pockets/": {
+        "/accounts/": {
             "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Get details of all pocket",
+                "description": "Get details of all accounts",
                 "consumes": [
                     "application/json"
                 ],
@@ -199,16 +363,16 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "pockets"
+                    "accounts"
                 ],
-                "summary": "Get all pocket",
+                "summary": "Get all accounts",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/pocket.Pocket"
+                                "$ref": "#/definitions/account.AccountResponseList"
                             }
                         }
                     }
@@ -220,7 +384,7 @@ const docTemplate = `{
                         "Bearer": []
                     }
                 ],
-                "description": "Create a new pocket with the provided data",
+                "description": "Create a new account with the input payload",
                 "consumes": [
                     "application/json"
                 ],
@@ -228,17 +392,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "pockets"
+                    "accounts"
                 ],
-                "summary": "Create a new pocket",
+                "summary": "Create a new account",
                 "parameters": [
                     {
-                        "description": "PocketCreate data",
-                        "name": "pocket",
+                        "description": "Create account",
+                        "name": "account",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/pocket.PocketCreate"
+                            "$ref": "#/definitions/account.AccountRequest"
                         }
                     }
                 ],
@@ -246,20 +410,20 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/pocket.SuccessResponse"
+                            "$ref": "#/definitions/account.SuccessResponse"
                         }
                     }
                 }
             }
         },
-        "/pockets/transfer/": {
+        "/accounts/transfer/": {
             "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Transfer funds from one pocket to another with pocket id",
+                "description": "Transfer funds from one accounts to another with account number",
                 "consumes": [
                     "application/json"
                 ],
@@ -267,17 +431,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "pockets"
+                    "accounts"
                 ],
-                "summary": "Transfer funds between pockets",
+                "summary": "Transfer funds between accounts",
                 "parameters": [
                     {
-                        "description": "PocketTransferRequest data",
+                        "description": "AccountTransferRequest data",
                         "name": "transfer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/pocket.PocketTransferRequest"
+                            "$ref": "#/definitions/account.AccountTransferRequest"
                         }
                     }
                 ],
@@ -285,115 +449,341 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/pocket.SuccessResponse"
+                            "$ref": "#/definitions/account.SuccessResponse"
                         }
                     }
                 }
             }
         },
-        "/pockets/{id}": {
-            "get": {
+        "/accounts/transfers/batch": {
+            "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Get pocket by ID",
+                "description": "Upload up to 1000 transfers as JSON, as CSV (text/csv or a multipart \"file\") with recipient, amount and memo columns, or as an ISO 20022 pain.001 credit transfer initiation (application/xml or a multipart \"file\"), with the mode in the query. Every row is validated first; an all_or_nothing batch (the default) with an invalid row is refused, a best_effort batch skips them. The transfers are then made in the background",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "pockets"
+                    "accounts"
                 ],
-                "summary": "Get pocket by ID",
+                "summary": "Upload a batch of transfers",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Pocket ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "BatchRequest data",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/batch.BatchRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "all_or_nothing or best_effort",
+                        "name": "mode",
+                        "in": "query"
                     }
                 ],
-                "responses": {}
-            },
-            "put": {
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/batch.Batch"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/batch.ValidationResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/transfers/batch/{id}": {
+            "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Update a pocket with the provided data",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get the status of a batch of transfers and how each row went",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "pockets"
+                    "accounts"
                 ],
-                "summary": "Update a pocket",
+                "summary": "Get a batch of transfers",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Pocket ID",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/batch.Batch"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/transfers/batch/{id}/result": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Download how each row of a batch of transfers went as a CSV file",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Download the result of a batch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/accounts/{id}/adjust": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Submit a manual credit (positive amount) or debit (negative amount) for approval",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Adjust an account balance",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Account ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "PocketUpdate data",
-                        "name": "pocket",
+                        "description": "AdjustmentRequest data",
+                        "name": "adjustment",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/pocket.PocketUpdate"
+                            "$ref": "#/definitions/account.AdjustmentRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/pocket.SuccessResponse"
+                            "$ref": "#/definitions/approval.PendingAction"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/accounts/{id}/close": {
+            "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Delete a pocket by ID",
+                "description": "Submit the closure of an empty account for approval",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "pockets"
+                    "admin"
                 ],
-                "summary": "Delete a pocket",
+                "summary": "Close an account",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Pocket ID",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/approval.PendingAction"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/accounts/{id}/limit": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Lower the daily transfer limit right away, or submit an increase for approval. 0 means no limit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set the daily transfer limit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Account ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "TransferLimitRequest data",
+                        "name": "limit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.TransferLimitRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/pocket.SuccessResponse"
+                            "$ref": "#/definitions/account.AccountResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/approval.PendingAction"
                         }
                     }
                 }
             }
         },
-        "/refresh": {
+        "/admin/approvals/": {
             "get": {
-                "description": "Refresh the access token using a valid refresh token",
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get actions submitted for approval, optionally filtered by status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get approval requests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pending, approved, rejected, expired or failed",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/approval.PendingAction"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/approvals/{id}/approve": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Approve an action submitted by another admin and execute it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Approve an action",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pending action ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/approval.PendingAction"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/approvals/{id}/reject": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Reject an action waiting for approval",
                 "consumes": [
                     "application/json"
                 ],
@@ -401,116 +791,3287 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
+                ],
+                "summary": "Reject an action",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pending action ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "RejectRequest data",
+                        "name": "reject",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/approval.RejectRequest"
+                        }
+                    }
                 ],
-                "summary": "Refresh access token",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/account.TokenResponse"
+                            "$ref": "#/definitions/approval.PendingAction"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/interest/accrue": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Accrue one day of interest on every active plan. Safe to run again for the same date.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Accrue interest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Date to accrue for, YYYY-MM-DD, default today",
+                        "name": "date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/interest.RunResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/interest/capitalize": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Post the accrued interest of a month, less withholding tax, to the accounts and pockets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Capitalize interest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Month to capitalize, YYYY-MM, default this month",
+                        "name": "period",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/interest.RunResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "account.AccountRequest": {
+        },
+        "/admin/interest/plans": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get every interest plan with its accrued but unpaid interest",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get interest plans",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/interest.InterestPlan"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Assign an annual rate to an account or a pocket, replacing its current plan",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set an interest plan",
+                "parameters": [
+                    {
+                        "description": "PlanRequest data",
+                        "name": "plan",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/interest.PlanRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/interest.InterestPlan"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliations": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the reports of past reconciliations, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get reconciliation reports",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only reports that found discrepancies",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Reports per page",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/reconciliation.ReportList"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Check every balance and transfer now, and keep what was found as a report",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reconcile balances",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/reconciliation.ReconciliationReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliations/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get one reconciliation report with its discrepancies",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a reconciliation report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/reconciliation.ReconciliationReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transfers/{id}/reverse": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Submit a compensating transfer for all or part of a completed transfer for approval",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reverse a transfer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "ReversalRequest data",
+                        "name": "reversal",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.ReversalRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/approval.PendingAction"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/transfers/{id}/settle": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Retry a reversal that was left pending because the recipient was short of funds",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Settle a pending reversal",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Reversal transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.AccountTransfer"
+                        }
+                    }
+                }
+            }
+        },
+        "/audit/": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Query the audit log, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Get audit records",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Acting account ID",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Action, e.g. pocket.update",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target, e.g. pockets:3",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 lower bound",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 upper bound",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Records per page",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/audit.RecordList"
+                        }
+                    }
+                }
+            }
+        },
+        "/audit/verify": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Recompute the hash chain of the whole audit log",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Verify the audit log",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/audit.VerifyResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get every budget of the caller with how much of it was spent this month",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Get all budgets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/budget.BudgetStatus"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Create a monthly budget for a transfer category or a pocket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Create a budget",
+                "parameters": [
+                    {
+                        "description": "BudgetRequest data",
+                        "name": "budget",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/budget.BudgetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/budget.BudgetStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Change the monthly amount of a budget or whether it carries over. The current month changes too",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Update a budget",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Budget ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "BudgetUpdate data",
+                        "name": "budget",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/budget.BudgetUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/budget.BudgetStatus"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete a budget and its history",
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Delete a budget",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Budget ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/budget.SuccessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/": {
+            "post": {
+                "description": "Authenticate account and obtain access and refresh tokens",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login account",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.Login"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.TokenResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the caller's notifications, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Get notifications",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only unread notifications",
+                        "name": "unread",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/notification.Notification"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/{id}/read": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Mark one of the caller's notifications as read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Mark a notification as read",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Notification ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/notification.Notification"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the payment requests the caller made, with their status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Get my payment requests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/payment.PaymentRequest"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Ask an account, by account number or alias, for an amount. Without a payer the request is a pay link anyone can pay once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Create a payment request",
+                "parameters": [
+                    {
+                        "description": "PaymentRequestCreate data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentRequestCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentRequest"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/incoming": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the pending payment requests addressed to the caller",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Get incoming payment requests",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/payment.PaymentRequest"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/link/{token}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the payment request behind a pay link",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Get a pay link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pay link token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentRequest"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/link/{token}/pay": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Pay the payment request behind a pay link with an account transfer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Pay a pay link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pay link token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentRequest"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/split": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Split an amount evenly between several payers, with one payment request per payer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Split a bill",
+                "parameters": [
+                    {
+                        "description": "SplitRequest data",
+                        "name": "split",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/payment.SplitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/payment.SplitResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Cancel a payment request the caller made",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Cancel a payment request",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Payment request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentRequest"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/{id}/decline": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Decline a payment request addressed to the caller",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Decline a payment request",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Payment request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentRequest"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/{id}/pay": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Pay a payment request addressed to the caller with an account transfer",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Pay a payment request",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Payment request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentRequest"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get details of all pocket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Get all pocket",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pocket.PocketResponse"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Create a new pocket with the provided data",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Create a new pocket",
+                "parameters": [
+                    {
+                        "description": "PocketCreate data",
+                        "name": "pocket",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.SuccessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/goals": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the progress of every pocket with a savings goal",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Get savings goals",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.GoalSummary"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/invitations": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the pocket invitations waiting for the caller",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Get pocket invitations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pocket.PocketMember"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/invitations/{id}/accept": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Accept an invitation and join the pocket",
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Accept a pocket invitation",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Invitation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketMember"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/invitations/{id}/decline": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Decline an invitation to a pocket",
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Decline a pocket invitation",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Invitation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.SuccessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/transfer/": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Transfer funds from one pocket to another with pocket id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Transfer funds between pockets",
+                "parameters": [
+                    {
+                        "description": "PocketTransferRequest data",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketTransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.SuccessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get pocket by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Get pocket by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Update a pocket with the provided data",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Update a pocket",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "PocketUpdate data",
+                        "name": "pocket",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.SuccessResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete a pocket by ID and pay its balance to the owner. A shared pocket can only be deleted once its members have taken out what they put in.",
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Delete a pocket",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Break a locked pocket before maturity and pay the penalty",
+                        "name": "early_withdrawal",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.SuccessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/{id}/deposit": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Move money from the main account balance into a pocket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Deposit into a pocket",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "PocketAmountRequest data",
+                        "name": "deposit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketAmountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketTransfer"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/{id}/members": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the members of a pocket with what each of them put in and took out",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Get pocket members",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pocket.MemberResponse"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Invite another account, by account number or alias, to share a pocket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Invite a member to a pocket",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "InviteRequest data",
+                        "name": "invite",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pocket.InviteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketMember"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/{id}/members/{member}": {
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Change the role or withdraw limit of a pocket member",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Update a pocket member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Member ID",
+                        "name": "member",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "MemberUpdate data",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pocket.MemberUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketMember"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Remove a member from a pocket. Owners can remove anyone and members can leave",
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Remove a pocket member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Member ID",
+                        "name": "member",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.SuccessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/{id}/transfers": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get every movement in or out of a pocket, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Get pocket transfer history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pocket.PocketTransfer"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/pockets/{id}/withdraw": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Move money from a pocket back to the main account balance",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pockets"
+                ],
+                "summary": "Withdraw from a pocket",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "PocketAmountRequest data",
+                        "name": "withdraw",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketAmountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/pocket.PocketTransfer"
+                        }
+                    }
+                }
+            }
+        },
+        "/qr/": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Generate a PromptPay QR code paying an account number or alias, the caller's account by default, with an optional fixed amount",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "qr"
+                ],
+                "summary": "Generate a QR code",
+                "parameters": [
+                    {
+                        "description": "QRRequest data",
+                        "name": "qr",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/qr.QRRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/qr.QRResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/qr/parse": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Decode a scanned PromptPay QR payload into a transfer request to send with POST /accounts/transfer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "qr"
+                ],
+                "summary": "Parse a QR code",
+                "parameters": [
+                    {
+                        "description": "ParseRequest data",
+                        "name": "qr",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/qr.ParseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.AccountTransferRequest"
+                        }
+                    }
+                }
+            }
+        },
+        "/refresh": {
+            "get": {
+                "description": "Refresh the access token using a valid refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh access token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.TokenResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/rules/": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the automation rules of the account",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Get all automation rules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/rule.Rule"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Create a rule that moves money from the account into a pocket automatically",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Create an automation rule",
+                "parameters": [
+                    {
+                        "description": "RuleRequest data",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rule.RuleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/rule.Rule"
+                        }
+                    }
+                }
+            }
+        },
+        "/rules/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Delete an automation rule by ID",
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Delete an automation rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rule.SuccessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/rules/{id}/executions": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the log of every time a rule ran, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "summary": "Get rule executions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/rule.RuleExecution"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "account.AccountRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "balance": {
+                    "type": "number"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.AccountResponse": {
+            "type": "object",
+            "properties": {
+                "account_number": {
+                    "type": "string"
+                },
+                "alias": {
+                    "type": "string"
+                },
+                "balance": {
+                    "type": "number"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "pocket_list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pocket.Pocket"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transfer_limit": {
+                    "type": "number"
+                }
+            }
+        },
+        "account.AccountResponseList": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "result": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/account.AccountResponse"
+                    }
+                },
+                "total_count": {
+                    "type": "integer"
+                },
+                "total_page": {
+                    "type": "integer"
+                }
+            }
+        },
+        "account.AccountTransfer": {
+            "type": "object",
+            "required": [
+                "amount",
+                "to"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "memo": {
+                    "type": "string"
+                },
+                "reason_code": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "reversal_of": {
+                    "type": "integer"
+                },
+                "reversed_amount": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.AccountTransferRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "tags",
+                "to"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "category": {
+                    "type": "string",
+                    "maxLength": 32
+                },
+                "memo": {
+                    "type": "string",
+                    "maxLength": 140
+                },
+                "reference": {
+                    "description": "Reference is the end-to-end reference of the payer, such as an\ninvoice number, passed on unchanged to statements.",
+                    "type": "string",
+                    "maxLength": 35
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.AdjustmentRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "reason_code"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "memo": {
+                    "type": "string",
+                    "maxLength": 140
+                },
+                "reason_code": {
+                    "type": "string",
+                    "enum": [
+                        "CORRECTION",
+                        "FEE",
+                        "GOODWILL",
+                        "REFUND"
+                    ]
+                }
+            }
+        },
+        "account.AliasRequest": {
+            "type": "object",
+            "required": [
+                "alias"
+            ],
+            "properties": {
+                "alias": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 3
+                }
+            }
+        },
+        "account.CategorySpend": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "change": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "previous": {
+                    "type": "number"
+                },
+                "share": {
+                    "type": "number"
+                }
+            }
+        },
+        "account.CategorySuggestion": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.CounterpartySpend": {
+            "type": "object",
+            "properties": {
+                "account_number": {
+                    "type": "string"
+                },
+                "amount": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "account.Insights": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/account.CategorySpend"
+                    }
+                },
+                "change": {
+                    "type": "number"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "previous_period": {
+                    "type": "string"
+                },
+                "previous_spent": {
+                    "type": "number"
+                },
+                "top_counterparties": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/account.CounterpartySpend"
+                    }
+                },
+                "total_spent": {
+                    "type": "number"
+                }
+            }
+        },
+        "account.Login": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.ReversalRequest": {
+            "type": "object",
+            "required": [
+                "reason_code"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "reason_code": {
+                    "type": "string",
+                    "enum": [
+                        "DUPLICATE",
+                        "ERRONEOUS",
+                        "FRAUD",
+                        "CUSTOMER_REQUEST",
+                        "REFUND"
+                    ]
+                }
+            }
+        },
+        "account.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.TransferLimitRequest": {
+            "type": "object",
+            "properties": {
+                "transfer_limit": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "apperr.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
+        "apperr.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apperr.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "approval.PendingAction": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "checker_id": {
+                    "type": "integer"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "decided_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "maker_id": {
+                    "type": "integer"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "update_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "approval.RejectRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "audit.Record": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor_id": {
+                    "type": "integer"
+                },
+                "after": {
+                    "type": "string"
+                },
+                "before": {
+                    "type": "string"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "prev_hash": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "audit.RecordList": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "result": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/audit.Record"
+                    }
+                },
+                "total_count": {
+                    "type": "integer"
+                },
+                "total_page": {
+                    "type": "integer"
+                }
+            }
+        },
+        "audit.VerifyResponse": {
+            "type": "object",
+            "properties": {
+                "broken_id": {
+                    "type": "integer"
+                },
+                "checked": {
+                    "type": "integer"
+                },
+                "head_hash": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "ok": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "batch.Batch": {
+            "type": "object",
+            "properties": {
+                "create_at": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "message_id": {
+                    "description": "MessageID is the GrpHdr/MsgId of a pain.001 upload, which may only be\nuploaded once.",
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/batch.Row"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "succeeded": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_amount": {
+                    "type": "number"
+                },
+                "update_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "batch.BatchRequest": {
+            "type": "object",
+            "required": [
+                "rows"
+            ],
+            "properties": {
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "all_or_nothing",
+                        "best_effort"
+                    ]
+                },
+                "rows": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/batch.RowRequest"
+                    }
+                }
+            }
+        },
+        "batch.Row": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "memo": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "transfer_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "batch.RowError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "batch.RowRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "recipient"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "memo": {
+                    "type": "string",
+                    "maxLength": 140
+                },
+                "recipient": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "reference": {
+                    "type": "string",
+                    "maxLength": 35
+                }
+            }
+        },
+        "batch.ValidationResponse": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/batch.RowError"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "budget.BudgetRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "carryover": {
+                    "type": "boolean"
+                },
+                "category": {
+                    "type": "string",
+                    "maxLength": 32
+                },
+                "pocket_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "budget.BudgetStatus": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "available": {
+                    "type": "number"
+                },
+                "carried": {
+                    "type": "number"
+                },
+                "carryover": {
+                    "type": "boolean"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "pocket_id": {
+                    "type": "integer"
+                },
+                "progress": {
+                    "type": "number"
+                },
+                "remaining": {
+                    "type": "number"
+                },
+                "spent": {
+                    "type": "number"
+                },
+                "update_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "budget.BudgetUpdate": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "carryover": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "budget.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "interest.InterestPlan": {
+            "type": "object",
+            "properties": {
+                "accrued_unpaid": {
+                    "type": "number"
+                },
+                "active": {
+                    "type": "boolean"
+                },
+                "annual_rate": {
+                    "type": "number"
+                },
+                "basis": {
+                    "type": "string"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "owner_type": {
+                    "type": "string"
+                },
+                "update_at": {
+                    "type": "string"
+                },
+                "withholding_tax_rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "interest.PlanRequest": {
+            "type": "object",
+            "required": [
+                "basis",
+                "owner_id",
+                "owner_type"
+            ],
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "annual_rate": {
+                    "type": "number",
+                    "maximum": 100,
+                    "minimum": 0
+                },
+                "basis": {
+                    "type": "string",
+                    "enum": [
+                        "ACT/365",
+                        "ACT/360"
+                    ]
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "owner_type": {
+                    "type": "string",
+                    "enum": [
+                        "account",
+                        "pocket"
+                    ]
+                },
+                "withholding_tax_rate": {
+                    "type": "number",
+                    "maximum": 100,
+                    "minimum": 0
+                }
+            }
+        },
+        "interest.RunResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "created": {
+                    "type": "integer"
+                },
+                "plans": {
+                    "type": "integer"
+                }
+            }
+        },
+        "ledger.HistoricalBalance": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "balance": {
+                    "type": "number"
+                },
+                "pockets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ledger.PocketBalance"
+                    }
+                },
+                "snapshot_at": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "ledger.PocketBalance": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "notification.Notification": {
+            "type": "object",
+            "properties": {
+                "create_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "read_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "payment.PaymentRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "memo": {
+                    "type": "string"
+                },
+                "paid_at": {
+                    "type": "string"
+                },
+                "payer_account": {
+                    "type": "string"
+                },
+                "requester_account": {
+                    "type": "string"
+                },
+                "split_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "transfer_id": {
+                    "type": "integer"
+                },
+                "update_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "payment.PaymentRequestCreate": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "memo": {
+                    "type": "string",
+                    "maxLength": 140
+                },
+                "payer": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "payment.SplitRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "payers"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "include_self": {
+                    "type": "boolean"
+                },
+                "memo": {
+                    "type": "string",
+                    "maxLength": 140
+                },
+                "payers": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "payment.SplitResponse": {
+            "type": "object",
+            "properties": {
+                "requests": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/payment.PaymentRequest"
+                    }
+                },
+                "split_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "pocket.GoalProgress": {
+            "type": "object",
+            "properties": {
+                "progress": {
+                    "type": "number"
+                },
+                "projected_completion": {
+                    "type": "string"
+                },
+                "reached": {
+                    "type": "boolean"
+                },
+                "remaining": {
+                    "type": "number"
+                },
+                "suggested_monthly_contribution": {
+                    "type": "number"
+                },
+                "target_amount": {
+                    "type": "number"
+                },
+                "target_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "pocket.GoalSummary": {
+            "type": "object",
+            "properties": {
+                "goals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pocket.PocketResponse"
+                    }
+                },
+                "progress": {
+                    "type": "number"
+                },
+                "reached": {
+                    "type": "integer"
+                },
+                "total_saved": {
+                    "type": "number"
+                },
+                "total_target": {
+                    "type": "number"
+                }
+            }
+        },
+        "pocket.InviteRequest": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "account_number": {
+                    "type": "string"
+                },
+                "alias": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "owner",
+                        "contributor",
+                        "viewer"
+                    ]
+                },
+                "withdraw_limit": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "pocket.MemberResponse": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "integer"
+                },
+                "account_number": {
+                    "type": "string"
+                },
+                "contributed": {
+                    "type": "number"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "invited_by": {
+                    "type": "integer"
+                },
+                "pocket_id": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "update_at": {
+                    "type": "string"
+                },
+                "withdraw_limit": {
+                    "type": "number"
+                },
+                "withdrawn": {
+                    "type": "number"
+                }
+            }
+        },
+        "pocket.MemberUpdate": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "owner",
+                        "contributor",
+                        "viewer"
+                    ]
+                },
+                "withdraw_limit": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "pocket.Pocket": {
+            "type": "object",
+            "required": [
+                "title"
+            ],
+            "properties": {
+                "balance": {
+                    "type": "number"
+                },
+                "bonus_rate": {
+                    "type": "number"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "goal_reached_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "maturity_action": {
+                    "type": "string"
+                },
+                "maturity_date": {
+                    "type": "string"
+                },
+                "penalty_rate": {
+                    "type": "number"
+                },
+                "target_amount": {
+                    "type": "number"
+                },
+                "target_date": {
+                    "type": "string"
+                },
+                "term_start": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "update_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "pocket.PocketAmountRequest": {
+            "type": "object",
+            "required": [
+                "amount"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "early_withdrawal": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "pocket.PocketCreate": {
+            "type": "object",
+            "required": [
+                "title"
+            ],
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "description": {
+                    "type": "string"
+                },
+                "maturity_action": {
+                    "type": "string",
+                    "enum": [
+                        "rollover",
+                        "release"
+                    ]
+                },
+                "maturity_date": {
+                    "type": "string"
+                },
+                "target_amount": {
+                    "type": "number"
+                },
+                "target_date": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "regular",
+                        "locked"
+                    ]
+                }
+            }
+        },
+        "pocket.PocketMember": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "integer"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "invited_by": {
+                    "type": "integer"
+                },
+                "pocket_id": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "update_at": {
+                    "type": "string"
+                },
+                "withdraw_limit": {
+                    "type": "number"
+                }
+            }
+        },
+        "pocket.PocketResponse": {
             "type": "object",
             "required": [
-                "email",
-                "password"
+                "title"
             ],
             "properties": {
                 "balance": {
                     "type": "number"
                 },
-                "email": {
-                    "type": "string"
+                "bonus_rate": {
+                    "type": "number"
                 },
-                "password": {
+                "create_at": {
                     "type": "string"
-                }
-            }
-        },
-        "account.AccountResponse": {
-            "type": "object",
-            "properties": {
-                "account_number": {
+                },
+                "description": {
                     "type": "string"
                 },
-                "balance": {
-                    "type": "number"
+                "goal": {
+                    "$ref": "#/definitions/pocket.GoalProgress"
                 },
-                "email": {
+                "goal_reached_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "pocket_list": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/pocket.Pocket"
-                    }
+                "maturity_action": {
+                    "type": "string"
+                },
+                "maturity_date": {
+                    "type": "string"
+                },
+                "penalty_rate": {
+                    "type": "number"
+                },
+                "target_amount": {
+                    "type": "number"
+                },
+                "target_date": {
+                    "type": "string"
+                },
+                "term_start": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "update_at": {
+                    "type": "string"
                 }
             }
         },
-        "account.AccountResponseList": {
+        "pocket.PocketTransfer": {
             "type": "object",
+            "required": [
+                "amount",
+                "from",
+                "to"
+            ],
             "properties": {
-                "count": {
-                    "type": "integer"
+                "amount": {
+                    "type": "number"
                 },
-                "page": {
-                    "type": "integer"
+                "create_at": {
+                    "type": "string"
                 },
-                "result": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/account.AccountResponse"
-                    }
+                "from": {
+                    "type": "integer"
                 },
-                "total_count": {
+                "id": {
                     "type": "integer"
                 },
-                "total_page": {
+                "to": {
                     "type": "integer"
+                },
+                "type": {
+                    "type": "string"
                 }
             }
         },
-        "account.AccountTransferRequest": {
+        "pocket.PocketTransferRequest": {
             "type": "object",
             "required": [
                 "amount",
+                "from",
                 "to"
             ],
             "properties": {
                 "amount": {
                     "type": "number"
                 },
+                "early_withdrawal": {
+                    "type": "boolean"
+                },
+                "from": {
+                    "type": "integer"
+                },
                 "to": {
-                    "type": "string"
+                    "type": "integer"
                 }
             }
         },
-        "account.Login": {
+        "pocket.PocketUpdate": {
             "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
             "properties": {
-                "email": {
+                "description": {
                     "type": "string"
                 },
-                "password": {
+                "maturity_action": {
+                    "type": "string",
+                    "enum": [
+                        "rollover",
+                        "release"
+                    ]
+                },
+                "target_amount": {
+                    "type": "number"
+                },
+                "target_date": {
+                    "type": "string"
+                },
+                "title": {
                     "type": "string"
                 }
             }
         },
-        "account.SuccessResponse": {
+        "pocket.SuccessResponse": {
             "type": "object",
             "properties": {
                 "message": {
@@ -518,97 +4079,248 @@ const docTemplate = `{
                 }
             }
         },
-        "account.TokenResponse": {
+        "qr.ParseRequest": {
             "type": "object",
+            "required": [
+                "payload"
+            ],
             "properties": {
-                "access_token": {
+                "payload": {
+                    "type": "string",
+                    "maxLength": 512
+                }
+            }
+        },
+        "qr.QRRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "target": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "qr.QRResponse": {
+            "type": "object",
+            "properties": {
+                "image": {
+                    "description": "Image is the QR code as a base64 encoded PNG.",
                     "type": "string"
                 },
-                "refresh_token": {
+                "payload": {
                     "type": "string"
                 }
             }
         },
-        "pocket.Pocket": {
+        "reconciliation.ReconciliationDiscrepancy": {
             "type": "object",
-            "required": [
-                "title"
-            ],
             "properties": {
-                "balance": {
+                "actual": {
                     "type": "number"
                 },
-                "create_at": {
+                "check": {
                     "type": "string"
                 },
-                "description": {
+                "detail": {
                     "type": "string"
                 },
+                "expected": {
+                    "type": "number"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "title": {
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "reconciliation.ReconciliationReport": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "integer"
+                },
+                "create_at": {
                     "type": "string"
                 },
-                "update_at": {
+                "discrepancies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reconciliation.ReconciliationDiscrepancy"
+                    }
+                },
+                "holdings": {
+                    "description": "Holdings is the sum of every balance, and Inflow what came into the\nbank from outside, less what left it.",
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "inflow": {
+                    "type": "number"
+                },
+                "ok": {
+                    "type": "boolean"
+                },
+                "pockets": {
+                    "type": "integer"
+                },
+                "transfers": {
+                    "type": "integer"
+                },
+                "trigger": {
+                    "description": "Trigger is what started the run: TriggerSchedule, TriggerAdmin or\nTriggerCLI.",
                     "type": "string"
                 }
             }
         },
-        "pocket.PocketCreate": {
+        "reconciliation.ReportList": {
             "type": "object",
-            "required": [
-                "title"
-            ],
             "properties": {
-                "balance": {
+                "count": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "result": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/reconciliation.ReconciliationReport"
+                    }
+                },
+                "total_count": {
+                    "type": "integer"
+                },
+                "total_page": {
+                    "type": "integer"
+                }
+            }
+        },
+        "rule.Rule": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "min_amount": {
                     "type": "number"
                 },
-                "description": {
+                "percent": {
+                    "type": "number"
+                },
+                "pocket_id": {
+                    "type": "integer"
+                },
+                "round_to": {
+                    "type": "number"
+                },
+                "threshold": {
+                    "type": "number"
+                },
+                "type": {
                     "type": "string"
                 },
-                "title": {
+                "update_at": {
                     "type": "string"
                 }
             }
         },
-        "pocket.PocketTransferRequest": {
+        "rule.RuleExecution": {
             "type": "object",
-            "required": [
-                "amount",
-                "from",
-                "to"
-            ],
             "properties": {
                 "amount": {
                     "type": "number"
                 },
-                "from": {
+                "create_at": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "to": {
+                "note": {
+                    "type": "string"
+                },
+                "rule_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transfer_id": {
                     "type": "integer"
                 }
             }
         },
-        "pocket.PocketUpdate": {
+        "rule.RuleRequest": {
             "type": "object",
+            "required": [
+                "pocket_id",
+                "type"
+            ],
             "properties": {
-                "description": {
-                    "type": "string"
+                "min_amount": {
+                    "type": "number",
+                    "minimum": 0
                 },
-                "title": {
-                    "type": "string"
+                "percent": {
+                    "type": "number",
+                    "maximum": 100,
+                    "minimum": 0
+                },
+                "pocket_id": {
+                    "type": "integer"
+                },
+                "round_to": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "threshold": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "percent_incoming",
+                        "round_up_outgoing",
+                        "sweep"
+                    ]
                 }
             }
         },
-        "pocket.SuccessResponse": {
+        "rule.SuccessResponse": {
             "type": "object",
             "properties": {
                 "message": {
                     "type": "string"
                 }
             }
+        },
+        "stream.SocketMessage": {
+            "type": "object",
+            "properties": {
+                "create_at": {
+                    "type": "string"
+                },
+                "data": {},
+                "id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
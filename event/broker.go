@@ -0,0 +1,106 @@
+package event
+
+import "sync"
+
+// Message is an event as a Broker delivers it, numbered so that a client
+// can tell which ones it has seen.
+type Message struct {
+	ID    uint64
+	Event Event
+}
+
+// Broker hands events to the subscribers of the account they are sent to.
+// It keeps the last few messages of every account, so a subscriber coming
+// back after losing its connection can get what it missed.
+type Broker struct {
+	mu      sync.Mutex
+	size    int
+	lastID  uint64
+	nextSub int
+	closed  bool
+	buffers map[uint][]Message
+	subs    map[uint]map[int]chan Message
+}
+
+// NewBroker is a Broker keeping the last size messages of every account.
+func NewBroker(size int) *Broker {
+	return &Broker{
+		size:    size,
+		buffers: map[uint][]Message{},
+		subs:    map[uint]map[int]chan Message{},
+	}
+}
+
+// Send delivers e to every subscriber of account accountID. A subscriber
+// too far behind misses it, and can only get it back by subscribing again.
+func (b *Broker) Send(accountID uint, e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	m := Message{ID: b.lastID, Event: e}
+
+	buf := append(b.buffers[accountID], m)
+	if len(buf) > b.size {
+		buf = buf[len(buf)-b.size:]
+	}
+	b.buffers[accountID] = buf
+
+	for _, ch := range b.subs[accountID] {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the kept messages of account accountID numbered after
+// lastID, and a channel of the ones sent from now on, until the returned
+// function is called or the broker is closed, which closes the channel.
+func (b *Broker) Subscribe(accountID uint, lastID uint64) ([]Message, <-chan Message, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	missed := []Message{}
+	for _, m := range b.buffers[accountID] {
+		if m.ID > lastID {
+			missed = append(missed, m)
+		}
+	}
+
+	ch := make(chan Message, b.size)
+	if b.closed {
+		close(ch)
+		return missed, ch, func() {}
+	}
+
+	b.nextSub++
+	id := b.nextSub
+	if b.subs[accountID] == nil {
+		b.subs[accountID] = map[int]chan Message{}
+	}
+	b.subs[accountID][id] = ch
+
+	return missed, ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[accountID], id)
+		if len(b.subs[accountID]) == 0 {
+			delete(b.subs, accountID)
+		}
+	}
+}
+
+// Close ends every subscription, and the ones made after end right away.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, subs := range b.subs {
+		for _, ch := range subs {
+			close(ch)
+		}
+	}
+	b.subs = map[uint]map[int]chan Message{}
+}
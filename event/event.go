@@ -0,0 +1,116 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	PocketGoalReached      = "pocket.goal_reached"
+	TransferSent           = "account.transfer_sent"
+	PocketWithdrawn        = "pocket.withdrawn"
+	PocketDeposited        = "pocket.deposited"
+	PocketClosed           = "pocket.closed"
+	BudgetThresholdReached = "budget.threshold_reached"
+	PaymentRequested       = "payment.requested"
+	PaymentRequestPaid     = "payment.request_paid"
+	DiscrepanciesFound     = "reconciliation.discrepancies_found"
+)
+
+type Event struct {
+	Type      string      `json:"type"`
+	AccountID uint        `json:"account_id"`
+	Payload   interface{} `json:"payload"`
+	CreatedAt time.Time   `json:"create_at"`
+}
+
+type Handler func(Event)
+
+var (
+	mu       sync.RWMutex
+	nextID   int
+	handlers = map[int]Handler{}
+)
+
+// Subscribe calls fn for every event published from now on, until the
+// returned function is called.
+func Subscribe(fn Handler) func() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	nextID++
+	id := nextID
+	handlers[id] = fn
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(handlers, id)
+	}
+}
+
+// Publish hands e to every subscriber, in the caller's goroutine.
+// Subscribers that do slow work should hand it off themselves.
+func Publish(e Event) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	mu.RLock()
+	fns := make([]Handler, 0, len(handlers))
+	for _, fn := range handlers {
+		fns = append(fns, fn)
+	}
+	mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(e)
+	}
+}
+
+type heldKey struct{}
+
+type held struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Hold returns a context under which PublishContext keeps events back, and
+// a function publishing the ones kept. It is for the owner of a database
+// transaction, to call once the transaction is committed, so that nothing
+// is told about changes that may still be rolled back. Under a context that
+// is already held, events are left to the outermost holder.
+func Hold(ctx context.Context) (context.Context, func()) {
+	if _, ok := ctx.Value(heldKey{}).(*held); ok {
+		return ctx, func() {}
+	}
+
+	h := &held{}
+	return context.WithValue(ctx, heldKey{}, h), func() {
+		h.mu.Lock()
+		events := h.events
+		h.events = nil
+		h.mu.Unlock()
+
+		for _, e := range events {
+			Publish(e)
+		}
+	}
+}
+
+// PublishContext publishes e, or keeps it back when ctx is held.
+func PublishContext(ctx context.Context, e Event) {
+	h, ok := ctx.Value(heldKey{}).(*held)
+	if !ok {
+		Publish(e)
+		return
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, e)
+}
@@ -0,0 +1,288 @@
+package interest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/ledger"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// DailyInterest is what balance earns in one day at annualRate percent. Both
+// bases count actual days; they only differ in the length of the year.
+func DailyInterest(balance, annualRate float64, basis string) decimal.Decimal {
+	bl := decimal.NewFromFloat(balance)
+	if !bl.IsPositive() {
+		return decimal.Zero
+	}
+
+	days := int64(365)
+	if basis == BasisACT360 {
+		days = 360
+	}
+	return bl.Mul(decimal.NewFromFloat(annualRate)).Div(hundred.Mul(decimal.NewFromInt(days))).Round(8)
+}
+
+// RunDaily accrues interest for the day that ended before day and, when
+// that was the last day of its month, capitalizes the whole month. Run just
+// after midnight, it sees every transfer of the day it accrues for.
+func RunDaily(db *gorm.DB, day time.Time) error {
+	day = day.AddDate(0, 0, -1)
+	if _, err := Accrue(db, day); err != nil {
+		return err
+	}
+	if day.AddDate(0, 0, 1).Day() == 1 {
+		if _, err := Capitalize(db, day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Accrue records one day of interest on the balance every active plan had at
+// the end of day, so a day made up later earns what it would have then.
+// Plans that already have an accrual for day, or whose month has already
+// been capitalized, are left alone, so it can be run again for the same day.
+func Accrue(db *gorm.DB, day time.Time) (*RunResponse, error) {
+	plans := []InterestPlan{}
+	if err := db.Where("active = ?", true).Find(&plans).Error; err != nil {
+		return nil, err
+	}
+
+	date := day.Format(dateLayout)
+	period := day.Format(periodLayout)
+	res := &RunResponse{Plans: len(plans)}
+	total := decimal.Zero
+
+	for _, p := range plans {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var posted int64
+			if err := tx.Model(&InterestPosting{}).Where("plan_id = ? AND period = ?", p.ID, period).Count(&posted).Error; err != nil {
+				return err
+			}
+			if posted > 0 {
+				return nil
+			}
+
+			bl, err := ownerBalance(tx, &p, ledger.EndOfDay(day))
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			amount := DailyInterest(bl, p.AnnualRate, p.Basis)
+			a := &InterestAccrual{PlanID: p.ID, Date: date, Balance: bl, Rate: p.AnnualRate}
+			a.Amount, _ = amount.Float64()
+
+			created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(a)
+			if created.Error != nil {
+				return created.Error
+			}
+			if created.RowsAffected == 0 {
+				return nil
+			}
+
+			if err := tx.Model(&InterestPlan{}).Where("id = ?", p.ID).
+				Update("accrued_unpaid", gorm.Expr("accrued_unpaid + ?", a.Amount)).Error; err != nil {
+				return err
+			}
+
+			res.Created++
+			total = total.Add(amount)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res.Amount, _ = total.Round(2).Float64()
+	return res, nil
+}
+
+// Capitalize posts every accrual of the month containing month that has not
+// been posted yet. Gross interest is rounded to the satang, withholding tax
+// is taken from the rounded gross, and both are recorded as movements on the
+// account or pocket, made no later than the end of the month so that a
+// month made up later compounds into the next.
+func Capitalize(db *gorm.DB, month time.Time) (*RunResponse, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	from, to := start.Format(dateLayout), start.AddDate(0, 1, 0).Format(dateLayout)
+	period := start.Format(periodLayout)
+	at := start.AddDate(0, 1, 0)
+	if now := time.Now(); now.Before(at) {
+		at = now
+	}
+
+	unposted := db.Model(&InterestAccrual{}).
+		Select("plan_id").
+		Where("posting_id IS NULL AND date >= ? AND date < ?", from, to)
+
+	plans := []InterestPlan{}
+	if err := db.Where("id IN (?)", unposted).Find(&plans).Error; err != nil {
+		return nil, err
+	}
+
+	res := &RunResponse{Plans: len(plans)}
+	total := decimal.Zero
+
+	for _, p := range plans {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			accruals := []InterestAccrual{}
+			if err := tx.Where("plan_id = ? AND posting_id IS NULL AND date >= ? AND date < ?", p.ID, from, to).
+				Find(&accruals).Error; err != nil {
+				return err
+			}
+
+			sum := decimal.Zero
+			ids := []uint{}
+			for _, a := range accruals {
+				sum = sum.Add(decimal.NewFromFloat(a.Amount))
+				ids = append(ids, a.ID)
+			}
+
+			gross := sum.Round(2)
+			tax := gross.Mul(decimal.NewFromFloat(p.WithholdingTaxRate)).Div(hundred).Round(2)
+
+			posting := &InterestPosting{PlanID: p.ID, Period: period}
+			posting.Gross, _ = gross.Float64()
+			posting.Tax, _ = tax.Float64()
+			posting.Net, _ = gross.Sub(tax).Float64()
+
+			created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(posting)
+			if created.Error != nil {
+				return created.Error
+			}
+			if created.RowsAffected == 0 {
+				return nil
+			}
+
+			if err := tx.Model(&InterestAccrual{}).Where("id IN ?", ids).Update("posting_id", posting.ID).Error; err != nil {
+				return err
+			}
+
+			s, _ := sum.Float64()
+			if err := tx.Model(&InterestPlan{}).Where("id = ?", p.ID).
+				Update("accrued_unpaid", gorm.Expr("accrued_unpaid - ?", s)).Error; err != nil {
+				return err
+			}
+
+			if err := post(tx, &p, gross, tax, at); err != nil {
+				return err
+			}
+
+			res.Created++
+			total = total.Add(gross.Sub(tax))
+			return nil
+		})
+		// An owner that is gone or closed is not paid; its accruals are
+		// left unposted.
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	res.Amount, _ = total.Float64()
+	return res, nil
+}
+
+// ownerBalance is the balance of the owner of p at at, as its movements add
+// it up. It returns gorm.ErrRecordNotFound for an owner that is gone or
+// closed, or that did not exist yet at at.
+func ownerBalance(tx *gorm.DB, p *InterestPlan, at time.Time) (float64, error) {
+	accountID := p.OwnerID
+	if p.OwnerType == OwnerPocket {
+		pk := &pocket.Pocket{}
+		if err := tx.First(pk, p.OwnerID).Error; err != nil {
+			return 0, err
+		}
+		accountID = pk.AccountID
+	} else {
+		acc := &account.Account{}
+		if err := tx.Where("status <> ?", account.StatusClosed).First(acc, p.OwnerID).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	h, err := ledger.BalanceAt(tx, accountID, at)
+	if errors.Is(err, account.ErrAccountNotFound) {
+		return 0, gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if p.OwnerType != OwnerPocket {
+		return h.Balance, nil
+	}
+	for _, pb := range h.Pockets {
+		if pb.ID == p.OwnerID {
+			return pb.Balance, nil
+		}
+	}
+	return 0, gorm.ErrRecordNotFound
+}
+
+// post credits gross and debits tax on the owner of p, recording each as a
+// typed movement made at at in its transfer history. The owner is locked
+// while its balance changes, and gorm.ErrRecordNotFound is returned for an
+// owner that is gone or closed.
+func post(tx *gorm.DB, p *InterestPlan, gross, tax decimal.Decimal, at time.Time) error {
+	if gross.IsZero() {
+		return nil
+	}
+	g, _ := gross.Float64()
+	t, _ := tax.Float64()
+
+	if p.OwnerType == OwnerPocket {
+		pk := &pocket.Pocket{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(pk, p.OwnerID).Error; err != nil {
+			return err
+		}
+		pk.Balance, _ = decimal.NewFromFloat(pk.Balance).Add(gross).Sub(tax).Float64()
+		if err := tx.Model(pk).Update("balance", pk.Balance).Error; err != nil {
+			return err
+		}
+
+		movements := []pocket.PocketTransfer{{Type: pocket.TransferTypeInterest, To: pk.ID, Amount: g, AccountID: pk.AccountID, CreatedAt: at}}
+		if tax.IsPositive() {
+			movements = append(movements, pocket.PocketTransfer{Type: pocket.TransferTypeWithholdingTax, From: pk.ID, Amount: t, AccountID: pk.AccountID, CreatedAt: at})
+		}
+		return tx.Create(&movements).Error
+	}
+
+	acc := &account.Account{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("status <> ?", account.StatusClosed).First(acc, p.OwnerID).Error
+	if err != nil {
+		return err
+	}
+	acc.Balance, _ = decimal.NewFromFloat(acc.Balance).Add(gross).Sub(tax).Float64()
+	if err := tx.Model(acc).Update("balance", acc.Balance).Error; err != nil {
+		return err
+	}
+
+	movements := []account.AccountTransfer{{
+		CreatedAt: at,
+		Type:      account.TransferTypeInterest,
+		Status:    account.TransferStatusCompleted,
+		To:        acc.AccountNumber,
+		Amount:    g,
+	}}
+	if tax.IsPositive() {
+		movements = append(movements, account.AccountTransfer{
+			CreatedAt: at,
+			Type:      account.TransferTypeWithholdingTax,
+			Status:    account.TransferStatusCompleted,
+			From:      acc.AccountNumber,
+			Amount:    t,
+		})
+	}
+	return tx.Create(&movements).Error
+}
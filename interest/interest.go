@@ -0,0 +1,80 @@
+package interest
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterestPlan assigns an annual rate to an account or a pocket. Rates are
+// percentages, so 1.5 means 1.5% a year.
+type InterestPlan struct {
+	ID                 uint      `gorm:"primarykey" json:"id"`
+	CreatedAt          time.Time `json:"create_at"`
+	UpdatedAt          time.Time `json:"update_at"`
+	OwnerType          string    `json:"owner_type" gorm:"uniqueIndex:idx_interest_plan_owner"`
+	OwnerID            uint      `json:"owner_id" gorm:"uniqueIndex:idx_interest_plan_owner"`
+	AnnualRate         float64   `json:"annual_rate"`
+	Basis              string    `json:"basis"`
+	WithholdingTaxRate float64   `json:"withholding_tax_rate"`
+	AccruedUnpaid      float64   `json:"accrued_unpaid"`
+	Active             bool      `json:"active"`
+}
+
+// InterestAccrual is the interest earned by a plan on one day. There is at
+// most one per plan and day, which is what makes accrual safe to re-run.
+type InterestAccrual struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"create_at"`
+	PlanID    uint      `json:"plan_id" gorm:"uniqueIndex:idx_interest_accrual_day"`
+	Date      string    `json:"date" gorm:"uniqueIndex:idx_interest_accrual_day"`
+	Balance   float64   `json:"balance"`
+	Rate      float64   `json:"rate"`
+	Amount    float64   `json:"amount"`
+	PostingID *uint     `json:"posting_id,omitempty" gorm:"index"`
+}
+
+// InterestPosting is the capitalization of one month of accruals.
+type InterestPosting struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"create_at"`
+	PlanID    uint      `json:"plan_id" gorm:"uniqueIndex:idx_interest_posting_period"`
+	Period    string    `json:"period" gorm:"uniqueIndex:idx_interest_posting_period"`
+	Gross     float64   `json:"gross"`
+	Tax       float64   `json:"tax"`
+	Net       float64   `json:"net"`
+}
+
+type PlanRequest struct {
+	OwnerType          string  `json:"owner_type" validate:"required,oneof=account pocket"`
+	OwnerID            uint    `json:"owner_id" validate:"required"`
+	AnnualRate         float64 `json:"annual_rate" validate:"gte=0,lte=100"`
+	Basis              string  `json:"basis" validate:"required,oneof=ACT/365 ACT/360"`
+	WithholdingTaxRate float64 `json:"withholding_tax_rate" validate:"gte=0,lte=100"`
+	Active             *bool   `json:"active"`
+}
+
+type RunResponse struct {
+	Plans   int     `json:"plans"`
+	Created int     `json:"created"`
+	Amount  float64 `json:"amount"`
+}
+
+const (
+	OwnerAccount = "account"
+	OwnerPocket  = "pocket"
+
+	BasisACT365 = "ACT/365"
+	BasisACT360 = "ACT/360"
+
+	dateLayout   = "2006-01-02"
+	periodLayout = "2006-01"
+)
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
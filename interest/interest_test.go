@@ -0,0 +1,241 @@
+package interest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/ledger"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestDailyInterest(t *testing.T) {
+	cases := []struct {
+		balance float64
+		rate    float64
+		basis   string
+		want    string
+	}{
+		{100000, 1.5, BasisACT365, "4.10958904"},
+		{100000, 1.5, BasisACT360, "4.16666667"},
+		{1000000, 0.25, BasisACT365, "6.84931507"},
+		{50000, 0.5, BasisACT365, "0.68493151"},
+		{0, 1.5, BasisACT365, "0"},
+		{-500, 1.5, BasisACT365, "0"},
+	}
+
+	for _, c := range cases {
+		got := DailyInterest(c.balance, c.rate, c.basis)
+		assert.Equal(t, c.want, got.String(), "%v at %v%% %s", c.balance, c.rate, c.basis)
+	}
+}
+
+func days(t *testing.T, db *gorm.DB, year int, month time.Month) {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	for d := start; d.Month() == month; d = d.AddDate(0, 0, 1) {
+		_, err := Accrue(db, d)
+		assert.NoError(t, err)
+	}
+}
+
+func TestAccountInterest(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{},
+		&ledger.BalanceSnapshot{}, &InterestPlan{}, &InterestAccrual{}, &InterestPosting{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	acc := account.Account{Email: "saver@example.com", AccountNumber: "1234567890", Balance: 100000}
+	tx.Create(&acc)
+	tx.Create(&account.AccountTransfer{CreatedAt: time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), Type: account.TransferTypeDeposit,
+		Status: account.TransferStatusCompleted, To: acc.AccountNumber, Amount: 100000})
+	plan := InterestPlan{OwnerType: OwnerAccount, OwnerID: acc.ID, AnnualRate: 1.5, Basis: BasisACT365, WithholdingTaxRate: 15, Active: true}
+	tx.Create(&plan)
+
+	// Act: accrue January, re-running one day
+	days(t, tx, 2026, time.January)
+	res, err := Accrue(tx, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, res.Created)
+
+	var accrued InterestPlan
+	tx.First(&accrued, plan.ID)
+	assert.InDelta(t, 127.39726, accrued.AccruedUnpaid, 0.00001)
+
+	// Act: capitalize January, twice
+	res, err = Capitalize(tx, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Created)
+	res, err = Capitalize(tx, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, res.Created)
+
+	// Assert: 127.40 gross, 19.11 tax, 108.29 net
+	var posting InterestPosting
+	tx.Where("plan_id = ? AND period = ?", plan.ID, "2026-01").First(&posting)
+	assert.Equal(t, 127.40, posting.Gross)
+	assert.Equal(t, 19.11, posting.Tax)
+	assert.Equal(t, 108.29, posting.Net)
+
+	var updated account.Account
+	tx.First(&updated, acc.ID)
+	assert.Equal(t, 100108.29, updated.Balance)
+
+	var capitalized InterestPlan
+	tx.First(&capitalized, plan.ID)
+	assert.InDelta(t, 0, capitalized.AccruedUnpaid, 0.00001)
+
+	var movements []account.AccountTransfer
+	tx.Where("type IN ?", []string{account.TransferTypeInterest, account.TransferTypeWithholdingTax}).Order("id").Find(&movements)
+	assert.Equal(t, 2, len(movements))
+	assert.Equal(t, acc.AccountNumber, movements[0].To)
+	assert.Equal(t, 127.40, movements[0].Amount)
+	assert.Equal(t, acc.AccountNumber, movements[1].From)
+	assert.Equal(t, 19.11, movements[1].Amount)
+
+	// Act: a posted month cannot be accrued again
+	res, err = Accrue(tx, time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, res.Created)
+
+	// Act: February compounds on the capitalized balance
+	days(t, tx, 2026, time.February)
+	_, err = Capitalize(tx, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	// Assert: 115.19 gross, 17.28 tax, 97.91 net
+	var february InterestPosting
+	tx.Where("plan_id = ? AND period = ?", plan.ID, "2026-02").First(&february)
+	assert.Equal(t, 115.19, february.Gross)
+	assert.Equal(t, 17.28, february.Tax)
+	assert.Equal(t, 97.91, february.Net)
+
+	var compounded account.Account
+	tx.First(&compounded, acc.ID)
+	assert.Equal(t, 100206.20, compounded.Balance)
+}
+
+func TestPocketInterest(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{},
+		&ledger.BalanceSnapshot{}, &InterestPlan{}, &InterestAccrual{}, &InterestPosting{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	opened := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	acc := account.Account{Email: "saver@example.com", AccountNumber: "1234567890"}
+	tx.Create(&acc)
+	p := pocket.Pocket{CreatedAt: opened, Title: "Savings", Balance: 10000, AccountID: acc.ID}
+	tx.Create(&p)
+	tx.Create(&pocket.PocketTransfer{CreatedAt: opened, Type: pocket.TransferTypeDeposit, To: p.ID, Amount: 10000, AccountID: acc.ID})
+	plan := InterestPlan{OwnerType: OwnerPocket, OwnerID: p.ID, AnnualRate: 2, Basis: BasisACT360, Active: true}
+	tx.Create(&plan)
+
+	// Act: 30 days of April, capitalized through the daily job
+	start := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	for d := start; d.Month() == time.April; d = d.AddDate(0, 0, 1) {
+		assert.NoError(t, RunDaily(tx, d.AddDate(0, 0, 1)))
+	}
+
+	// Assert: 16.67 gross and no tax
+	var posting InterestPosting
+	tx.Where("plan_id = ? AND period = ?", plan.ID, "2026-04").First(&posting)
+	assert.Equal(t, 16.67, posting.Gross)
+	assert.Equal(t, 0.0, posting.Tax)
+
+	var updated pocket.Pocket
+	tx.First(&updated, p.ID)
+	assert.Equal(t, 10016.67, updated.Balance)
+
+	var movements []pocket.PocketTransfer
+	tx.Where("type = ?", pocket.TransferTypeInterest).Find(&movements)
+	assert.Equal(t, 1, len(movements))
+	assert.Equal(t, p.ID, movements[0].To)
+	assert.Equal(t, 16.67, movements[0].Amount)
+}
+
+func TestAccrueBackfill(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{},
+		&ledger.BalanceSnapshot{}, &InterestPlan{}, &InterestAccrual{}, &InterestPosting{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	// 100000 at the end of June 2026, half of it spent since.
+	acc := account.Account{Email: "saver@example.com", AccountNumber: "1234567890", Balance: 50000}
+	tx.Create(&acc)
+	tx.Create(&account.AccountTransfer{CreatedAt: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), Type: account.TransferTypeDeposit,
+		Status: account.TransferStatusCompleted, To: acc.AccountNumber, Amount: 100000})
+	tx.Create(&account.AccountTransfer{CreatedAt: time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC), Type: account.TransferTypeTransfer,
+		Status: account.TransferStatusCompleted, From: acc.AccountNumber, To: "1234567891", Amount: 50000})
+	plan := InterestPlan{OwnerType: OwnerAccount, OwnerID: acc.ID, AnnualRate: 1.5, Basis: BasisACT365, Active: true}
+	tx.Create(&plan)
+
+	// Act
+	june, juneErr := Accrue(tx, time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC))
+	july, julyErr := Accrue(tx, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC))
+	accruals := []InterestAccrual{}
+	tx.Where("plan_id = ?", plan.ID).Order("date").Find(&accruals)
+
+	// Assert
+	assert.NoError(t, juneErr)
+	assert.Equal(t, 4.11, june.Amount)
+	assert.NoError(t, julyErr)
+	assert.Equal(t, 2.05, july.Amount)
+	assert.Len(t, accruals, 2)
+	assert.Equal(t, 100000.0, accruals[0].Balance)
+	assert.Equal(t, 50000.0, accruals[1].Balance)
+}
+
+func TestCapitalizeClosedOwner(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{},
+		&ledger.BalanceSnapshot{}, &InterestPlan{}, &InterestAccrual{}, &InterestPosting{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	acc := account.Account{Email: "saver@example.com", AccountNumber: "1234567890", Balance: 100000}
+	tx.Create(&acc)
+	tx.Create(&account.AccountTransfer{CreatedAt: time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), Type: account.TransferTypeDeposit,
+		Status: account.TransferStatusCompleted, To: acc.AccountNumber, Amount: 100000})
+	plan := InterestPlan{OwnerType: OwnerAccount, OwnerID: acc.ID, AnnualRate: 1.5, Basis: BasisACT365, Active: true}
+	tx.Create(&plan)
+	days(t, tx, 2026, time.January)
+	tx.Model(&acc).Update("status", account.StatusClosed)
+
+	// Act
+	res, err := Capitalize(tx, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, res.Created)
+	var postings, unposted int64
+	tx.Model(&InterestPosting{}).Count(&postings)
+	tx.Model(&InterestAccrual{}).Where("plan_id = ? AND posting_id IS NULL", plan.ID).Count(&unposted)
+	assert.Zero(t, postings)
+	assert.Equal(t, int64(31), unposted)
+	var closed account.Account
+	tx.First(&closed, acc.ID)
+	assert.Equal(t, 100000.0, closed.Balance)
+}
@@ -0,0 +1,116 @@
+package interest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// @Summary Set an interest plan
+// @Description Assign an annual rate to an account or a pocket, replacing its current plan
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param plan body interest.PlanRequest true "PlanRequest data"
+// @Success 200 {object} interest.InterestPlan
+// @Security  Bearer
+// @Router /admin/interest/plans [post]
+func (h *handler) SetPlan(c *fiber.Ctx) error {
+	req := &PlanRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	var owner interface{} = &account.Account{}
+	if req.OwnerType == OwnerPocket {
+		owner = &pocket.Pocket{}
+	}
+	if err := h.DB.First(owner, req.OwnerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodeNotFound, req.OwnerType+" not found")
+		}
+		return apperr.Internal(err)
+	}
+
+	p := &InterestPlan{}
+	tx := h.DB.Where(&InterestPlan{OwnerType: req.OwnerType, OwnerID: req.OwnerID}).FirstOrInit(p)
+	if tx.Error != nil {
+		return apperr.Internal(tx.Error)
+	}
+
+	p.AnnualRate = req.AnnualRate
+	p.Basis = req.Basis
+	p.WithholdingTaxRate = req.WithholdingTaxRate
+	p.Active = req.Active == nil || *req.Active
+
+	if err := h.DB.Save(p).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(p)
+}
+
+// @Summary Get interest plans
+// @Description Get every interest plan with its accrued but unpaid interest
+// @Tags admin
+// @Produce json
+// @Success 200 {array} interest.InterestPlan
+// @Security  Bearer
+// @Router /admin/interest/plans [get]
+func (h *handler) GetAllPlans(c *fiber.Ctx) error {
+	p := []InterestPlan{}
+	if err := h.DB.Order("id").Find(&p).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(p)
+}
+
+// @Summary Accrue interest
+// @Description Accrue one day of interest on every active plan. Safe to run again for the same date.
+// @Tags admin
+// @Produce json
+// @Param date query string false "Date to accrue for, YYYY-MM-DD, default today"
+// @Success 200 {object} interest.RunResponse
+// @Security  Bearer
+// @Router /admin/interest/accrue [post]
+func (h *handler) RunAccrual(c *fiber.Ctx) error {
+	day, err := time.ParseInLocation(dateLayout, c.Query("date", time.Now().Format(dateLayout)), time.Local)
+	if err != nil {
+		return apperr.BadRequest("invalid date parameter")
+	}
+
+	res, err := Accrue(h.DB, day)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+// @Summary Capitalize interest
+// @Description Post the accrued interest of a month, less withholding tax, to the accounts and pockets
+// @Tags admin
+// @Produce json
+// @Param period query string false "Month to capitalize, YYYY-MM, default this month"
+// @Success 200 {object} interest.RunResponse
+// @Security  Bearer
+// @Router /admin/interest/capitalize [post]
+func (h *handler) RunCapitalization(c *fiber.Ctx) error {
+	month, err := time.ParseInLocation(periodLayout, c.Query("period", time.Now().Format(periodLayout)), time.Local)
+	if err != nil {
+		return apperr.BadRequest("invalid period parameter")
+	}
+
+	res, err := Capitalize(h.DB, month)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(res)
+}
@@ -0,0 +1,83 @@
+// Package job runs the daily jobs of the server. Every replica schedules
+// them, and a job_runs row per job and day decides which one runs each.
+package job
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobRun is one run of a daily job. Name and Day are unique together, so of
+// the replicas due to run a job on the same day only the one that records
+// the run first runs it.
+type JobRun struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	Name       string     `json:"name" gorm:"uniqueIndex:idx_job_runs_day"`
+	Day        string     `json:"day" gorm:"uniqueIndex:idx_job_runs_day"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Error      string     `json:"error"`
+}
+
+const dayLayout = "2006-01-02"
+
+// Daily calls fn once a day at offset past local midnight, until ctx is
+// cancelled. fn gets the time it was due to run; errors are logged and the
+// next run happens as usual. Runs another replica already made for the same
+// day are skipped.
+func Daily(ctx context.Context, db *gorm.DB, name string, offset time.Duration, fn func(time.Time) error) {
+	for {
+		next := nextRun(time.Now(), offset)
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := Once(db, name, next, fn); err != nil {
+			log.Printf("job %s: %s", name, err)
+		}
+	}
+}
+
+// Once runs fn for day, unless job name already ran for day, and records
+// how it went.
+func Once(db *gorm.DB, name string, day time.Time, fn func(time.Time) error) error {
+	r := &JobRun{Name: name, Day: day.Format(dayLayout), StartedAt: time.Now()}
+	created := db.Clauses(clause.OnConflict{DoNothing: true}).Create(r)
+	if created.Error != nil {
+		return created.Error
+	}
+	if created.RowsAffected == 0 {
+		log.Printf("job %s: already ran for %s", name, r.Day)
+		return nil
+	}
+
+	log.Printf("job %s: running for %s", name, day.Format(time.RFC3339))
+	err := fn(day)
+	now := time.Now()
+	r.FinishedAt = &now
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if uerr := db.Model(r).Select("finished_at", "error").Updates(r).Error; uerr != nil {
+		log.Printf("job %s: recording the run: %s", name, uerr)
+	}
+	return err
+}
+
+func nextRun(now time.Time, offset time.Duration) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	next := midnight.Add(offset)
+	if !next.After(now) {
+		next = midnight.AddDate(0, 0, 1).Add(offset)
+	}
+	return next
+}
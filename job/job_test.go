@@ -0,0 +1,60 @@
+package job
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestOnce(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&JobRun{}))
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	day := time.Date(2026, 10, 19, 0, 5, 0, 0, time.UTC)
+	calls := 0
+	fn := func(time.Time) error {
+		calls++
+		return nil
+	}
+	failed := errors.New("no balances")
+
+	// Act: two replicas due the same day, then the next day, which fails
+	first := Once(tx, "maturity", day, fn)
+	second := Once(tx, "maturity", day, fn)
+	other := Once(tx, "snapshot", day, fn)
+	next := Once(tx, "maturity", day.AddDate(0, 0, 1), func(time.Time) error { return failed })
+	runs := []JobRun{}
+	tx.Order("id").Find(&runs)
+
+	// Assert
+	assert.NoError(t, first)
+	assert.NoError(t, second)
+	assert.NoError(t, other)
+	assert.ErrorIs(t, next, failed)
+	assert.Equal(t, 2, calls)
+	assert.Len(t, runs, 3)
+	assert.Equal(t, "2026-10-19", runs[0].Day)
+	assert.NotNil(t, runs[0].FinishedAt)
+	assert.Equal(t, "no balances", runs[2].Error)
+}
+
+func TestNextRun(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 10, 19, 23, 40, 0, 0, time.UTC)
+
+	// Act
+	later := nextRun(now, 23*time.Hour+55*time.Minute)
+	tomorrow := nextRun(now, 5*time.Minute)
+
+	// Assert
+	assert.Equal(t, time.Date(2026, 10, 19, 23, 55, 0, 0, time.UTC), later)
+	assert.Equal(t, time.Date(2026, 10, 20, 0, 5, 0, 0, time.UTC), tomorrow)
+}
@@ -0,0 +1,42 @@
+package ledger
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Get a past balance
+// @Description Get the balance of the caller's account and pockets at a point in time, now by default, worked out from the last end-of-day snapshot before it and the movements made after the snapshot
+// @Tags accounts
+// @Produce json
+// @Param at query string false "RFC 3339 time, e.g. 2026-09-30T23:59:59Z"
+// @Success 200 {object} ledger.HistoricalBalance
+// @Security  Bearer
+// @Router /account/balance [get]
+func (h *handler) GetBalance(c *fiber.Ctx) error {
+	now := time.Now()
+	at := now
+	if v := c.Query("at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return apperr.BadRequest("at must be an RFC 3339 time")
+		}
+		if t.After(now) {
+			return apperr.BadRequest("at must not be in the future")
+		}
+		at = t
+	}
+
+	b, err := BalanceAt(h.DB, uint(c.Locals("account_id").(int)), at)
+	if err != nil {
+		if errors.Is(err, account.ErrAccountNotFound) {
+			return apperr.NotFound(apperr.CodeAccountNotFound, "account not found")
+		}
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(b)
+}
@@ -0,0 +1,205 @@
+// Package ledger works out the balances of accounts and pockets from the
+// movements recorded for them, the same way statements read them, so that
+// the balances kept on the rows can be checked against, and rebuilt from,
+// their history, and past balances looked up from end-of-day snapshots.
+package ledger
+
+import (
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	KindAccount = "account"
+	KindPocket  = "pocket"
+)
+
+// Balance is the balance kept for an account or a pocket next to the one
+// its movements add up to.
+type Balance struct {
+	Kind      string  `json:"kind"`
+	ID        uint    `json:"id"`
+	AccountID uint    `json:"account_id"`
+	Name      string  `json:"name"`
+	Recorded  float64 `json:"recorded"`
+	Expected  float64 `json:"expected"`
+}
+
+// Off reports whether the recorded balance is not what the movements add
+// up to.
+func (b Balance) Off() bool {
+	return !decimal.NewFromFloat(b.Recorded).Equal(decimal.NewFromFloat(b.Expected))
+}
+
+// total is the sum of the amounts of the movements grouped under holder.
+type total[K comparable] struct {
+	Holder K
+	Total  float64
+}
+
+func sums[K comparable](q *gorm.DB, column string) (map[K]decimal.Decimal, error) {
+	rows := []total[K]{}
+	err := q.Select("? AS holder, SUM(amount) AS total", clause.Column{Name: column}).Group(column).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	m := map[K]decimal.Decimal{}
+	for _, r := range rows {
+		m[r.Holder] = decimal.NewFromFloat(r.Total)
+	}
+	return m, nil
+}
+
+// Balances lists the main balance of account id and the balances of its
+// pockets, or those of every account when id is 0, next to what their
+// movements add up to.
+func Balances(db *gorm.DB, id uint) ([]Balance, error) {
+	accounts := []account.Account{}
+	q := db.Order("id")
+	if id != 0 {
+		q = q.Where("id = ?", id)
+	}
+	if err := q.Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	if id != 0 && len(accounts) == 0 {
+		return nil, account.ErrAccountNotFound
+	}
+
+	pockets := []pocket.Pocket{}
+	q = db.Order("id")
+	if id != 0 {
+		q = q.Where("account_id = ?", id)
+	}
+	if err := q.Find(&pockets).Error; err != nil {
+		return nil, err
+	}
+
+	held, kept, err := net(db, id, accounts, pockets, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	balances := []Balance{}
+	for _, a := range accounts {
+		b := Balance{Kind: KindAccount, ID: a.ID, AccountID: a.ID, Name: a.AccountNumber, Recorded: a.Balance}
+		b.Expected, _ = held[a.ID].Round(2).Float64()
+		balances = append(balances, b)
+	}
+	for _, p := range pockets {
+		b := Balance{Kind: KindPocket, ID: p.ID, AccountID: p.AccountID, Name: p.Title, Recorded: p.Balance}
+		b.Expected, _ = kept[p.ID].Round(2).Float64()
+		balances = append(balances, b)
+	}
+	return balances, nil
+}
+
+// net adds up the movements of accounts and pockets, those of account id
+// or of every account when id is 0, made after after and until until. A
+// zero time leaves that end open.
+//
+// The main balance of an account adds up the completed transfers to it,
+// less those from it, less the money it moved into pockets, plus the money
+// it took out of them. A pocket adds up every movement into it, less every
+// movement out of it.
+func net(db *gorm.DB, id uint, accounts []account.Account, pockets []pocket.Pocket, after, until time.Time) (map[uint]decimal.Decimal, map[uint]decimal.Decimal, error) {
+	transfers := db.Model(&account.AccountTransfer{}).Where("status = ?", account.TransferStatusCompleted)
+	movements := db.Model(&pocket.PocketTransfer{})
+	if id != 0 {
+		ids := []uint{}
+		for _, p := range pockets {
+			ids = append(ids, p.ID)
+		}
+		number := accounts[0].AccountNumber
+		transfers = transfers.Where(`"from" = ? OR "to" = ?`, number, number)
+		movements = movements.Where(`account_id = ? OR "from" IN ? OR "to" IN ?`, id, ids, ids)
+	}
+	if !after.IsZero() {
+		transfers = transfers.Where("created_at > ?", after)
+		movements = movements.Where("created_at > ?", after)
+	}
+	if !until.IsZero() {
+		transfers = transfers.Where("created_at <= ?", until)
+		movements = movements.Where("created_at <= ?", until)
+	}
+	transfers = transfers.Session(&gorm.Session{})
+	movements = movements.Session(&gorm.Session{})
+
+	credits, err := sums[string](transfers, "to")
+	if err != nil {
+		return nil, nil, err
+	}
+	debits, err := sums[string](transfers, "from")
+	if err != nil {
+		return nil, nil, err
+	}
+	saved, err := sums[uint](movements.Where(`"from" = 0 AND type IN ?`, []string{pocket.TransferTypeDeposit, pocket.TransferTypeRule}), "account_id")
+	if err != nil {
+		return nil, nil, err
+	}
+	withdrawn, err := sums[uint](movements.Where(`"to" = 0 AND type = ?`, pocket.TransferTypeWithdrawal), "account_id")
+	if err != nil {
+		return nil, nil, err
+	}
+	into, err := sums[uint](movements, "to")
+	if err != nil {
+		return nil, nil, err
+	}
+	outOf, err := sums[uint](movements, "from")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	held := map[uint]decimal.Decimal{}
+	for _, a := range accounts {
+		held[a.ID] = credits[a.AccountNumber].Sub(debits[a.AccountNumber]).Sub(saved[a.ID]).Add(withdrawn[a.ID])
+	}
+	kept := map[uint]decimal.Decimal{}
+	for _, p := range pockets {
+		kept[p.ID] = into[p.ID].Sub(outOf[p.ID])
+	}
+	return held, kept, nil
+}
+
+// Rebuild sets every balance of Balances that is off to what its movements
+// add up to, and returns the ones it changed.
+func Rebuild(db *gorm.DB, id uint) ([]Balance, error) {
+	changed := []Balance{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		balances, err := Balances(tx, id)
+		if err != nil {
+			return err
+		}
+		for _, b := range balances {
+			if !b.Off() {
+				continue
+			}
+			q := tx.Model(&account.Account{})
+			if b.Kind == KindPocket {
+				q = tx.Model(&pocket.Pocket{})
+			}
+			if err := q.Where("id = ?", b.ID).Update("balance", b.Expected).Error; err != nil {
+				return err
+			}
+			changed = append(changed, b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
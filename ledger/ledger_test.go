@@ -0,0 +1,183 @@
+package ledger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{}, &BalanceSnapshot{})
+	assert.NoError(t, err)
+	return db
+}
+
+func TestBalances(t *testing.T) {
+	// Arrange
+	tx := setup(t).Begin()
+	defer tx.Rollback()
+
+	a := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 60}
+	b := &account.Account{Email: "b@test.com", AccountNumber: "1000000002", Balance: 40}
+	tx.Create(a)
+	tx.Create(b)
+	p := &pocket.Pocket{Title: "Holiday", Balance: 14.5, AccountID: a.ID}
+	tx.Create(p)
+
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: a.AccountNumber, Amount: 100})
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeTransfer, From: a.AccountNumber, To: b.AccountNumber, Amount: 30})
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeReversal, Status: account.TransferStatusPending, From: b.AccountNumber, To: a.AccountNumber, Amount: 30})
+	tx.Create(&pocket.PocketTransfer{Type: pocket.TransferTypeDeposit, To: p.ID, Amount: 20, AccountID: a.ID})
+	tx.Create(&pocket.PocketTransfer{Type: pocket.TransferTypeInterest, To: p.ID, Amount: 0.5})
+	tx.Create(&pocket.PocketTransfer{Type: pocket.TransferTypeWithdrawal, From: p.ID, Amount: 5, AccountID: a.ID})
+	tx.Create(&pocket.PocketTransfer{Type: pocket.TransferTypePenalty, From: p.ID, Amount: 1, AccountID: a.ID})
+
+	// Act
+	all, err := Balances(tx, 0)
+	one, oneErr := Balances(tx, a.ID)
+	_, missingErr := Balances(tx, 99)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []Balance{
+		{Kind: KindAccount, ID: a.ID, AccountID: a.ID, Name: "1000000001", Recorded: 60, Expected: 55},
+		{Kind: KindAccount, ID: b.ID, AccountID: b.ID, Name: "1000000002", Recorded: 40, Expected: 30},
+		{Kind: KindPocket, ID: p.ID, AccountID: a.ID, Name: "Holiday", Recorded: 14.5, Expected: 14.5},
+	}, all)
+	assert.True(t, all[0].Off())
+	assert.False(t, all[2].Off())
+	assert.NoError(t, oneErr)
+	assert.Equal(t, []Balance{all[0], all[2]}, one)
+	assert.ErrorIs(t, missingErr, account.ErrAccountNotFound)
+}
+
+func TestRebuild(t *testing.T) {
+	// Arrange
+	tx := setup(t).Begin()
+	defer tx.Rollback()
+
+	a := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 90}
+	tx.Create(a)
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: a.AccountNumber, Amount: 100})
+
+	// Act
+	changed, err := Rebuild(tx, 0)
+	again, againErr := Rebuild(tx, a.ID)
+	after := account.Account{}
+	tx.First(&after, a.ID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, changed, 1)
+	assert.Equal(t, 100.0, after.Balance)
+	assert.NoError(t, againErr)
+	assert.Empty(t, again)
+}
+
+func TestSnapshot(t *testing.T) {
+	// Arrange
+	tx := setup(t).Begin()
+	defer tx.Rollback()
+
+	day := func(d, h int) time.Time { return time.Date(2026, 9, d, h, 0, 0, 0, time.UTC) }
+	a := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 50, CreatedAt: day(1, 9)}
+	b := &account.Account{Email: "b@test.com", AccountNumber: "1000000002", Balance: 30, CreatedAt: day(1, 9)}
+	tx.Create(a)
+	tx.Create(b)
+	p := &pocket.Pocket{Title: "Holiday", Balance: 20.5, AccountID: a.ID, CreatedAt: day(2, 9)}
+	tx.Create(p)
+
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: a.AccountNumber, Amount: 100, CreatedAt: day(1, 10)})
+	tx.Create(&pocket.PocketTransfer{Type: pocket.TransferTypeDeposit, To: p.ID, Amount: 20, AccountID: a.ID, CreatedAt: day(2, 12)})
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeTransfer, From: a.AccountNumber, To: b.AccountNumber, Amount: 30, CreatedAt: day(3, 9)})
+	tx.Create(&pocket.PocketTransfer{Type: pocket.TransferTypeInterest, To: p.ID, Amount: 0.5, CreatedAt: day(3, 23)})
+
+	// Act
+	before, beforeErr := BalanceAt(tx, a.ID, day(2, 13))
+	first, firstErr := Snapshot(tx, day(2, 0))
+	again, againErr := Snapshot(tx, day(2, 0))
+	second, secondErr := Snapshot(tx, day(3, 0))
+	snapshots := []BalanceSnapshot{}
+	tx.Where("at = ?", day(4, 0)).Order("kind, holder_id").Find(&snapshots)
+	tx.Model(&BalanceSnapshot{}).Where("kind = ? AND holder_id = ? AND at = ?", KindAccount, a.ID, day(3, 0)).Update("balance", 81)
+	after, afterErr := BalanceAt(tx, a.ID, day(3, 12))
+	_, missingErr := BalanceAt(tx, 99, day(3, 12))
+
+	// Assert
+	assert.NoError(t, beforeErr)
+	assert.Nil(t, before.SnapshotAt)
+	assert.Equal(t, 80.0, before.Balance)
+	assert.Equal(t, []PocketBalance{{ID: p.ID, Name: "Holiday", Balance: 20}}, before.Pockets)
+	assert.Equal(t, 100.0, before.Total)
+
+	assert.NoError(t, firstErr)
+	assert.Equal(t, 3, first)
+	assert.NoError(t, againErr)
+	assert.Equal(t, 3, again)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, 3, second)
+	var count int64
+	tx.Model(&BalanceSnapshot{}).Count(&count)
+	assert.Equal(t, int64(6), count)
+	assert.Len(t, snapshots, 3)
+	assert.Equal(t, []float64{50, 30, 20.5}, []float64{snapshots[0].Balance, snapshots[1].Balance, snapshots[2].Balance})
+
+	assert.NoError(t, afterErr)
+	assert.True(t, day(3, 0).Equal(*after.SnapshotAt))
+	assert.Equal(t, 51.0, after.Balance)
+	assert.Equal(t, []PocketBalance{{ID: p.ID, Name: "Holiday", Balance: 20}}, after.Pockets)
+	assert.Equal(t, 71.0, after.Total)
+	assert.ErrorIs(t, missingErr, account.ErrAccountNotFound)
+}
+
+func TestGetBalance(t *testing.T) {
+	// Arrange
+	tx := setup(t).Begin()
+	defer tx.Rollback()
+
+	a := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 100, CreatedAt: time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC)}
+	tx.Create(a)
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: a.AccountNumber, Amount: 60, CreatedAt: time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)})
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: a.AccountNumber, Amount: 40, CreatedAt: time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)})
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(a.ID))
+		return c.Next()
+	})
+	app.Get("/account/balance", New(tx).GetBalance)
+
+	// Act
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/account/balance?at=2026-09-30T23:59:59Z", nil))
+	got := HistoricalBalance{}
+	json.NewDecoder(resp.Body).Decode(&got)
+	nowResp, nowErr := app.Test(httptest.NewRequest(http.MethodGet, "/account/balance", nil))
+	current := HistoricalBalance{}
+	json.NewDecoder(nowResp.Body).Decode(&current)
+	badResp, badErr := app.Test(httptest.NewRequest(http.MethodGet, "/account/balance?at=yesterday", nil))
+	futureResp, futureErr := app.Test(httptest.NewRequest(http.MethodGet, "/account/balance?at=2999-01-01T00:00:00Z", nil))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, 60.0, got.Balance)
+	assert.Equal(t, 60.0, got.Total)
+	assert.NoError(t, nowErr)
+	assert.Equal(t, 100.0, current.Balance)
+	assert.NoError(t, badErr)
+	assert.Equal(t, fiber.StatusBadRequest, badResp.StatusCode)
+	assert.NoError(t, futureErr)
+	assert.Equal(t, fiber.StatusBadRequest, futureResp.StatusCode)
+}
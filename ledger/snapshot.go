@@ -0,0 +1,189 @@
+package ledger
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// BalanceSnapshot is the balance of an account or a pocket at the end of a
+// day: what its movements made until then add up to.
+type BalanceSnapshot struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	Kind      string    `json:"kind" gorm:"uniqueIndex:idx_balance_snapshots_holder"`
+	HolderID  uint      `json:"id" gorm:"uniqueIndex:idx_balance_snapshots_holder"`
+	At        time.Time `json:"at" gorm:"uniqueIndex:idx_balance_snapshots_holder;index"`
+	AccountID uint      `json:"account_id" gorm:"index"`
+	Balance   float64   `json:"balance"`
+}
+
+// HistoricalBalance is the main balance of an account and the balances of
+// its pockets at At. SnapshotAt is when the snapshot it was worked out from
+// was taken, nil when there was none and every movement was added up.
+type HistoricalBalance struct {
+	At         time.Time       `json:"at"`
+	SnapshotAt *time.Time      `json:"snapshot_at"`
+	Balance    float64         `json:"balance"`
+	Pockets    []PocketBalance `json:"pockets"`
+	Total      float64         `json:"total"`
+}
+
+type PocketBalance struct {
+	ID      uint    `json:"id"`
+	Name    string  `json:"name"`
+	Balance float64 `json:"balance"`
+}
+
+// EndOfDay is when the snapshot of day is taken: the midnight it ends at.
+func EndOfDay(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+}
+
+// Snapshot takes the snapshot of every account and pocket at the end of
+// day, replacing the one taken before, and returns how many balances it
+// kept.
+func Snapshot(db *gorm.DB, day time.Time) (int, error) {
+	at := EndOfDay(day)
+	taken := []BalanceSnapshot{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("at = ?", at).Delete(&BalanceSnapshot{}).Error; err != nil {
+			return err
+		}
+		var err error
+		if taken, _, err = balancesAt(tx, 0, at); err != nil {
+			return err
+		}
+		if len(taken) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(taken, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(taken), nil
+}
+
+// RunDaily takes the snapshot of the day that ended before day.
+func RunDaily(db *gorm.DB, day time.Time) error {
+	_, err := Snapshot(db, day.AddDate(0, 0, -1))
+	return err
+}
+
+// BalanceAt works out the balances of account id at at, from the last
+// snapshot taken until then and the movements made after it.
+func BalanceAt(db *gorm.DB, id uint, at time.Time) (*HistoricalBalance, error) {
+	balances, base, err := balancesAt(db, id, at)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[uint]string{}
+	pockets := []pocket.Pocket{}
+	if err := db.Unscoped().Where("account_id = ?", id).Find(&pockets).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range pockets {
+		names[p.ID] = p.Title
+	}
+
+	h := &HistoricalBalance{At: at, Pockets: []PocketBalance{}}
+	if base != nil {
+		h.SnapshotAt = &base.At
+	}
+	total := decimal.Zero
+	for _, b := range balances {
+		total = total.Add(decimal.NewFromFloat(b.Balance))
+		if b.Kind == KindAccount {
+			h.Balance = b.Balance
+			continue
+		}
+		h.Pockets = append(h.Pockets, PocketBalance{ID: b.HolderID, Name: names[b.HolderID], Balance: b.Balance})
+	}
+	h.Total, _ = total.Round(2).Float64()
+	return h, nil
+}
+
+// balancesAt works out the balances at at of account id and its pockets, or
+// of every account and pocket when id is 0: those of the last snapshot
+// taken until then, plus what moved after it. It returns that snapshot as
+// well, nil when there was none.
+func balancesAt(db *gorm.DB, id uint, at time.Time) ([]BalanceSnapshot, *BalanceSnapshot, error) {
+	accounts := []account.Account{}
+	q := db.Order("id")
+	if id != 0 {
+		q = q.Where("id = ?", id)
+	} else {
+		q = q.Unscoped().Where("created_at <= ? AND (deleted_at IS NULL OR deleted_at > ?)", at, at)
+	}
+	if err := q.Find(&accounts).Error; err != nil {
+		return nil, nil, err
+	}
+	if id != 0 && len(accounts) == 0 {
+		return nil, nil, account.ErrAccountNotFound
+	}
+
+	pockets := []pocket.Pocket{}
+	q = db.Unscoped().Where("created_at <= ? AND (deleted_at IS NULL OR deleted_at > ?)", at, at).Order("id")
+	if id != 0 {
+		q = q.Where("account_id = ?", id)
+	}
+	if err := q.Find(&pockets).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var base *BalanceSnapshot
+	last := BalanceSnapshot{}
+	q = db.Where("at <= ?", at)
+	if id != 0 {
+		q = q.Where("account_id = ?", id)
+	}
+	err := q.Order("at desc").First(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+	held := map[uint]decimal.Decimal{}
+	kept := map[uint]decimal.Decimal{}
+	var after time.Time
+	if err == nil {
+		base = &last
+		after = last.At
+		snapshots := []BalanceSnapshot{}
+		q = db.Where("at = ?", last.At)
+		if id != 0 {
+			q = q.Where("account_id = ?", id)
+		}
+		if err := q.Find(&snapshots).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, s := range snapshots {
+			if s.Kind == KindAccount {
+				held[s.HolderID] = decimal.NewFromFloat(s.Balance)
+			} else {
+				kept[s.HolderID] = decimal.NewFromFloat(s.Balance)
+			}
+		}
+	}
+
+	movedHeld, movedKept, err := net(db, id, accounts, pockets, after, at)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	balances := []BalanceSnapshot{}
+	for _, a := range accounts {
+		s := BalanceSnapshot{Kind: KindAccount, HolderID: a.ID, At: at, AccountID: a.ID}
+		s.Balance, _ = held[a.ID].Add(movedHeld[a.ID]).Round(2).Float64()
+		balances = append(balances, s)
+	}
+	for _, p := range pockets {
+		s := BalanceSnapshot{Kind: KindPocket, HolderID: p.ID, At: at, AccountID: p.AccountID}
+		s.Balance, _ = kept[p.ID].Add(movedKept[p.ID]).Round(2).Float64()
+		balances = append(balances, s)
+	}
+	return balances, base, nil
+}
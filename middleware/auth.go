@@ -1,31 +1,45 @@
 package middleware
 
 import (
+	"github.com/arthit666/make_app/apperr"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-type Err struct {
-	Massage string `json:"massage"`
-}
-
 func ExtractUserFromJWT(c *fiber.Ctx) error {
 	token := c.Locals("user").(*jwt.Token)
 	if token == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(Err{Massage: "missing jwt token"})
+		return apperr.Unauthenticated("missing jwt token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(Err{Massage: "invalid jwt token"})
+		return apperr.Unauthenticated("invalid jwt token")
 	}
 
 	accountIDFloat, ok := claims["account_id"].(float64)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(Err{Massage: "invalid jwt token"})
+		return apperr.Unauthenticated("invalid jwt token")
 	}
 
+	role, _ := claims["role"].(string)
+
 	accountID := int(accountIDFloat)
 	c.Locals("account_id", accountID)
+	c.Locals("role", role)
 	return c.Next()
 }
+
+// RequireRole only lets the request through when the role taken from the
+// JWT by ExtractUserFromJWT is one of roles.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		for _, r := range roles {
+			if role == r {
+				return c.Next()
+			}
+		}
+		return apperr.Forbidden("forbidden")
+	}
+}
@@ -0,0 +1,80 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Usage explains the arguments of Command.
+const Usage = `usage: migrate <command>
+
+  up            apply every pending migration
+  down          roll back the last migration applied
+  status        list the migrations and when they were applied
+  to <version>  apply or roll back migrations until version is the last applied (0 rolls back all)`
+
+var ErrUsage = errors.New(Usage)
+
+// Command runs the migrate subcommand given by args against db, and writes
+// what it did to w.
+func Command(db *gorm.DB, args []string, w io.Writer) error {
+	m, err := New(db)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	var ran []Migration
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		ran, err = m.Up()
+	case args[0] == "down" && len(args) == 1:
+		ran, err = m.Down()
+	case args[0] == "to" && len(args) == 2:
+		version, perr := strconv.Atoi(args[1])
+		if perr != nil {
+			return ErrUsage
+		}
+		ran, err = m.To(version)
+	case args[0] == "status" && len(args) == 1:
+		return status(m, w)
+	default:
+		return ErrUsage
+	}
+
+	for _, mg := range ran {
+		fmt.Fprintf(w, "%04d_%s\n", mg.Version, mg.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(ran) == 0 {
+		fmt.Fprintln(w, "nothing to migrate")
+	}
+	return nil
+}
+
+func status(m *Migrator, w io.Writer) error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		at := "pending"
+		if s.AppliedAt != nil {
+			at = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, at)
+	}
+	return tw.Flush()
+}
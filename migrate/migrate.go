@@ -0,0 +1,299 @@
+// Package migrate changes the database schema through the numbered SQL
+// files in sql/<dialect>, each with an up and a down half, and records the
+// ones applied in schema_migrations. On Postgres every run holds an
+// advisory lock, so that only one replica migrates at a time.
+package migrate
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql
+var files embed.FS
+
+// lockID is the key of the Postgres advisory lock held while migrating.
+const lockID = 8_470_112_047
+
+// baseline is the version of the migration that takes over databases
+// AutoMigrate created.
+const baseline = 1
+
+// createTable matches the CREATE TABLE IF NOT EXISTS statements of a
+// migration, with the name of the table and its column definitions.
+var createTable = regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\);`)
+
+var (
+	ErrUnknownVersion = errors.New("no migration has that version")
+	ErrDirty          = errors.New("an applied migration has no file")
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and when it was applied, if it was.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+type applied struct {
+	Version   int `gorm:"primarykey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (applied) TableName() string {
+	return "schema_migrations"
+}
+
+// tables are the definitions of schema_migrations in every dialect.
+var tables = map[string]string{
+	"postgres": "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, name text NOT NULL, applied_at timestamptz NOT NULL)",
+	"sqlite":   "CREATE TABLE IF NOT EXISTS schema_migrations (version integer PRIMARY KEY, name text NOT NULL, applied_at datetime NOT NULL)",
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New is a Migrator of db with the migrations of its dialect.
+func New(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	if _, ok := tables[dialect]; !ok {
+		return nil, fmt.Errorf("migrate: unsupported dialect %q", dialect)
+	}
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// load reads the migrations of dialect in the order of their versions.
+// Every version needs both a <version>_<name>.up.sql and a .down.sql file.
+func load(dialect string) ([]Migration, error) {
+	dir := path.Join("sql", dialect)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".sql"), ".")
+		number, name, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || !found || err != nil || version <= 0 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migrate: %s is not named <version>_<name>.up.sql or .down.sql", e.Name())
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migrate: version %d is both %s and %s", version, m.Name, name)
+		}
+
+		body, err := fs.ReadFile(files, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrate: version %d needs both an up and a down file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest is the version of the newest migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every migration not applied yet.
+func (m *Migrator) Up() ([]Migration, error) {
+	return m.run(func([]int) int { return m.Latest() })
+}
+
+// Down rolls back the last migration applied.
+func (m *Migrator) Down() ([]Migration, error) {
+	return m.run(func(versions []int) int {
+		if len(versions) < 2 {
+			return 0
+		}
+		return versions[len(versions)-2]
+	})
+}
+
+// To applies or rolls back migrations until version is the last one
+// applied. Version 0 rolls back all of them.
+func (m *Migrator) To(version int) ([]Migration, error) {
+	if version != 0 && m.find(version) == nil {
+		return nil, ErrUnknownVersion
+	}
+	return m.run(func([]int) int { return version })
+}
+
+// Status lists every migration, and when it was applied.
+func (m *Migrator) Status() ([]Status, error) {
+	if err := m.db.Exec(tables[m.dialect]).Error; err != nil {
+		return nil, err
+	}
+	done := []applied{}
+	if err := m.db.Order("version").Find(&done).Error; err != nil {
+		return nil, err
+	}
+	at := map[int]time.Time{}
+	for _, a := range done {
+		at[a.Version] = a.AppliedAt
+	}
+
+	statuses := []Status{}
+	for _, mg := range m.migrations {
+		s := Status{Version: mg.Version, Name: mg.Name}
+		if t, ok := at[mg.Version]; ok {
+			s.AppliedAt = &t
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// run moves the schema to the version target picks from the versions
+// already applied, applying missing migrations up to it and rolling back
+// the ones after it, each in its own transaction. It returns the
+// migrations it ran.
+func (m *Migrator) run(target func(versions []int) int) ([]Migration, error) {
+	ran := []Migration{}
+	err := m.db.Connection(func(conn *gorm.DB) error {
+		if m.dialect == "postgres" {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", lockID).Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", lockID)
+		}
+
+		if err := conn.Exec(tables[m.dialect]).Error; err != nil {
+			return err
+		}
+		versions := []int{}
+		if err := conn.Model(&applied{}).Order("version").Pluck("version", &versions).Error; err != nil {
+			return err
+		}
+		done := map[int]bool{}
+		for _, v := range versions {
+			if m.find(v) == nil {
+				return fmt.Errorf("%w: version %d", ErrDirty, v)
+			}
+			done[v] = true
+		}
+
+		to := target(versions)
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if mg.Version <= to || !done[mg.Version] {
+				continue
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(mg.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&applied{}, mg.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("rolling back %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			ran = append(ran, mg)
+		}
+		for _, mg := range m.migrations {
+			if mg.Version > to || done[mg.Version] {
+				continue
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if mg.Version == baseline {
+					if err := adopt(tx, mg.Up); err != nil {
+						return err
+					}
+				}
+				if err := tx.Exec(mg.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&applied{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("applying %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			ran = append(ran, mg)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// adopt adds to the tables up creates that are there already the columns
+// they lack, so that CREATE TABLE IF NOT EXISTS, which leaves them as they
+// are, does not leave out the columns added since AutoMigrate created them.
+// Columns with a default get it on every row they are added to.
+func adopt(tx *gorm.DB, up string) error {
+	for _, match := range createTable.FindAllStringSubmatch(up, -1) {
+		table := match[1]
+		if !tx.Migrator().HasTable(table) {
+			continue
+		}
+		for _, line := range strings.Split(match[2], "\n") {
+			def := strings.TrimSuffix(strings.TrimSpace(line), ",")
+			column, _, _ := strings.Cut(def, " ")
+			column = strings.Trim(column, `"`)
+			if column == "" || strings.Contains(def, "PRIMARY KEY") || tx.Migrator().HasColumn(table, column) {
+				continue
+			}
+			if err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + def).Error; err != nil {
+				return fmt.Errorf("adding %s.%s: %w", table, column, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package migrate
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/approval"
+	"github.com/arthit666/make_app/audit"
+	"github.com/arthit666/make_app/batch"
+	"github.com/arthit666/make_app/budget"
+	"github.com/arthit666/make_app/interest"
+	"github.com/arthit666/make_app/job"
+	"github.com/arthit666/make_app/ledger"
+	"github.com/arthit666/make_app/notification"
+	"github.com/arthit666/make_app/payment"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/reconciliation"
+	"github.com/arthit666/make_app/rule"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// models are every model the migrations have a table for.
+var models = []interface{}{
+	&account.Account{}, &account.AccountTransfer{},
+	&pocket.Pocket{}, &pocket.PocketTransfer{}, &pocket.PocketMember{},
+	&approval.PendingAction{}, &audit.Record{},
+	&interest.InterestPlan{}, &interest.InterestAccrual{}, &interest.InterestPosting{},
+	&rule.Rule{}, &rule.RuleExecution{}, &budget.Budget{}, &budget.BudgetPeriod{},
+	&notification.Notification{}, &payment.PaymentRequest{}, &batch.Batch{}, &batch.Row{},
+	&reconciliation.ReconciliationReport{}, &reconciliation.ReconciliationDiscrepancy{}, &ledger.BalanceSnapshot{},
+	&job.JobRun{},
+}
+
+func setup(t *testing.T) (*gorm.DB, *Migrator) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "bank.db")), &gorm.Config{})
+	assert.NoError(t, err)
+	m, err := New(db)
+	assert.NoError(t, err)
+	return db, m
+}
+
+func TestUp(t *testing.T) {
+	// Arrange
+	db, m := setup(t)
+
+	// Act
+	ran, err := m.Up()
+	again, againErr := m.Up()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, m.Latest(), ran[len(ran)-1].Version)
+	assert.NoError(t, againErr)
+	assert.Empty(t, again)
+
+	hasColumns(t, db)
+
+	acc := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 100}
+	assert.NoError(t, db.Create(acc).Error)
+	assert.Equal(t, account.RoleUser, acc.Role)
+	assert.Error(t, db.Create(&pocket.Pocket{Title: "Short", Balance: -1, AccountID: acc.ID}).Error)
+	p := &pocket.Pocket{Title: "Holiday", Balance: 10, AccountID: acc.ID}
+	assert.NoError(t, db.Create(p).Error)
+	assert.Error(t, db.Model(p).Update("balance", -5).Error)
+}
+
+// hasColumns checks that db has a column for every field of every model.
+func hasColumns(t *testing.T, db *gorm.DB) {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		assert.NoError(t, stmt.Parse(model))
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName != "" {
+				assert.True(t, db.Migrator().HasColumn(model, f.DBName), "%s.%s", stmt.Schema.Table, f.DBName)
+			}
+		}
+	}
+}
+
+// The models as they were before the migrations, when AutoMigrate built
+// the schema.
+type (
+	autoAccount struct {
+		ID            uint `gorm:"primarykey"`
+		CreatedAt     time.Time
+		UpdatedAt     time.Time
+		DeletedAt     gorm.DeletedAt `gorm:"index"`
+		Email         string         `gorm:"unique"`
+		Password      string
+		Balance       float64
+		AccountNumber string
+		PocketList    []autoPocket `gorm:"ForeignKey:AccountID"`
+	}
+	autoAccountTransfer struct {
+		ID        uint `gorm:"primarykey"`
+		CreatedAt time.Time
+		From      string
+		To        string
+		Amount    float64
+	}
+	autoPocket struct {
+		ID          uint `gorm:"primarykey"`
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+		DeletedAt   gorm.DeletedAt `gorm:"index"`
+		Title       string
+		Balance     float64
+		Description *string
+		AccountID   uint
+	}
+	autoPocketTransfer struct {
+		ID        uint `gorm:"primarykey"`
+		CreatedAt time.Time
+		From      uint
+		To        uint
+		Amount    float64
+		AccountID uint
+	}
+)
+
+func (autoAccount) TableName() string         { return "accounts" }
+func (autoAccountTransfer) TableName() string { return "account_transfers" }
+func (autoPocket) TableName() string          { return "pockets" }
+func (autoPocketTransfer) TableName() string  { return "pocket_transfers" }
+
+func TestUpFromAutoMigrate(t *testing.T) {
+	// Arrange
+	db, m := setup(t)
+	assert.NoError(t, db.AutoMigrate(&autoAccount{}, &autoAccountTransfer{}, &autoPocket{}, &autoPocketTransfer{}))
+	db.Create(&autoAccount{Email: "a@test.com", AccountNumber: "1000000001", Balance: 70})
+	db.Create(&autoAccount{Email: "b@test.com", AccountNumber: "1000000002", Balance: 30})
+	db.Create(&autoAccountTransfer{From: "1000000001", To: "1000000002", Amount: 30})
+
+	// Act
+	_, err := m.Up()
+	accounts := []account.Account{}
+	db.Order("id").Find(&accounts)
+	transfers := []account.AccountTransfer{}
+	db.Order("id").Find(&transfers)
+
+	// Assert
+	assert.NoError(t, err)
+	hasColumns(t, db)
+	assert.Len(t, accounts, 2)
+	assert.Equal(t, account.RoleUser, accounts[0].Role)
+	assert.Equal(t, account.StatusActive, accounts[0].Status)
+	assert.Len(t, transfers, 2)
+	assert.Equal(t, account.TransferTypeTransfer, transfers[0].Type)
+	assert.Equal(t, account.TransferStatusCompleted, transfers[0].Status)
+	assert.Equal(t, account.TransferTypeDeposit, transfers[1].Type)
+	assert.Equal(t, 100.0, transfers[1].Amount)
+	assert.NoError(t, db.Create(&account.Account{Email: "c@test.com", AccountNumber: "1000000003"}).Error)
+}
+
+func TestDownAndTo(t *testing.T) {
+	// Arrange
+	db, m := setup(t)
+	_, err := m.Up()
+	assert.NoError(t, err)
+
+	// Act
+	down, downErr := m.Down()
+	negative := db.Create(&pocket.Pocket{Title: "Short", Balance: -1}).Error
+	statuses, statusErr := m.Status()
+	none, noneErr := m.To(0)
+	hasAccounts := db.Migrator().HasTable(&account.Account{})
+	all, allErr := m.To(m.Latest())
+	_, unknownErr := m.To(99)
+
+	// Assert
+	assert.NoError(t, downErr)
+	assert.Equal(t, []int{6}, versions(down))
+	assert.Error(t, negative)
+
+	assert.NoError(t, statusErr)
+	assert.NotNil(t, statuses[4].AppliedAt)
+	assert.Nil(t, statuses[5].AppliedAt)
+
+	assert.NoError(t, noneErr)
+	assert.Equal(t, []int{5, 4, 3, 2, 1}, versions(none))
+	assert.False(t, hasAccounts)
+
+	assert.NoError(t, allErr)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, versions(all))
+	assert.ErrorIs(t, unknownErr, ErrUnknownVersion)
+}
+
+func TestOpeningDeposits(t *testing.T) {
+	// Arrange
+	db, m := setup(t)
+	_, err := m.To(2)
+	assert.NoError(t, err)
+	opened := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 70}
+	db.Create(opened)
+	db.Create(&account.Account{Email: "b@test.com", AccountNumber: "1000000002", Balance: 30})
+	db.Create(&account.Account{Email: "c@test.com", AccountNumber: "1000000003"})
+	db.Create(&account.AccountTransfer{Type: account.TransferTypeTransfer, From: "1000000001", To: "1000000002", Amount: 30})
+	db.Create(&pocket.PocketTransfer{Type: pocket.TransferTypeDeposit, To: 1, Amount: 20, AccountID: opened.ID})
+
+	// Act
+	_, upErr := m.Up()
+	deposits := []account.AccountTransfer{}
+	db.Where(&account.AccountTransfer{Type: account.TransferTypeDeposit}).Order("id").Find(&deposits)
+	_, downErr := m.To(2)
+	var left int64
+	db.Model(&account.AccountTransfer{}).Where(&account.AccountTransfer{Type: account.TransferTypeDeposit}).Count(&left)
+
+	// Assert
+	assert.NoError(t, upErr)
+	assert.Len(t, deposits, 1)
+	assert.Equal(t, "1000000001", deposits[0].To)
+	assert.Equal(t, 120.0, deposits[0].Amount)
+	assert.NoError(t, downErr)
+	assert.Zero(t, left)
+}
+
+func versions(migrations []Migration) []int {
+	v := []int{}
+	for _, m := range migrations {
+		v = append(v, m.Version)
+	}
+	return v
+}
+
+func TestCommand(t *testing.T) {
+	// Arrange
+	db, _ := setup(t)
+	out := &bytes.Buffer{}
+
+	// Act
+	upErr := Command(db, []string{"to", "1"}, out)
+	up := out.String()
+	out.Reset()
+	statusErr := Command(db, []string{"status"}, out)
+	status := out.String()
+
+	// Assert
+	assert.NoError(t, upErr)
+	assert.Equal(t, "0001_init\n", up)
+	assert.NoError(t, statusErr)
+	assert.Contains(t, status, "0003     opening_deposits   pending")
+	assert.Contains(t, status, "0004     reconciliation     pending")
+	assert.Contains(t, status, "0005     balance_snapshots  pending")
+	assert.Contains(t, status, "0006     job_runs           pending")
+	assert.ErrorIs(t, Command(db, []string{"sideways"}, out), ErrUsage)
+	assert.ErrorIs(t, Command(db, []string{"to", "two"}, out), ErrUsage)
+}
@@ -0,0 +1,279 @@
+-- The schema as AutoMigrate left it. Databases it created are taken over:
+-- before this runs, the migrator adds to the tables already there the
+-- columns they lack, which CREATE TABLE IF NOT EXISTS would leave out.
+CREATE TABLE IF NOT EXISTS accounts (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    email text CONSTRAINT uni_accounts_email UNIQUE,
+    password text,
+    balance decimal,
+    account_number text,
+    alias text,
+    role text DEFAULT 'user',
+    status text DEFAULT 'active',
+    transfer_limit decimal
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_alias ON accounts (alias);
+CREATE INDEX IF NOT EXISTS idx_accounts_deleted_at ON accounts (deleted_at);
+
+CREATE TABLE IF NOT EXISTS account_transfers (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    type text DEFAULT 'transfer',
+    status text DEFAULT 'completed',
+    "from" text,
+    "to" text,
+    amount decimal,
+    reversed_amount decimal,
+    reversal_of bigint,
+    reason_code text,
+    memo text,
+    reference text,
+    category text,
+    tags text
+);
+CREATE INDEX IF NOT EXISTS idx_account_transfers_category ON account_transfers (category);
+CREATE INDEX IF NOT EXISTS idx_account_transfers_reversal_of ON account_transfers (reversal_of);
+
+CREATE TABLE IF NOT EXISTS pockets (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    title text,
+    balance decimal,
+    description text,
+    account_id bigint CONSTRAINT fk_accounts_pocket_list REFERENCES accounts (id),
+    target_amount decimal,
+    target_date timestamptz,
+    goal_reached_at timestamptz,
+    type text DEFAULT 'regular',
+    term_start timestamptz,
+    maturity_date timestamptz,
+    maturity_action text,
+    bonus_rate decimal,
+    penalty_rate decimal
+);
+CREATE INDEX IF NOT EXISTS idx_pockets_deleted_at ON pockets (deleted_at);
+
+CREATE TABLE IF NOT EXISTS pocket_transfers (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    type text DEFAULT 'transfer',
+    "from" bigint,
+    "to" bigint,
+    amount decimal,
+    account_id bigint
+);
+
+CREATE TABLE IF NOT EXISTS pocket_members (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    pocket_id bigint,
+    account_id bigint,
+    role text,
+    status text,
+    invited_by bigint,
+    withdraw_limit decimal
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pocket_member ON pocket_members (pocket_id, account_id);
+
+CREATE TABLE IF NOT EXISTS pending_actions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    action text,
+    payload text,
+    maker_id bigint,
+    checker_id bigint,
+    status text,
+    reason text,
+    result text,
+    expires_at timestamptz,
+    decided_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_pending_actions_status ON pending_actions (status);
+CREATE INDEX IF NOT EXISTS idx_pending_actions_action ON pending_actions (action);
+
+CREATE TABLE IF NOT EXISTS records (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    actor_id bigint,
+    ip text,
+    user_agent text,
+    action text,
+    target text,
+    status bigint,
+    before text,
+    after text,
+    prev_hash text,
+    hash text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_records_hash ON records (hash);
+CREATE INDEX IF NOT EXISTS idx_records_target ON records (target);
+CREATE INDEX IF NOT EXISTS idx_records_action ON records (action);
+CREATE INDEX IF NOT EXISTS idx_records_actor_id ON records (actor_id);
+
+CREATE TABLE IF NOT EXISTS interest_plans (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    owner_type text,
+    owner_id bigint,
+    annual_rate decimal,
+    basis text,
+    withholding_tax_rate decimal,
+    accrued_unpaid decimal,
+    active boolean
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_interest_plan_owner ON interest_plans (owner_type, owner_id);
+
+CREATE TABLE IF NOT EXISTS interest_accruals (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    plan_id bigint,
+    date text,
+    balance decimal,
+    rate decimal,
+    amount decimal,
+    posting_id bigint
+);
+CREATE INDEX IF NOT EXISTS idx_interest_accruals_posting_id ON interest_accruals (posting_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_interest_accrual_day ON interest_accruals (plan_id, date);
+
+CREATE TABLE IF NOT EXISTS interest_postings (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    plan_id bigint,
+    period text,
+    gross decimal,
+    tax decimal,
+    net decimal
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_interest_posting_period ON interest_postings (plan_id, period);
+
+CREATE TABLE IF NOT EXISTS rules (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    account_id bigint,
+    pocket_id bigint,
+    type text,
+    percent decimal,
+    min_amount decimal,
+    round_to decimal,
+    threshold decimal,
+    active boolean
+);
+CREATE INDEX IF NOT EXISTS idx_rules_account_id ON rules (account_id);
+CREATE INDEX IF NOT EXISTS idx_rules_deleted_at ON rules (deleted_at);
+
+CREATE TABLE IF NOT EXISTS rule_executions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    rule_id bigint,
+    transfer_id bigint,
+    amount decimal,
+    status text,
+    note text
+);
+CREATE INDEX IF NOT EXISTS idx_rule_executions_rule_id ON rule_executions (rule_id);
+
+CREATE TABLE IF NOT EXISTS budgets (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    account_id bigint,
+    category text,
+    pocket_id bigint,
+    amount decimal,
+    carryover boolean
+);
+CREATE INDEX IF NOT EXISTS idx_budgets_account_id ON budgets (account_id);
+
+CREATE TABLE IF NOT EXISTS budget_periods (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    budget_id bigint,
+    period text,
+    amount decimal,
+    carried decimal,
+    spent decimal,
+    notified bigint
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_period ON budget_periods (budget_id, period);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    account_id bigint,
+    type text,
+    message text,
+    payload text,
+    read_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_account_id ON notifications (account_id);
+
+CREATE TABLE IF NOT EXISTS payment_requests (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    requester_id bigint,
+    requester_account text,
+    payer_id bigint,
+    payer_account text,
+    amount decimal,
+    memo text,
+    status text,
+    expires_at timestamptz,
+    token text,
+    split_id text,
+    paid_by bigint,
+    transfer_id bigint,
+    paid_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_payment_requests_status ON payment_requests (status);
+CREATE INDEX IF NOT EXISTS idx_payment_requests_payer_id ON payment_requests (payer_id);
+CREATE INDEX IF NOT EXISTS idx_payment_requests_requester_id ON payment_requests (requester_id);
+CREATE INDEX IF NOT EXISTS idx_payment_requests_split_id ON payment_requests (split_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_requests_token ON payment_requests (token);
+
+CREATE TABLE IF NOT EXISTS batches (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    account_id bigint,
+    mode text,
+    message_id text,
+    status text,
+    total bigint,
+    succeeded bigint,
+    failed bigint,
+    total_amount decimal,
+    finished_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_batches_status ON batches (status);
+CREATE INDEX IF NOT EXISTS idx_batches_message_id ON batches (message_id);
+CREATE INDEX IF NOT EXISTS idx_batches_account_id ON batches (account_id);
+
+CREATE TABLE IF NOT EXISTS batch_rows (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    batch_id bigint CONSTRAINT fk_batches_rows REFERENCES batches (id),
+    line bigint,
+    recipient text,
+    "to" text,
+    amount decimal,
+    memo text,
+    reference text,
+    status text,
+    error text,
+    transfer_id bigint
+);
+CREATE INDEX IF NOT EXISTS idx_batch_rows_batch_id ON batch_rows (batch_id);
@@ -0,0 +1,3 @@
+ALTER TABLE pocket_transfers DROP CONSTRAINT IF EXISTS chk_pocket_transfers_amount;
+ALTER TABLE account_transfers DROP CONSTRAINT IF EXISTS chk_account_transfers_amount;
+ALTER TABLE pockets DROP CONSTRAINT IF EXISTS chk_pockets_balance;
@@ -0,0 +1,5 @@
+-- Account balances are left out: REVERSAL_SHORTFALL_POLICY=negative lets a
+-- reversal take an account below zero.
+ALTER TABLE pockets ADD CONSTRAINT chk_pockets_balance CHECK (balance >= 0);
+ALTER TABLE account_transfers ADD CONSTRAINT chk_account_transfers_amount CHECK (amount >= 0);
+ALTER TABLE pocket_transfers ADD CONSTRAINT chk_pocket_transfers_amount CHECK (amount >= 0);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS reconciliation_reports (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    "trigger" text,
+    accounts bigint,
+    pockets bigint,
+    transfers bigint,
+    holdings decimal,
+    inflow decimal,
+    ok boolean
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id bigserial PRIMARY KEY,
+    report_id bigint CONSTRAINT fk_reconciliation_reports_discrepancies REFERENCES reconciliation_reports (id),
+    "check" text,
+    target text,
+    expected decimal,
+    actual decimal,
+    detail text
+);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_report_id ON reconciliation_discrepancies (report_id);
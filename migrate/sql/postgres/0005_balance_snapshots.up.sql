@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    id bigserial PRIMARY KEY,
+    kind text,
+    holder_id bigint,
+    at timestamptz,
+    account_id bigint,
+    balance decimal
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_snapshots_holder ON balance_snapshots (kind, holder_id, at);
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_at ON balance_snapshots (at);
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_account_id ON balance_snapshots (account_id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS job_runs (
+    id bigserial PRIMARY KEY,
+    name text,
+    day text,
+    started_at timestamptz,
+    finished_at timestamptz,
+    error text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_runs_day ON job_runs (name, day);
@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS batch_rows;
+DROP TABLE IF EXISTS batches;
+DROP TABLE IF EXISTS payment_requests;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS budget_periods;
+DROP TABLE IF EXISTS budgets;
+DROP TABLE IF EXISTS rule_executions;
+DROP TABLE IF EXISTS rules;
+DROP TABLE IF EXISTS interest_postings;
+DROP TABLE IF EXISTS interest_accruals;
+DROP TABLE IF EXISTS interest_plans;
+DROP TABLE IF EXISTS records;
+DROP TABLE IF EXISTS pending_actions;
+DROP TABLE IF EXISTS pocket_members;
+DROP TABLE IF EXISTS pocket_transfers;
+DROP TABLE IF EXISTS pockets;
+DROP TABLE IF EXISTS account_transfers;
+DROP TABLE IF EXISTS accounts;
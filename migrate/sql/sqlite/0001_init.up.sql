@@ -0,0 +1,279 @@
+-- The schema as AutoMigrate left it. Databases it created are taken over:
+-- before this runs, the migrator adds to the tables already there the
+-- columns they lack, which CREATE TABLE IF NOT EXISTS would leave out.
+CREATE TABLE IF NOT EXISTS accounts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    email text CONSTRAINT uni_accounts_email UNIQUE,
+    password text,
+    balance real,
+    account_number text,
+    alias text,
+    role text DEFAULT 'user',
+    status text DEFAULT 'active',
+    transfer_limit real
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_alias ON accounts (alias);
+CREATE INDEX IF NOT EXISTS idx_accounts_deleted_at ON accounts (deleted_at);
+
+CREATE TABLE IF NOT EXISTS account_transfers (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    type text DEFAULT 'transfer',
+    status text DEFAULT 'completed',
+    "from" text,
+    "to" text,
+    amount real,
+    reversed_amount real,
+    reversal_of integer,
+    reason_code text,
+    memo text,
+    reference text,
+    category text,
+    tags text
+);
+CREATE INDEX IF NOT EXISTS idx_account_transfers_category ON account_transfers (category);
+CREATE INDEX IF NOT EXISTS idx_account_transfers_reversal_of ON account_transfers (reversal_of);
+
+CREATE TABLE IF NOT EXISTS pockets (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    title text,
+    balance real,
+    description text,
+    account_id integer CONSTRAINT fk_accounts_pocket_list REFERENCES accounts (id),
+    target_amount real,
+    target_date datetime,
+    goal_reached_at datetime,
+    type text DEFAULT 'regular',
+    term_start datetime,
+    maturity_date datetime,
+    maturity_action text,
+    bonus_rate real,
+    penalty_rate real
+);
+CREATE INDEX IF NOT EXISTS idx_pockets_deleted_at ON pockets (deleted_at);
+
+CREATE TABLE IF NOT EXISTS pocket_transfers (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    type text DEFAULT 'transfer',
+    "from" integer,
+    "to" integer,
+    amount real,
+    account_id integer
+);
+
+CREATE TABLE IF NOT EXISTS pocket_members (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    pocket_id integer,
+    account_id integer,
+    role text,
+    status text,
+    invited_by integer,
+    withdraw_limit real
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pocket_member ON pocket_members (pocket_id, account_id);
+
+CREATE TABLE IF NOT EXISTS pending_actions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    action text,
+    payload text,
+    maker_id integer,
+    checker_id integer,
+    status text,
+    reason text,
+    result text,
+    expires_at datetime,
+    decided_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_pending_actions_status ON pending_actions (status);
+CREATE INDEX IF NOT EXISTS idx_pending_actions_action ON pending_actions (action);
+
+CREATE TABLE IF NOT EXISTS records (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    actor_id integer,
+    ip text,
+    user_agent text,
+    action text,
+    target text,
+    status integer,
+    before text,
+    after text,
+    prev_hash text,
+    hash text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_records_hash ON records (hash);
+CREATE INDEX IF NOT EXISTS idx_records_target ON records (target);
+CREATE INDEX IF NOT EXISTS idx_records_action ON records (action);
+CREATE INDEX IF NOT EXISTS idx_records_actor_id ON records (actor_id);
+
+CREATE TABLE IF NOT EXISTS interest_plans (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    owner_type text,
+    owner_id integer,
+    annual_rate real,
+    basis text,
+    withholding_tax_rate real,
+    accrued_unpaid real,
+    active numeric
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_interest_plan_owner ON interest_plans (owner_type, owner_id);
+
+CREATE TABLE IF NOT EXISTS interest_accruals (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    plan_id integer,
+    date text,
+    balance real,
+    rate real,
+    amount real,
+    posting_id integer
+);
+CREATE INDEX IF NOT EXISTS idx_interest_accruals_posting_id ON interest_accruals (posting_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_interest_accrual_day ON interest_accruals (plan_id, date);
+
+CREATE TABLE IF NOT EXISTS interest_postings (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    plan_id integer,
+    period text,
+    gross real,
+    tax real,
+    net real
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_interest_posting_period ON interest_postings (plan_id, period);
+
+CREATE TABLE IF NOT EXISTS rules (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    account_id integer,
+    pocket_id integer,
+    type text,
+    percent real,
+    min_amount real,
+    round_to real,
+    threshold real,
+    active numeric
+);
+CREATE INDEX IF NOT EXISTS idx_rules_account_id ON rules (account_id);
+CREATE INDEX IF NOT EXISTS idx_rules_deleted_at ON rules (deleted_at);
+
+CREATE TABLE IF NOT EXISTS rule_executions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    rule_id integer,
+    transfer_id integer,
+    amount real,
+    status text,
+    note text
+);
+CREATE INDEX IF NOT EXISTS idx_rule_executions_rule_id ON rule_executions (rule_id);
+
+CREATE TABLE IF NOT EXISTS budgets (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    account_id integer,
+    category text,
+    pocket_id integer,
+    amount real,
+    carryover numeric
+);
+CREATE INDEX IF NOT EXISTS idx_budgets_account_id ON budgets (account_id);
+
+CREATE TABLE IF NOT EXISTS budget_periods (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    budget_id integer,
+    period text,
+    amount real,
+    carried real,
+    spent real,
+    notified integer
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_period ON budget_periods (budget_id, period);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    account_id integer,
+    type text,
+    message text,
+    payload text,
+    read_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_account_id ON notifications (account_id);
+
+CREATE TABLE IF NOT EXISTS payment_requests (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    requester_id integer,
+    requester_account text,
+    payer_id integer,
+    payer_account text,
+    amount real,
+    memo text,
+    status text,
+    expires_at datetime,
+    token text,
+    split_id text,
+    paid_by integer,
+    transfer_id integer,
+    paid_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_payment_requests_status ON payment_requests (status);
+CREATE INDEX IF NOT EXISTS idx_payment_requests_payer_id ON payment_requests (payer_id);
+CREATE INDEX IF NOT EXISTS idx_payment_requests_requester_id ON payment_requests (requester_id);
+CREATE INDEX IF NOT EXISTS idx_payment_requests_split_id ON payment_requests (split_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_requests_token ON payment_requests (token);
+
+CREATE TABLE IF NOT EXISTS batches (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    account_id integer,
+    mode text,
+    message_id text,
+    status text,
+    total integer,
+    succeeded integer,
+    failed integer,
+    total_amount real,
+    finished_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_batches_status ON batches (status);
+CREATE INDEX IF NOT EXISTS idx_batches_message_id ON batches (message_id);
+CREATE INDEX IF NOT EXISTS idx_batches_account_id ON batches (account_id);
+
+CREATE TABLE IF NOT EXISTS batch_rows (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    batch_id integer CONSTRAINT fk_batches_rows REFERENCES batches (id),
+    line integer,
+    recipient text,
+    "to" text,
+    amount real,
+    memo text,
+    reference text,
+    status text,
+    error text,
+    transfer_id integer
+);
+CREATE INDEX IF NOT EXISTS idx_batch_rows_batch_id ON batch_rows (batch_id);
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS chk_pocket_transfers_amount_update;
+DROP TRIGGER IF EXISTS chk_pocket_transfers_amount_insert;
+DROP TRIGGER IF EXISTS chk_account_transfers_amount_update;
+DROP TRIGGER IF EXISTS chk_account_transfers_amount_insert;
+DROP TRIGGER IF EXISTS chk_pockets_balance_update;
+DROP TRIGGER IF EXISTS chk_pockets_balance_insert;
@@ -0,0 +1,39 @@
+-- SQLite cannot add a check constraint to an existing table, so triggers
+-- enforce them instead. Account balances are left out:
+-- REVERSAL_SHORTFALL_POLICY=negative lets a reversal take an account below
+-- zero.
+CREATE TRIGGER IF NOT EXISTS chk_pockets_balance_insert BEFORE INSERT ON pockets
+WHEN NEW.balance < 0
+BEGIN
+    SELECT RAISE(ABORT, 'pockets.balance must not be negative');
+END;
+
+CREATE TRIGGER IF NOT EXISTS chk_pockets_balance_update BEFORE UPDATE OF balance ON pockets
+WHEN NEW.balance < 0
+BEGIN
+    SELECT RAISE(ABORT, 'pockets.balance must not be negative');
+END;
+
+CREATE TRIGGER IF NOT EXISTS chk_account_transfers_amount_insert BEFORE INSERT ON account_transfers
+WHEN NEW.amount < 0
+BEGIN
+    SELECT RAISE(ABORT, 'account_transfers.amount must not be negative');
+END;
+
+CREATE TRIGGER IF NOT EXISTS chk_account_transfers_amount_update BEFORE UPDATE OF amount ON account_transfers
+WHEN NEW.amount < 0
+BEGIN
+    SELECT RAISE(ABORT, 'account_transfers.amount must not be negative');
+END;
+
+CREATE TRIGGER IF NOT EXISTS chk_pocket_transfers_amount_insert BEFORE INSERT ON pocket_transfers
+WHEN NEW.amount < 0
+BEGIN
+    SELECT RAISE(ABORT, 'pocket_transfers.amount must not be negative');
+END;
+
+CREATE TRIGGER IF NOT EXISTS chk_pocket_transfers_amount_update BEFORE UPDATE OF amount ON pocket_transfers
+WHEN NEW.amount < 0
+BEGIN
+    SELECT RAISE(ABORT, 'pocket_transfers.amount must not be negative');
+END;
@@ -0,0 +1 @@
+DELETE FROM account_transfers WHERE type = 'deposit' AND reason_code = 'BACKFILL';
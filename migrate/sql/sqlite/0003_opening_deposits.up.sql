@@ -0,0 +1,15 @@
+-- Accounts opened before opening balances were recorded as deposits get one,
+-- dated when they were opened, for the part of their balance that no
+-- transfer or pocket movement explains.
+INSERT INTO account_transfers (created_at, type, status, "from", "to", amount, reversed_amount, reason_code, memo, reference, category)
+SELECT created_at, 'deposit', 'completed', '', account_number, opening, 0, 'BACKFILL', 'opening balance', '', ''
+FROM (
+    SELECT a.created_at, a.account_number, ROUND(a.balance
+        - COALESCE((SELECT SUM(t.amount) FROM account_transfers t WHERE t.status = 'completed' AND t."to" = a.account_number), 0)
+        + COALESCE((SELECT SUM(t.amount) FROM account_transfers t WHERE t.status = 'completed' AND t."from" = a.account_number), 0)
+        + COALESCE((SELECT SUM(m.amount) FROM pocket_transfers m WHERE m.account_id = a.id AND m.type IN ('deposit', 'rule') AND m."from" = 0), 0)
+        - COALESCE((SELECT SUM(m.amount) FROM pocket_transfers m WHERE m.account_id = a.id AND m.type = 'withdrawal' AND m."to" = 0), 0), 2) AS opening
+    FROM accounts a
+    WHERE a.deleted_at IS NULL
+) o
+WHERE opening > 0;
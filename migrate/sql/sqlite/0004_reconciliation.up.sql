@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS reconciliation_reports (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    "trigger" text,
+    accounts integer,
+    pockets integer,
+    transfers integer,
+    holdings real,
+    inflow real,
+    ok numeric
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    report_id integer CONSTRAINT fk_reconciliation_reports_discrepancies REFERENCES reconciliation_reports (id),
+    "check" text,
+    target text,
+    expected real,
+    actual real,
+    detail text
+);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_report_id ON reconciliation_discrepancies (report_id);
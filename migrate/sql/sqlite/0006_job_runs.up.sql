@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS job_runs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text,
+    day text,
+    started_at datetime,
+    finished_at datetime,
+    error text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_job_runs_day ON job_runs (name, day);
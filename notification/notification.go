@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/arthit666/make_app/event"
+	"gorm.io/gorm"
+)
+
+// Notification is an event kept for the account it concerns until the
+// account reads it.
+type Notification struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"create_at"`
+	AccountID uint       `json:"-" gorm:"index"`
+	Type      string     `json:"type"`
+	Message   string     `json:"message"`
+	Payload   string     `json:"payload"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+// Messager is implemented by event payloads that can describe themselves to
+// the account they are sent to.
+type Messager interface {
+	Message() string
+}
+
+// Listen stores every event of one of types as a notification, until the
+// returned function is called.
+func Listen(db *gorm.DB, types ...string) func() {
+	wanted := map[string]bool{}
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	return event.Subscribe(func(e event.Event) {
+		if !wanted[e.Type] || e.AccountID == 0 {
+			return
+		}
+		if err := store(db, e); err != nil {
+			log.Printf("notification %s for account %d: %s", e.Type, e.AccountID, err)
+		}
+	})
+}
+
+// Send stores e as a notification for the account it concerns, for events
+// that are not published to every listener.
+func Send(db *gorm.DB, e event.Event) error {
+	return store(db, e)
+}
+
+func store(db *gorm.DB, e event.Event) error {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		return err
+	}
+
+	n := &Notification{
+		CreatedAt: e.CreatedAt,
+		AccountID: e.AccountID,
+		Type:      e.Type,
+		Payload:   string(payload),
+	}
+	if m, ok := e.Payload.(Messager); ok {
+		n.Message = m.Message()
+	}
+	return db.Create(n).Error
+}
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/event"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type alert struct {
+	Level int `json:"level"`
+}
+
+func (a alert) Message() string {
+	return fmt.Sprintf("level %d", a.Level)
+}
+
+func TestListen(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Notification{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	stop := Listen(tx, "test.alert")
+	defer stop()
+
+	// Act
+	event.Publish(event.Event{Type: "test.alert", AccountID: 5, Payload: alert{Level: 2}})
+	event.Publish(event.Event{Type: "test.ignored", AccountID: 5, Payload: alert{Level: 3}})
+
+	// Assert
+	var n []Notification
+	tx.Where("account_id = ?", 5).Find(&n)
+	assert.Equal(t, 1, len(n))
+	assert.Equal(t, "test.alert", n[0].Type)
+	assert.Equal(t, "level 2", n[0].Message)
+	assert.Equal(t, `{"level":2}`, n[0].Payload)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", 5)
+		return c.Next()
+	})
+	handler := New(tx)
+	app.Post("/notifications/:id/read", handler.MarkRead)
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/notifications/%d/read", n[0].ID), nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var read Notification
+	tx.First(&read, n[0].ID)
+	assert.NotNil(t, read.ReadAt)
+}
@@ -0,0 +1,60 @@
+package notification
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// @Summary Get notifications
+// @Description Get the caller's notifications, newest first
+// @Tags notifications
+// @Produce json
+// @Param unread query bool false "Only unread notifications"
+// @Success 200 {array} notification.Notification
+// @Security  Bearer
+// @Router /notifications/ [get]
+func (h *handler) GetAllNotifications(c *fiber.Ctx) error {
+	acc := c.Locals("account_id").(int)
+	q := h.DB.Where("account_id = ?", acc)
+	if c.QueryBool("unread") {
+		q = q.Where("read_at IS NULL")
+	}
+
+	n := []Notification{}
+	if err := q.Order("id desc").Find(&n).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(n)
+}
+
+// @Summary Mark a notification as read
+// @Description Mark one of the caller's notifications as read
+// @Tags notifications
+// @Produce json
+// @Param id path int true "Notification ID"
+// @Success 200 {object} notification.Notification
+// @Security  Bearer
+// @Router /notifications/{id}/read [post]
+func (h *handler) MarkRead(c *fiber.Ctx) error {
+	acc := c.Locals("account_id").(int)
+	n := &Notification{}
+	if err := h.DB.Where("account_id = ?", acc).First(n, c.Params("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodeNotFound, "notification not found")
+		}
+		return apperr.Internal(err)
+	}
+
+	if n.ReadAt == nil {
+		now := time.Now()
+		n.ReadAt = &now
+		if err := h.DB.Model(n).Update("read_at", now).Error; err != nil {
+			return apperr.Internal(err)
+		}
+	}
+	return c.Status(fiber.StatusOK).JSON(n)
+}
@@ -0,0 +1,104 @@
+package payment
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotPending = errors.New("payment request is not pending")
+	ErrOwnRequest = errors.New("cannot pay your own payment request")
+)
+
+// PaymentRequest asks an account for Amount. Requests without a payer are
+// pay links anyone who knows the token can pay once.
+type PaymentRequest struct {
+	ID               uint       `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time  `json:"create_at"`
+	UpdatedAt        time.Time  `json:"update_at"`
+	RequesterID      uint       `json:"-" gorm:"index"`
+	RequesterAccount string     `json:"requester_account"`
+	PayerID          *uint      `json:"-" gorm:"index"`
+	PayerAccount     string     `json:"payer_account,omitempty"`
+	Amount           float64    `json:"amount"`
+	Memo             string     `json:"memo,omitempty"`
+	Status           string     `json:"status" gorm:"index"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	Token            *string    `json:"token,omitempty" gorm:"uniqueIndex"`
+	SplitID          string     `json:"split_id,omitempty" gorm:"index"`
+	PaidBy           *uint      `json:"-"`
+	TransferID       *uint      `json:"transfer_id,omitempty"`
+	PaidAt           *time.Time `json:"paid_at,omitempty"`
+}
+
+func (p PaymentRequest) Message() string {
+	if p.Status == StatusPaid {
+		return fmt.Sprintf("Your payment request for %.2f has been paid.", p.Amount)
+	}
+	return fmt.Sprintf("Account %s asked you for %.2f: %s", p.RequesterAccount, p.Amount, p.Memo)
+}
+
+type PaymentRequestCreate struct {
+	Payer     string     `json:"payer" validate:"max=64"`
+	Amount    float64    `json:"amount" validate:"required,numeric,gt=0"`
+	Memo      string     `json:"memo" validate:"max=140"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// SplitRequest splits Amount evenly between Payers, and the requester too
+// when IncludeSelf is set, with one payment request per payer.
+type SplitRequest struct {
+	Payers      []string   `json:"payers" validate:"required,min=1,max=50,dive,required,max=64"`
+	Amount      float64    `json:"amount" validate:"required,numeric,gt=0"`
+	Memo        string     `json:"memo" validate:"max=140"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	IncludeSelf bool       `json:"include_self"`
+}
+
+type SplitResponse struct {
+	SplitID  string           `json:"split_id"`
+	Requests []PaymentRequest `json:"requests"`
+}
+
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusPaid       = "paid"
+	StatusDeclined   = "declined"
+	StatusCancelled  = "cancelled"
+	StatusExpired    = "expired"
+
+	defaultTTL = 7 * 24 * time.Hour
+)
+
+// ExpireOverdue marks every pending request past its expiry as expired.
+func ExpireOverdue(db *gorm.DB) error {
+	return db.Model(&PaymentRequest{}).
+		Where("status = ? AND expires_at <= ?", StatusPending, time.Now()).
+		Update("status", StatusExpired).Error
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,275 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/rule"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{},
+		&rule.Rule{}, &rule.RuleExecution{}, &PaymentRequest{})
+	assert.NoError(t, err)
+	return db
+}
+
+// as serves h to the account with id.
+func as(h *handler, id uint) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(id))
+		return c.Next()
+	})
+	app.Post("/payment-requests/", h.CreatePaymentRequest)
+	app.Post("/payment-requests/split", h.SplitBill)
+	app.Get("/payment-requests/", h.GetAllPaymentRequests)
+	app.Get("/payment-requests/incoming", h.GetIncoming)
+	app.Get("/payment-requests/link/:token", h.GetLink)
+	app.Post("/payment-requests/link/:token/pay", h.PayLink)
+	app.Post("/payment-requests/:id/pay", h.Pay)
+	app.Post("/payment-requests/:id/decline", h.Decline)
+	app.Post("/payment-requests/:id/cancel", h.Cancel)
+	return app
+}
+
+func send(t *testing.T, app *fiber.App, method, url string, body interface{}, out interface{}) int {
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, url, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestPaymentRequest(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	alias := "bob"
+	alice := &account.Account{Email: "alice@test.com", AccountNumber: "1000000001", Balance: 100}
+	bob := &account.Account{Email: "bob@test.com", AccountNumber: "1000000002", Balance: 500, Alias: &alias}
+	tx.Create(alice)
+	tx.Create(bob)
+
+	h := New(tx)
+	asAlice, asBob := as(h, alice.ID), as(h, bob.ID)
+
+	// Act & Assert
+	status := send(t, asAlice, http.MethodPost, "/payment-requests/", PaymentRequestCreate{Payer: alice.AccountNumber, Amount: 10}, nil)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+
+	status = send(t, asAlice, http.MethodPost, "/payment-requests/", PaymentRequestCreate{Payer: "nobody", Amount: 10}, nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+
+	past := time.Now().Add(-time.Hour)
+	status = send(t, asAlice, http.MethodPost, "/payment-requests/", PaymentRequestCreate{Payer: "bob", Amount: 10, ExpiresAt: &past}, nil)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	r := PaymentRequest{}
+	status = send(t, asAlice, http.MethodPost, "/payment-requests/", PaymentRequestCreate{Payer: "bob", Amount: 120, Memo: "dinner"}, &r)
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.Equal(t, bob.AccountNumber, r.PayerAccount)
+	assert.Equal(t, StatusPending, r.Status)
+	assert.Nil(t, r.Token)
+
+	incoming := []PaymentRequest{}
+	send(t, asBob, http.MethodGet, "/payment-requests/incoming", nil, &incoming)
+	assert.Equal(t, 1, len(incoming))
+	assert.Equal(t, "dinner", incoming[0].Memo)
+
+	send(t, asAlice, http.MethodGet, "/payment-requests/incoming", nil, &incoming)
+	assert.Equal(t, 0, len(incoming))
+
+	url := "/payment-requests/" + itoa(r.ID)
+	status = send(t, asAlice, http.MethodPost, url+"/pay", nil, nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+
+	status = send(t, asBob, http.MethodPost, url+"/pay", nil, &r)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, StatusPaid, r.Status)
+	assert.NotNil(t, r.TransferID)
+
+	tr := account.AccountTransfer{}
+	tx.First(&tr, *r.TransferID)
+	assert.Equal(t, bob.AccountNumber, tr.From)
+	assert.Equal(t, alice.AccountNumber, tr.To)
+	assert.Equal(t, 120.0, tr.Amount)
+	assert.Equal(t, "dinner", tr.Memo)
+
+	var updated account.Account
+	tx.First(&updated, alice.ID)
+	assert.Equal(t, 220.0, updated.Balance)
+
+	status = send(t, asBob, http.MethodPost, url+"/pay", nil, nil)
+	assert.Equal(t, fiber.StatusConflict, status)
+
+	// A failed transfer leaves the request pending.
+	send(t, asBob, http.MethodPost, "/payment-requests/", PaymentRequestCreate{Payer: alice.AccountNumber, Amount: 1000}, &r)
+	status = send(t, asAlice, http.MethodPost, "/payment-requests/"+itoa(r.ID)+"/pay", nil, nil)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+	tx.First(&r, r.ID)
+	assert.Equal(t, StatusPending, r.Status)
+
+	status = send(t, asAlice, http.MethodPost, "/payment-requests/"+itoa(r.ID)+"/decline", nil, &r)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, StatusDeclined, r.Status)
+
+	status = send(t, asBob, http.MethodPost, "/payment-requests/"+itoa(r.ID)+"/cancel", nil, nil)
+	assert.Equal(t, fiber.StatusConflict, status)
+
+	outgoing := []PaymentRequest{}
+	send(t, asAlice, http.MethodGet, "/payment-requests/?status=paid", nil, &outgoing)
+	assert.Equal(t, 1, len(outgoing))
+}
+
+func TestPayRollsBack(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	alice := &account.Account{Email: "alice@test.com", AccountNumber: "1000000001", Balance: 100}
+	bob := &account.Account{Email: "bob@test.com", AccountNumber: "1000000002", Balance: 500}
+	tx.Create(alice)
+	tx.Create(bob)
+	r := &PaymentRequest{RequesterID: alice.ID, RequesterAccount: alice.AccountNumber, PayerID: &bob.ID,
+		Amount: 50, Status: StatusPending, ExpiresAt: time.Now().Add(time.Hour)}
+	tx.Create(r)
+
+	// Marking the request paid fails after the transfer was made.
+	db.Callback().Update().Before("gorm:update").Register("test:fail_paid", func(d *gorm.DB) {
+		if p, ok := d.Statement.Model.(*PaymentRequest); ok && p.Status == StatusPaid {
+			d.AddError(errors.New("disk full"))
+		}
+	})
+	defer db.Callback().Update().Remove("test:fail_paid")
+
+	// Act
+	_, err := Pay(tx, r, bob.ID)
+
+	// Assert
+	assert.EqualError(t, err, "disk full")
+	stored := PaymentRequest{}
+	tx.First(&stored, r.ID)
+	assert.Equal(t, StatusPending, stored.Status)
+	assert.Nil(t, stored.TransferID)
+	var transfers int64
+	tx.Model(&account.AccountTransfer{}).Count(&transfers)
+	assert.Zero(t, transfers)
+	payer := account.Account{}
+	tx.First(&payer, bob.ID)
+	assert.Equal(t, 500.0, payer.Balance)
+}
+
+func TestPayLink(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	alice := &account.Account{Email: "alice@test.com", AccountNumber: "2000000001"}
+	carol := &account.Account{Email: "carol@test.com", AccountNumber: "2000000002", Balance: 50}
+	tx.Create(alice)
+	tx.Create(carol)
+
+	h := New(tx)
+
+	// Act
+	r := PaymentRequest{}
+	status := send(t, as(h, alice.ID), http.MethodPost, "/payment-requests/", PaymentRequestCreate{Amount: 30, Memo: "tickets"}, &r)
+
+	// Assert
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.NotNil(t, r.Token)
+
+	url := "/payment-requests/link/" + *r.Token
+	status = send(t, as(h, carol.ID), http.MethodGet, url, nil, &r)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, 30.0, r.Amount)
+
+	status = send(t, as(h, alice.ID), http.MethodPost, url+"/pay", nil, nil)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, status)
+
+	status = send(t, as(h, carol.ID), http.MethodPost, url+"/pay", nil, &r)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, StatusPaid, r.Status)
+
+	var updated account.Account
+	tx.First(&updated, carol.ID)
+	assert.Equal(t, 20.0, updated.Balance)
+
+	status = send(t, as(h, carol.ID), http.MethodGet, "/payment-requests/link/unknown", nil, nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+}
+
+func TestSplitBill(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	alias := "dave"
+	alice := &account.Account{Email: "alice@test.com", AccountNumber: "3000000001"}
+	bob := &account.Account{Email: "bob@test.com", AccountNumber: "3000000002"}
+	dave := &account.Account{Email: "dave@test.com", AccountNumber: "3000000003", Alias: &alias}
+	tx.Create(alice)
+	tx.Create(bob)
+	tx.Create(dave)
+
+	app := as(New(tx), alice.ID)
+
+	// Act
+	res := SplitResponse{}
+	status := send(t, app, http.MethodPost, "/payment-requests/split",
+		SplitRequest{Payers: []string{bob.AccountNumber, "dave"}, Amount: 100, Memo: "rent", IncludeSelf: true}, &res)
+
+	// Assert
+	assert.Equal(t, fiber.StatusCreated, status)
+	assert.NotEmpty(t, res.SplitID)
+	assert.Equal(t, 2, len(res.Requests))
+	assert.Equal(t, 33.34, res.Requests[0].Amount)
+	assert.Equal(t, 33.33, res.Requests[1].Amount)
+	assert.Equal(t, dave.AccountNumber, res.Requests[1].PayerAccount)
+
+	var count int64
+	tx.Model(&PaymentRequest{}).Where("split_id = ?", res.SplitID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	status = send(t, app, http.MethodPost, "/payment-requests/split",
+		SplitRequest{Payers: []string{"dave", dave.AccountNumber}, Amount: 100}, nil)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	status = send(t, app, http.MethodPost, "/payment-requests/split",
+		SplitRequest{Payers: []string{bob.AccountNumber, "dave"}, Amount: 0.02, IncludeSelf: true}, nil)
+	tx.Model(&PaymentRequest{}).Count(&count)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, int64(2), count)
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
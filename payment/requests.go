@@ -0,0 +1,438 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/event"
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// @Summary Create a payment request
+// @Description Ask an account, by account number or alias, for an amount. Without a payer the request is a pay link anyone can pay once
+// @Tags payment-requests
+// @Accept json
+// @Produce json
+// @Param request body payment.PaymentRequestCreate true "PaymentRequestCreate data"
+// @Success 201 {object} payment.PaymentRequest
+// @Security  Bearer
+// @Router /payment-requests/ [post]
+func (h *handler) CreatePaymentRequest(c *fiber.Ctx) error {
+	req := &PaymentRequestCreate{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	requester, expires, err := h.prepare(c, req.ExpiresAt)
+	if err != nil || requester == nil {
+		return err
+	}
+
+	r := PaymentRequest{
+		RequesterID:      requester.ID,
+		RequesterAccount: requester.AccountNumber,
+		Amount:           req.Amount,
+		Memo:             strings.TrimSpace(req.Memo),
+		Status:           StatusPending,
+		ExpiresAt:        expires,
+	}
+
+	if req.Payer == "" {
+		token, err := newToken()
+		if err != nil {
+			return apperr.Internal(err)
+		}
+		r.Token = &token
+	} else {
+		payer, err := resolve(h.DB, req.Payer)
+		if err != nil {
+			return payerError(err)
+		}
+		if payer.ID == requester.ID {
+			return apperr.Unprocessable(apperr.CodeUnprocessable, ErrOwnRequest.Error())
+		}
+		r.PayerID = &payer.ID
+		r.PayerAccount = payer.AccountNumber
+	}
+
+	if err := h.DB.Create(&r).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	requested(r)
+
+	return c.Status(fiber.StatusCreated).JSON(r)
+}
+
+// @Summary Split a bill
+// @Description Split an amount evenly between several payers, with one payment request per payer
+// @Tags payment-requests
+// @Accept json
+// @Produce json
+// @Param split body payment.SplitRequest true "SplitRequest data"
+// @Success 201 {object} payment.SplitResponse
+// @Security  Bearer
+// @Router /payment-requests/split [post]
+func (h *handler) SplitBill(c *fiber.Ctx) error {
+	req := &SplitRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	requester, expires, err := h.prepare(c, req.ExpiresAt)
+	if err != nil || requester == nil {
+		return err
+	}
+
+	seen := map[uint]bool{}
+	payers := []*account.Account{}
+	for _, p := range req.Payers {
+		payer, err := resolve(h.DB, p)
+		if err != nil {
+			return payerError(err)
+		}
+		if payer.ID == requester.ID {
+			return apperr.Unprocessable(apperr.CodeUnprocessable, ErrOwnRequest.Error())
+		}
+		if seen[payer.ID] {
+			return apperr.BadRequest("payload invalid: payer " + p + " is listed twice")
+		}
+		seen[payer.ID] = true
+		payers = append(payers, payer)
+	}
+
+	parts := len(payers)
+	if req.IncludeSelf {
+		parts++
+	}
+	// Every share has to be at least a cent.
+	total := decimal.NewFromFloat(req.Amount)
+	if total.Mul(decimal.NewFromInt(100)).Round(0).IntPart() < int64(parts) {
+		return apperr.BadRequest(fmt.Sprintf("payload invalid: amount is too small to split %d ways", parts))
+	}
+	shares := split(total, parts)
+
+	splitID, err := newToken()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	res := SplitResponse{SplitID: splitID, Requests: []PaymentRequest{}}
+	for i, payer := range payers {
+		r := PaymentRequest{
+			RequesterID:      requester.ID,
+			RequesterAccount: requester.AccountNumber,
+			PayerID:          &payers[i].ID,
+			PayerAccount:     payer.AccountNumber,
+			Memo:             strings.TrimSpace(req.Memo),
+			Status:           StatusPending,
+			ExpiresAt:        expires,
+			SplitID:          splitID,
+		}
+		r.Amount, _ = shares[i].Float64()
+		res.Requests = append(res.Requests, r)
+	}
+
+	if err := h.DB.Create(&res.Requests).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	for _, r := range res.Requests {
+		requested(r)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(res)
+}
+
+// split divides total into parts shares that differ by at most a cent, the
+// larger ones first.
+func split(total decimal.Decimal, parts int) []decimal.Decimal {
+	cents := total.Mul(decimal.NewFromInt(100)).Round(0).IntPart()
+	n := int64(parts)
+	shares := make([]decimal.Decimal, parts)
+	for i := range shares {
+		c := cents / n
+		if int64(i) < cents%n {
+			c++
+		}
+		shares[i] = decimal.New(c, -2)
+	}
+	return shares
+}
+
+// prepare loads the caller and works out when a new request expires. The
+// error it returns is the one to answer with.
+func (h *handler) prepare(c *fiber.Ctx, expiresAt *time.Time) (*account.Account, time.Time, error) {
+	expires := time.Now().Add(defaultTTL)
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return nil, expires, apperr.BadRequest("payload invalid: expires_at must be in the future")
+		}
+		expires = *expiresAt
+	}
+
+	acc := &account.Account{}
+	if err := h.DB.First(acc, c.Locals("account_id").(int)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, expires, apperr.NotFound(apperr.CodeAccountNotFound, "account not found")
+		}
+		return nil, expires, apperr.Internal(err)
+	}
+	return acc, expires, nil
+}
+
+// resolve finds the account with account number or alias name.
+func resolve(db *gorm.DB, name string) (*account.Account, error) {
+	acc := &account.Account{}
+	err := db.Where("account_number = ? OR alias = ?", name, name).First(acc).Error
+	if err != nil {
+		return nil, err
+	}
+	if acc.Status == account.StatusClosed {
+		return nil, account.ErrRecipientClosed
+	}
+	return acc, nil
+}
+
+func payerError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperr.NotFound(apperr.CodeAccountNotFound, "payer account not found")
+	case errors.Is(err, account.ErrRecipientClosed):
+		return apperr.Unprocessable(apperr.CodeAccountClosed, "payer account is closed")
+	}
+	return apperr.Internal(err)
+}
+
+func requested(r PaymentRequest) {
+	if r.PayerID != nil {
+		event.Publish(event.Event{Type: event.PaymentRequested, AccountID: *r.PayerID, Payload: r})
+	}
+}
+
+// @Summary Get my payment requests
+// @Description Get the payment requests the caller made, with their status
+// @Tags payment-requests
+// @Produce json
+// @Param status query string false "Filter by status"
+// @Success 200 {array} payment.PaymentRequest
+// @Security  Bearer
+// @Router /payment-requests/ [get]
+func (h *handler) GetAllPaymentRequests(c *fiber.Ctx) error {
+	if err := ExpireOverdue(h.DB); err != nil {
+		return apperr.Internal(err)
+	}
+
+	q := h.DB.Where("requester_id = ?", c.Locals("account_id").(int))
+	if status := c.Query("status"); status != "" {
+		q = q.Where("status = ?", status)
+	}
+
+	r := []PaymentRequest{}
+	if err := q.Order("id desc").Find(&r).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(r)
+}
+
+// @Summary Get incoming payment requests
+// @Description Get the pending payment requests addressed to the caller
+// @Tags payment-requests
+// @Produce json
+// @Success 200 {array} payment.PaymentRequest
+// @Security  Bearer
+// @Router /payment-requests/incoming [get]
+func (h *handler) GetIncoming(c *fiber.Ctx) error {
+	if err := ExpireOverdue(h.DB); err != nil {
+		return apperr.Internal(err)
+	}
+
+	r := []PaymentRequest{}
+	tx := h.DB.Where("payer_id = ? AND status = ?", c.Locals("account_id").(int), StatusPending).Order("id desc").Find(&r)
+	if tx.Error != nil {
+		return apperr.Internal(tx.Error)
+	}
+	return c.Status(fiber.StatusOK).JSON(r)
+}
+
+// @Summary Get a pay link
+// @Description Get the payment request behind a pay link
+// @Tags payment-requests
+// @Produce json
+// @Param token path string true "Pay link token"
+// @Success 200 {object} payment.PaymentRequest
+// @Security  Bearer
+// @Router /payment-requests/link/{token} [get]
+func (h *handler) GetLink(c *fiber.Ctx) error {
+	if err := ExpireOverdue(h.DB); err != nil {
+		return apperr.Internal(err)
+	}
+
+	r := &PaymentRequest{}
+	if err := h.DB.Where("token = ?", c.Params("token")).First(r).Error; err != nil {
+		return requestError(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(r)
+}
+
+// @Summary Pay a payment request
+// @Description Pay a payment request addressed to the caller with an account transfer
+// @Tags payment-requests
+// @Produce json
+// @Param id path int true "Payment request ID"
+// @Success 200 {object} payment.PaymentRequest
+// @Security  Bearer
+// @Router /payment-requests/{id}/pay [post]
+func (h *handler) Pay(c *fiber.Ctx) error {
+	acc := uint(c.Locals("account_id").(int))
+	r := &PaymentRequest{}
+	if err := h.DB.Where("payer_id = ?", acc).First(r, c.Params("id")).Error; err != nil {
+		return requestError(err)
+	}
+
+	r, err := Pay(h.DB, r, acc)
+	if err != nil {
+		return requestError(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(r)
+}
+
+// @Summary Pay a pay link
+// @Description Pay the payment request behind a pay link with an account transfer
+// @Tags payment-requests
+// @Produce json
+// @Param token path string true "Pay link token"
+// @Success 200 {object} payment.PaymentRequest
+// @Security  Bearer
+// @Router /payment-requests/link/{token}/pay [post]
+func (h *handler) PayLink(c *fiber.Ctx) error {
+	r := &PaymentRequest{}
+	if err := h.DB.Where("token = ?", c.Params("token")).First(r).Error; err != nil {
+		return requestError(err)
+	}
+
+	r, err := Pay(h.DB, r, uint(c.Locals("account_id").(int)))
+	if err != nil {
+		return requestError(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(r)
+}
+
+// Pay pays r from account payerID through the regular account transfer.
+// Claiming the request, the transfer and marking the request paid happen in
+// one transaction, so the request is either paid with its transfer or left
+// pending without one, and cannot be paid twice.
+func Pay(db *gorm.DB, r *PaymentRequest, payerID uint) (*PaymentRequest, error) {
+	if r.RequesterID == payerID {
+		return nil, ErrOwnRequest
+	}
+
+	ctx, publish := event.Hold(context.Background())
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&PaymentRequest{}).
+			Where("id = ? AND status = ? AND expires_at > ?", r.ID, StatusPending, time.Now()).
+			Update("status", StatusProcessing)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotPending
+		}
+
+		t, err := account.Send(tx, payerID, &account.AccountTransferRequest{To: r.RequesterAccount, Amount: r.Amount, Memo: r.Memo})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		r.Status = StatusPaid
+		r.PaidBy = &payerID
+		r.TransferID = &t.ID
+		r.PaidAt = &now
+		return tx.Model(r).Select("status", "paid_by", "transfer_id", "paid_at").Updates(r).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publish()
+	event.Publish(event.Event{Type: event.PaymentRequestPaid, AccountID: r.RequesterID, Payload: *r})
+	return r, nil
+}
+
+// @Summary Decline a payment request
+// @Description Decline a payment request addressed to the caller
+// @Tags payment-requests
+// @Produce json
+// @Param id path int true "Payment request ID"
+// @Success 200 {object} payment.PaymentRequest
+// @Security  Bearer
+// @Router /payment-requests/{id}/decline [post]
+func (h *handler) Decline(c *fiber.Ctx) error {
+	return h.close(c, "payer_id", StatusDeclined)
+}
+
+// @Summary Cancel a payment request
+// @Description Cancel a payment request the caller made
+// @Tags payment-requests
+// @Produce json
+// @Param id path int true "Payment request ID"
+// @Success 200 {object} payment.PaymentRequest
+// @Security  Bearer
+// @Router /payment-requests/{id}/cancel [post]
+func (h *handler) Cancel(c *fiber.Ctx) error {
+	return h.close(c, "requester_id", StatusCancelled)
+}
+
+// close moves a pending request the caller is party to as column to status.
+func (h *handler) close(c *fiber.Ctx, column, status string) error {
+	r := &PaymentRequest{}
+	if err := h.DB.Where(column+" = ?", c.Locals("account_id").(int)).First(r, c.Params("id")).Error; err != nil {
+		return requestError(err)
+	}
+
+	res := h.DB.Model(r).Where("status = ?", StatusPending).Update("status", status)
+	if res.Error != nil {
+		return requestError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return requestError(ErrNotPending)
+	}
+	r.Status = status
+	return c.Status(fiber.StatusOK).JSON(r)
+}
+
+func requestError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperr.NotFound(apperr.CodeNotFound, "payment request not found")
+	case errors.Is(err, ErrNotPending):
+		return apperr.Conflict(apperr.CodeNotPending, err.Error())
+	case errors.Is(err, ErrOwnRequest):
+		return apperr.Unprocessable(apperr.CodeUnprocessable, err.Error())
+	case errors.Is(err, account.ErrInsufficientBalance):
+		return apperr.Unprocessable(apperr.CodeInsufficientFunds, err.Error())
+	case errors.Is(err, account.ErrTransferLimitExceeded):
+		return apperr.Unprocessable(apperr.CodeLimitExceeded, err.Error())
+	case errors.Is(err, account.ErrRecipientClosed):
+		return apperr.Unprocessable(apperr.CodeAccountClosed, err.Error())
+	case errors.Is(err, account.ErrAccountClosed):
+		return apperr.New(fiber.StatusForbidden, apperr.CodeAccountClosed, err.Error())
+	case errors.Is(err, account.ErrAccountFrozen):
+		return apperr.New(fiber.StatusForbidden, apperr.CodeAccountFrozen, err.Error())
+	}
+	return apperr.Internal(err)
+}
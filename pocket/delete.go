@@ -1,17 +1,16 @@
 package pocket
 
 import (
-	"errors"
 	"strconv"
 
-	"github.com/arthit666/make_app/balance"
+	"github.com/arthit666/make_app/apperr"
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 // @Summary Delete a pocket
-// @Description Delete a pocket by ID
+// @Description Delete a pocket by ID and pay its balance to the owner. A shared pocket can only be deleted once its members have taken out what they put in.
 // @Param id path int true "Pocket ID"
+// @Param early_withdrawal query bool false "Break a locked pocket before maturity and pay the penalty"
 // @Tags pockets
 // @Security  Bearer
 // @Success 200 {object} pocket.SuccessResponse
@@ -19,27 +18,11 @@ import (
 func (h *handler) DeletePocket(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.ErrBadRequest)
+		return apperr.BadRequest("invalid id")
 	}
 	acc := c.Locals("account_id").(int)
-	accStr := strconv.Itoa(acc)
-	p, err := getById(c.Params("id"), accStr, h)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(Err{Message: "pocket not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Message: "error: " + err.Error()})
-	}
-
-	err = balance.Add(h.DB, p.AccountID, p.Balance)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Message: "error: " + err.Error()})
-	}
-
-	tx := h.DB.Delete(&Pocket{}, id)
-	if tx.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Message: "error: " + tx.Error.Error()})
+	if err := h.pockets.Delete(c.UserContext(), uint(id), uint(acc), c.QueryBool("early_withdrawal")); err != nil {
+		return pocketError(err)
 	}
 	return c.Status(fiber.StatusOK).JSON(SuccessResponse{Message: "delete pocket success"})
-
 }
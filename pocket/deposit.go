@@ -0,0 +1,89 @@
+package pocket
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Deposit into a pocket
+// @Description Move money from the main account balance into a pocket
+// @Tags pockets
+// @Accept json
+// @Produce json
+// @Param id path int true "Pocket ID"
+// @Param deposit body pocket.PocketAmountRequest true "PocketAmountRequest data"
+// @Success 201 {object} pocket.PocketTransfer
+// @Security  Bearer
+// @Router /pockets/{id}/deposit [post]
+func (h *handler) Deposit(c *fiber.Ctx) error {
+	return h.moveMain(c, TransferTypeDeposit)
+}
+
+// @Summary Withdraw from a pocket
+// @Description Move money from a pocket back to the main account balance
+// @Tags pockets
+// @Accept json
+// @Produce json
+// @Param id path int true "Pocket ID"
+// @Param withdraw body pocket.PocketAmountRequest true "PocketAmountRequest data"
+// @Success 201 {object} pocket.PocketTransfer
+// @Security  Bearer
+// @Router /pockets/{id}/withdraw [post]
+func (h *handler) Withdraw(c *fiber.Ctx) error {
+	return h.moveMain(c, TransferTypeWithdrawal)
+}
+
+func (h *handler) moveMain(c *fiber.Ctx, typ string) error {
+	req := &PocketAmountRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	acc := uint(c.Locals("account_id").(int))
+	t, err := h.pockets.Move(c.UserContext(), uint(id), acc, typ, req.Amount, req.EarlyWithdrawal)
+	if err != nil {
+		return pocketError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(t)
+}
+
+// @Summary Get pocket transfer history
+// @Description Get every movement in or out of a pocket, newest first
+// @Tags pockets
+// @Produce json
+// @Param id path int true "Pocket ID"
+// @Success 200 {array} pocket.PocketTransfer
+// @Security  Bearer
+// @Router /pockets/{id}/transfers [get]
+func (h *handler) GetTransfers(c *fiber.Ctx) error {
+	acc := c.Locals("account_id").(int)
+	p, _, err := h.access(c, uint(acc), RoleOwner, RoleContributor, RoleViewer)
+	if err != nil {
+		return pocketError(err)
+	}
+
+	transfers, err := h.pockets.History(c.UserContext(), p.ID)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(transfers)
+}
+
+// History is every movement in or out of pocket id, newest first.
+func (s *PocketService) History(ctx context.Context, id uint) ([]PocketTransfer, error) {
+	return s.store.Pockets().History(ctx, id)
+}
@@ -1,11 +1,8 @@
 package pocket
 
 import (
-	"errors"
-	"strconv"
-
+	"github.com/arthit666/make_app/apperr"
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 // @Summary Get all pocket
@@ -14,17 +11,24 @@ import (
 // @Accept  json
 // @Produce  json
 // @Security  Bearer
-// @Success 200 {array} pocket.Pocket
+// @Success 200 {array} pocket.PocketResponse
 // @Router /pockets/ [get]
 func (h *handler) GetAllPockets(c *fiber.Ctx) error {
-	p := []Pocket{}
 	acc := c.Locals("account_id").(int)
-	accStr := strconv.Itoa(acc)
-	tx := h.DB.Where("account_id = ?", accStr).Find(&p)
-	if tx.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Message: "error: " + tx.Error.Error()})
+	p, err := h.pockets.List(c.UserContext(), uint(acc))
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	res := []PocketResponse{}
+	for i := range p {
+		r, err := h.pockets.response(c.UserContext(), &p[i])
+		if err != nil {
+			return apperr.Internal(err)
+		}
+		res = append(res, r)
 	}
-	return c.Status(fiber.StatusOK).JSON(p)
+	return c.Status(fiber.StatusOK).JSON(res)
 }
 
 // @Summary Get pocket by ID
@@ -32,27 +36,19 @@ func (h *handler) GetAllPockets(c *fiber.Ctx) error {
 // @Tags pockets
 // @Produce json
 // @Param id path int true "Pocket ID"
+// @Success 200 {object} pocket.PocketResponse
 // @Security  Bearer
 // @Router /pockets/{id} [get]
 func (h *handler) GetPocketById(c *fiber.Ctx) error {
-	id := c.Params("id")
 	acc := c.Locals("account_id").(int)
-	accStr := strconv.Itoa(acc)
-	p, err := getById(id, accStr, h)
+	p, _, err := h.access(c, uint(acc), RoleOwner, RoleContributor, RoleViewer)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(Err{Message: "pocket not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Message: "error: " + err.Error()})
+		return pocketError(err)
 	}
-	return c.Status(fiber.StatusOK).JSON(p)
-}
 
-func getById(id string, accStr string, h *handler) (*Pocket, error) {
-	p := &Pocket{}
-	tx := h.DB.Where("account_id = ?", accStr).First(p, id)
-	if tx.Error != nil {
-		return nil, tx.Error
+	res, err := h.pockets.response(c.UserContext(), p)
+	if err != nil {
+		return apperr.Internal(err)
 	}
-	return p, nil
+	return c.Status(fiber.StatusOK).JSON(res)
 }
@@ -0,0 +1,133 @@
+package pocket
+
+import (
+	"context"
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+)
+
+// contributionWindow is how far back contributions are averaged to project
+// when a goal will be reached.
+const contributionWindow = 90 * 24 * time.Hour
+
+// daysPerMonth is the average length of a month, used to spread what is left
+// of a goal over the months until its target date.
+const daysPerMonth = 30.4375
+
+// @Summary Get savings goals
+// @Description Get the progress of every pocket with a savings goal
+// @Tags pockets
+// @Produce json
+// @Security  Bearer
+// @Success 200 {object} pocket.GoalSummary
+// @Router /pockets/goals [get]
+func (h *handler) GetGoals(c *fiber.Ctx) error {
+	acc := c.Locals("account_id").(int)
+	sum, err := h.pockets.Goals(c.UserContext(), uint(acc))
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(sum)
+}
+
+// Goals sums up the progress of every pocket of account accountID with a
+// savings goal.
+func (s *PocketService) Goals(ctx context.Context, accountID uint) (GoalSummary, error) {
+	p, err := s.store.Pockets().Goals(ctx, accountID)
+	if err != nil {
+		return GoalSummary{}, err
+	}
+
+	sum := GoalSummary{Goals: []PocketResponse{}}
+	target, saved := decimal.Zero, decimal.Zero
+	for i := range p {
+		res, err := s.response(ctx, &p[i])
+		if err != nil {
+			return GoalSummary{}, err
+		}
+		sum.Goals = append(sum.Goals, res)
+
+		target = target.Add(decimal.NewFromFloat(res.Goal.TargetAmount))
+		saved = saved.Add(decimal.Min(decimal.NewFromFloat(p[i].Balance), decimal.NewFromFloat(res.Goal.TargetAmount)))
+		if res.Goal.Reached {
+			sum.Reached++
+		}
+	}
+
+	sum.TotalTarget, _ = target.Float64()
+	sum.TotalSaved, _ = saved.Float64()
+	if target.IsPositive() {
+		sum.Progress, _ = saved.Div(target).Mul(decimal.NewFromInt(100)).Round(2).Float64()
+	}
+	return sum, nil
+}
+
+// response is p with the progress of its goal, when it has one.
+func (s *PocketService) response(ctx context.Context, p *Pocket) (PocketResponse, error) {
+	res := PocketResponse{Pocket: *p}
+	if p.TargetAmount == nil {
+		return res, nil
+	}
+
+	now := time.Now()
+	window := now.Sub(p.CreatedAt)
+	if window > contributionWindow {
+		window = contributionWindow
+	}
+
+	contributed, err := s.store.Pockets().ReceivedSince(ctx, p.ID, now.Add(-window))
+	if err != nil {
+		return res, err
+	}
+
+	res.Goal = goalProgress(p, contributed, window, now)
+	return res, nil
+}
+
+// goalProgress works out how far p is from its goal. contributed is what went
+// into the pocket over the last window and is used to project when the goal
+// will be reached at the current pace.
+func goalProgress(p *Pocket, contributed float64, window time.Duration, now time.Time) *GoalProgress {
+	target := decimal.NewFromFloat(*p.TargetAmount)
+	bl := decimal.NewFromFloat(p.Balance)
+
+	remaining := decimal.Max(target.Sub(bl), decimal.Zero)
+	progress := decimal.Min(bl.Div(target).Mul(decimal.NewFromInt(100)), decimal.NewFromInt(100))
+
+	g := &GoalProgress{
+		TargetDate: p.TargetDate,
+		Reached:    remaining.IsZero(),
+	}
+	g.TargetAmount, _ = target.Float64()
+	g.Remaining, _ = remaining.Float64()
+	g.Progress, _ = progress.Round(2).Float64()
+
+	if g.Reached {
+		return g
+	}
+
+	days := decimal.NewFromFloat(window.Hours() / 24)
+	if days.LessThan(decimal.NewFromInt(1)) {
+		days = decimal.NewFromInt(1)
+	}
+	if perDay := decimal.NewFromFloat(contributed).Div(days); perDay.IsPositive() {
+		left, _ := remaining.Div(perDay).Float64()
+		at := now.Add(time.Duration(left * float64(24*time.Hour)))
+		g.ProjectedCompletion = &at
+	}
+
+	if p.TargetDate != nil {
+		months := decimal.NewFromFloat(p.TargetDate.Sub(now).Hours() / 24 / daysPerMonth)
+		if months.LessThan(decimal.NewFromInt(1)) {
+			months = decimal.NewFromInt(1)
+		}
+		monthly, _ := remaining.Div(months).RoundUp(2).Float64()
+		g.SuggestedMonthlyContribution = &monthly
+	}
+
+	return g
+}
@@ -0,0 +1,117 @@
+package pocket
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/arthit666/make_app/balance"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// lockedAt reports whether money cannot leave p at now without an early
+// withdrawal.
+func (p *Pocket) lockedAt(now time.Time) bool {
+	return p.Type == TypeLocked && p.MaturityDate != nil && now.Before(*p.MaturityDate)
+}
+
+// withdrawalPenalty is what taking amount out of p at now costs. A locked
+// pocket refuses unless early is set, in which case PenaltyRate percent of
+// amount is kept back.
+func withdrawalPenalty(p *Pocket, amount float64, early bool, now time.Time) (decimal.Decimal, error) {
+	if !p.lockedAt(now) {
+		return decimal.Zero, nil
+	}
+	if !early {
+		return decimal.Zero, ErrPocketLocked
+	}
+
+	amountDec := decimal.NewFromFloat(amount)
+	penalty := amountDec.Mul(decimal.NewFromFloat(p.PenaltyRate)).Div(decimal.NewFromInt(100)).Round(2)
+	return decimal.Min(penalty, amountDec), nil
+}
+
+// bonusInterest is the bonus earned by principal over one term of p, simple
+// interest on an ACT/365 basis.
+func bonusInterest(p *Pocket, principal decimal.Decimal) decimal.Decimal {
+	days := p.MaturityDate.Sub(*p.TermStart).Hours() / 24
+	return principal.
+		Mul(decimal.NewFromFloat(p.BonusRate)).
+		Div(decimal.NewFromInt(100)).
+		Mul(decimal.NewFromFloat(days)).
+		Div(decimal.NewFromInt(365)).
+		Round(2)
+}
+
+// Mature pays the bonus on every locked pocket that reached its maturity date
+// by now, then either starts a new term of the same length or releases the
+// balance to the main account, as the owner chose. Pockets that missed
+// several terms are caught up one term at a time.
+func Mature(db *gorm.DB, now time.Time) error {
+	pockets := []Pocket{}
+	if err := db.Where("type = ? AND maturity_date <= ?", TypeLocked, now).Order("id").Find(&pockets).Error; err != nil {
+		return err
+	}
+
+	for i := range pockets {
+		p := &pockets[i]
+		for p.Type == TypeLocked && !p.MaturityDate.After(now) {
+			if err := db.Transaction(func(tx *gorm.DB) error { return mature(tx, p) }); err != nil {
+				log.Printf("pocket %d: maturity failed: %s", p.ID, err)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func mature(tx *gorm.DB, p *Pocket) error {
+	maturity := *p.MaturityDate
+	bl := decimal.NewFromFloat(p.Balance)
+	bonus := bonusInterest(p, bl)
+	bl = bl.Add(bonus)
+
+	updates := map[string]interface{}{}
+	if p.MaturityAction == MaturityRollover {
+		next := maturity.Add(maturity.Sub(*p.TermStart))
+		updates["term_start"] = maturity
+		updates["maturity_date"] = next
+		updates["balance"], _ = bl.Float64()
+	} else {
+		updates["type"] = TypeRegular
+		updates["balance"] = 0
+	}
+
+	// The maturity date in the condition makes sure a term is only paid once.
+	res := tx.Model(&Pocket{}).Where("id = ? AND type = ? AND maturity_date = ?", p.ID, TypeLocked, maturity).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("pocket changed while maturing")
+	}
+
+	movements := []PocketTransfer{}
+	if bonus.IsPositive() {
+		t := PocketTransfer{Type: TransferTypeBonusInterest, To: p.ID, AccountID: p.AccountID}
+		t.Amount, _ = bonus.Float64()
+		movements = append(movements, t)
+	}
+	if p.MaturityAction != MaturityRollover && bl.IsPositive() {
+		t := PocketTransfer{Type: TransferTypeWithdrawal, From: p.ID, AccountID: p.AccountID}
+		t.Amount, _ = bl.Float64()
+		movements = append(movements, t)
+
+		if err := balance.Add(tx, p.AccountID, t.Amount); err != nil {
+			return err
+		}
+	}
+	if len(movements) > 0 {
+		if err := tx.Create(&movements).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.First(p, p.ID).Error
+}
@@ -0,0 +1,383 @@
+package pocket
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+)
+
+// accountRef is the part of an account needed to find invitees.
+type accountRef struct {
+	ID            uint
+	AccountNumber string
+	Alias         *string
+}
+
+func (accountRef) TableName() string {
+	return "accounts"
+}
+
+// access loads the pocket of the id route parameter for accID through
+// PocketService.Access.
+func (h *handler) access(c *fiber.Ctx, accID uint, roles ...string) (*Pocket, string, error) {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, "", ErrPocketNotFound
+	}
+	return h.pockets.Access(c.UserContext(), uint(id), accID, roles...)
+}
+
+func pocketError(err error) error {
+	switch {
+	case errors.Is(err, ErrPocketNotFound), errors.Is(err, ErrFromPocketNotFound), errors.Is(err, ErrToPocketNotFound):
+		return apperr.NotFound(apperr.CodePocketNotFound, err.Error())
+	case errors.Is(err, ErrAccountNotFound):
+		return apperr.NotFound(apperr.CodeAccountNotFound, err.Error())
+	case errors.Is(err, ErrMemberNotFound), errors.Is(err, ErrInvitationNotFound):
+		return apperr.NotFound(apperr.CodeNotFound, err.Error())
+	case errors.Is(err, ErrAlreadyMember):
+		return apperr.Conflict(apperr.CodeAlreadyExists, err.Error())
+	case errors.Is(err, ErrMembersHoldFunds):
+		return apperr.Conflict(apperr.CodeConflict, err.Error())
+	case errors.Is(err, ErrForbidden):
+		return apperr.Forbidden(err.Error())
+	case errors.Is(err, ErrLockedTerms), errors.Is(err, ErrSamePocket):
+		return apperr.BadRequest(err.Error())
+	case errors.Is(err, ErrInsufficientAccountBalance), errors.Is(err, ErrInsufficientPocketBalance):
+		return apperr.Unprocessable(apperr.CodeInsufficientFunds, err.Error())
+	case errors.Is(err, ErrPocketLocked), errors.Is(err, ErrLockedDeposit):
+		return apperr.Unprocessable(apperr.CodePocketLocked, err.Error())
+	case errors.Is(err, ErrAllowanceExceeded):
+		return apperr.Unprocessable(apperr.CodeLimitExceeded, err.Error())
+	}
+	return apperr.Internal(err)
+}
+
+// @Summary Invite a member to a pocket
+// @Description Invite another account, by account number or alias, to share a pocket
+// @Tags pockets
+// @Accept json
+// @Produce json
+// @Param id path int true "Pocket ID"
+// @Param invite body pocket.InviteRequest true "InviteRequest data"
+// @Success 201 {object} pocket.PocketMember
+// @Security  Bearer
+// @Router /pockets/{id}/members [post]
+func (h *handler) InviteMember(c *fiber.Ctx) error {
+	req := &InviteRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	acc := uint(c.Locals("account_id").(int))
+	p, _, err := h.access(c, acc, RoleOwner)
+	if err != nil {
+		return pocketError(err)
+	}
+
+	m, err := h.pockets.Invite(c.UserContext(), p, acc, req)
+	if err != nil {
+		return pocketError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(m)
+}
+
+// @Summary Get pocket members
+// @Description Get the members of a pocket with what each of them put in and took out
+// @Tags pockets
+// @Produce json
+// @Param id path int true "Pocket ID"
+// @Success 200 {array} pocket.MemberResponse
+// @Security  Bearer
+// @Router /pockets/{id}/members [get]
+func (h *handler) GetMembers(c *fiber.Ctx) error {
+	acc := uint(c.Locals("account_id").(int))
+	p, _, err := h.access(c, acc, RoleOwner, RoleContributor, RoleViewer)
+	if err != nil {
+		return pocketError(err)
+	}
+
+	res, err := h.pockets.Members(c.UserContext(), p)
+	if err != nil {
+		return pocketError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(res)
+}
+
+// @Summary Update a pocket member
+// @Description Change the role or withdraw limit of a pocket member
+// @Tags pockets
+// @Accept json
+// @Produce json
+// @Param id path int true "Pocket ID"
+// @Param member path int true "Member ID"
+// @Param update body pocket.MemberUpdate true "MemberUpdate data"
+// @Success 200 {object} pocket.PocketMember
+// @Security  Bearer
+// @Router /pockets/{id}/members/{member} [put]
+func (h *handler) UpdateMember(c *fiber.Ctx) error {
+	req := &MemberUpdate{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	acc := uint(c.Locals("account_id").(int))
+	p, _, err := h.access(c, acc, RoleOwner)
+	if err != nil {
+		return pocketError(err)
+	}
+
+	m, err := h.pockets.UpdateMember(c.UserContext(), p, memberID(c), req)
+	if err != nil {
+		return pocketError(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(m)
+}
+
+// @Summary Remove a pocket member
+// @Description Remove a member from a pocket. Owners can remove anyone and members can leave
+// @Tags pockets
+// @Param id path int true "Pocket ID"
+// @Param member path int true "Member ID"
+// @Success 200 {object} pocket.SuccessResponse
+// @Security  Bearer
+// @Router /pockets/{id}/members/{member} [delete]
+func (h *handler) RemoveMember(c *fiber.Ctx) error {
+	acc := uint(c.Locals("account_id").(int))
+	p, role, err := h.access(c, acc, RoleOwner, RoleContributor, RoleViewer)
+	if err != nil {
+		return pocketError(err)
+	}
+
+	if err := h.pockets.RemoveMember(c.UserContext(), p, acc, role, memberID(c)); err != nil {
+		return pocketError(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(SuccessResponse{Message: "remove member success"})
+}
+
+// @Summary Get pocket invitations
+// @Description Get the pocket invitations waiting for the caller
+// @Tags pockets
+// @Produce json
+// @Success 200 {array} pocket.PocketMember
+// @Security  Bearer
+// @Router /pockets/invitations [get]
+func (h *handler) GetInvitations(c *fiber.Ctx) error {
+	acc := uint(c.Locals("account_id").(int))
+	invitations, err := h.pockets.Invitations(c.UserContext(), acc)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(invitations)
+}
+
+// @Summary Accept a pocket invitation
+// @Description Accept an invitation and join the pocket
+// @Tags pockets
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} pocket.PocketMember
+// @Security  Bearer
+// @Router /pockets/invitations/{id}/accept [post]
+func (h *handler) AcceptInvitation(c *fiber.Ctx) error {
+	acc := uint(c.Locals("account_id").(int))
+	id, _ := strconv.Atoi(c.Params("id"))
+	m, err := h.pockets.Accept(c.UserContext(), uint(id), acc)
+	if err != nil {
+		return pocketError(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(m)
+}
+
+// @Summary Decline a pocket invitation
+// @Description Decline an invitation to a pocket
+// @Tags pockets
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} pocket.SuccessResponse
+// @Security  Bearer
+// @Router /pockets/invitations/{id}/decline [post]
+func (h *handler) DeclineInvitation(c *fiber.Ctx) error {
+	acc := uint(c.Locals("account_id").(int))
+	id, _ := strconv.Atoi(c.Params("id"))
+	if err := h.pockets.Decline(c.UserContext(), uint(id), acc); err != nil {
+		return pocketError(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(SuccessResponse{Message: "decline invitation success"})
+}
+
+// memberID is the member route parameter, 0 when it is not a number, which
+// no member has.
+func memberID(c *fiber.Ctx) uint {
+	id, _ := strconv.Atoi(c.Params("member"))
+	return uint(id)
+}
+
+// Invite invites the account req names to pocket p of account accountID,
+// which has to be its owner.
+func (s *PocketService) Invite(ctx context.Context, p *Pocket, accountID uint, req *InviteRequest) (*PocketMember, error) {
+	m := &PocketMember{
+		PocketID:      p.ID,
+		Role:          req.Role,
+		Status:        MemberInvited,
+		InvitedBy:     accountID,
+		WithdrawLimit: req.WithdrawLimit,
+	}
+	err := s.store.Transaction(ctx, func(store Store) error {
+		pockets := store.Pockets()
+		cond := accountRef{AccountNumber: req.AccountNumber}
+		if req.AccountNumber == "" {
+			cond.Alias = &req.Alias
+		}
+		invitee, err := pockets.Account(ctx, cond)
+		if err != nil {
+			return err
+		}
+		if invitee.ID == p.AccountID {
+			return ErrAlreadyMember
+		}
+
+		_, err = pockets.Member(ctx, p.ID, invitee.ID)
+		if err == nil {
+			return ErrAlreadyMember
+		}
+		if !errors.Is(err, ErrMemberNotFound) {
+			return err
+		}
+
+		m.AccountID = invitee.ID
+		return pockets.AddMember(ctx, m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Members is the owner of pocket p followed by its members, with what each
+// of them put in and took out.
+func (s *PocketService) Members(ctx context.Context, p *Pocket) ([]MemberResponse, error) {
+	pockets := s.store.Pockets()
+	members, err := pockets.Members(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	members = append([]PocketMember{{PocketID: p.ID, AccountID: p.AccountID, Role: RoleOwner, Status: MemberActive}}, members...)
+
+	res := []MemberResponse{}
+	for _, m := range members {
+		r := MemberResponse{PocketMember: m}
+		if ref, err := pockets.Account(ctx, accountRef{ID: m.AccountID}); err == nil {
+			r.AccountNumber = ref.AccountNumber
+		}
+		if r.Contributed, err = pockets.Contributed(ctx, p.ID, m.AccountID); err != nil {
+			return nil, err
+		}
+		if r.Withdrawn, err = pockets.Withdrawn(ctx, p.ID, m.AccountID); err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// UpdateMember changes the role or withdraw limit of member id of pocket p.
+func (s *PocketService) UpdateMember(ctx context.Context, p *Pocket, id uint, req *MemberUpdate) (*PocketMember, error) {
+	m, err := member(ctx, s.store.Pockets(), p.ID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Role != "" {
+		m.Role = req.Role
+	}
+	if req.WithdrawLimit != nil {
+		m.WithdrawLimit = *req.WithdrawLimit
+	}
+	if err := s.store.Pockets().UpdateMember(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RemoveMember removes member id from pocket p for account accountID, which
+// has role in it. Owners can remove anyone and members only themselves.
+func (s *PocketService) RemoveMember(ctx context.Context, p *Pocket, accountID uint, role string, id uint) error {
+	m, err := member(ctx, s.store.Pockets(), p.ID, id)
+	if err != nil {
+		return err
+	}
+	if role != RoleOwner && m.AccountID != accountID {
+		return ErrForbidden
+	}
+	return s.store.Pockets().RemoveMember(ctx, m)
+}
+
+// Invitations is every invitation waiting for account accountID.
+func (s *PocketService) Invitations(ctx context.Context, accountID uint) ([]PocketMember, error) {
+	return s.store.Pockets().Invitations(ctx, accountID)
+}
+
+// Accept makes account accountID an active member of the pocket invitation
+// id is for.
+func (s *PocketService) Accept(ctx context.Context, id, accountID uint) (*PocketMember, error) {
+	m, err := s.invitation(ctx, id, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Status = MemberActive
+	if err := s.store.Pockets().UpdateMember(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Decline turns down invitation id waiting for account accountID.
+func (s *PocketService) Decline(ctx context.Context, id, accountID uint) error {
+	m, err := s.invitation(ctx, id, accountID)
+	if err != nil {
+		return err
+	}
+	return s.store.Pockets().RemoveMember(ctx, m)
+}
+
+// invitation is invitation id waiting for account accountID.
+func (s *PocketService) invitation(ctx context.Context, id, accountID uint) (*PocketMember, error) {
+	invitations, err := s.store.Pockets().Invitations(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range invitations {
+		if m.ID == id {
+			return &m, nil
+		}
+	}
+	return nil, ErrInvitationNotFound
+}
+
+// member is member id of pocket pocketID.
+func member(ctx context.Context, pockets PocketRepository, pocketID, id uint) (*PocketMember, error) {
+	members, err := pockets.Members(ctx, pocketID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.ID == id {
+			return &m, nil
+		}
+	}
+	return nil, ErrMemberNotFound
+}
@@ -1,58 +1,181 @@
 package pocket
 
 import (
+	"errors"
 	"time"
 
+	"github.com/arthit666/make_app/config"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrInsufficientAccountBalance = errors.New("insufficient balance in account")
+	ErrInsufficientPocketBalance  = errors.New("insufficient balance in pocket")
+	ErrPocketLocked               = errors.New("pocket is locked until maturity")
+	ErrLockedDeposit              = errors.New("money cannot be added to a locked pocket")
+	ErrForbidden                  = errors.New("forbidden")
+	ErrAllowanceExceeded          = errors.New("withdrawal exceeds your allowance")
+	ErrAlreadyMember              = errors.New("account is already a member")
+	ErrPocketNotFound             = errors.New("pocket not found")
+	ErrFromPocketNotFound         = errors.New("from pocket not found")
+	ErrToPocketNotFound           = errors.New("target pocket not found")
+	ErrSamePocket                 = errors.New("cannot transfer to the same pocket")
+	ErrMemberNotFound             = errors.New("member not found")
+	ErrInvitationNotFound         = errors.New("invitation not found")
+	ErrAccountNotFound            = errors.New("account not found")
+	ErrLockedTerms                = errors.New("a locked pocket needs a balance and a future maturity date")
+	ErrMembersHoldFunds           = errors.New("members still have money in the pocket")
+)
+
 type Pocket struct {
-	ID          uint           `gorm:"primarykey" json:"id"`
-	CreatedAt   time.Time      `json:"create_at"`
-	UpdatedAt   time.Time      `json:"update_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-	Title       string         `json:"title" validate:"required"`
-	Balance     float64        `json:"balance"`
-	Description *string        `json:"description"`
-	AccountID   uint           `json:"-" validate:"required"`
+	ID             uint           `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time      `json:"create_at"`
+	UpdatedAt      time.Time      `json:"update_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	Title          string         `json:"title" validate:"required"`
+	Balance        float64        `json:"balance"`
+	Description    *string        `json:"description"`
+	AccountID      uint           `json:"-" validate:"required"`
+	TargetAmount   *float64       `json:"target_amount"`
+	TargetDate     *time.Time     `json:"target_date"`
+	GoalReachedAt  *time.Time     `json:"goal_reached_at"`
+	Type           string         `json:"type" gorm:"default:regular"`
+	TermStart      *time.Time     `json:"term_start,omitempty"`
+	MaturityDate   *time.Time     `json:"maturity_date,omitempty"`
+	MaturityAction string         `json:"maturity_action,omitempty"`
+	BonusRate      float64        `json:"bonus_rate,omitempty"`
+	PenaltyRate    float64        `json:"penalty_rate,omitempty"`
+}
+
+// PocketMember gives another account access to a pocket. The account a pocket
+// belongs to is always its owner and has no member row.
+type PocketMember struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time `json:"create_at"`
+	UpdatedAt     time.Time `json:"update_at"`
+	PocketID      uint      `json:"pocket_id" gorm:"uniqueIndex:idx_pocket_member"`
+	AccountID     uint      `json:"account_id" gorm:"uniqueIndex:idx_pocket_member"`
+	Role          string    `json:"role"`
+	Status        string    `json:"status"`
+	InvitedBy     uint      `json:"invited_by"`
+	WithdrawLimit float64   `json:"withdraw_limit"`
+}
+
+type MemberResponse struct {
+	PocketMember
+	AccountNumber string  `json:"account_number"`
+	Contributed   float64 `json:"contributed"`
+	Withdrawn     float64 `json:"withdrawn"`
+}
+
+type InviteRequest struct {
+	AccountNumber string  `json:"account_number" validate:"required_without=Alias"`
+	Alias         string  `json:"alias" validate:"required_without=AccountNumber"`
+	Role          string  `json:"role" validate:"required,oneof=owner contributor viewer"`
+	WithdrawLimit float64 `json:"withdraw_limit" validate:"gte=0"`
+}
+
+type MemberUpdate struct {
+	Role          string   `json:"role" validate:"omitempty,oneof=owner contributor viewer"`
+	WithdrawLimit *float64 `json:"withdraw_limit" validate:"omitempty,gte=0"`
+}
+
+type PocketResponse struct {
+	Pocket
+	Goal *GoalProgress `json:"goal,omitempty"`
+}
+
+type GoalProgress struct {
+	TargetAmount                 float64    `json:"target_amount"`
+	TargetDate                   *time.Time `json:"target_date"`
+	Remaining                    float64    `json:"remaining"`
+	Progress                     float64    `json:"progress"`
+	Reached                      bool       `json:"reached"`
+	ProjectedCompletion          *time.Time `json:"projected_completion"`
+	SuggestedMonthlyContribution *float64   `json:"suggested_monthly_contribution"`
+}
+
+type GoalSummary struct {
+	Goals       []PocketResponse `json:"goals"`
+	TotalTarget float64          `json:"total_target"`
+	TotalSaved  float64          `json:"total_saved"`
+	Progress    float64          `json:"progress"`
+	Reached     int              `json:"reached"`
 }
 
 type PocketUpdate struct {
-	Title       string  `json:"title"`
-	Description *string `json:"description"`
+	Title          string     `json:"title"`
+	Description    *string    `json:"description"`
+	TargetAmount   *float64   `json:"target_amount" validate:"omitempty,gt=0"`
+	TargetDate     *time.Time `json:"target_date"`
+	MaturityAction string     `json:"maturity_action" validate:"omitempty,oneof=rollover release"`
 }
 
 type PocketCreate struct {
-	Title       string  `json:"title" validate:"required"`
-	Balance     float64 `json:"balance"`
-	Description *string `json:"description"`
+	Title          string     `json:"title" validate:"required"`
+	Balance        float64    `json:"balance" validate:"gte=0"`
+	Description    *string    `json:"description"`
+	TargetAmount   *float64   `json:"target_amount" validate:"omitempty,gt=0"`
+	TargetDate     *time.Time `json:"target_date"`
+	Type           string     `json:"type" validate:"omitempty,oneof=regular locked"`
+	MaturityDate   *time.Time `json:"maturity_date"`
+	MaturityAction string     `json:"maturity_action" validate:"omitempty,oneof=rollover release"`
 }
 
 type PocketTransfer struct {
 	ID        uint      `gorm:"primarykey" json:"id"`
 	CreatedAt time.Time `json:"create_at"`
+	Type      string    `json:"type" gorm:"default:transfer"`
 	From      uint      `json:"from" validate:"required"`
 	To        uint      `json:"to" validate:"required"`
 	Amount    float64   `json:"amount" validate:"required,numeric,gt=0"`
 	AccountID uint      `json:"-"`
 }
 
+const (
+	TransferTypeTransfer       = "transfer"
+	TransferTypeInterest       = "interest"
+	TransferTypeWithholdingTax = "withholding_tax"
+	TransferTypeRule           = "rule"
+	TransferTypeDeposit        = "deposit"
+	TransferTypeWithdrawal     = "withdrawal"
+	TransferTypePenalty        = "penalty"
+	TransferTypeBonusInterest  = "bonus_interest"
+
+	TypeRegular = "regular"
+	TypeLocked  = "locked"
+
+	MaturityRollover = "rollover"
+	MaturityRelease  = "release"
+
+	RoleOwner       = "owner"
+	RoleContributor = "contributor"
+	RoleViewer      = "viewer"
+
+	MemberInvited = "invited"
+	MemberActive  = "active"
+)
+
 type PocketTransferRequest struct {
-	From   uint    `json:"from" validate:"required"`
-	To     uint    `json:"to" validate:"required"`
-	Amount float64 `json:"amount" validate:"required,numeric,gt=0"`
+	From            uint    `json:"from" validate:"required"`
+	To              uint    `json:"to" validate:"required"`
+	Amount          float64 `json:"amount" validate:"required,numeric,gt=0"`
+	EarlyWithdrawal bool    `json:"early_withdrawal"`
 }
 
-type handler struct {
-	DB *gorm.DB
+// PocketAmountRequest moves Amount between the main account balance and a
+// pocket.
+type PocketAmountRequest struct {
+	Amount          float64 `json:"amount" validate:"required,numeric,gt=0"`
+	EarlyWithdrawal bool    `json:"early_withdrawal"`
 }
 
-func New(db *gorm.DB) *handler {
-	return &handler{db}
+type handler struct {
+	pockets *PocketService
 }
 
-type Err struct {
-	Message string `json:"message"`
+func New(db *gorm.DB, cfg *config.Config) *handler {
+	return &handler{pockets: NewPocketService(NewStore(db), cfg.Policy)}
 }
 
 type SuccessResponse struct {
@@ -2,12 +2,18 @@ package pocket
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/config"
+	"github.com/arthit666/make_app/event"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -24,18 +30,18 @@ func TestCreatePocket(t *testing.T) {
 	// Arrange
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 	assert.NoError(t, err)
-	err = db.AutoMigrate(Account{}, &Pocket{})
+	err = db.AutoMigrate(Account{}, &Pocket{}, &PocketTransfer{})
 	assert.NoError(t, err)
 
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
 	app.Use(func(c *fiber.Ctx) error {
 		c.Locals("account_id", 1)
 		return c.Next()
 	})
-	handler := New(tx)
+	handler := New(tx, config.Default())
 	app.Post("/pockets", handler.CreatePocket)
 
 	account := Account{
@@ -76,18 +82,18 @@ func TestGetAllPockets(t *testing.T) {
 	// Arrange
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 	assert.NoError(t, err)
-	err = db.AutoMigrate(&Account{}, &Pocket{})
+	err = db.AutoMigrate(&Account{}, &Pocket{}, &PocketMember{})
 	assert.NoError(t, err)
 
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
 	app.Use(func(c *fiber.Ctx) error {
 		c.Locals("account_id", 1)
 		return c.Next()
 	})
-	handler := New(tx)
+	handler := New(tx, config.Default())
 	app.Get("/pockets", handler.GetAllPockets)
 
 	account := Account{
@@ -135,12 +141,12 @@ func TestGetPocketById(t *testing.T) {
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
 	app.Use(func(c *fiber.Ctx) error {
 		c.Locals("account_id", 1)
 		return c.Next()
 	})
-	handler := New(tx)
+	handler := New(tx, config.Default())
 	app.Get("/pockets/:id", handler.GetPocketById)
 
 	account := Account{
@@ -182,12 +188,12 @@ func TestUpdatePocket(t *testing.T) {
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
 	app.Use(func(c *fiber.Ctx) error {
 		c.Locals("account_id", 1)
 		return c.Next()
 	})
-	handler := New(tx)
+	handler := New(tx, config.Default())
 	app.Put("/pockets/:id", handler.UpdatePocket)
 
 	account := Account{
@@ -234,18 +240,18 @@ func TestDeletePocket(t *testing.T) {
 	// Arrange
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 	assert.NoError(t, err)
-	err = db.AutoMigrate(&Account{}, &Pocket{})
+	err = db.AutoMigrate(&Account{}, &Pocket{}, &PocketTransfer{})
 	assert.NoError(t, err)
 
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
 	app.Use(func(c *fiber.Ctx) error {
 		c.Locals("account_id", 1)
 		return c.Next()
 	})
-	handler := New(tx)
+	handler := New(tx, config.Default())
 	app.Delete("/pockets/:id", handler.DeletePocket)
 
 	account := Account{
@@ -288,12 +294,12 @@ func TestTransfer(t *testing.T) {
 	err = db.AutoMigrate(&Account{}, &Pocket{}, &PocketTransfer{})
 	assert.NoError(t, err)
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
 	app.Use(func(c *fiber.Ctx) error {
 		c.Locals("account_id", 1)
 		return c.Next()
 	})
-	handler := New(db)
+	handler := New(db, config.Default())
 	app.Post("/pockets/transfer", handler.Transfer)
 
 	account := Account{
@@ -351,3 +357,919 @@ func TestTransfer(t *testing.T) {
 	assert.Equal(t, float64(300), updatedToPocket.Balance)
 
 }
+
+func TestGoalProgress(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	target := 1000.0
+	targetDate := now.AddDate(0, 0, 120)
+
+	// Arrange: 400 saved, 300 of it over the last 30 days
+	p := &Pocket{Balance: 400, TargetAmount: &target, TargetDate: &targetDate}
+
+	// Act
+	g := goalProgress(p, 300, 30*24*time.Hour, now)
+
+	// Assert
+	assert.False(t, g.Reached)
+	assert.Equal(t, 40.0, g.Progress)
+	assert.Equal(t, 600.0, g.Remaining)
+	assert.Equal(t, now.AddDate(0, 0, 60), *g.ProjectedCompletion)
+	assert.Equal(t, 152.19, *g.SuggestedMonthlyContribution)
+
+	// Arrange: nothing contributed lately and no target date
+	p = &Pocket{Balance: 400, TargetAmount: &target}
+
+	// Act
+	g = goalProgress(p, 0, 30*24*time.Hour, now)
+
+	// Assert
+	assert.Nil(t, g.ProjectedCompletion)
+	assert.Nil(t, g.SuggestedMonthlyContribution)
+
+	// Arrange: past the target
+	p = &Pocket{Balance: 1200, TargetAmount: &target, TargetDate: &targetDate}
+
+	// Act
+	g = goalProgress(p, 300, 30*24*time.Hour, now)
+
+	// Assert
+	assert.True(t, g.Reached)
+	assert.Equal(t, 100.0, g.Progress)
+	assert.Equal(t, 0.0, g.Remaining)
+	assert.Nil(t, g.SuggestedMonthlyContribution)
+}
+
+func TestGetGoals(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &Pocket{}, &PocketTransfer{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", 1)
+		return c.Next()
+	})
+	handler := New(tx, config.Default())
+	app.Get("/pockets/goals", handler.GetGoals)
+
+	account := Account{
+		Email:   "test@example.com",
+		Balance: 1000,
+	}
+	tx.Create(&account)
+
+	holiday, car := 1000.0, 200.0
+	pockets := []Pocket{
+		{Title: "Holiday", Balance: 400, AccountID: 1, TargetAmount: &holiday},
+		{Title: "Car", Balance: 300, AccountID: 1, TargetAmount: &car},
+		{Title: "No goal", Balance: 100, AccountID: 1},
+	}
+	for i := range pockets {
+		tx.Create(&pockets[i])
+	}
+	tx.Create(&PocketTransfer{From: pockets[2].ID, To: pockets[0].ID, Amount: 100, AccountID: 1})
+
+	req := httptest.NewRequest(http.MethodGet, "/pockets/goals", nil)
+
+	// Act
+	resp, err := app.Test(req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var responseBody GoalSummary
+	err = json.NewDecoder(resp.Body).Decode(&responseBody)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(responseBody.Goals))
+	assert.Equal(t, 1200.0, responseBody.TotalTarget)
+	assert.Equal(t, 600.0, responseBody.TotalSaved)
+	assert.Equal(t, 50.0, responseBody.Progress)
+	assert.Equal(t, 1, responseBody.Reached)
+
+	assert.Equal(t, "Holiday", responseBody.Goals[0].Title)
+	assert.Equal(t, 40.0, responseBody.Goals[0].Goal.Progress)
+	assert.NotNil(t, responseBody.Goals[0].Goal.ProjectedCompletion)
+	assert.True(t, responseBody.Goals[1].Goal.Reached)
+}
+
+func TestCheckGoal(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &Pocket{}, &PocketTransfer{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	var reached []event.Event
+	unsubscribe := event.Subscribe(func(e event.Event) {
+		if e.Type == event.PocketGoalReached {
+			reached = append(reached, e)
+		}
+	})
+	defer unsubscribe()
+
+	target := 250.0
+	to := Pocket{Title: "Goal Pocket", Balance: 200, AccountID: 1, TargetAmount: &target}
+	tx.Create(&to)
+
+	// Act
+	to.Balance = 250
+	tx.Save(&to)
+	s := NewPocketService(NewStore(tx), config.Default().Policy)
+	s.checkGoal(context.Background(), &to)
+
+	// Assert
+	assert.Equal(t, 1, len(reached))
+	assert.Equal(t, uint(1), reached[0].AccountID)
+
+	var updated Pocket
+	tx.First(&updated, to.ID)
+	assert.NotNil(t, updated.GoalReachedAt)
+
+	// Act: further deposits do not announce the goal again
+	s.checkGoal(context.Background(), &updated)
+
+	// Assert
+	assert.Equal(t, 1, len(reached))
+}
+
+func TestDepositAndWithdraw(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &Pocket{}, &PocketTransfer{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	account := Account{Email: "deposit@example.com", Balance: 1000}
+	tx.Create(&account)
+	p := Pocket{Title: "Holiday", Balance: 200, AccountID: account.ID}
+	tx.Create(&p)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(account.ID))
+		return c.Next()
+	})
+	handler := New(tx, config.Default())
+	app.Post("/pockets/:id/deposit", handler.Deposit)
+	app.Post("/pockets/:id/withdraw", handler.Withdraw)
+	app.Get("/pockets/:id/transfers", handler.GetTransfers)
+
+	post := func(action string, amount float64) *http.Response {
+		jsonPayload, _ := json.Marshal(PocketAmountRequest{Amount: amount})
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/pockets/%d/%s", p.ID, action), bytes.NewBuffer(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	balances := func() (float64, float64) {
+		var a Account
+		tx.First(&a, account.ID)
+		var pk Pocket
+		tx.First(&pk, p.ID)
+		return a.Balance, pk.Balance
+	}
+
+	// Act & Assert
+	resp := post("deposit", 300.5)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var deposit PocketTransfer
+	err = json.NewDecoder(resp.Body).Decode(&deposit)
+	assert.NoError(t, err)
+	assert.Equal(t, TransferTypeDeposit, deposit.Type)
+	assert.Equal(t, p.ID, deposit.To)
+	assert.Equal(t, uint(0), deposit.From)
+
+	accBl, pocketBl := balances()
+	assert.Equal(t, 699.5, accBl)
+	assert.Equal(t, 500.5, pocketBl)
+
+	resp = post("withdraw", 100.25)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	accBl, pocketBl = balances()
+	assert.Equal(t, 799.75, accBl)
+	assert.Equal(t, 400.25, pocketBl)
+
+	resp = post("deposit", 1000)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = post("withdraw", 500)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = post("withdraw", 0)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	accBl, pocketBl = balances()
+	assert.Equal(t, 799.75, accBl)
+	assert.Equal(t, 400.25, pocketBl)
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/pockets/%d/transfers", p.ID), nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var history []PocketTransfer
+	err = json.NewDecoder(resp.Body).Decode(&history)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, TransferTypeWithdrawal, history[0].Type)
+	assert.Equal(t, p.ID, history[0].From)
+	assert.Equal(t, 100.25, history[0].Amount)
+	assert.Equal(t, TransferTypeDeposit, history[1].Type)
+}
+
+func TestLockedPocket(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &Pocket{}, &PocketTransfer{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	account := Account{Email: "locked@example.com", Balance: 10000}
+	tx.Create(&account)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(account.ID))
+		return c.Next()
+	})
+	handler := New(tx, config.Default())
+	app.Post("/pockets", handler.CreatePocket)
+	app.Post("/pockets/:id/deposit", handler.Deposit)
+	app.Post("/pockets/:id/withdraw", handler.Withdraw)
+	app.Delete("/pockets/:id", handler.DeletePocket)
+
+	send := func(method, url string, body interface{}) *http.Response {
+		jsonPayload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	past := time.Now().Add(-time.Hour)
+	resp := send(http.MethodPost, "/pockets", PocketCreate{Title: "Too late", Balance: 5000, Type: TypeLocked, MaturityDate: &past})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	maturity := time.Now().AddDate(0, 6, 0)
+	resp = send(http.MethodPost, "/pockets", PocketCreate{Title: "Time deposit", Balance: 5000, Type: TypeLocked, MaturityDate: &maturity})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var p Pocket
+	tx.Where("account_id = ?", account.ID).Last(&p)
+	assert.Equal(t, TypeLocked, p.Type)
+	assert.Equal(t, MaturityRelease, p.MaturityAction)
+	assert.Equal(t, 1.0, p.BonusRate)
+	assert.Equal(t, 2.0, p.PenaltyRate)
+	url := fmt.Sprintf("/pockets/%d", p.ID)
+
+	// Act & Assert: locked until maturity
+	resp = send(http.MethodPost, url+"/deposit", PocketAmountRequest{Amount: 100})
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = send(http.MethodPost, url+"/withdraw", PocketAmountRequest{Amount: 1000})
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = send(http.MethodDelete, url, nil)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+	// Act & Assert: early withdrawal pays 2%
+	resp = send(http.MethodPost, url+"/withdraw", PocketAmountRequest{Amount: 1000, EarlyWithdrawal: true})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var updated Account
+	tx.First(&updated, account.ID)
+	assert.Equal(t, 5980.0, updated.Balance)
+
+	var penalty PocketTransfer
+	tx.Where("type = ? AND \"from\" = ?", TransferTypePenalty, p.ID).First(&penalty)
+	assert.Equal(t, 20.0, penalty.Amount)
+
+	resp = send(http.MethodDelete, url+"?early_withdrawal=true", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	tx.First(&updated, account.ID)
+	assert.Equal(t, 9900.0, updated.Balance)
+}
+
+func TestMature(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &Pocket{}, &PocketTransfer{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	account := Account{Email: "mature@example.com", Balance: 0}
+	tx.Create(&account)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	yearEnd := start.AddDate(1, 0, 0)
+	halfYear := start.AddDate(0, 0, 182)
+
+	release := Pocket{Title: "Release", Balance: 10000, AccountID: account.ID, Type: TypeLocked,
+		TermStart: &start, MaturityDate: &yearEnd, MaturityAction: MaturityRelease, BonusRate: 1.5}
+	tx.Create(&release)
+	rollover := Pocket{Title: "Rollover", Balance: 10000, AccountID: account.ID, Type: TypeLocked,
+		TermStart: &start, MaturityDate: &halfYear, MaturityAction: MaturityRollover, BonusRate: 1.5}
+	tx.Create(&rollover)
+
+	// Act: the rollover pocket has matured, the other has not
+	now := halfYear.Add(time.Hour)
+	err = Mature(tx, now)
+	assert.NoError(t, err)
+	err = Mature(tx, now)
+	assert.NoError(t, err)
+
+	// Assert: 10000 * 1.5% * 182/365 = 74.79
+	var rolled Pocket
+	tx.First(&rolled, rollover.ID)
+	assert.Equal(t, TypeLocked, rolled.Type)
+	assert.Equal(t, 10074.79, rolled.Balance)
+	assert.True(t, halfYear.Equal(*rolled.TermStart))
+	assert.True(t, halfYear.AddDate(0, 0, 182).Equal(*rolled.MaturityDate))
+
+	// Act: a year in, both have matured
+	err = Mature(tx, yearEnd.Add(time.Hour))
+	assert.NoError(t, err)
+
+	// Assert: 10000 * 1.5% = 150 and 10074.79 * 1.5% * 182/365 = 75.35
+	var released Pocket
+	tx.First(&released, release.ID)
+	assert.Equal(t, TypeRegular, released.Type)
+	assert.Equal(t, 0.0, released.Balance)
+
+	tx.First(&rolled, rollover.ID)
+	assert.Equal(t, 10150.14, rolled.Balance)
+
+	var updated Account
+	tx.First(&updated, account.ID)
+	assert.Equal(t, 10150.0, updated.Balance)
+
+	var movements []PocketTransfer
+	tx.Where(&PocketTransfer{AccountID: account.ID}).Order("id").Find(&movements)
+	assert.Equal(t, 4, len(movements))
+	assert.Equal(t, TransferTypeBonusInterest, movements[0].Type)
+	assert.Equal(t, 74.79, movements[0].Amount)
+}
+
+func TestSharedPocket(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&Account{}, &accountRef{}, &Pocket{}, &PocketTransfer{}, &PocketMember{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	owner := Account{Email: "owner@example.com", Balance: 1000}
+	tx.Create(&owner)
+	friend := Account{Email: "friend@example.com", Balance: 1000}
+	tx.Create(&friend)
+	tx.Model(&accountRef{ID: friend.ID}).Updates(map[string]interface{}{"account_number": "1112223334", "alias": "friend"})
+	guest := Account{Email: "guest@example.com", Balance: 1000}
+	tx.Create(&guest)
+	tx.Model(&accountRef{ID: guest.ID}).Update("account_number", "5556667778")
+
+	trip := Pocket{Title: "Trip", Balance: 0, AccountID: owner.ID}
+	tx.Create(&trip)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		id, _ := strconv.Atoi(c.Get("X-Account"))
+		c.Locals("account_id", id)
+		return c.Next()
+	})
+	handler := New(tx, config.Default())
+	app.Get("/pockets", handler.GetAllPockets)
+	app.Get("/pockets/invitations", handler.GetInvitations)
+	app.Post("/pockets/invitations/:id/accept", handler.AcceptInvitation)
+	app.Post("/pockets/:id/deposit", handler.Deposit)
+	app.Post("/pockets/:id/withdraw", handler.Withdraw)
+	app.Get("/pockets/:id/members", handler.GetMembers)
+	app.Post("/pockets/:id/members", handler.InviteMember)
+
+	send := func(as uint, method, url string, body interface{}) *http.Response {
+		jsonPayload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Account", strconv.Itoa(int(as)))
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+	url := fmt.Sprintf("/pockets/%d", trip.ID)
+
+	// Act & Assert: invitations
+	resp := send(owner.ID, http.MethodPost, url+"/members", InviteRequest{Alias: "friend", Role: RoleContributor, WithdrawLimit: 300})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = send(owner.ID, http.MethodPost, url+"/members", InviteRequest{AccountNumber: "5556667778", Role: RoleViewer})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = send(owner.ID, http.MethodPost, url+"/members", InviteRequest{Alias: "friend", Role: RoleViewer})
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	resp = send(friend.ID, http.MethodPost, url+"/members", InviteRequest{AccountNumber: "5556667778", Role: RoleViewer})
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	// Not a member until the invitation is accepted
+	resp = send(friend.ID, http.MethodPost, url+"/deposit", PocketAmountRequest{Amount: 500})
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	for _, as := range []uint{friend.ID, guest.ID} {
+		resp = send(as, http.MethodGet, "/pockets/invitations", nil)
+		var invitations []PocketMember
+		err = json.NewDecoder(resp.Body).Decode(&invitations)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(invitations))
+		resp = send(as, http.MethodPost, fmt.Sprintf("/pockets/invitations/%d/accept", invitations[0].ID), nil)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+
+	// Act & Assert: roles and allowance
+	resp = send(friend.ID, http.MethodPost, url+"/deposit", PocketAmountRequest{Amount: 500})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = send(owner.ID, http.MethodPost, url+"/deposit", PocketAmountRequest{Amount: 200})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = send(guest.ID, http.MethodPost, url+"/deposit", PocketAmountRequest{Amount: 100})
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+	resp = send(friend.ID, http.MethodPost, url+"/withdraw", PocketAmountRequest{Amount: 200})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	resp = send(friend.ID, http.MethodPost, url+"/withdraw", PocketAmountRequest{Amount: 150})
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = send(owner.ID, http.MethodPost, url+"/withdraw", PocketAmountRequest{Amount: 400})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var balances []Account
+	tx.Where("id IN ?", []uint{owner.ID, friend.ID}).Order("id").Find(&balances)
+	assert.Equal(t, 1200.0, balances[0].Balance)
+	assert.Equal(t, 700.0, balances[1].Balance)
+
+	resp = send(guest.ID, http.MethodGet, "/pockets", nil)
+	var pockets []PocketResponse
+	err = json.NewDecoder(resp.Body).Decode(&pockets)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pockets))
+	assert.Equal(t, 100.0, pockets[0].Balance)
+
+	resp = send(guest.ID, http.MethodGet, url+"/members", nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var members []MemberResponse
+	err = json.NewDecoder(resp.Body).Decode(&members)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(members))
+	assert.Equal(t, RoleOwner, members[0].Role)
+	assert.Equal(t, 200.0, members[0].Contributed)
+	assert.Equal(t, 400.0, members[0].Withdrawn)
+	assert.Equal(t, "1112223334", members[1].AccountNumber)
+	assert.Equal(t, 500.0, members[1].Contributed)
+	assert.Equal(t, 200.0, members[1].Withdrawn)
+	assert.Equal(t, 300.0, members[1].WithdrawLimit)
+}
+
+// fakeStore keeps pockets, members, movements and main balances in memory. A
+// transaction works on a copy that replaces the store when it commits.
+type fakeStore struct {
+	pockets   map[uint]Pocket
+	members   []PocketMember
+	transfers []PocketTransfer
+	mains     map[uint]float64
+	accounts  []accountRef
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{pockets: map[uint]Pocket{}, mains: map[uint]float64{}}
+}
+
+func (s *fakeStore) Pockets() PocketRepository { return fakePockets{s} }
+
+func (s *fakeStore) Transaction(ctx context.Context, fn func(Store) error) error {
+	tx := newFakeStore()
+	for id, p := range s.pockets {
+		tx.pockets[id] = p
+	}
+	for id, bl := range s.mains {
+		tx.mains[id] = bl
+	}
+	tx.members = append(tx.members, s.members...)
+	tx.transfers = append(tx.transfers, s.transfers...)
+	tx.accounts = s.accounts
+	if err := fn(tx); err != nil {
+		return err
+	}
+	*s = *tx
+	return nil
+}
+
+type fakePockets struct{ *fakeStore }
+
+func (r fakePockets) Get(ctx context.Context, id uint) (*Pocket, error) {
+	p, ok := r.pockets[id]
+	if !ok {
+		return nil, ErrPocketNotFound
+	}
+	return &p, nil
+}
+
+func (r fakePockets) Lock(ctx context.Context, ids ...uint) (map[uint]*Pocket, error) {
+	locked := map[uint]*Pocket{}
+	for _, id := range ids {
+		p, ok := r.pockets[id]
+		if !ok {
+			return nil, ErrPocketNotFound
+		}
+		locked[id] = &p
+	}
+	return locked, nil
+}
+
+func (r fakePockets) GetOwned(ctx context.Context, id, accountID uint) (*Pocket, error) {
+	p, ok := r.pockets[id]
+	if !ok || p.AccountID != accountID {
+		return nil, ErrPocketNotFound
+	}
+	return &p, nil
+}
+
+func (r fakePockets) List(ctx context.Context, accountID uint) ([]Pocket, error) {
+	pockets := []Pocket{}
+	for _, p := range r.pockets {
+		if p.AccountID == accountID {
+			pockets = append(pockets, p)
+			continue
+		}
+		for _, m := range r.members {
+			if m.PocketID == p.ID && m.AccountID == accountID && m.Status == MemberActive {
+				pockets = append(pockets, p)
+			}
+		}
+	}
+	return pockets, nil
+}
+
+func (r fakePockets) Goals(ctx context.Context, accountID uint) ([]Pocket, error) {
+	pockets := []Pocket{}
+	for _, p := range r.pockets {
+		if p.AccountID == accountID && p.TargetAmount != nil {
+			pockets = append(pockets, p)
+		}
+	}
+	return pockets, nil
+}
+
+func (r fakePockets) Update(ctx context.Context, p *Pocket) error {
+	r.pockets[p.ID] = *p
+	return nil
+}
+
+func (r fakePockets) Create(ctx context.Context, p *Pocket) error {
+	p.ID = uint(len(r.pockets) + 1)
+	r.pockets[p.ID] = *p
+	return nil
+}
+
+func (r fakePockets) UpdateBalance(ctx context.Context, p *Pocket) error {
+	stored := r.pockets[p.ID]
+	stored.Balance = p.Balance
+	r.pockets[p.ID] = stored
+	return nil
+}
+
+func (r fakePockets) MarkGoalReached(ctx context.Context, p *Pocket, at time.Time) error {
+	stored := r.pockets[p.ID]
+	stored.GoalReachedAt = &at
+	r.pockets[p.ID] = stored
+	return nil
+}
+
+func (r fakePockets) Delete(ctx context.Context, p *Pocket) error {
+	delete(r.pockets, p.ID)
+	return nil
+}
+
+func (r fakePockets) Member(ctx context.Context, pocketID, accountID uint) (*PocketMember, error) {
+	for _, m := range r.members {
+		if m.PocketID == pocketID && m.AccountID == accountID {
+			return &m, nil
+		}
+	}
+	return nil, ErrMemberNotFound
+}
+
+func (r fakePockets) Members(ctx context.Context, pocketID uint) ([]PocketMember, error) {
+	members := []PocketMember{}
+	for _, m := range r.members {
+		if m.PocketID == pocketID {
+			members = append(members, m)
+		}
+	}
+	return members, nil
+}
+
+func (r fakePockets) Invitations(ctx context.Context, accountID uint) ([]PocketMember, error) {
+	invitations := []PocketMember{}
+	for _, m := range r.members {
+		if m.AccountID == accountID && m.Status == MemberInvited {
+			invitations = append(invitations, m)
+		}
+	}
+	return invitations, nil
+}
+
+func (r fakePockets) AddMember(ctx context.Context, m *PocketMember) error {
+	m.ID = uint(len(r.members) + 1)
+	r.members = append(r.members, *m)
+	return nil
+}
+
+func (r fakePockets) UpdateMember(ctx context.Context, m *PocketMember) error {
+	for i := range r.members {
+		if r.members[i].ID == m.ID {
+			r.members[i] = *m
+		}
+	}
+	return nil
+}
+
+func (r fakePockets) RemoveMember(ctx context.Context, m *PocketMember) error {
+	for i := range r.members {
+		if r.members[i].ID == m.ID {
+			r.members = append(r.members[:i], r.members[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r fakePockets) Account(ctx context.Context, cond accountRef) (*accountRef, error) {
+	for _, a := range r.accounts {
+		if (cond.ID == 0 || a.ID == cond.ID) && (cond.AccountNumber == "" || a.AccountNumber == cond.AccountNumber) &&
+			(cond.Alias == nil || (a.Alias != nil && *a.Alias == *cond.Alias)) {
+			return &a, nil
+		}
+	}
+	return nil, ErrAccountNotFound
+}
+
+func (r fakePockets) Contributed(ctx context.Context, pocketID, accountID uint) (float64, error) {
+	contributed := 0.0
+	for _, t := range r.transfers {
+		if t.To == pocketID && t.AccountID == accountID {
+			contributed += t.Amount
+		}
+	}
+	return contributed, nil
+}
+
+func (r fakePockets) ReceivedSince(ctx context.Context, pocketID uint, since time.Time) (float64, error) {
+	received := 0.0
+	for _, t := range r.transfers {
+		if t.To == pocketID && !t.CreatedAt.Before(since) {
+			received += t.Amount
+		}
+	}
+	return received, nil
+}
+
+func (r fakePockets) History(ctx context.Context, pocketID uint) ([]PocketTransfer, error) {
+	history := []PocketTransfer{}
+	for i := len(r.transfers) - 1; i >= 0; i-- {
+		if t := r.transfers[i]; t.From == pocketID || t.To == pocketID {
+			history = append(history, t)
+		}
+	}
+	return history, nil
+}
+
+func (r fakePockets) Withdrawn(ctx context.Context, pocketID, accountID uint) (float64, error) {
+	withdrawn := 0.0
+	for _, t := range r.transfers {
+		if t.From == pocketID && t.AccountID == accountID {
+			withdrawn += t.Amount
+		}
+	}
+	return withdrawn, nil
+}
+
+func (r fakePockets) Record(ctx context.Context, t *PocketTransfer) error {
+	t.ID = uint(len(r.transfers) + 1)
+	r.transfers = append(r.transfers, *t)
+	return nil
+}
+
+func (r fakePockets) MainBalance(ctx context.Context, accountID uint) (float64, error) {
+	return r.mains[accountID], nil
+}
+
+func (r fakePockets) SetMainBalance(ctx context.Context, accountID uint, balance float64) error {
+	r.mains[accountID] = balance
+	return nil
+}
+
+func TestPocketService(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create", func(t *testing.T) {
+		// Arrange
+		store := newFakeStore()
+		store.mains[1] = 1000
+		s := NewPocketService(store, config.Default().Policy)
+
+		// Act
+		p, err := s.Create(ctx, 1, &PocketCreate{Title: "Trip", Balance: 300})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 700.0, store.mains[1])
+		assert.Equal(t, 300.0, store.pockets[p.ID].Balance)
+		assert.Equal(t, []PocketTransfer{{ID: 1, Type: TransferTypeDeposit, To: p.ID, Amount: 300, AccountID: 1}}, store.transfers)
+
+		// Act: more than the main balance
+		_, err = s.Create(ctx, 1, &PocketCreate{Title: "Car", Balance: 800})
+
+		// Assert
+		assert.ErrorIs(t, err, ErrInsufficientAccountBalance)
+		assert.Equal(t, 700.0, store.mains[1])
+		assert.Len(t, store.pockets, 1)
+
+		// Act: a locked pocket without a maturity date
+		_, err = s.Create(ctx, 1, &PocketCreate{Title: "Term", Balance: 100, Type: TypeLocked})
+
+		// Assert
+		assert.ErrorIs(t, err, ErrLockedTerms)
+	})
+
+	t.Run("move", func(t *testing.T) {
+		// Arrange
+		store := newFakeStore()
+		store.mains[1], store.mains[2] = 500, 0
+		store.pockets[1] = Pocket{ID: 1, AccountID: 1, Balance: 100}
+		store.members = []PocketMember{
+			{PocketID: 1, AccountID: 2, Role: RoleContributor, Status: MemberActive, WithdrawLimit: 50},
+			{PocketID: 1, AccountID: 3, Role: RoleViewer, Status: MemberActive},
+		}
+		s := NewPocketService(store, config.Default().Policy)
+
+		// Act
+		_, depositErr := s.Move(ctx, 1, 1, TransferTypeDeposit, 200, false)
+		_, withdrawErr := s.Move(ctx, 1, 2, TransferTypeWithdrawal, 40, false)
+		_, overErr := s.Move(ctx, 1, 2, TransferTypeWithdrawal, 20, false)
+		_, viewerErr := s.Move(ctx, 1, 3, TransferTypeDeposit, 10, false)
+		_, strangerErr := s.Move(ctx, 1, 4, TransferTypeDeposit, 10, false)
+
+		// Assert
+		assert.NoError(t, depositErr)
+		assert.NoError(t, withdrawErr)
+		assert.ErrorIs(t, overErr, ErrAllowanceExceeded)
+		assert.ErrorIs(t, viewerErr, ErrForbidden)
+		assert.ErrorIs(t, strangerErr, ErrPocketNotFound)
+		assert.Equal(t, 300.0, store.mains[1])
+		assert.Equal(t, 40.0, store.mains[2])
+		assert.Equal(t, 260.0, store.pockets[1].Balance)
+	})
+
+	t.Run("transfer", func(t *testing.T) {
+		// Arrange
+		maturity := time.Now().AddDate(0, 6, 0)
+		store := newFakeStore()
+		store.pockets[1] = Pocket{ID: 1, AccountID: 1, Balance: 100}
+		store.pockets[2] = Pocket{ID: 2, AccountID: 1, Balance: 0}
+		store.pockets[3] = Pocket{ID: 3, AccountID: 1, Balance: 100, Type: TypeLocked, MaturityDate: &maturity, PenaltyRate: 10}
+		store.pockets[4] = Pocket{ID: 4, AccountID: 2, Balance: 100}
+		s := NewPocketService(store, config.Default().Policy)
+
+		cases := []struct {
+			name string
+			req  PocketTransferRequest
+			want error
+		}{
+			{"not my pocket", PocketTransferRequest{From: 4, To: 2, Amount: 10}, ErrFromPocketNotFound},
+			{"unknown target", PocketTransferRequest{From: 1, To: 9, Amount: 10}, ErrToPocketNotFound},
+			{"into a locked pocket", PocketTransferRequest{From: 1, To: 3, Amount: 10}, ErrLockedDeposit},
+			{"out of a locked pocket", PocketTransferRequest{From: 3, To: 2, Amount: 10}, ErrPocketLocked},
+			{"too much", PocketTransferRequest{From: 1, To: 2, Amount: 150}, ErrInsufficientPocketBalance},
+			{"to the same pocket", PocketTransferRequest{From: 1, To: 1, Amount: 10}, ErrSamePocket},
+		}
+		for _, c := range cases {
+			// Act
+			_, err := s.Transfer(ctx, 1, &c.req)
+
+			// Assert
+			assert.ErrorIs(t, err, c.want, c.name)
+		}
+		assert.Empty(t, store.transfers)
+		assert.Equal(t, 100.0, store.pockets[1].Balance)
+
+		// Act: breaking the locked pocket early
+		tr, err := s.Transfer(ctx, 1, &PocketTransferRequest{From: 3, To: 2, Amount: 50, EarlyWithdrawal: true})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 45.0, tr.Amount)
+		assert.Equal(t, 50.0, store.pockets[3].Balance)
+		assert.Equal(t, 45.0, store.pockets[2].Balance)
+		assert.Len(t, store.transfers, 2)
+	})
+
+	t.Run("members", func(t *testing.T) {
+		// Arrange
+		alias := "friend"
+		store := newFakeStore()
+		store.accounts = []accountRef{{ID: 1, AccountNumber: "1000000001"}, {ID: 2, AccountNumber: "1000000002", Alias: &alias}}
+		store.pockets[1] = Pocket{ID: 1, AccountID: 1}
+		s := NewPocketService(store, config.Default().Policy)
+		p := &Pocket{ID: 1, AccountID: 1}
+
+		// Act
+		invited, inviteErr := s.Invite(ctx, p, 1, &InviteRequest{Alias: "friend", Role: RoleViewer})
+		_, againErr := s.Invite(ctx, p, 1, &InviteRequest{AccountNumber: "1000000002", Role: RoleViewer})
+		_, ownerErr := s.Invite(ctx, p, 1, &InviteRequest{AccountNumber: "1000000001", Role: RoleViewer})
+		_, unknownErr := s.Invite(ctx, p, 1, &InviteRequest{AccountNumber: "1999999999", Role: RoleViewer})
+		_, strangerErr := s.Accept(ctx, invited.ID, 3)
+		accepted, acceptErr := s.Accept(ctx, invited.ID, 2)
+		_, updateErr := s.UpdateMember(ctx, p, invited.ID, &MemberUpdate{Role: RoleContributor})
+		members, membersErr := s.Members(ctx, p)
+		_, missingErr := s.UpdateMember(ctx, p, 9, &MemberUpdate{Role: RoleViewer})
+		removeErr := s.RemoveMember(ctx, p, 2, RoleContributor, invited.ID)
+
+		// Assert
+		assert.NoError(t, inviteErr)
+		assert.Equal(t, MemberInvited, invited.Status)
+		assert.ErrorIs(t, againErr, ErrAlreadyMember)
+		assert.ErrorIs(t, ownerErr, ErrAlreadyMember)
+		assert.ErrorIs(t, unknownErr, ErrAccountNotFound)
+		assert.ErrorIs(t, strangerErr, ErrInvitationNotFound)
+		assert.NoError(t, acceptErr)
+		assert.Equal(t, MemberActive, accepted.Status)
+		assert.NoError(t, updateErr)
+		assert.NoError(t, membersErr)
+		assert.Len(t, members, 2)
+		assert.Equal(t, RoleOwner, members[0].Role)
+		assert.Equal(t, "1000000002", members[1].AccountNumber)
+		assert.Equal(t, RoleContributor, members[1].Role)
+		assert.ErrorIs(t, missingErr, ErrMemberNotFound)
+		assert.NoError(t, removeErr)
+		assert.Empty(t, store.members)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		// Arrange
+		store := newFakeStore()
+		store.mains[1] = 10
+		store.pockets[1] = Pocket{ID: 1, AccountID: 1, Balance: 90}
+		s := NewPocketService(store, config.Default().Policy)
+
+		// Act
+		otherErr := s.Delete(ctx, 1, 2, false)
+		err := s.Delete(ctx, 1, 1, false)
+
+		// Assert
+		assert.ErrorIs(t, otherErr, ErrPocketNotFound)
+		assert.NoError(t, err)
+		assert.Empty(t, store.pockets)
+		assert.Equal(t, 100.0, store.mains[1])
+		assert.Equal(t, TransferTypeWithdrawal, store.transfers[0].Type)
+	})
+
+	t.Run("delete shared", func(t *testing.T) {
+		// Arrange
+		store := newFakeStore()
+		store.mains[1], store.mains[2] = 0, 0
+		store.pockets[1] = Pocket{ID: 1, AccountID: 1, Balance: 100}
+		store.members = []PocketMember{{PocketID: 1, AccountID: 2, Role: RoleContributor, Status: MemberActive, WithdrawLimit: 50}}
+		store.transfers = []PocketTransfer{
+			{ID: 1, Type: TransferTypeDeposit, To: 1, Amount: 70, AccountID: 1},
+			{ID: 2, Type: TransferTypeDeposit, To: 1, Amount: 30, AccountID: 2},
+		}
+		s := NewPocketService(store, config.Default().Policy)
+
+		// Act
+		fundedErr := s.Delete(ctx, 1, 1, false)
+		_, withdrawErr := s.Move(ctx, 1, 2, TransferTypeWithdrawal, 30, false)
+		err := s.Delete(ctx, 1, 1, false)
+
+		// Assert
+		assert.ErrorIs(t, fundedErr, ErrMembersHoldFunds)
+		assert.NoError(t, withdrawErr)
+		assert.NoError(t, err)
+		assert.Empty(t, store.pockets)
+		assert.Equal(t, 70.0, store.mains[1])
+		assert.Equal(t, 30.0, store.mains[2])
+	})
+}
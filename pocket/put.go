@@ -1,12 +1,11 @@
 package pocket
 
 import (
-	"errors"
+	"context"
 	"strconv"
 
-	"github.com/go-playground/validator"
+	"github.com/arthit666/make_app/apperr"
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 // @Summary Update a pocket
@@ -20,39 +19,54 @@ import (
 // @Security  Bearer
 // @Router /pockets/{id} [put]
 func (h *handler) UpdatePocket(c *fiber.Ctx) error {
-	id, err := strconv.Atoi(c.Params("id"))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.ErrBadRequest)
+	if _, err := strconv.Atoi(c.Params("id")); err != nil {
+		return apperr.BadRequest("invalid id")
 	}
 	pr := &PocketUpdate{}
 	if err := c.BodyParser(pr); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.ErrBadRequest)
+		return apperr.BadRequest("invalid request body")
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(pr); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(Err{Message: "payload invalid: " + err.Error()})
+	if err := apperr.Validate(pr); err != nil {
+		return err
 	}
 
 	acc := c.Locals("account_id").(int)
-	accStr := strconv.Itoa(acc)
-
-	p, err := getById(c.Params("id"), accStr, h)
+	p, _, err := h.access(c, uint(acc), RoleOwner)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(Err{Message: "pocket not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Message: "error: " + err.Error()})
+		return pocketError(err)
 	}
 
-	p.ID = uint(id)
-	p.Title = pr.Title
-	p.Description = pr.Description
-
-	tx := h.DB.Model(p).Updates(*p)
-	if tx.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(Err{Message: "update error: " + tx.Error.Error()})
+	if err := h.pockets.Update(c.UserContext(), p, pr); err != nil {
+		return pocketError(err)
 	}
+
 	return c.Status(fiber.StatusOK).JSON(SuccessResponse{Message: "update pocket success"})
+}
+
+// Update changes pocket p as req says. Only what req sets is changed, and a
+// new target amount has to be reached all over again.
+func (s *PocketService) Update(ctx context.Context, p *Pocket, req *PocketUpdate) error {
+	if req.Title != "" {
+		p.Title = req.Title
+	}
+	if req.Description != nil {
+		p.Description = req.Description
+	}
+	if req.MaturityAction != "" && p.Type == TypeLocked {
+		p.MaturityAction = req.MaturityAction
+	}
+	if req.TargetDate != nil {
+		p.TargetDate = req.TargetDate
+	}
+	if req.TargetAmount != nil {
+		p.TargetAmount = req.TargetAmount
+		p.GoalReachedAt = nil
+	}
+	if err := s.store.Pockets().Update(ctx, p); err != nil {
+		return err
+	}
 
+	s.checkGoal(ctx, p)
+	return nil
 }
@@ -0,0 +1,260 @@
+package pocket
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arthit666/make_app/balance"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PocketRepository stores pockets, their members and every movement in and
+// out of them, along with the main balance of the accounts they belong to.
+// A missing pocket is ErrPocketNotFound and a missing member
+// ErrMemberNotFound.
+type PocketRepository interface {
+	Get(ctx context.Context, id uint) (*Pocket, error)
+	// Lock loads pockets ids and locks their rows until the transaction
+	// ends, in ID order so two transactions cannot deadlock.
+	Lock(ctx context.Context, ids ...uint) (map[uint]*Pocket, error)
+	// GetOwned is pocket id when it belongs to account accountID.
+	GetOwned(ctx context.Context, id, accountID uint) (*Pocket, error)
+	// List is every pocket account accountID owns or is an active member of.
+	List(ctx context.Context, accountID uint) ([]Pocket, error)
+	// Goals is every pocket of account accountID with a savings goal.
+	Goals(ctx context.Context, accountID uint) ([]Pocket, error)
+	Create(ctx context.Context, p *Pocket) error
+	// Update saves the title, description, maturity action and goal of p.
+	Update(ctx context.Context, p *Pocket) error
+	UpdateBalance(ctx context.Context, p *Pocket) error
+	MarkGoalReached(ctx context.Context, p *Pocket, at time.Time) error
+	// Delete removes p and its members.
+	Delete(ctx context.Context, p *Pocket) error
+
+	// Member is the membership of account accountID in pocket pocketID,
+	// whatever its status.
+	Member(ctx context.Context, pocketID, accountID uint) (*PocketMember, error)
+	// Members is every member of pocket pocketID, whatever their status,
+	// in the order they were invited.
+	Members(ctx context.Context, pocketID uint) ([]PocketMember, error)
+	// Invitations is every invitation waiting for account accountID.
+	Invitations(ctx context.Context, accountID uint) ([]PocketMember, error)
+	AddMember(ctx context.Context, m *PocketMember) error
+	// UpdateMember saves the role, withdraw limit and status of m.
+	UpdateMember(ctx context.Context, m *PocketMember) error
+	RemoveMember(ctx context.Context, m *PocketMember) error
+	// Account is the account matching the fields set in cond,
+	// ErrAccountNotFound when there is none.
+	Account(ctx context.Context, cond accountRef) (*accountRef, error)
+
+	// Contributed sums what account accountID put into pocket pocketID.
+	Contributed(ctx context.Context, pocketID, accountID uint) (float64, error)
+	// Withdrawn sums what account accountID took out of pocket pocketID.
+	// Early withdrawal penalties count as taken out.
+	Withdrawn(ctx context.Context, pocketID, accountID uint) (float64, error)
+	// ReceivedSince sums everything moved into pocket pocketID since since.
+	ReceivedSince(ctx context.Context, pocketID uint, since time.Time) (float64, error)
+	// History is every movement in or out of pocket pocketID, newest first.
+	History(ctx context.Context, pocketID uint) ([]PocketTransfer, error)
+	Record(ctx context.Context, t *PocketTransfer) error
+
+	// MainBalance reads the main balance of account accountID and locks it
+	// until the transaction ends. It is locked before any pocket is.
+	MainBalance(ctx context.Context, accountID uint) (float64, error)
+	SetMainBalance(ctx context.Context, accountID uint, balance float64) error
+}
+
+// Store hands out the repositories of this package. Transaction runs fn
+// with a Store whose repositories share one transaction, committed when fn
+// returns nil.
+type Store interface {
+	Pockets() PocketRepository
+	Transaction(ctx context.Context, fn func(Store) error) error
+}
+
+// NewStore is the Store of db.
+func NewStore(db *gorm.DB) Store {
+	return gormStore{db}
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func (s gormStore) Pockets() PocketRepository { return gormPockets(s) }
+
+func (s gormStore) Transaction(ctx context.Context, fn func(Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(gormStore{tx})
+	})
+}
+
+type gormPockets struct {
+	db *gorm.DB
+}
+
+func (r gormPockets) Get(ctx context.Context, id uint) (*Pocket, error) {
+	p := &Pocket{}
+	err := r.db.WithContext(ctx).First(p, id).Error
+	return p, notFound(err, ErrPocketNotFound)
+}
+
+func (r gormPockets) Lock(ctx context.Context, ids ...uint) (map[uint]*Pocket, error) {
+	pockets := []Pocket{}
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Order("id").Find(&pockets, ids).Error
+	if err != nil {
+		return nil, err
+	}
+	locked := map[uint]*Pocket{}
+	for i := range pockets {
+		locked[pockets[i].ID] = &pockets[i]
+	}
+	for _, id := range ids {
+		if locked[id] == nil {
+			return nil, ErrPocketNotFound
+		}
+	}
+	return locked, nil
+}
+
+func (r gormPockets) GetOwned(ctx context.Context, id, accountID uint) (*Pocket, error) {
+	p := &Pocket{}
+	err := r.db.WithContext(ctx).Where("account_id = ?", accountID).First(p, id).Error
+	return p, notFound(err, ErrPocketNotFound)
+}
+
+func (r gormPockets) List(ctx context.Context, accountID uint) ([]Pocket, error) {
+	p := []Pocket{}
+	db := r.db.WithContext(ctx)
+	shared := db.Model(&PocketMember{}).Select("pocket_id").Where(&PocketMember{AccountID: accountID, Status: MemberActive})
+	err := db.Where("account_id = ?", accountID).Or("id IN (?)", shared).Find(&p).Error
+	return p, err
+}
+
+func (r gormPockets) Goals(ctx context.Context, accountID uint) ([]Pocket, error) {
+	p := []Pocket{}
+	err := r.db.WithContext(ctx).Where("account_id = ? AND target_amount IS NOT NULL", accountID).Find(&p).Error
+	return p, err
+}
+
+func (r gormPockets) Create(ctx context.Context, p *Pocket) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+func (r gormPockets) Update(ctx context.Context, p *Pocket) error {
+	return r.db.WithContext(ctx).Model(p).
+		Select("title", "description", "maturity_action", "target_amount", "target_date", "goal_reached_at").
+		Updates(p).Error
+}
+
+func (r gormPockets) UpdateBalance(ctx context.Context, p *Pocket) error {
+	return r.db.WithContext(ctx).Model(p).Update("balance", p.Balance).Error
+}
+
+func (r gormPockets) MarkGoalReached(ctx context.Context, p *Pocket, at time.Time) error {
+	return r.db.WithContext(ctx).Model(p).Update("goal_reached_at", at).Error
+}
+
+func (r gormPockets) Delete(ctx context.Context, p *Pocket) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Where(&PocketMember{PocketID: p.ID}).Delete(&PocketMember{}).Error; err != nil {
+		return err
+	}
+	return db.Delete(&Pocket{}, p.ID).Error
+}
+
+func (r gormPockets) Member(ctx context.Context, pocketID, accountID uint) (*PocketMember, error) {
+	m := &PocketMember{}
+	err := r.db.WithContext(ctx).Where(&PocketMember{PocketID: pocketID, AccountID: accountID}).First(m).Error
+	return m, notFound(err, ErrMemberNotFound)
+}
+
+func (r gormPockets) Members(ctx context.Context, pocketID uint) ([]PocketMember, error) {
+	members := []PocketMember{}
+	err := r.db.WithContext(ctx).Where(&PocketMember{PocketID: pocketID}).Order("id").Find(&members).Error
+	return members, err
+}
+
+func (r gormPockets) Invitations(ctx context.Context, accountID uint) ([]PocketMember, error) {
+	invitations := []PocketMember{}
+	err := r.db.WithContext(ctx).Where(&PocketMember{AccountID: accountID, Status: MemberInvited}).Order("id").Find(&invitations).Error
+	return invitations, err
+}
+
+func (r gormPockets) AddMember(ctx context.Context, m *PocketMember) error {
+	return r.db.WithContext(ctx).Create(m).Error
+}
+
+func (r gormPockets) UpdateMember(ctx context.Context, m *PocketMember) error {
+	return r.db.WithContext(ctx).Model(m).Select("role", "withdraw_limit", "status").Updates(m).Error
+}
+
+func (r gormPockets) RemoveMember(ctx context.Context, m *PocketMember) error {
+	return r.db.WithContext(ctx).Delete(m).Error
+}
+
+func (r gormPockets) Account(ctx context.Context, cond accountRef) (*accountRef, error) {
+	a := &accountRef{}
+	err := r.db.WithContext(ctx).Where(&cond).First(a).Error
+	return a, notFound(err, ErrAccountNotFound)
+}
+
+func (r gormPockets) Contributed(ctx context.Context, pocketID, accountID uint) (float64, error) {
+	var contributed float64
+	err := r.db.WithContext(ctx).Model(&PocketTransfer{}).
+		Where(&PocketTransfer{To: pocketID, AccountID: accountID}).
+		Where("type IN ?", []string{TransferTypeDeposit, TransferTypeTransfer}).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&contributed).Error
+	return contributed, err
+}
+
+func (r gormPockets) Withdrawn(ctx context.Context, pocketID, accountID uint) (float64, error) {
+	var withdrawn float64
+	err := r.db.WithContext(ctx).Model(&PocketTransfer{}).
+		Where(&PocketTransfer{From: pocketID, AccountID: accountID}).
+		Where("type IN ?", []string{TransferTypeWithdrawal, TransferTypeTransfer, TransferTypePenalty}).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&withdrawn).Error
+	return withdrawn, err
+}
+
+func (r gormPockets) ReceivedSince(ctx context.Context, pocketID uint, since time.Time) (float64, error) {
+	var sum float64
+	err := r.db.WithContext(ctx).Model(&PocketTransfer{}).
+		Where(&PocketTransfer{To: pocketID}).
+		Where("created_at >= ?", since).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+func (r gormPockets) History(ctx context.Context, pocketID uint) ([]PocketTransfer, error) {
+	transfers := []PocketTransfer{}
+	err := r.db.WithContext(ctx).Where(&PocketTransfer{From: pocketID}).Or(&PocketTransfer{To: pocketID}).Order("id desc").Find(&transfers).Error
+	return transfers, err
+}
+
+func (r gormPockets) Record(ctx context.Context, t *PocketTransfer) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r gormPockets) MainBalance(ctx context.Context, accountID uint) (float64, error) {
+	acc := &balance.Account{}
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(acc, accountID).Error
+	return acc.Balance, err
+}
+
+func (r gormPockets) SetMainBalance(ctx context.Context, accountID uint, bl float64) error {
+	return r.db.WithContext(ctx).Model(&balance.Account{ID: accountID}).Update("balance", bl).Error
+}
+
+// notFound turns gorm.ErrRecordNotFound into err.
+func notFound(dbErr, err error) error {
+	if errors.Is(dbErr, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return dbErr
+}
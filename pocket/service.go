@@ -0,0 +1,434 @@
+package pocket
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/arthit666/make_app/config"
+	"github.com/arthit666/make_app/event"
+	"github.com/shopspring/decimal"
+)
+
+// PocketService opens and closes pockets and moves money in and out of
+// them, opening time deposits at the rates of policy.
+type PocketService struct {
+	store  Store
+	policy config.Policy
+}
+
+func NewPocketService(store Store, policy config.Policy) *PocketService {
+	return &PocketService{store, policy}
+}
+
+// Access loads pocket id for account accountID, which has to own it or be an
+// active member with one of roles. The account a pocket belongs to passes
+// every check and gets RoleOwner.
+func (s *PocketService) Access(ctx context.Context, id, accountID uint, roles ...string) (*Pocket, string, error) {
+	pockets := s.store.Pockets()
+	p, err := pockets.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if p.AccountID == accountID {
+		return p, RoleOwner, nil
+	}
+
+	m, err := pockets.Member(ctx, p.ID, accountID)
+	if errors.Is(err, ErrMemberNotFound) || (err == nil && m.Status != MemberActive) {
+		return nil, "", ErrPocketNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	for _, r := range roles {
+		if m.Role == r {
+			return p, m.Role, nil
+		}
+	}
+	return nil, "", ErrForbidden
+}
+
+// List is every pocket account accountID can see: its own and the ones it
+// is an active member of.
+func (s *PocketService) List(ctx context.Context, accountID uint) ([]Pocket, error) {
+	return s.store.Pockets().List(ctx, accountID)
+}
+
+// Create opens the pocket pc describes for account accountID, moving its
+// opening balance out of the main balance. A locked pocket starts its term
+// now.
+func (s *PocketService) Create(ctx context.Context, accountID uint, pc *PocketCreate) (*Pocket, error) {
+	p := &Pocket{
+		Title:        pc.Title,
+		AccountID:    accountID,
+		Balance:      pc.Balance,
+		Description:  pc.Description,
+		TargetAmount: pc.TargetAmount,
+		TargetDate:   pc.TargetDate,
+		Type:         TypeRegular,
+	}
+
+	if pc.Type == TypeLocked {
+		now := time.Now()
+		if pc.Balance <= 0 || pc.MaturityDate == nil || !pc.MaturityDate.After(now) {
+			return nil, ErrLockedTerms
+		}
+
+		p.Type = TypeLocked
+		p.TermStart = &now
+		p.MaturityDate = pc.MaturityDate
+		p.MaturityAction = MaturityRelease
+		if pc.MaturityAction != "" {
+			p.MaturityAction = pc.MaturityAction
+		}
+		// The bank's current rates are fixed on the pocket, so a change to
+		// them only affects new time deposits.
+		p.BonusRate = s.policy.TimeDepositBonusRate
+		p.PenaltyRate = s.policy.EarlyWithdrawalPenaltyRate
+	}
+
+	var opening *PocketTransfer
+	err := s.store.Transaction(ctx, func(store Store) error {
+		pockets := store.Pockets()
+		if p.Balance > 0 {
+			main, err := pockets.MainBalance(ctx, accountID)
+			if err != nil {
+				return err
+			}
+			bl := decimal.NewFromFloat(main)
+			if bl.LessThan(decimal.NewFromFloat(p.Balance)) {
+				return ErrInsufficientAccountBalance
+			}
+			main, _ = bl.Sub(decimal.NewFromFloat(p.Balance)).Float64()
+			if err := pockets.SetMainBalance(ctx, accountID, main); err != nil {
+				return err
+			}
+		}
+
+		if err := pockets.Create(ctx, p); err != nil {
+			return err
+		}
+
+		// The opening balance is a deposit like any other, so the pocket's
+		// history adds up to its balance.
+		if p.Balance > 0 {
+			opening = &PocketTransfer{Type: TransferTypeDeposit, To: p.ID, Amount: p.Balance, AccountID: accountID}
+			return pockets.Record(ctx, opening)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opening != nil {
+		event.Publish(event.Event{Type: event.PocketDeposited, AccountID: accountID, Payload: *opening})
+	}
+	s.checkGoal(ctx, p)
+	return p, nil
+}
+
+// Move moves amount between the main balance of account accountID and
+// pocket id, into the pocket for a deposit and out of it for a withdrawal.
+// Both balances are re-read inside the transaction and the movement is
+// recorded with typ, with the main balance as the zero side. Contributors
+// may only withdraw up to their allowance. Withdrawing from a locked pocket
+// needs early, and the penalty is recorded separately and kept back from
+// what reaches the main balance.
+func (s *PocketService) Move(ctx context.Context, id, accountID uint, typ string, amount float64, early bool) (*PocketTransfer, error) {
+	p, role, err := s.Access(ctx, id, accountID, RoleOwner, RoleContributor)
+	if err != nil {
+		return nil, err
+	}
+	amountDec := decimal.NewFromFloat(amount)
+	t := &PocketTransfer{Type: typ, Amount: amount, AccountID: accountID}
+
+	err = s.store.Transaction(ctx, func(store Store) error {
+		pockets := store.Pockets()
+		main, err := pockets.MainBalance(ctx, accountID)
+		if err != nil {
+			return err
+		}
+		locked, err := pockets.Lock(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		p = locked[p.ID]
+
+		accBl := decimal.NewFromFloat(main)
+		pocketBl := decimal.NewFromFloat(p.Balance)
+
+		switch typ {
+		case TransferTypeDeposit:
+			if p.Type == TypeLocked {
+				return ErrLockedDeposit
+			}
+			if accBl.LessThan(amountDec) {
+				return ErrInsufficientAccountBalance
+			}
+			accBl = accBl.Sub(amountDec)
+			pocketBl = pocketBl.Add(amountDec)
+			t.To = p.ID
+		case TransferTypeWithdrawal:
+			// Checked once the pocket is locked, so that two withdrawals
+			// of a contributor cannot both fit in what is left of the
+			// allowance.
+			if err := checkAllowance(ctx, pockets, p, accountID, role, amount); err != nil {
+				return err
+			}
+			if pocketBl.LessThan(amountDec) {
+				return ErrInsufficientPocketBalance
+			}
+			penalty, err := withdrawalPenalty(p, amount, early, time.Now())
+			if err != nil {
+				return err
+			}
+			if penalty.IsPositive() {
+				pt := &PocketTransfer{Type: TransferTypePenalty, From: p.ID, AccountID: accountID}
+				pt.Amount, _ = penalty.Float64()
+				if err := pockets.Record(ctx, pt); err != nil {
+					return err
+				}
+			}
+			accBl = accBl.Add(amountDec.Sub(penalty))
+			pocketBl = pocketBl.Sub(amountDec)
+			t.From = p.ID
+			t.Amount, _ = amountDec.Sub(penalty).Float64()
+		}
+
+		main, _ = accBl.Float64()
+		if err := pockets.SetMainBalance(ctx, accountID, main); err != nil {
+			return err
+		}
+		p.Balance, _ = pocketBl.Float64()
+		if err := pockets.UpdateBalance(ctx, p); err != nil {
+			return err
+		}
+		return pockets.Record(ctx, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if typ == TransferTypeDeposit {
+		event.Publish(event.Event{Type: event.PocketDeposited, AccountID: accountID, Payload: *t})
+		s.checkGoal(ctx, p)
+	} else {
+		event.Publish(event.Event{Type: event.PocketWithdrawn, AccountID: accountID, Payload: *t})
+	}
+	return t, nil
+}
+
+// Transfer moves req.Amount between two pockets of account accountID. Any
+// early withdrawal penalty is kept back from what reaches the target and
+// recorded as a separate movement, so the movement returned holds the
+// amount actually received.
+func (s *PocketService) Transfer(ctx context.Context, accountID uint, req *PocketTransferRequest) (*PocketTransfer, error) {
+	if req.From == req.To {
+		return nil, ErrSamePocket
+	}
+	t := &PocketTransfer{
+		Type:      TransferTypeTransfer,
+		From:      req.From,
+		To:        req.To,
+		Amount:    req.Amount,
+		AccountID: accountID,
+	}
+
+	var to *Pocket
+	err := s.store.Transaction(ctx, func(store Store) error {
+		pockets := store.Pockets()
+		from, err := pockets.GetOwned(ctx, req.From, accountID)
+		if err != nil {
+			if errors.Is(err, ErrPocketNotFound) {
+				return ErrFromPocketNotFound
+			}
+			return err
+		}
+		if to, err = pockets.GetOwned(ctx, req.To, accountID); err != nil {
+			if errors.Is(err, ErrPocketNotFound) {
+				return ErrToPocketNotFound
+			}
+			return err
+		}
+		if to.Type == TypeLocked {
+			return ErrLockedDeposit
+		}
+
+		// Both balances are re-read with their rows locked, so that
+		// movements made at the same time cannot spend the same money twice.
+		locked, err := pockets.Lock(ctx, from.ID, to.ID)
+		if err != nil {
+			return err
+		}
+		from, to = locked[from.ID], locked[to.ID]
+
+		penalty, err := withdrawalPenalty(from, req.Amount, req.EarlyWithdrawal, time.Now())
+		if err != nil {
+			return err
+		}
+
+		amount := decimal.NewFromFloat(req.Amount)
+		bl := decimal.NewFromFloat(from.Balance)
+		if bl.LessThan(amount) {
+			return ErrInsufficientPocketBalance
+		}
+		from.Balance, _ = bl.Sub(amount).Float64()
+		if err := pockets.UpdateBalance(ctx, from); err != nil {
+			return err
+		}
+
+		received := amount.Sub(penalty)
+		to.Balance, _ = decimal.NewFromFloat(to.Balance).Add(received).Float64()
+		if err := pockets.UpdateBalance(ctx, to); err != nil {
+			return err
+		}
+
+		t.Amount, _ = received.Float64()
+		if err := pockets.Record(ctx, t); err != nil {
+			return err
+		}
+		if penalty.IsPositive() {
+			pt := &PocketTransfer{Type: TransferTypePenalty, From: from.ID, AccountID: from.AccountID}
+			pt.Amount, _ = penalty.Float64()
+			return pockets.Record(ctx, pt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.checkGoal(ctx, to)
+	event.Publish(event.Event{Type: event.PocketWithdrawn, AccountID: accountID, Payload: *t})
+	return t, nil
+}
+
+// Delete closes pocket id of account accountID, giving what is left in it
+// back to the main balance, and publishes event.PocketClosed. Breaking a
+// locked pocket before maturity needs early and costs the penalty.
+func (s *PocketService) Delete(ctx context.Context, id, accountID uint, early bool) error {
+	var p *Pocket
+	err := s.store.Transaction(ctx, func(store Store) error {
+		pockets := store.Pockets()
+		var err error
+		p, err = pockets.GetOwned(ctx, id, accountID)
+		if err != nil {
+			return err
+		}
+		main, err := pockets.MainBalance(ctx, p.AccountID)
+		if err != nil {
+			return err
+		}
+		locked, err := pockets.Lock(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		p = locked[p.ID]
+		if err := checkMembersPaidOut(ctx, pockets, p); err != nil {
+			return err
+		}
+
+		penalty, err := withdrawalPenalty(p, p.Balance, early, time.Now())
+		if err != nil {
+			return err
+		}
+
+		refund := decimal.NewFromFloat(p.Balance).Sub(penalty)
+		main, _ = decimal.NewFromFloat(main).Add(refund).Float64()
+		if err := pockets.SetMainBalance(ctx, p.AccountID, main); err != nil {
+			return err
+		}
+
+		if refund.IsPositive() {
+			pt := &PocketTransfer{Type: TransferTypeWithdrawal, From: p.ID, AccountID: p.AccountID}
+			pt.Amount, _ = refund.Float64()
+			if err := pockets.Record(ctx, pt); err != nil {
+				return err
+			}
+		}
+		if penalty.IsPositive() {
+			pt := &PocketTransfer{Type: TransferTypePenalty, From: p.ID, AccountID: p.AccountID}
+			pt.Amount, _ = penalty.Float64()
+			if err := pockets.Record(ctx, pt); err != nil {
+				return err
+			}
+		}
+		return pockets.Delete(ctx, p)
+	})
+	if err != nil {
+		return err
+	}
+
+	event.Publish(event.Event{Type: event.PocketClosed, AccountID: accountID, Payload: *p})
+	return nil
+}
+
+// checkMembersPaidOut refuses with ErrMembersHoldFunds while a member of p
+// other than its owner has put more into it than they took out, since
+// deleting p pays its whole balance to the owner.
+func checkMembersPaidOut(ctx context.Context, pockets PocketRepository, p *Pocket) error {
+	members, err := pockets.Members(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		contributed, err := pockets.Contributed(ctx, p.ID, m.AccountID)
+		if err != nil {
+			return err
+		}
+		withdrawn, err := pockets.Withdrawn(ctx, p.ID, m.AccountID)
+		if err != nil {
+			return err
+		}
+		if decimal.NewFromFloat(contributed).GreaterThan(decimal.NewFromFloat(withdrawn)) {
+			return ErrMembersHoldFunds
+		}
+	}
+	return nil
+}
+
+// checkGoal marks the goal of p as reached the first time its balance gets
+// to the target amount, and publishes event.PocketGoalReached.
+func (s *PocketService) checkGoal(ctx context.Context, p *Pocket) {
+	if p.TargetAmount == nil || p.GoalReachedAt != nil {
+		return
+	}
+	if decimal.NewFromFloat(p.Balance).LessThan(decimal.NewFromFloat(*p.TargetAmount)) {
+		return
+	}
+
+	now := time.Now()
+	if err := s.store.Pockets().MarkGoalReached(ctx, p, now); err != nil {
+		log.Printf("pocket %d: failed to mark goal reached: %s", p.ID, err)
+		return
+	}
+	p.GoalReachedAt = &now
+
+	event.Publish(event.Event{Type: event.PocketGoalReached, AccountID: p.AccountID, Payload: *p})
+}
+
+// checkAllowance makes sure a contributor taking amount out of p stays within
+// the withdraw limit its owner gave it. Owners are not limited.
+func checkAllowance(ctx context.Context, pockets PocketRepository, p *Pocket, accountID uint, role string, amount float64) error {
+	if role == RoleOwner {
+		return nil
+	}
+
+	m, err := pockets.Member(ctx, p.ID, accountID)
+	if err != nil {
+		return err
+	}
+	withdrawn, err := pockets.Withdrawn(ctx, p.ID, accountID)
+	if err != nil {
+		return err
+	}
+
+	total := decimal.NewFromFloat(withdrawn).Add(decimal.NewFromFloat(amount))
+	if total.GreaterThan(decimal.NewFromFloat(m.WithdrawLimit)) {
+		return ErrAllowanceExceeded
+	}
+	return nil
+}
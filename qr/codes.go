@@ -0,0 +1,119 @@
+package qr
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+const imageSize = 256
+
+// @Summary Generate a QR code
+// @Description Generate a PromptPay QR code paying an account number or alias, the caller's account by default, with an optional fixed amount
+// @Tags qr
+// @Accept json
+// @Produce json
+// @Param qr body qr.QRRequest true "QRRequest data"
+// @Success 200 {object} qr.QRResponse
+// @Security  Bearer
+// @Router /qr/ [post]
+func (h *handler) Generate(c *fiber.Ctx) error {
+	req := &QRRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	acc := &account.Account{}
+	q := h.DB
+	if target := strings.TrimSpace(req.Target); target != "" {
+		q = q.Where("account_number = ? OR alias = ?", target, target)
+	} else {
+		q = q.Where("id = ?", c.Locals("account_id").(int))
+	}
+	if err := q.First(acc).Error; err != nil {
+		return accountError(err)
+	}
+	if acc.Status == account.StatusClosed {
+		return accountError(account.ErrRecipientClosed)
+	}
+
+	p := proxy(acc.AccountNumber, acc.Alias)
+	p.Amount = req.Amount
+	payload := Encode(p)
+
+	png, err := qrcode.Encode(payload, qrcode.Medium, imageSize)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(QRResponse{
+		Payload: payload,
+		Image:   base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// @Summary Parse a QR code
+// @Description Decode a scanned PromptPay QR payload into a transfer request to send with POST /accounts/transfer
+// @Tags qr
+// @Accept json
+// @Produce json
+// @Param qr body qr.ParseRequest true "ParseRequest data"
+// @Success 200 {object} account.AccountTransferRequest
+// @Security  Bearer
+// @Router /qr/parse [post]
+func (h *handler) Parse(c *fiber.Ctx) error {
+	req := &ParseRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+
+	p, err := Decode(req.Payload)
+	if err != nil {
+		if errors.Is(err, ErrUnsupported) {
+			return apperr.Unprocessable(apperr.CodeUnprocessable, err.Error())
+		}
+		return apperr.BadRequest(err.Error())
+	}
+
+	acc := &account.Account{}
+	q := h.DB.Where("alias = ?", p.Target)
+	if p.Proxy == ProxyAccount {
+		q = h.DB.Where("account_number = ?", p.Target)
+	}
+	if err := q.First(acc).Error; err != nil {
+		return accountError(err)
+	}
+	if acc.Status == account.StatusClosed {
+		return accountError(account.ErrRecipientClosed)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(account.AccountTransferRequest{
+		To:     acc.AccountNumber,
+		Amount: p.Amount,
+		Tags:   []string{},
+	})
+}
+
+func accountError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperr.NotFound(apperr.CodeAccountNotFound, "account not found")
+	case errors.Is(err, account.ErrRecipientClosed):
+		return apperr.Unprocessable(apperr.CodeAccountClosed, err.Error())
+	}
+	return apperr.Internal(err)
+}
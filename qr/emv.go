@@ -0,0 +1,198 @@
+package qr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// EMVCo merchant-presented QR tags used by PromptPay.
+const (
+	tagFormat    = "00"
+	tagInitiate  = "01"
+	tagPromptPay = "29"
+	tagCurrency  = "53"
+	tagAmount    = "54"
+	tagCountry   = "58"
+	tagCRC       = "63"
+
+	promptPayAID = "A000000677010111"
+	currencyTHB  = "764"
+	countryTH    = "TH"
+
+	// A static QR code can be paid any amount many times, a dynamic one
+	// carries a fixed amount.
+	initiateStatic  = "11"
+	initiateDynamic = "12"
+)
+
+// Proxy types of the PromptPay merchant account information.
+const (
+	ProxyMobile     = "01"
+	ProxyNationalID = "02"
+	ProxyEWallet    = "03"
+	ProxyAccount    = "04"
+)
+
+var (
+	mobile     = regexp.MustCompile(`^0[0-9]{9}$`)
+	nationalID = regexp.MustCompile(`^[0-9]{13}$`)
+	eWallet    = regexp.MustCompile(`^[0-9]{15}$`)
+)
+
+// Payment is what a PromptPay QR payload carries. Target is a mobile number
+// in its local 0XXXXXXXXX form, a national ID, an e-wallet ID or an account
+// number, as told by Proxy. A zero Amount leaves the amount to the payer.
+type Payment struct {
+	Proxy  string
+	Target string
+	Amount float64
+}
+
+// proxy picks the PromptPay proxy an account is paid through: its alias
+// when that is a mobile number, national ID or e-wallet ID, and its account
+// number otherwise.
+func proxy(accountNumber string, alias *string) Payment {
+	if alias != nil {
+		switch {
+		case mobile.MatchString(*alias):
+			return Payment{Proxy: ProxyMobile, Target: *alias}
+		case nationalID.MatchString(*alias):
+			return Payment{Proxy: ProxyNationalID, Target: *alias}
+		case eWallet.MatchString(*alias):
+			return Payment{Proxy: ProxyEWallet, Target: *alias}
+		}
+	}
+	return Payment{Proxy: ProxyAccount, Target: accountNumber}
+}
+
+// Encode builds the EMVCo payload of p, ending with its CRC16.
+func Encode(p Payment) string {
+	target := p.Target
+	if p.Proxy == ProxyMobile {
+		target = "0066" + strings.TrimPrefix(target, "0")
+	}
+
+	initiate := initiateStatic
+	if p.Amount > 0 {
+		initiate = initiateDynamic
+	}
+
+	var b strings.Builder
+	b.WriteString(tlv(tagFormat, "01"))
+	b.WriteString(tlv(tagInitiate, initiate))
+	b.WriteString(tlv(tagPromptPay, tlv("00", promptPayAID)+tlv(p.Proxy, target)))
+	b.WriteString(tlv(tagCurrency, currencyTHB))
+	if p.Amount > 0 {
+		b.WriteString(tlv(tagAmount, decimal.NewFromFloat(p.Amount).StringFixed(2)))
+	}
+	b.WriteString(tlv(tagCountry, countryTH))
+	b.WriteString(tagCRC + "04")
+	b.WriteString(fmt.Sprintf("%04X", crc16(b.String())))
+	return b.String()
+}
+
+// Decode reads a PromptPay payload back into a Payment after checking its
+// CRC16.
+func Decode(payload string) (*Payment, error) {
+	payload = strings.TrimSpace(payload)
+	if len(payload) < 8 || payload[len(payload)-8:len(payload)-4] != tagCRC+"04" {
+		return nil, ErrInvalidPayload
+	}
+	sum, err := strconv.ParseUint(payload[len(payload)-4:], 16, 16)
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+	if uint16(sum) != crc16(payload[:len(payload)-4]) {
+		return nil, ErrChecksum
+	}
+
+	tags, err := fields(payload[:len(payload)-8])
+	if err != nil {
+		return nil, err
+	}
+	if tags[tagFormat] != "01" {
+		return nil, ErrInvalidPayload
+	}
+	if c, ok := tags[tagCurrency]; ok && c != currencyTHB {
+		return nil, ErrUnsupported
+	}
+
+	account, ok := tags[tagPromptPay]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	sub, err := fields(account)
+	if err != nil {
+		return nil, err
+	}
+	if sub["00"] != promptPayAID {
+		return nil, ErrUnsupported
+	}
+
+	p := &Payment{}
+	for _, proxy := range []string{ProxyMobile, ProxyNationalID, ProxyEWallet, ProxyAccount} {
+		if v, ok := sub[proxy]; ok {
+			p.Proxy, p.Target = proxy, v
+			break
+		}
+	}
+	if p.Proxy == "" {
+		return nil, ErrUnsupported
+	}
+	if p.Proxy == ProxyMobile {
+		if !strings.HasPrefix(p.Target, "0066") {
+			return nil, ErrUnsupported
+		}
+		p.Target = "0" + strings.TrimPrefix(p.Target, "0066")
+	}
+
+	if a, ok := tags[tagAmount]; ok {
+		amount, err := decimal.NewFromString(a)
+		if err != nil || !amount.IsPositive() {
+			return nil, ErrInvalidPayload
+		}
+		p.Amount, _ = amount.Float64()
+	}
+	return p, nil
+}
+
+func tlv(tag, value string) string {
+	return fmt.Sprintf("%s%02d%s", tag, len(value), value)
+}
+
+// fields splits a run of EMVCo tag-length-value fields by tag.
+func fields(s string) (map[string]string, error) {
+	tags := map[string]string{}
+	for len(s) > 0 {
+		if len(s) < 4 {
+			return nil, ErrInvalidPayload
+		}
+		n, err := strconv.Atoi(s[2:4])
+		if err != nil || len(s) < 4+n {
+			return nil, ErrInvalidPayload
+		}
+		tags[s[:2]] = s[4 : 4+n]
+		s = s[4+n:]
+	}
+	return tags, nil
+}
+
+// crc16 is the CRC-16/CCITT-FALSE checksum EMVCo payloads end with.
+func crc16(s string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
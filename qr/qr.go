@@ -0,0 +1,39 @@
+package qr
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPayload = errors.New("invalid QR payload")
+	ErrChecksum       = errors.New("QR payload checksum mismatch")
+	ErrUnsupported    = errors.New("QR payload is not a PromptPay transfer")
+)
+
+// QRRequest asks for a QR code paying Target, an account number or alias.
+// Without a target the QR code pays the caller. Without an amount the payer
+// enters one.
+type QRRequest struct {
+	Target string  `json:"target" validate:"max=64"`
+	Amount float64 `json:"amount" validate:"omitempty,gt=0"`
+}
+
+type QRResponse struct {
+	Payload string `json:"payload"`
+	// Image is the QR code as a base64 encoded PNG.
+	Image string `json:"image"`
+}
+
+type ParseRequest struct {
+	Payload string `json:"payload" validate:"required,max=512"`
+}
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
@@ -0,0 +1,153 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCRC16(t *testing.T) {
+	// The CRC-16/CCITT-FALSE check value.
+	assert.Equal(t, uint16(0x29B1), crc16("123456789"))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	// Arrange
+	cases := []struct {
+		payment Payment
+		payload string
+	}{
+		{
+			Payment{Proxy: ProxyMobile, Target: "0812345678"},
+			"00020101021129370016A0000006770101110113006681234567853037645802TH6304823E",
+		},
+		{
+			Payment{Proxy: ProxyMobile, Target: "0812345678", Amount: 420},
+			"00020101021229370016A0000006770101110113006681234567853037645406420.005802TH6304946D",
+		},
+		{Payment{Proxy: ProxyNationalID, Target: "1234567890123", Amount: 12.5}, ""},
+		{Payment{Proxy: ProxyAccount, Target: "1234567890"}, ""},
+	}
+
+	for _, c := range cases {
+		// Act
+		payload := Encode(c.payment)
+		p, err := Decode(payload)
+
+		// Assert
+		if c.payload != "" {
+			assert.Equal(t, c.payload, payload)
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, c.payment, *p)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	valid := Encode(Payment{Proxy: ProxyAccount, Target: "1234567890", Amount: 10})
+
+	_, err := Decode(valid[:len(valid)-1] + "0")
+	assert.ErrorIs(t, err, ErrChecksum)
+
+	_, err = Decode("hello")
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+
+	// A payload in US dollars.
+	body := "000201010211" + tlv(tagPromptPay, tlv("00", promptPayAID)+tlv(ProxyAccount, "1234567890")) + tlv(tagCurrency, "840") + "6304"
+	_, err = Decode(body + sprintCRC(body))
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	// A payload for some other scheme.
+	body = "000201010211" + tlv("26", tlv("00", "D156000000000000")) + "6304"
+	_, err = Decode(body + sprintCRC(body))
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func sprintCRC(s string) string {
+	return fmt.Sprintf("%04X", crc16(s))
+}
+
+func TestGenerateAndParse(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	phone := "0898765432"
+	alice := &account.Account{Email: "alice@test.com", AccountNumber: "4000000001"}
+	bob := &account.Account{Email: "bob@test.com", AccountNumber: "4000000002", Alias: &phone}
+	tx.Create(alice)
+	tx.Create(bob)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(alice.ID))
+		return c.Next()
+	})
+	h := New(tx)
+	app.Post("/qr/", h.Generate)
+	app.Post("/qr/parse", h.Parse)
+
+	post := func(url string, body interface{}, out interface{}) int {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		if out != nil {
+			json.NewDecoder(resp.Body).Decode(out)
+		}
+		return resp.StatusCode
+	}
+
+	// Act & Assert
+	res := QRResponse{}
+	status := post("/qr/", QRRequest{}, &res)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Contains(t, res.Payload, tlv(ProxyAccount, alice.AccountNumber))
+
+	img, err := base64.StdEncoding.DecodeString(res.Image)
+	assert.NoError(t, err)
+	_, err = png.Decode(bytes.NewReader(img))
+	assert.NoError(t, err)
+
+	tr := account.AccountTransferRequest{}
+	status = post("/qr/parse", ParseRequest{Payload: res.Payload}, &tr)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, alice.AccountNumber, tr.To)
+	assert.Equal(t, 0.0, tr.Amount)
+
+	status = post("/qr/", QRRequest{Target: phone, Amount: 99.5}, &res)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Contains(t, res.Payload, tlv(ProxyMobile, "0066898765432"))
+
+	status = post("/qr/parse", ParseRequest{Payload: res.Payload}, &tr)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, bob.AccountNumber, tr.To)
+	assert.Equal(t, 99.5, tr.Amount)
+
+	status = post("/qr/", QRRequest{Target: "nobody"}, nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+
+	status = post("/qr/parse", ParseRequest{Payload: Encode(Payment{Proxy: ProxyMobile, Target: "0811111111"})}, nil)
+	assert.Equal(t, fiber.StatusNotFound, status)
+
+	status = post("/qr/parse", ParseRequest{Payload: "00020101021163041234"}, nil)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+}
@@ -0,0 +1,322 @@
+// Package reconciliation checks that the balances of the bank add up: that
+// every balance is what its history says, that none is negative, that
+// every transfer moved money between accounts that exist, and that what
+// the bank holds is what came into it from outside. Every run is kept as a
+// report, and admins are notified of the ones that found anything.
+package reconciliation
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/event"
+	"github.com/arthit666/make_app/ledger"
+	"github.com/arthit666/make_app/notification"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ReconciliationReport is the outcome of one run.
+type ReconciliationReport struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"create_at"`
+	// Trigger is what started the run: TriggerSchedule, TriggerAdmin or
+	// TriggerCLI.
+	Trigger   string `json:"trigger"`
+	Accounts  int    `json:"accounts"`
+	Pockets   int    `json:"pockets"`
+	Transfers int64  `json:"transfers"`
+	// Holdings is the sum of every balance, and Inflow what came into the
+	// bank from outside, less what left it.
+	Holdings      float64                     `json:"holdings"`
+	Inflow        float64                     `json:"inflow"`
+	OK            bool                        `json:"ok"`
+	Discrepancies []ReconciliationDiscrepancy `json:"discrepancies" gorm:"foreignKey:ReportID"`
+}
+
+// ReconciliationDiscrepancy is one check that failed, on Target: an
+// account (accounts:7), a pocket (pockets:3), a transfer
+// (account_transfers:12) or the whole bank.
+type ReconciliationDiscrepancy struct {
+	ID       uint    `gorm:"primarykey" json:"id"`
+	ReportID uint    `json:"-" gorm:"index"`
+	Check    string  `json:"check"`
+	Target   string  `json:"target"`
+	Expected float64 `json:"expected"`
+	Actual   float64 `json:"actual"`
+	Detail   string  `json:"detail"`
+}
+
+type ReportList struct {
+	Reports    []ReconciliationReport `json:"result"`
+	Page       int                    `json:"page"`
+	TotalPage  int                    `json:"total_page"`
+	Count      int                    `json:"count"`
+	TotalCount int64                  `json:"total_count"`
+}
+
+const (
+	TriggerSchedule = "schedule"
+	TriggerAdmin    = "admin"
+	TriggerCLI      = "cli"
+
+	// CheckBalance is an account whose balance and pocket balances do not
+	// add up to its deposits and net transfers.
+	CheckBalance = "balance"
+	// CheckNegative is a balance below zero.
+	CheckNegative = "negative_balance"
+	// CheckTransfer is a transfer that did not move money between
+	// accounts that exist, or whose reversals do not add up.
+	CheckTransfer = "transfer"
+	// CheckInflow is the bank holding other than what came into it.
+	CheckInflow = "inflow"
+)
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
+
+// Flagged is the notification admins get of a report that found
+// discrepancies.
+type Flagged struct {
+	ReportID      uint `json:"report_id"`
+	Discrepancies int  `json:"discrepancies"`
+}
+
+func (f Flagged) Message() string {
+	return fmt.Sprintf("Reconciliation report %d found %d discrepancies", f.ReportID, f.Discrepancies)
+}
+
+// Run checks every balance and transfer, stores what it found as a report
+// started by trigger, and notifies every admin when anything is off.
+func Run(db *gorm.DB, trigger string) (*ReconciliationReport, error) {
+	r := &ReconciliationReport{Trigger: trigger}
+
+	// On Postgres the checks read one snapshot, so transfers made while
+	// they run do not show up as discrepancies.
+	var opts []*sql.TxOptions
+	if db.Dialector.Name() == "postgres" {
+		opts = append(opts, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error { return check(tx, r) }, opts...); err != nil {
+		return nil, err
+	}
+	r.OK = len(r.Discrepancies) == 0
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(r).Error; err != nil {
+			return err
+		}
+		if r.OK {
+			return nil
+		}
+		admins := []account.Account{}
+		if err := tx.Where("role = ? AND status <> ?", account.RoleAdmin, account.StatusClosed).Find(&admins).Error; err != nil {
+			return err
+		}
+		for _, a := range admins {
+			e := event.Event{
+				Type:      event.DiscrepanciesFound,
+				AccountID: a.ID,
+				Payload:   Flagged{ReportID: r.ID, Discrepancies: len(r.Discrepancies)},
+				CreatedAt: r.CreatedAt,
+			}
+			if err := notification.Send(tx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RunDaily is Run on a schedule.
+func RunDaily(db *gorm.DB, _ time.Time) error {
+	_, err := Run(db, TriggerSchedule)
+	return err
+}
+
+func check(tx *gorm.DB, r *ReconciliationReport) error {
+	balances, err := ledger.Balances(tx, 0)
+	if err != nil {
+		return err
+	}
+	checkBalances(r, balances)
+
+	if err := checkTransfers(tx, r); err != nil {
+		return err
+	}
+	return checkInflow(tx, r, balances)
+}
+
+// checkBalances compares the balance of every account and its pockets
+// together with what their history adds up to, and looks for negative
+// balances.
+func checkBalances(r *ReconciliationReport, balances []ledger.Balance) {
+	type sum struct {
+		recorded, expected decimal.Decimal
+	}
+	order := []uint{}
+	byAccount := map[uint]*sum{}
+	for _, b := range balances {
+		s := byAccount[b.AccountID]
+		if s == nil {
+			s = &sum{}
+			byAccount[b.AccountID] = s
+			order = append(order, b.AccountID)
+		}
+		s.recorded = s.recorded.Add(decimal.NewFromFloat(b.Recorded))
+		s.expected = s.expected.Add(decimal.NewFromFloat(b.Expected))
+
+		if b.Kind == ledger.KindAccount {
+			r.Accounts++
+		} else {
+			r.Pockets++
+		}
+		if b.Recorded < 0 {
+			r.Discrepancies = append(r.Discrepancies, ReconciliationDiscrepancy{
+				Check:  CheckNegative,
+				Target: fmt.Sprintf("%ss:%d", b.Kind, b.ID),
+				Actual: b.Recorded,
+				Detail: fmt.Sprintf("%s %s is below zero", b.Kind, b.Name),
+			})
+		}
+	}
+
+	for _, id := range order {
+		s := byAccount[id]
+		if s.recorded.Equal(s.expected) {
+			continue
+		}
+		d := ReconciliationDiscrepancy{
+			Check:  CheckBalance,
+			Target: fmt.Sprintf("accounts:%d", id),
+			Detail: "the account and its pockets hold other than its deposits and net transfers",
+		}
+		d.Expected, _ = s.expected.Float64()
+		d.Actual, _ = s.recorded.Float64()
+		r.Discrepancies = append(r.Discrepancies, d)
+	}
+}
+
+// checkTransfers looks for transfers from or to accounts that do not exist,
+// and for transfers whose reversed amount is not what their reversals add
+// up to.
+func checkTransfers(tx *gorm.DB, r *ReconciliationReport) error {
+	if err := tx.Model(&account.AccountTransfer{}).Count(&r.Transfers).Error; err != nil {
+		return err
+	}
+
+	numbers := tx.Unscoped().Model(&account.Account{}).Select("account_number")
+	orphans := []account.AccountTransfer{}
+	err := tx.Where("status = ?", account.TransferStatusCompleted).
+		Where(tx.Where(`"from" <> '' AND "from" NOT IN (?)`, numbers).
+			Or(`"to" <> '' AND "to" NOT IN (?)`, numbers).
+			Or(`"from" = '' AND "to" = ''`)).
+		Order("id").Find(&orphans).Error
+	if err != nil {
+		return err
+	}
+	for _, t := range orphans {
+		r.Discrepancies = append(r.Discrepancies, ReconciliationDiscrepancy{
+			Check:    CheckTransfer,
+			Target:   fmt.Sprintf("account_transfers:%d", t.ID),
+			Expected: t.Amount,
+			Detail:   fmt.Sprintf("%s transfer of %s from %q to %q did not move money between two accounts", t.Type, decimal.NewFromFloat(t.Amount).StringFixed(2), t.From, t.To),
+		})
+	}
+
+	type reversed struct {
+		ReversalOf uint
+		Total      float64
+	}
+	sums := []reversed{}
+	err = tx.Model(&account.AccountTransfer{}).Select("reversal_of, SUM(amount) AS total").
+		Where("type = ? AND reversal_of IS NOT NULL", account.TransferTypeReversal).
+		Group("reversal_of").Scan(&sums).Error
+	if err != nil {
+		return err
+	}
+	byOriginal := map[uint]decimal.Decimal{}
+	for _, s := range sums {
+		byOriginal[s.ReversalOf] = decimal.NewFromFloat(s.Total)
+	}
+
+	originals := []account.AccountTransfer{}
+	ids := []uint{}
+	for id := range byOriginal {
+		ids = append(ids, id)
+	}
+	if err := tx.Where("reversed_amount <> 0 OR id IN ?", ids).Order("id").Find(&originals).Error; err != nil {
+		return err
+	}
+	for _, t := range originals {
+		total := byOriginal[t.ID]
+		if total.Equal(decimal.NewFromFloat(t.ReversedAmount)) {
+			continue
+		}
+		d := ReconciliationDiscrepancy{
+			Check:  CheckTransfer,
+			Target: fmt.Sprintf("account_transfers:%d", t.ID),
+			Actual: t.ReversedAmount,
+			Detail: "the reversed amount is not what the reversals of the transfer add up to",
+		}
+		d.Expected, _ = total.Float64()
+		r.Discrepancies = append(r.Discrepancies, d)
+	}
+	return nil
+}
+
+// checkInflow compares everything the bank holds with what came into it
+// from outside: deposits, credits and interest, less debits, taxes and
+// penalties.
+func checkInflow(tx *gorm.DB, r *ReconciliationReport, balances []ledger.Balance) error {
+	holdings := decimal.Zero
+	for _, b := range balances {
+		holdings = holdings.Add(decimal.NewFromFloat(b.Recorded))
+	}
+
+	var in, out, earned, charged float64
+	transfers := tx.Model(&account.AccountTransfer{}).Select("COALESCE(SUM(amount), 0)").
+		Where("status = ?", account.TransferStatusCompleted).Session(&gorm.Session{})
+	if err := transfers.Where(`"from" = ''`).Scan(&in).Error; err != nil {
+		return err
+	}
+	if err := transfers.Where(`"to" = ''`).Scan(&out).Error; err != nil {
+		return err
+	}
+	movements := tx.Model(&pocket.PocketTransfer{}).Select("COALESCE(SUM(amount), 0)").Session(&gorm.Session{})
+	err := movements.Where(`"from" = 0 AND type NOT IN ?`, []string{pocket.TransferTypeDeposit, pocket.TransferTypeRule}).Scan(&earned).Error
+	if err != nil {
+		return err
+	}
+	if err := movements.Where(`"to" = 0 AND type <> ?`, pocket.TransferTypeWithdrawal).Scan(&charged).Error; err != nil {
+		return err
+	}
+
+	inflow := decimal.NewFromFloat(in).Sub(decimal.NewFromFloat(out)).
+		Add(decimal.NewFromFloat(earned)).Sub(decimal.NewFromFloat(charged)).Round(2)
+	holdings = holdings.Round(2)
+	r.Holdings, _ = holdings.Float64()
+	r.Inflow, _ = inflow.Float64()
+	if !holdings.Equal(inflow) {
+		r.Discrepancies = append(r.Discrepancies, ReconciliationDiscrepancy{
+			Check:    CheckInflow,
+			Target:   "bank",
+			Expected: r.Inflow,
+			Actual:   r.Holdings,
+			Detail:   "the bank holds other than what came into it from outside",
+		})
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package reconciliation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/event"
+	"github.com/arthit666/make_app/notification"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{},
+		&notification.Notification{}, &ReconciliationReport{}, &ReconciliationDiscrepancy{})
+	assert.NoError(t, err)
+	return db
+}
+
+func TestRun(t *testing.T) {
+	// Arrange
+	tx := setup(t).Begin()
+	defer tx.Rollback()
+
+	admin := &account.Account{Email: "ops@test.com", AccountNumber: "1000000009", Role: account.RoleAdmin}
+	a := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 50}
+	b := &account.Account{Email: "b@test.com", AccountNumber: "1000000002", Balance: 30}
+	tx.Create(admin)
+	tx.Create(a)
+	tx.Create(b)
+	p := &pocket.Pocket{Title: "Holiday", Balance: 20.5, AccountID: a.ID}
+	tx.Create(p)
+
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: a.AccountNumber, Amount: 100})
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeTransfer, From: a.AccountNumber, To: b.AccountNumber, Amount: 30})
+	tx.Create(&pocket.PocketTransfer{Type: pocket.TransferTypeDeposit, To: p.ID, Amount: 20, AccountID: a.ID})
+	tx.Create(&pocket.PocketTransfer{Type: pocket.TransferTypeInterest, To: p.ID, Amount: 0.5})
+
+	// Act
+	r, err := Run(tx, TriggerCLI)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, r.OK)
+	assert.Empty(t, r.Discrepancies)
+	assert.Equal(t, 3, r.Accounts)
+	assert.Equal(t, 1, r.Pockets)
+	assert.Equal(t, int64(2), r.Transfers)
+	assert.Equal(t, 100.5, r.Holdings)
+	assert.Equal(t, 100.5, r.Inflow)
+
+	var stored, notified int64
+	tx.Model(&ReconciliationReport{}).Where("trigger = ? AND ok = ?", TriggerCLI, true).Count(&stored)
+	tx.Model(&notification.Notification{}).Count(&notified)
+	assert.Equal(t, int64(1), stored)
+	assert.Equal(t, int64(0), notified)
+}
+
+func TestDiscrepancies(t *testing.T) {
+	// Arrange
+	tx := setup(t).Begin()
+	defer tx.Rollback()
+
+	admin := &account.Account{Email: "ops@test.com", AccountNumber: "1000000009", Role: account.RoleAdmin, Balance: 10}
+	retired := &account.Account{Email: "old@test.com", AccountNumber: "1000000008", Role: account.RoleAdmin, Status: account.StatusClosed}
+	a := &account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: 110}
+	b := &account.Account{Email: "b@test.com", AccountNumber: "1000000002", Balance: -5}
+	tx.Create(admin)
+	tx.Create(retired)
+	tx.Create(a)
+	tx.Create(b)
+
+	tx.Create(&account.AccountTransfer{Type: account.TransferTypeDeposit, To: a.AccountNumber, Amount: 100})
+	paid := &account.AccountTransfer{Type: account.TransferTypeTransfer, From: a.AccountNumber, To: admin.AccountNumber, Amount: 10, ReversedAmount: 10}
+	tx.Create(paid)
+	orphan := &account.AccountTransfer{Type: account.TransferTypeAdjustment, From: "9999999999", Amount: 7}
+	tx.Create(orphan)
+
+	// Act
+	r, err := Run(tx, TriggerSchedule)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, r.OK)
+	assert.Equal(t, []ReconciliationDiscrepancy{
+		{Check: CheckNegative, Target: fmt.Sprintf("accounts:%d", b.ID), Actual: -5},
+		{Check: CheckBalance, Target: fmt.Sprintf("accounts:%d", a.ID), Expected: 90, Actual: 110},
+		{Check: CheckBalance, Target: fmt.Sprintf("accounts:%d", b.ID), Expected: 0, Actual: -5},
+		{Check: CheckTransfer, Target: fmt.Sprintf("account_transfers:%d", orphan.ID), Expected: 7},
+		{Check: CheckTransfer, Target: fmt.Sprintf("account_transfers:%d", paid.ID), Expected: 0, Actual: 10},
+		{Check: CheckInflow, Target: "bank", Expected: 93, Actual: 115},
+	}, checks(r.Discrepancies))
+
+	stored := ReconciliationReport{}
+	tx.Preload("Discrepancies").First(&stored, r.ID)
+	assert.Len(t, stored.Discrepancies, 6)
+
+	n := []notification.Notification{}
+	tx.Find(&n)
+	assert.Len(t, n, 1)
+	assert.Equal(t, admin.ID, n[0].AccountID)
+	assert.Equal(t, event.DiscrepanciesFound, n[0].Type)
+	assert.Equal(t, fmt.Sprintf("Reconciliation report %d found 6 discrepancies", r.ID), n[0].Message)
+}
+
+// checks strips what is only there to be read from discrepancies.
+func checks(discrepancies []ReconciliationDiscrepancy) []ReconciliationDiscrepancy {
+	stripped := []ReconciliationDiscrepancy{}
+	for _, d := range discrepancies {
+		stripped = append(stripped, ReconciliationDiscrepancy{Check: d.Check, Target: d.Target, Expected: d.Expected, Actual: d.Actual})
+	}
+	return stripped
+}
+
+func TestReports(t *testing.T) {
+	// Arrange
+	tx := setup(t).Begin()
+	defer tx.Rollback()
+
+	tx.Create(&account.Account{Email: "a@test.com", AccountNumber: "1000000001", Balance: -1})
+	_, err := Run(tx, TriggerSchedule)
+	assert.NoError(t, err)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	h := New(tx)
+	app.Get("/admin/reconciliations", h.GetAllReports)
+	app.Get("/admin/reconciliations/:id", h.GetReport)
+	app.Post("/admin/reconciliations", h.RunReconciliation)
+
+	// Act
+	runResp, runErr := app.Test(httptest.NewRequest(http.MethodPost, "/admin/reconciliations", nil))
+	ran := ReconciliationReport{}
+	json.NewDecoder(runResp.Body).Decode(&ran)
+	listResp, listErr := app.Test(httptest.NewRequest(http.MethodGet, "/admin/reconciliations?failed=true&count=1", nil))
+	list := ReportList{}
+	json.NewDecoder(listResp.Body).Decode(&list)
+	getResp, getErr := app.Test(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/admin/reconciliations/%d", ran.ID), nil))
+	got := ReconciliationReport{}
+	json.NewDecoder(getResp.Body).Decode(&got)
+	missingResp, missingErr := app.Test(httptest.NewRequest(http.MethodGet, "/admin/reconciliations/999", nil))
+	badResp, badErr := app.Test(httptest.NewRequest(http.MethodGet, "/admin/reconciliations?failed=maybe", nil))
+
+	// Assert
+	assert.NoError(t, runErr)
+	assert.Equal(t, fiber.StatusOK, runResp.StatusCode)
+	assert.Equal(t, TriggerAdmin, ran.Trigger)
+	assert.NoError(t, listErr)
+	assert.Equal(t, int64(2), list.TotalCount)
+	assert.Equal(t, 2, list.TotalPage)
+	assert.Equal(t, ran.ID, list.Reports[0].ID)
+	assert.Len(t, list.Reports[0].Discrepancies, 3)
+	assert.NoError(t, getErr)
+	assert.Equal(t, fiber.StatusOK, getResp.StatusCode)
+	assert.Equal(t, ran.Discrepancies, got.Discrepancies)
+	assert.NoError(t, missingErr)
+	assert.Equal(t, fiber.StatusNotFound, missingResp.StatusCode)
+	assert.NoError(t, badErr)
+	assert.Equal(t, fiber.StatusBadRequest, badResp.StatusCode)
+}
@@ -0,0 +1,98 @@
+package reconciliation
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// @Summary Reconcile balances
+// @Description Check every balance and transfer now, and keep what was found as a report
+// @Tags admin
+// @Produce json
+// @Success 200 {object} reconciliation.ReconciliationReport
+// @Security  Bearer
+// @Router /admin/reconciliations [post]
+func (h *handler) RunReconciliation(c *fiber.Ctx) error {
+	r, err := Run(h.DB, TriggerAdmin)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(r)
+}
+
+// @Summary Get reconciliation reports
+// @Description Get the reports of past reconciliations, newest first
+// @Tags admin
+// @Produce json
+// @Param failed query bool false "Only reports that found discrepancies"
+// @Param page query int false "Page"
+// @Param count query int false "Reports per page"
+// @Success 200 {object} reconciliation.ReportList
+// @Security  Bearer
+// @Router /admin/reconciliations [get]
+func (h *handler) GetAllReports(c *fiber.Ctx) error {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return apperr.BadRequest("invalid page parameter")
+	}
+	limit, err := strconv.Atoi(c.Query("count", "20"))
+	if err != nil || limit < 1 {
+		return apperr.BadRequest("invalid count parameter")
+	}
+
+	tx := h.DB.Model(&ReconciliationReport{})
+	if v := c.Query("failed"); v != "" {
+		failed, err := strconv.ParseBool(v)
+		if err != nil {
+			return apperr.BadRequest("invalid failed parameter")
+		}
+		tx = tx.Where("ok = ?", !failed)
+	}
+
+	var totalCount int64
+	if err := tx.Count(&totalCount).Error; err != nil {
+		return apperr.Internal(err)
+	}
+
+	reports := []ReconciliationReport{}
+	if err := tx.Preload("Discrepancies").Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&reports).Error; err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(ReportList{
+		Reports:    reports,
+		Page:       page,
+		Count:      limit,
+		TotalPage:  int(math.Ceil(float64(totalCount) / float64(limit))),
+		TotalCount: totalCount,
+	})
+}
+
+// @Summary Get a reconciliation report
+// @Description Get one reconciliation report with its discrepancies
+// @Tags admin
+// @Produce json
+// @Param id path int true "Report ID"
+// @Success 200 {object} reconciliation.ReconciliationReport
+// @Security  Bearer
+// @Router /admin/reconciliations/{id} [get]
+func (h *handler) GetReport(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	r := &ReconciliationReport{}
+	if err := h.DB.Preload("Discrepancies").First(r, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodeNotFound, "report not found")
+		}
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(r)
+}
@@ -1,14 +1,28 @@
 package routes
 
 import (
-	"os"
-
 	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/approval"
+	"github.com/arthit666/make_app/audit"
+	"github.com/arthit666/make_app/batch"
+	"github.com/arthit666/make_app/budget"
+	"github.com/arthit666/make_app/config"
+	"github.com/arthit666/make_app/interest"
+	"github.com/arthit666/make_app/ledger"
+	"github.com/arthit666/make_app/reconciliation"
 
 	"github.com/arthit666/make_app/middleware"
+	"github.com/arthit666/make_app/notification"
+	"github.com/arthit666/make_app/payment"
 	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/qr"
+	"github.com/arthit666/make_app/rule"
+	"github.com/arthit666/make_app/statement"
+	"github.com/arthit666/make_app/stream"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	jwtware "github.com/gofiber/jwt/v2"
 	"gorm.io/gorm"
 
@@ -16,25 +30,27 @@ import (
 	_ "github.com/arthit666/make_app/docs"
 )
 
-func RegRoute(db *gorm.DB) *fiber.App {
-	app := fiber.New()
+func RegRoute(db *gorm.DB, cfg *config.Config) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+
+	app.Use(requestid.New())
 
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins: cfg.CORS.AllowOrigins,
+		AllowHeaders: cfg.CORS.AllowHeaders,
 	}))
 
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
-	a := account.New(db)
-	app.Post("/login/", a.Login)
+	a := account.New(db, cfg)
+	app.Post("/login/", audit.Log(db, "auth.login", audit.AccountByEmail), a.Login)
 	app.Get("/refresh/", a.RefreshAccessToken)
-	app.Post("/accounts/", a.CreateAccount)
+	app.Post("/accounts/", audit.Log(db, "account.create", audit.AccountByEmail), a.CreateAccount)
 
 	app.Use(jwtware.New(jwtware.Config{
-		SigningKey: []byte(os.Getenv("JWT_SECRET")),
+		SigningKeys: account.SigningKeys(cfg.JWT),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			return fiber.ErrUnauthorized
+			return apperr.Unauthenticated("missing or invalid jwt token")
 		},
 	}))
 
@@ -42,15 +58,106 @@ func RegRoute(db *gorm.DB) *fiber.App {
 
 	app.Get("/accounts/", a.GetAllAccounts)
 	app.Get("/account/", a.GetAccountDetail)
-	app.Post("/accounts/transfer", a.Transfer)
+	app.Put("/account/alias", audit.Log(db, "account.alias", audit.Caller), a.SetAlias)
+	app.Get("/account/insights", a.GetInsights)
+	app.Get("/account/categories/suggest", a.SuggestCategory)
+	app.Post("/accounts/transfer", audit.Log(db, "account.transfer", audit.Caller), a.Transfer)
+
+	bt := batch.New(db)
+	app.Post("/accounts/transfers/batch", audit.Log(db, "account.transfer.batch", audit.Caller), bt.CreateBatch)
+	app.Get("/accounts/transfers/batch/:id", bt.GetBatch)
+	app.Get("/accounts/transfers/batch/:id/result", bt.GetResult)
+
+	sm := stream.New(db)
+	app.Get("/account/stream", sm.GetStream)
+	app.Get("/account/stream/ws", sm.Upgrade, sm.GetSocket())
+
+	st := statement.New(db)
+	app.Get("/account/statement", st.GetStatement)
+	app.Get("/account/export", st.GetExport)
+
+	lg := ledger.New(db)
+	app.Get("/account/balance", lg.GetBalance)
+
+	account.RegisterActions(cfg.Policy)
+
+	admin := app.Group("/admin", middleware.RequireRole(account.RoleAdmin))
+	admin.Post("/transfers/:id/reverse", audit.Log(db, "admin.transfer.reverse", audit.Row("account_transfers", "id")), a.ReverseTransfer)
+	admin.Post("/transfers/:id/settle", audit.Log(db, "admin.transfer.settle", audit.Row("account_transfers", "id")), a.SettleReversal)
+	admin.Post("/accounts/:id/adjust", audit.Log(db, "admin.account.adjust", audit.Row("accounts", "id")), a.AdjustBalance)
+	admin.Post("/accounts/:id/limit", audit.Log(db, "admin.account.limit", audit.Row("accounts", "id")), a.SetTransferLimit)
+	admin.Post("/accounts/:id/close", audit.Log(db, "admin.account.close", audit.Row("accounts", "id")), a.CloseAccount)
+
+	ap := approval.New(db)
+	admin.Get("/approvals/", ap.GetAllPendingActions)
+	admin.Post("/approvals/:id/approve", audit.Log(db, "admin.approval.approve", audit.Row("pending_actions", "id")), ap.Approve)
+	admin.Post("/approvals/:id/reject", audit.Log(db, "admin.approval.reject", audit.Row("pending_actions", "id")), ap.Reject)
 
-	p := pocket.New(db)
-	app.Post("/pockets/", p.CreatePocket)
+	in := interest.New(db)
+	admin.Get("/interest/plans", in.GetAllPlans)
+	admin.Post("/interest/plans", audit.Log(db, "admin.interest.plan", audit.Named("interest_plans")), in.SetPlan)
+	admin.Post("/interest/accrue", audit.Log(db, "admin.interest.accrue", audit.Named("interest_accruals")), in.RunAccrual)
+	admin.Post("/interest/capitalize", audit.Log(db, "admin.interest.capitalize", audit.Named("interest_postings")), in.RunCapitalization)
+
+	rc := reconciliation.New(db)
+	admin.Get("/reconciliations", rc.GetAllReports)
+	admin.Get("/reconciliations/:id", rc.GetReport)
+	admin.Post("/reconciliations", audit.Log(db, "admin.reconciliation.run", audit.Named("reconciliation_reports")), rc.RunReconciliation)
+
+	au := audit.New(db)
+	auditor := app.Group("/audit", middleware.RequireRole(account.RoleAuditor))
+	auditor.Get("/", au.GetAllRecords)
+	auditor.Get("/verify", au.VerifyLog)
+
+	p := pocket.New(db, cfg)
+	app.Post("/pockets/", audit.Log(db, "pocket.create", audit.Caller), p.CreatePocket)
 	app.Get("/pockets/", p.GetAllPockets)
+	app.Get("/pockets/goals", p.GetGoals)
+	app.Get("/pockets/invitations", p.GetInvitations)
+	app.Post("/pockets/invitations/:id/accept", audit.Log(db, "pocket.invitation.accept", audit.Row("pocket_members", "id")), p.AcceptInvitation)
+	app.Post("/pockets/invitations/:id/decline", audit.Log(db, "pocket.invitation.decline", audit.Row("pocket_members", "id")), p.DeclineInvitation)
 	app.Get("/pockets/:id", p.GetPocketById)
-	app.Put("/pockets/:id", p.UpdatePocket)
-	app.Delete("/pockets/:id", p.DeletePocket)
-	app.Post("/pockets/transfer", p.Transfer)
+	app.Put("/pockets/:id", audit.Log(db, "pocket.update", audit.Row("pockets", "id")), p.UpdatePocket)
+	app.Delete("/pockets/:id", audit.Log(db, "pocket.delete", audit.Caller), p.DeletePocket)
+	app.Post("/pockets/transfer", audit.Log(db, "pocket.transfer", audit.Caller), p.Transfer)
+	app.Post("/pockets/:id/deposit", audit.Log(db, "pocket.deposit", audit.Row("pockets", "id")), p.Deposit)
+	app.Post("/pockets/:id/withdraw", audit.Log(db, "pocket.withdraw", audit.Row("pockets", "id")), p.Withdraw)
+	app.Get("/pockets/:id/transfers", p.GetTransfers)
+	app.Get("/pockets/:id/members", p.GetMembers)
+	app.Post("/pockets/:id/members", audit.Log(db, "pocket.member.invite", audit.Row("pockets", "id")), p.InviteMember)
+	app.Put("/pockets/:id/members/:member", audit.Log(db, "pocket.member.update", audit.Row("pocket_members", "member")), p.UpdateMember)
+	app.Delete("/pockets/:id/members/:member", audit.Log(db, "pocket.member.remove", audit.Row("pocket_members", "member")), p.RemoveMember)
+
+	b := budget.New(db)
+	app.Post("/budgets/", audit.Log(db, "budget.create", audit.Caller), b.CreateBudget)
+	app.Get("/budgets/", b.GetAllBudgets)
+	app.Put("/budgets/:id", audit.Log(db, "budget.update", audit.Row("budgets", "id")), b.UpdateBudget)
+	app.Delete("/budgets/:id", audit.Log(db, "budget.delete", audit.Row("budgets", "id")), b.DeleteBudget)
+
+	n := notification.New(db)
+	app.Get("/notifications/", n.GetAllNotifications)
+	app.Post("/notifications/:id/read", audit.Log(db, "notification.read", audit.Row("notifications", "id")), n.MarkRead)
+
+	pr := payment.New(db)
+	app.Post("/payment-requests/", audit.Log(db, "payment_request.create", audit.Caller), pr.CreatePaymentRequest)
+	app.Post("/payment-requests/split", audit.Log(db, "payment_request.split", audit.Caller), pr.SplitBill)
+	app.Get("/payment-requests/", pr.GetAllPaymentRequests)
+	app.Get("/payment-requests/incoming", pr.GetIncoming)
+	app.Get("/payment-requests/link/:token", pr.GetLink)
+	app.Post("/payment-requests/link/:token/pay", audit.Log(db, "payment_request.pay", audit.Caller), pr.PayLink)
+	app.Post("/payment-requests/:id/pay", audit.Log(db, "payment_request.pay", audit.Row("payment_requests", "id")), pr.Pay)
+	app.Post("/payment-requests/:id/decline", audit.Log(db, "payment_request.decline", audit.Row("payment_requests", "id")), pr.Decline)
+	app.Post("/payment-requests/:id/cancel", audit.Log(db, "payment_request.cancel", audit.Row("payment_requests", "id")), pr.Cancel)
+
+	q := qr.New(db)
+	app.Post("/qr/", q.Generate)
+	app.Post("/qr/parse", q.Parse)
+
+	r := rule.New(db)
+	app.Post("/rules/", audit.Log(db, "rule.create", audit.Caller), r.CreateRule)
+	app.Get("/rules/", r.GetAllRules)
+	app.Delete("/rules/:id", audit.Log(db, "rule.delete", audit.Row("rules", "id")), r.DeleteRule)
+	app.Get("/rules/:id/executions", r.GetExecutions)
 
 	return app
 }
@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/arthit666/make_app/audit"
+	"github.com/arthit666/make_app/rpc/bankpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// actions are the audit actions of the methods that change something,
+// named like the REST routes doing the same.
+var actions = map[string]string{
+	"/bank.v1.Bank/Login":                  "auth.login",
+	"/bank.v1.Bank/Transfer":               "account.transfer",
+	"/bank.v1.Bank/CreatePocket":           "pocket.create",
+	"/bank.v1.Bank/DeletePocket":           "pocket.delete",
+	"/bank.v1.Bank/Deposit":                "pocket.deposit",
+	"/bank.v1.Bank/Withdraw":               "pocket.withdraw",
+	"/bank.v1.Bank/TransferBetweenPockets": "pocket.transfer",
+}
+
+// httpStatus is the HTTP status the REST API answers with for code, so
+// records from both APIs read the same.
+var httpStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.NotFound:           http.StatusNotFound,
+	codes.FailedPrecondition: http.StatusUnprocessableEntity,
+}
+
+// audit writes an audit record for every call to one of actions, with the
+// caller's account before and after it, like audit.Log does for routes.
+func (s *server) audit(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	action, ok := actions[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	target := func() (string, map[string]interface{}) {
+		var snap map[string]interface{}
+		if login, ok := req.(*bankpb.LoginRequest); ok {
+			snap = audit.AccountSnapshot(s.DB, "email = ?", login.Email)
+		} else {
+			snap = audit.AccountSnapshot(s.DB, "id = ?", callerFrom(ctx).accountID)
+		}
+		if snap == nil {
+			return "accounts:", nil
+		}
+		return fmt.Sprintf("accounts:%v", snap["id"]), snap
+	}
+
+	_, before := target()
+	res, err := handler(ctx, req)
+	name, after := target()
+
+	r := &audit.Record{
+		Action: action,
+		Target: name,
+		Status: http.StatusInternalServerError,
+		Before: encode(before),
+		After:  encode(after),
+	}
+	if code, ok := httpStatus[status.Code(err)]; ok {
+		r.Status = code
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.IP = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("user-agent")) > 0 {
+		r.UserAgent = md.Get("user-agent")[0]
+	}
+	if c := callerFrom(ctx); c.accountID != 0 {
+		r.ActorID = &c.accountID
+	}
+
+	if aerr := audit.Append(s.DB, r); aerr != nil {
+		log.Printf("audit: failed to record %s on %s: %s", action, name, aerr)
+	}
+	return res, err
+}
+
+func encode(snap map[string]interface{}) string {
+	if snap == nil {
+		return ""
+	}
+	b, _ := json.Marshal(snap)
+	return string(b)
+}
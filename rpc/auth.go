@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/config"
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// public are the methods that can be called without a token.
+var public = map[string]bool{
+	"/bank.v1.Bank/Login": true,
+}
+
+type caller struct {
+	accountID uint
+	role      string
+}
+
+type callerKey struct{}
+
+// callerFrom is the account the token of the call was issued to.
+func callerFrom(ctx context.Context) caller {
+	c, _ := ctx.Value(callerKey{}).(caller)
+	return c
+}
+
+// authenticate checks the bearer token in the "authorization" metadata of
+// ctx the way the REST API checks the Authorization header, and adds the
+// account and role it carries to ctx.
+func authenticate(ctx context.Context, cfg config.JWT) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing jwt token")
+	}
+
+	token, err := jwt.Parse(strings.TrimPrefix(values[0], "Bearer "), account.Keyfunc(cfg))
+	if err != nil || !token.Valid {
+		return nil, status.Error(codes.Unauthenticated, "invalid jwt token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid jwt token")
+	}
+	accountID, ok := claims["account_id"].(float64)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid jwt token")
+	}
+	role, _ := claims["role"].(string)
+
+	return context.WithValue(ctx, callerKey{}, caller{accountID: uint(accountID), role: role}), nil
+}
+
+// UnaryAuth rejects unary calls to anything but the public methods without
+// a valid token signed with one of the keys of cfg.
+func UnaryAuth(cfg config.JWT) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuth rejects streams without a valid token signed with one of the
+// keys of cfg.
+func StreamAuth(cfg config.JWT) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), cfg)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authStream{ss, ctx})
+	}
+}
+
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
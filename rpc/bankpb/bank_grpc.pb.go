@@ -0,0 +1,579 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: bank/v1/bank.proto
+
+package bankpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Bank_Login_FullMethodName                  = "/bank.v1.Bank/Login"
+	Bank_GetAccount_FullMethodName             = "/bank.v1.Bank/GetAccount"
+	Bank_Transfer_FullMethodName               = "/bank.v1.Bank/Transfer"
+	Bank_ListPockets_FullMethodName            = "/bank.v1.Bank/ListPockets"
+	Bank_GetPocket_FullMethodName              = "/bank.v1.Bank/GetPocket"
+	Bank_CreatePocket_FullMethodName           = "/bank.v1.Bank/CreatePocket"
+	Bank_DeletePocket_FullMethodName           = "/bank.v1.Bank/DeletePocket"
+	Bank_Deposit_FullMethodName                = "/bank.v1.Bank/Deposit"
+	Bank_Withdraw_FullMethodName               = "/bank.v1.Bank/Withdraw"
+	Bank_TransferBetweenPockets_FullMethodName = "/bank.v1.Bank/TransferBetweenPockets"
+	Bank_WatchBalance_FullMethodName           = "/bank.v1.Bank/WatchBalance"
+	Bank_WatchTransfers_FullMethodName         = "/bank.v1.Bank/WatchTransfers"
+)
+
+// BankClient is the client API for Bank service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BankClient interface {
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*AccountTransfer, error)
+	ListPockets(ctx context.Context, in *ListPocketsRequest, opts ...grpc.CallOption) (*ListPocketsResponse, error)
+	GetPocket(ctx context.Context, in *GetPocketRequest, opts ...grpc.CallOption) (*Pocket, error)
+	CreatePocket(ctx context.Context, in *CreatePocketRequest, opts ...grpc.CallOption) (*Pocket, error)
+	DeletePocket(ctx context.Context, in *DeletePocketRequest, opts ...grpc.CallOption) (*DeletePocketResponse, error)
+	Deposit(ctx context.Context, in *PocketAmountRequest, opts ...grpc.CallOption) (*PocketTransfer, error)
+	Withdraw(ctx context.Context, in *PocketAmountRequest, opts ...grpc.CallOption) (*PocketTransfer, error)
+	TransferBetweenPockets(ctx context.Context, in *PocketTransferRequest, opts ...grpc.CallOption) (*PocketTransfer, error)
+	// WatchBalance sends the balances of the account now and again every
+	// time money moves in or out of it or its pockets.
+	WatchBalance(ctx context.Context, in *WatchBalanceRequest, opts ...grpc.CallOption) (Bank_WatchBalanceClient, error)
+	// WatchTransfers sends every transfer the account sends or receives from
+	// now on.
+	WatchTransfers(ctx context.Context, in *WatchTransfersRequest, opts ...grpc.CallOption) (Bank_WatchTransfersClient, error)
+}
+
+type bankClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBankClient(cc grpc.ClientConnInterface) BankClient {
+	return &bankClient{cc}
+}
+
+func (c *bankClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, Bank_Login_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, Bank_GetAccount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*AccountTransfer, error) {
+	out := new(AccountTransfer)
+	err := c.cc.Invoke(ctx, Bank_Transfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) ListPockets(ctx context.Context, in *ListPocketsRequest, opts ...grpc.CallOption) (*ListPocketsResponse, error) {
+	out := new(ListPocketsResponse)
+	err := c.cc.Invoke(ctx, Bank_ListPockets_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) GetPocket(ctx context.Context, in *GetPocketRequest, opts ...grpc.CallOption) (*Pocket, error) {
+	out := new(Pocket)
+	err := c.cc.Invoke(ctx, Bank_GetPocket_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) CreatePocket(ctx context.Context, in *CreatePocketRequest, opts ...grpc.CallOption) (*Pocket, error) {
+	out := new(Pocket)
+	err := c.cc.Invoke(ctx, Bank_CreatePocket_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) DeletePocket(ctx context.Context, in *DeletePocketRequest, opts ...grpc.CallOption) (*DeletePocketResponse, error) {
+	out := new(DeletePocketResponse)
+	err := c.cc.Invoke(ctx, Bank_DeletePocket_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) Deposit(ctx context.Context, in *PocketAmountRequest, opts ...grpc.CallOption) (*PocketTransfer, error) {
+	out := new(PocketTransfer)
+	err := c.cc.Invoke(ctx, Bank_Deposit_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) Withdraw(ctx context.Context, in *PocketAmountRequest, opts ...grpc.CallOption) (*PocketTransfer, error) {
+	out := new(PocketTransfer)
+	err := c.cc.Invoke(ctx, Bank_Withdraw_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) TransferBetweenPockets(ctx context.Context, in *PocketTransferRequest, opts ...grpc.CallOption) (*PocketTransfer, error) {
+	out := new(PocketTransfer)
+	err := c.cc.Invoke(ctx, Bank_TransferBetweenPockets_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankClient) WatchBalance(ctx context.Context, in *WatchBalanceRequest, opts ...grpc.CallOption) (Bank_WatchBalanceClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Bank_ServiceDesc.Streams[0], Bank_WatchBalance_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bankWatchBalanceClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Bank_WatchBalanceClient interface {
+	Recv() (*Balance, error)
+	grpc.ClientStream
+}
+
+type bankWatchBalanceClient struct {
+	grpc.ClientStream
+}
+
+func (x *bankWatchBalanceClient) Recv() (*Balance, error) {
+	m := new(Balance)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *bankClient) WatchTransfers(ctx context.Context, in *WatchTransfersRequest, opts ...grpc.CallOption) (Bank_WatchTransfersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Bank_ServiceDesc.Streams[1], Bank_WatchTransfers_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bankWatchTransfersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Bank_WatchTransfersClient interface {
+	Recv() (*AccountTransfer, error)
+	grpc.ClientStream
+}
+
+type bankWatchTransfersClient struct {
+	grpc.ClientStream
+}
+
+func (x *bankWatchTransfersClient) Recv() (*AccountTransfer, error) {
+	m := new(AccountTransfer)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BankServer is the server API for Bank service.
+// All implementations must embed UnimplementedBankServer
+// for forward compatibility
+type BankServer interface {
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	GetAccount(context.Context, *GetAccountRequest) (*Account, error)
+	Transfer(context.Context, *TransferRequest) (*AccountTransfer, error)
+	ListPockets(context.Context, *ListPocketsRequest) (*ListPocketsResponse, error)
+	GetPocket(context.Context, *GetPocketRequest) (*Pocket, error)
+	CreatePocket(context.Context, *CreatePocketRequest) (*Pocket, error)
+	DeletePocket(context.Context, *DeletePocketRequest) (*DeletePocketResponse, error)
+	Deposit(context.Context, *PocketAmountRequest) (*PocketTransfer, error)
+	Withdraw(context.Context, *PocketAmountRequest) (*PocketTransfer, error)
+	TransferBetweenPockets(context.Context, *PocketTransferRequest) (*PocketTransfer, error)
+	// WatchBalance sends the balances of the account now and again every
+	// time money moves in or out of it or its pockets.
+	WatchBalance(*WatchBalanceRequest, Bank_WatchBalanceServer) error
+	// WatchTransfers sends every transfer the account sends or receives from
+	// now on.
+	WatchTransfers(*WatchTransfersRequest, Bank_WatchTransfersServer) error
+	mustEmbedUnimplementedBankServer()
+}
+
+// UnimplementedBankServer must be embedded to have forward compatible implementations.
+type UnimplementedBankServer struct {
+}
+
+func (UnimplementedBankServer) Login(context.Context, *LoginRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedBankServer) GetAccount(context.Context, *GetAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccount not implemented")
+}
+func (UnimplementedBankServer) Transfer(context.Context, *TransferRequest) (*AccountTransfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedBankServer) ListPockets(context.Context, *ListPocketsRequest) (*ListPocketsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPockets not implemented")
+}
+func (UnimplementedBankServer) GetPocket(context.Context, *GetPocketRequest) (*Pocket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPocket not implemented")
+}
+func (UnimplementedBankServer) CreatePocket(context.Context, *CreatePocketRequest) (*Pocket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePocket not implemented")
+}
+func (UnimplementedBankServer) DeletePocket(context.Context, *DeletePocketRequest) (*DeletePocketResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePocket not implemented")
+}
+func (UnimplementedBankServer) Deposit(context.Context, *PocketAmountRequest) (*PocketTransfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deposit not implemented")
+}
+func (UnimplementedBankServer) Withdraw(context.Context, *PocketAmountRequest) (*PocketTransfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Withdraw not implemented")
+}
+func (UnimplementedBankServer) TransferBetweenPockets(context.Context, *PocketTransferRequest) (*PocketTransfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferBetweenPockets not implemented")
+}
+func (UnimplementedBankServer) WatchBalance(*WatchBalanceRequest, Bank_WatchBalanceServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchBalance not implemented")
+}
+func (UnimplementedBankServer) WatchTransfers(*WatchTransfersRequest, Bank_WatchTransfersServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchTransfers not implemented")
+}
+func (UnimplementedBankServer) mustEmbedUnimplementedBankServer() {}
+
+// UnsafeBankServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BankServer will
+// result in compilation errors.
+type UnsafeBankServer interface {
+	mustEmbedUnimplementedBankServer()
+}
+
+func RegisterBankServer(s grpc.ServiceRegistrar, srv BankServer) {
+	s.RegisterService(&Bank_ServiceDesc, srv)
+}
+
+func _Bank_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_GetAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).GetAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_GetAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).GetAccount(ctx, req.(*GetAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_Transfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_ListPockets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPocketsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).ListPockets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_ListPockets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).ListPockets(ctx, req.(*ListPocketsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_GetPocket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPocketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).GetPocket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_GetPocket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).GetPocket(ctx, req.(*GetPocketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_CreatePocket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePocketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).CreatePocket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_CreatePocket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).CreatePocket(ctx, req.(*CreatePocketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_DeletePocket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePocketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).DeletePocket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_DeletePocket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).DeletePocket(ctx, req.(*DeletePocketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_Deposit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PocketAmountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).Deposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_Deposit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).Deposit(ctx, req.(*PocketAmountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_Withdraw_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PocketAmountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).Withdraw(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_Withdraw_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).Withdraw(ctx, req.(*PocketAmountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_TransferBetweenPockets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PocketTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankServer).TransferBetweenPockets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Bank_TransferBetweenPockets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankServer).TransferBetweenPockets(ctx, req.(*PocketTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bank_WatchBalance_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchBalanceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BankServer).WatchBalance(m, &bankWatchBalanceServer{stream})
+}
+
+type Bank_WatchBalanceServer interface {
+	Send(*Balance) error
+	grpc.ServerStream
+}
+
+type bankWatchBalanceServer struct {
+	grpc.ServerStream
+}
+
+func (x *bankWatchBalanceServer) Send(m *Balance) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Bank_WatchTransfers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTransfersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BankServer).WatchTransfers(m, &bankWatchTransfersServer{stream})
+}
+
+type Bank_WatchTransfersServer interface {
+	Send(*AccountTransfer) error
+	grpc.ServerStream
+}
+
+type bankWatchTransfersServer struct {
+	grpc.ServerStream
+}
+
+func (x *bankWatchTransfersServer) Send(m *AccountTransfer) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Bank_ServiceDesc is the grpc.ServiceDesc for Bank service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Bank_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bank.v1.Bank",
+	HandlerType: (*BankServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Login",
+			Handler:    _Bank_Login_Handler,
+		},
+		{
+			MethodName: "GetAccount",
+			Handler:    _Bank_GetAccount_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _Bank_Transfer_Handler,
+		},
+		{
+			MethodName: "ListPockets",
+			Handler:    _Bank_ListPockets_Handler,
+		},
+		{
+			MethodName: "GetPocket",
+			Handler:    _Bank_GetPocket_Handler,
+		},
+		{
+			MethodName: "CreatePocket",
+			Handler:    _Bank_CreatePocket_Handler,
+		},
+		{
+			MethodName: "DeletePocket",
+			Handler:    _Bank_DeletePocket_Handler,
+		},
+		{
+			MethodName: "Deposit",
+			Handler:    _Bank_Deposit_Handler,
+		},
+		{
+			MethodName: "Withdraw",
+			Handler:    _Bank_Withdraw_Handler,
+		},
+		{
+			MethodName: "TransferBetweenPockets",
+			Handler:    _Bank_TransferBetweenPockets_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchBalance",
+			Handler:       _Bank_WatchBalance_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchTransfers",
+			Handler:       _Bank_WatchTransfers_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bank/v1/bank.proto",
+}
@@ -0,0 +1,223 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/audit"
+	"github.com/arthit666/make_app/config"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/rpc/bankpb"
+	"github.com/arthit666/make_app/rule"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{},
+		&pocket.PocketMember{}, &rule.Rule{}, &rule.RuleExecution{}, &audit.Record{})
+	assert.NoError(t, err)
+	return db
+}
+
+// dial serves db over an in-memory connection and returns a client of it.
+func dial(t *testing.T, db *gorm.DB) bankpb.BankClient {
+	lis := bufconn.Listen(1 << 20)
+	cfg := config.Default()
+	cfg.JWT.Secret = "secret"
+	srv := New(db, cfg)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return bankpb.NewBankClient(conn)
+}
+
+// login creates an account with balance and returns it with a context
+// carrying its access token.
+func login(t *testing.T, tx *gorm.DB, client bankpb.BankClient, email, number string, balance float64) (*account.Account, context.Context) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	acc := &account.Account{Email: email, Password: string(hashed), AccountNumber: number, Balance: balance}
+	assert.NoError(t, tx.Create(acc).Error)
+
+	res, err := client.Login(context.Background(), &bankpb.LoginRequest{Email: email, Password: "secret"})
+	assert.NoError(t, err)
+	return acc, metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+res.AccessToken)
+}
+
+func TestAuth(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+	client := dial(t, tx)
+	acc, ctx := login(t, tx, client, "owner@test.com", "7000000001", 100)
+
+	// Act
+	_, noToken := client.GetAccount(context.Background(), &bankpb.GetAccountRequest{})
+	_, badToken := client.GetAccount(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer nope"), &bankpb.GetAccountRequest{})
+	_, badPassword := client.Login(context.Background(), &bankpb.LoginRequest{Email: "owner@test.com", Password: "wrong"})
+	got, err := client.GetAccount(ctx, &bankpb.GetAccountRequest{})
+
+	// Assert
+	assert.Equal(t, codes.Unauthenticated, status.Code(noToken))
+	assert.Equal(t, codes.Unauthenticated, status.Code(badToken))
+	assert.Equal(t, codes.Unauthenticated, status.Code(badPassword))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(acc.ID), got.Id)
+	assert.Equal(t, "7000000001", got.AccountNumber)
+	assert.Equal(t, 100.0, got.Balance)
+}
+
+func TestTransfer(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+	client := dial(t, tx)
+	from, ctx := login(t, tx, client, "from@test.com", "7000000011", 500)
+	to, _ := login(t, tx, client, "to@test.com", "7000000012", 0)
+
+	// Act
+	res, err := client.Transfer(ctx, &bankpb.TransferRequest{To: to.AccountNumber, Amount: 120.5, Memo: "rent"})
+	_, tooMuch := client.Transfer(ctx, &bankpb.TransferRequest{To: to.AccountNumber, Amount: 1000})
+	_, unknown := client.Transfer(ctx, &bankpb.TransferRequest{To: "0000000000", Amount: 1})
+	_, invalid := client.Transfer(ctx, &bankpb.TransferRequest{To: to.AccountNumber, Amount: -1})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, from.AccountNumber, res.From)
+	assert.Equal(t, "housing", res.Category)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(tooMuch))
+	assert.Equal(t, codes.NotFound, status.Code(unknown))
+	assert.Equal(t, codes.InvalidArgument, status.Code(invalid))
+
+	tx.First(from, from.ID)
+	tx.First(to, to.ID)
+	assert.Equal(t, 379.5, from.Balance)
+	assert.Equal(t, 120.5, to.Balance)
+
+	var records []audit.Record
+	tx.Where("action = ?", "account.transfer").Order("id").Find(&records)
+	assert.Equal(t, 4, len(records))
+	assert.Equal(t, 200, records[0].Status)
+	assert.Equal(t, from.ID, *records[0].ActorID)
+	assert.Equal(t, 422, records[1].Status)
+}
+
+func TestPockets(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+	client := dial(t, tx)
+	acc, ctx := login(t, tx, client, "pockets@test.com", "7000000021", 1000)
+	_, otherCtx := login(t, tx, client, "other@test.com", "7000000022", 0)
+
+	// Act
+	holiday, err := client.CreatePocket(ctx, &bankpb.CreatePocketRequest{Title: "Holiday", Balance: 300})
+	assert.NoError(t, err)
+	rainy, err := client.CreatePocket(ctx, &bankpb.CreatePocketRequest{Title: "Rainy day"})
+	assert.NoError(t, err)
+
+	_, depositErr := client.Deposit(ctx, &bankpb.PocketAmountRequest{Id: rainy.Id, Amount: 200})
+	_, withdrawErr := client.Withdraw(ctx, &bankpb.PocketAmountRequest{Id: holiday.Id, Amount: 50})
+	moved, transferErr := client.TransferBetweenPockets(ctx, &bankpb.PocketTransferRequest{From: holiday.Id, To: rainy.Id, Amount: 100})
+	_, overdrawn := client.Withdraw(ctx, &bankpb.PocketAmountRequest{Id: holiday.Id, Amount: 1000})
+	_, notMine := client.GetPocket(otherCtx, &bankpb.GetPocketRequest{Id: holiday.Id})
+	list, listErr := client.ListPockets(ctx, &bankpb.ListPocketsRequest{})
+	_, deleteErr := client.DeletePocket(ctx, &bankpb.DeletePocketRequest{Id: holiday.Id})
+
+	// Assert
+	assert.NoError(t, depositErr)
+	assert.NoError(t, withdrawErr)
+	assert.NoError(t, transferErr)
+	assert.Equal(t, 100.0, moved.Amount)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(overdrawn))
+	assert.Equal(t, codes.NotFound, status.Code(notMine))
+	assert.NoError(t, listErr)
+	assert.Equal(t, 2, len(list.Pockets))
+	assert.NoError(t, deleteErr)
+
+	tx.First(acc, acc.ID)
+	assert.Equal(t, 700.0, acc.Balance)
+
+	got, err := client.GetPocket(ctx, &bankpb.GetPocketRequest{Id: rainy.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, 300.0, got.Balance)
+}
+
+func TestWatch(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+	client := dial(t, tx)
+	from, fromCtx := login(t, tx, client, "watch-from@test.com", "7000000031", 500)
+	to, toCtx := login(t, tx, client, "watch-to@test.com", "7000000032", 0)
+
+	ctx, cancel := context.WithCancel(fromCtx)
+	defer cancel()
+	balances, err := client.WatchBalance(ctx, &bankpb.WatchBalanceRequest{})
+	assert.NoError(t, err)
+	_, err = balances.Header()
+	assert.NoError(t, err)
+
+	toCtx, cancelTo := context.WithCancel(toCtx)
+	defer cancelTo()
+	transfers, err := client.WatchTransfers(toCtx, &bankpb.WatchTransfersRequest{})
+	assert.NoError(t, err)
+	_, err = transfers.Header()
+	assert.NoError(t, err)
+
+	first, err := balances.Recv()
+	assert.NoError(t, err)
+
+	// Act
+	_, err = client.Transfer(fromCtx, &bankpb.TransferRequest{To: to.AccountNumber, Amount: 200})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, 500.0, first.Balance)
+
+	next, err := balances.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, 300.0, next.Balance)
+
+	received, err := transfers.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, from.AccountNumber, received.From)
+	assert.Equal(t, 200.0, received.Amount)
+}
+
+func TestToStatus(t *testing.T) {
+	// Act
+	notFound := status.Convert(toStatus(pocket.ErrPocketNotFound))
+	internal := status.Convert(toStatus(errors.New(`pq: relation "accounts" does not exist`)))
+
+	// Assert
+	assert.Equal(t, codes.NotFound, notFound.Code())
+	assert.Equal(t, pocket.ErrPocketNotFound.Error(), notFound.Message())
+	assert.Equal(t, codes.Internal, internal.Code())
+	assert.Equal(t, "internal server error", internal.Message())
+}
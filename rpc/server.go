@@ -0,0 +1,293 @@
+// Package rpc serves the gRPC API of the bank, defined in
+// proto/bank/v1/bank.proto. It runs the same services as the REST API, so
+// both enforce the same rules.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/config"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/rpc/bankpb"
+	"github.com/go-playground/validator"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+type server struct {
+	bankpb.UnimplementedBankServer
+
+	DB        *gorm.DB
+	cfg       *config.Config
+	accounts  *account.AccountService
+	transfers *account.TransferService
+	pockets   *pocket.PocketService
+}
+
+// New is a gRPC server with the Bank service registered, checking tokens
+// and writing every call that changes something to the audit log.
+func New(db *gorm.DB, cfg *config.Config) *grpc.Server {
+	s := &server{
+		DB:        db,
+		cfg:       cfg,
+		accounts:  account.NewAccountService(account.NewStore(db)),
+		transfers: account.NewTransferService(account.NewStore(db)),
+		pockets:   pocket.NewPocketService(pocket.NewStore(db), cfg.Policy),
+	}
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryAuth(cfg.JWT), s.audit),
+		grpc.StreamInterceptor(StreamAuth(cfg.JWT)),
+	)
+	bankpb.RegisterBankServer(srv, s)
+	return srv
+}
+
+func (s *server) Login(ctx context.Context, req *bankpb.LoginRequest) (*bankpb.LoginResponse, error) {
+	acc, err := s.accounts.Login(ctx, req.Email, req.Password)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	tokens, err := account.IssueTokens(s.cfg.JWT, acc)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &bankpb.LoginResponse{AccessToken: tokens.AccessToken, RefreshToken: tokens.RefreshToken}, nil
+}
+
+func (s *server) GetAccount(ctx context.Context, req *bankpb.GetAccountRequest) (*bankpb.Account, error) {
+	acc, err := s.accounts.Get(ctx, callerFrom(ctx).accountID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &bankpb.Account{
+		Id:            uint64(acc.ID),
+		Email:         acc.Email,
+		AccountNumber: acc.AccountNumber,
+		Balance:       acc.Balance,
+		Status:        acc.Status,
+		TransferLimit: acc.TransferLimit,
+	}
+	if acc.Alias != nil {
+		res.Alias = *acc.Alias
+	}
+	for i := range acc.PocketList {
+		res.Pockets = append(res.Pockets, toPocket(&acc.PocketList[i]))
+	}
+	return res, nil
+}
+
+func (s *server) Transfer(ctx context.Context, req *bankpb.TransferRequest) (*bankpb.AccountTransfer, error) {
+	tr := &account.AccountTransferRequest{
+		To:        req.To,
+		Amount:    req.Amount,
+		Memo:      req.Memo,
+		Reference: req.Reference,
+		Category:  req.Category,
+		Tags:      req.Tags,
+	}
+	if err := validate(tr); err != nil {
+		return nil, err
+	}
+
+	t, err := s.transfers.Send(ctx, callerFrom(ctx).accountID, tr)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTransfer(t), nil
+}
+
+func (s *server) ListPockets(ctx context.Context, req *bankpb.ListPocketsRequest) (*bankpb.ListPocketsResponse, error) {
+	pockets, err := s.pockets.List(ctx, callerFrom(ctx).accountID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &bankpb.ListPocketsResponse{}
+	for i := range pockets {
+		res.Pockets = append(res.Pockets, toPocket(&pockets[i]))
+	}
+	return res, nil
+}
+
+func (s *server) GetPocket(ctx context.Context, req *bankpb.GetPocketRequest) (*bankpb.Pocket, error) {
+	p, _, err := s.pockets.Access(ctx, uint(req.Id), callerFrom(ctx).accountID, pocket.RoleOwner, pocket.RoleContributor, pocket.RoleViewer)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toPocket(p), nil
+}
+
+func (s *server) CreatePocket(ctx context.Context, req *bankpb.CreatePocketRequest) (*bankpb.Pocket, error) {
+	pc := &pocket.PocketCreate{
+		Title:          req.Title,
+		Balance:        req.Balance,
+		TargetAmount:   req.TargetAmount,
+		TargetDate:     toTime(req.TargetDate),
+		Type:           req.Type,
+		MaturityDate:   toTime(req.MaturityDate),
+		MaturityAction: req.MaturityAction,
+	}
+	if req.Description != "" {
+		pc.Description = &req.Description
+	}
+	if err := validate(pc); err != nil {
+		return nil, err
+	}
+
+	p, err := s.pockets.Create(ctx, callerFrom(ctx).accountID, pc)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toPocket(p), nil
+}
+
+func (s *server) DeletePocket(ctx context.Context, req *bankpb.DeletePocketRequest) (*bankpb.DeletePocketResponse, error) {
+	if err := s.pockets.Delete(ctx, uint(req.Id), callerFrom(ctx).accountID, req.EarlyWithdrawal); err != nil {
+		return nil, toStatus(err)
+	}
+	return &bankpb.DeletePocketResponse{}, nil
+}
+
+func (s *server) Deposit(ctx context.Context, req *bankpb.PocketAmountRequest) (*bankpb.PocketTransfer, error) {
+	return s.move(ctx, req, pocket.TransferTypeDeposit)
+}
+
+func (s *server) Withdraw(ctx context.Context, req *bankpb.PocketAmountRequest) (*bankpb.PocketTransfer, error) {
+	return s.move(ctx, req, pocket.TransferTypeWithdrawal)
+}
+
+func (s *server) move(ctx context.Context, req *bankpb.PocketAmountRequest, typ string) (*bankpb.PocketTransfer, error) {
+	if err := validate(&pocket.PocketAmountRequest{Amount: req.Amount, EarlyWithdrawal: req.EarlyWithdrawal}); err != nil {
+		return nil, err
+	}
+
+	t, err := s.pockets.Move(ctx, uint(req.Id), callerFrom(ctx).accountID, typ, req.Amount, req.EarlyWithdrawal)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toPocketTransfer(t), nil
+}
+
+func (s *server) TransferBetweenPockets(ctx context.Context, req *bankpb.PocketTransferRequest) (*bankpb.PocketTransfer, error) {
+	tr := &pocket.PocketTransferRequest{
+		From:            uint(req.From),
+		To:              uint(req.To),
+		Amount:          req.Amount,
+		EarlyWithdrawal: req.EarlyWithdrawal,
+	}
+	if err := validate(tr); err != nil {
+		return nil, err
+	}
+
+	t, err := s.pockets.Transfer(ctx, callerFrom(ctx).accountID, tr)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toPocketTransfer(t), nil
+}
+
+// validate checks req against its validate tags, like the REST handlers do
+// with request bodies.
+func validate(req interface{}) error {
+	if err := validator.New().Struct(req); err != nil {
+		return status.Error(codes.InvalidArgument, "payload invalid: "+err.Error())
+	}
+	return nil
+}
+
+// toStatus is the gRPC status of an error from the services, with the codes
+// matching the HTTP statuses the REST API answers with.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, account.ErrAccountNotFound), errors.Is(err, account.ErrSenderNotFound),
+		errors.Is(err, account.ErrRecipientNotFound), errors.Is(err, pocket.ErrPocketNotFound),
+		errors.Is(err, pocket.ErrFromPocketNotFound), errors.Is(err, pocket.ErrToPocketNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, account.ErrInvalidCredentials):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, account.ErrAccountClosed), errors.Is(err, account.ErrAccountFrozen), errors.Is(err, pocket.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, account.ErrSelfTransfer), errors.Is(err, pocket.ErrLockedTerms), errors.Is(err, pocket.ErrSamePocket):
+		return status.Error(codes.InvalidArgument, "payload invalid: "+err.Error())
+	case errors.Is(err, account.ErrRecipientClosed), errors.Is(err, account.ErrTransferLimitExceeded),
+		errors.Is(err, account.ErrInsufficientBalance), errors.Is(err, pocket.ErrInsufficientAccountBalance),
+		errors.Is(err, pocket.ErrInsufficientPocketBalance), errors.Is(err, pocket.ErrPocketLocked),
+		errors.Is(err, pocket.ErrLockedDeposit), errors.Is(err, pocket.ErrAllowanceExceeded),
+		errors.Is(err, pocket.ErrMembersHoldFunds):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	// Like apperr.Internal, what went wrong inside is only logged.
+	log.Printf("rpc: %s", err)
+	return status.Error(codes.Internal, "internal server error")
+}
+
+func toPocket(p *pocket.Pocket) *bankpb.Pocket {
+	res := &bankpb.Pocket{
+		Id:             uint64(p.ID),
+		Title:          p.Title,
+		Balance:        p.Balance,
+		Type:           p.Type,
+		TargetAmount:   p.TargetAmount,
+		TargetDate:     toTimestamp(p.TargetDate),
+		MaturityDate:   toTimestamp(p.MaturityDate),
+		MaturityAction: p.MaturityAction,
+		CreatedAt:      timestamppb.New(p.CreatedAt),
+	}
+	if p.Description != nil {
+		res.Description = *p.Description
+	}
+	return res
+}
+
+func toTransfer(t *account.AccountTransfer) *bankpb.AccountTransfer {
+	return &bankpb.AccountTransfer{
+		Id:        uint64(t.ID),
+		Type:      t.Type,
+		Status:    t.Status,
+		From:      t.From,
+		To:        t.To,
+		Amount:    t.Amount,
+		Memo:      t.Memo,
+		Reference: t.Reference,
+		Category:  t.Category,
+		Tags:      t.Tags,
+		CreatedAt: timestamppb.New(t.CreatedAt),
+	}
+}
+
+func toPocketTransfer(t *pocket.PocketTransfer) *bankpb.PocketTransfer {
+	return &bankpb.PocketTransfer{
+		Id:        uint64(t.ID),
+		Type:      t.Type,
+		From:      uint64(t.From),
+		To:        uint64(t.To),
+		Amount:    t.Amount,
+		CreatedAt: timestamppb.New(t.CreatedAt),
+	}
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func toTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
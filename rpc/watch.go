@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/event"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/arthit666/make_app/rpc/bankpb"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchBuffer is how many events a stream may fall behind before the ones
+// after are dropped. event.Publish runs in the goroutine of whoever made
+// the change, so a slow client must never block it.
+const watchBuffer = 64
+
+// subscribe hands out the events concerning the account numbered number
+// with ID id, until the returned function is called.
+func subscribe(id uint, number string) (<-chan event.Event, func()) {
+	events := make(chan event.Event, watchBuffer)
+	unsubscribe := event.Subscribe(func(e event.Event) {
+		if !concerns(e, id, number) {
+			return
+		}
+		select {
+		case events <- e:
+		default:
+			log.Printf("rpc: stream of account %d is behind, dropped %s", id, e.Type)
+		}
+	})
+	return events, unsubscribe
+}
+
+// forward calls send for every event from events until ctx is done or send
+// fails.
+func forward(ctx context.Context, events <-chan event.Event, send func(event.Event) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-events:
+			if err := send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// concerns tells whether money of the account numbered number with ID id
+// moved in e: a transfer it sent or received, or a movement in or out of
+// one of its pockets.
+func concerns(e event.Event, id uint, number string) bool {
+	switch t := e.Payload.(type) {
+	case account.AccountTransfer:
+		return e.Type == event.TransferSent && (t.From == number || t.To == number)
+	case pocket.PocketTransfer:
+		return e.AccountID == id
+	case pocket.Pocket:
+		return e.Type == event.PocketClosed && e.AccountID == id
+	}
+	return false
+}
+
+// WatchBalance sends the response headers once it is subscribed, so clients
+// waiting for them miss no change made after. WatchTransfers does the same.
+func (s *server) WatchBalance(req *bankpb.WatchBalanceRequest, stream bankpb.Bank_WatchBalanceServer) error {
+	ctx := stream.Context()
+	acc, err := s.accounts.Get(ctx, callerFrom(ctx).accountID)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	events, unsubscribe := subscribe(acc.ID, acc.AccountNumber)
+	defer unsubscribe()
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	if err := stream.Send(toBalance(acc)); err != nil {
+		return err
+	}
+	return forward(ctx, events, func(event.Event) error {
+		acc, err := s.accounts.Get(ctx, acc.ID)
+		if err != nil {
+			return toStatus(err)
+		}
+		return stream.Send(toBalance(acc))
+	})
+}
+
+func (s *server) WatchTransfers(req *bankpb.WatchTransfersRequest, stream bankpb.Bank_WatchTransfersServer) error {
+	ctx := stream.Context()
+	acc, err := s.accounts.Get(ctx, callerFrom(ctx).accountID)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	events, unsubscribe := subscribe(acc.ID, acc.AccountNumber)
+	defer unsubscribe()
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	return forward(ctx, events, func(e event.Event) error {
+		t, ok := e.Payload.(account.AccountTransfer)
+		if !ok {
+			return nil
+		}
+		return stream.Send(toTransfer(&t))
+	})
+}
+
+func toBalance(acc *account.Account) *bankpb.Balance {
+	res := &bankpb.Balance{Balance: acc.Balance, At: timestamppb.New(time.Now())}
+	for _, p := range acc.PocketList {
+		res.Pockets = append(res.Pockets, &bankpb.PocketBalance{Id: uint64(p.ID), Balance: p.Balance})
+	}
+	return res
+}
@@ -0,0 +1,139 @@
+package rule
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arthit666/make_app/balance"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Apply runs the rules of accountID that react to a transfer going in
+// direction. It has to be given the transaction the transfer is made in, so
+// that the transfer and what the rules move commit or roll back together. A
+// rule that cannot be carried out, e.g. for lack of funds, is logged as
+// skipped without failing the transfer.
+func Apply(tx *gorm.DB, direction string, accountID, transferID uint, amount float64) error {
+	typ := TypePercentIncoming
+	if direction == Outgoing {
+		typ = TypeRoundUpOutgoing
+	}
+
+	rules := []Rule{}
+	if err := tx.Where("account_id = ? AND type = ? AND active = ?", accountID, typ, true).Order("id").Find(&rules).Error; err != nil {
+		return err
+	}
+
+	for i := range rules {
+		if err := execute(tx, &rules[i], &transferID, ruleAmount(&rules[i], decimal.NewFromFloat(amount))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sweep runs every sweep rule, moving whatever is above the threshold of an
+// account into the rule's pocket.
+func Sweep(db *gorm.DB, _ time.Time) error {
+	rules := []Rule{}
+	if err := db.Where("type = ? AND active = ?", TypeSweep, true).Order("id").Find(&rules).Error; err != nil {
+		return err
+	}
+
+	for i := range rules {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			acc := &balance.Account{}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(acc, rules[i].AccountID).Error; err != nil {
+				return err
+			}
+			excess := decimal.NewFromFloat(acc.Balance).Sub(decimal.NewFromFloat(rules[i].Threshold))
+			return execute(tx, &rules[i], nil, excess)
+		})
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ruleAmount is what r moves for a transfer of amount.
+func ruleAmount(r *Rule, amount decimal.Decimal) decimal.Decimal {
+	switch r.Type {
+	case TypePercentIncoming:
+		if amount.LessThan(decimal.NewFromFloat(r.MinAmount)) {
+			return decimal.Zero
+		}
+		return amount.Mul(decimal.NewFromFloat(r.Percent)).Div(decimal.NewFromInt(100)).Round(2)
+	case TypeRoundUpOutgoing:
+		step := decimal.NewFromFloat(r.RoundTo)
+		if !step.IsPositive() {
+			return decimal.Zero
+		}
+		return amount.Div(step).Ceil().Mul(step).Sub(amount)
+	}
+	return decimal.Zero
+}
+
+func execute(tx *gorm.DB, r *Rule, transferID *uint, amount decimal.Decimal) error {
+	e := &RuleExecution{RuleID: r.ID, TransferID: transferID, Status: StatusApplied}
+	e.Amount, _ = amount.Float64()
+
+	note, err := move(tx, r, amount)
+	if err != nil {
+		return err
+	}
+	if note != "" {
+		e.Status = StatusSkipped
+		e.Note = note
+	}
+
+	return tx.Create(e).Error
+}
+
+// move moves amount from the account of r into its pocket. It returns why
+// nothing was moved, or "" if it was. Both rows are locked until tx ends,
+// the account first as everywhere else.
+func move(tx *gorm.DB, r *Rule, amount decimal.Decimal) (string, error) {
+	if !amount.IsPositive() {
+		return "nothing to move", nil
+	}
+
+	acc := &balance.Account{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(acc, r.AccountID).Error; err != nil {
+		return "", err
+	}
+
+	p := &pocket.Pocket{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("account_id = ?", r.AccountID).First(p, r.PocketID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "pocket not found", nil
+		}
+		return "", err
+	}
+	if p.Type == pocket.TypeLocked {
+		return "pocket is locked", nil
+	}
+
+	bl := decimal.NewFromFloat(acc.Balance)
+	if bl.LessThan(amount) {
+		return "insufficient balance", nil
+	}
+
+	acc.Balance, _ = bl.Sub(amount).Float64()
+	if err := tx.Model(acc).Update("balance", acc.Balance).Error; err != nil {
+		return "", err
+	}
+
+	p.Balance, _ = decimal.NewFromFloat(p.Balance).Add(amount).Float64()
+	if err := tx.Model(p).Update("balance", p.Balance).Error; err != nil {
+		return "", err
+	}
+
+	t := &pocket.PocketTransfer{Type: pocket.TransferTypeRule, To: p.ID, AccountID: r.AccountID}
+	t.Amount, _ = amount.Float64()
+	return "", tx.Create(t).Error
+}
@@ -0,0 +1,73 @@
+package rule
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rule moves money from an account into one of its pockets automatically.
+// Percent and round-up rules run with every transfer in or out of the
+// account, sweep rules run nightly.
+type Rule struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"create_at"`
+	UpdatedAt time.Time      `json:"update_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	AccountID uint           `json:"-" gorm:"index"`
+	PocketID  uint           `json:"pocket_id"`
+	Type      string         `json:"type"`
+	Percent   float64        `json:"percent,omitempty"`
+	MinAmount float64        `json:"min_amount,omitempty"`
+	RoundTo   float64        `json:"round_to,omitempty"`
+	Threshold float64        `json:"threshold,omitempty"`
+	Active    bool           `json:"active"`
+}
+
+type RuleExecution struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"create_at"`
+	RuleID     uint      `json:"rule_id" gorm:"index"`
+	TransferID *uint     `json:"transfer_id,omitempty"`
+	Amount     float64   `json:"amount"`
+	Status     string    `json:"status"`
+	Note       string    `json:"note,omitempty"`
+}
+
+type RuleRequest struct {
+	PocketID  uint    `json:"pocket_id" validate:"required"`
+	Type      string  `json:"type" validate:"required,oneof=percent_incoming round_up_outgoing sweep"`
+	Percent   float64 `json:"percent" validate:"gte=0,lte=100"`
+	MinAmount float64 `json:"min_amount" validate:"gte=0"`
+	RoundTo   float64 `json:"round_to" validate:"gte=0"`
+	Threshold float64 `json:"threshold" validate:"gte=0"`
+}
+
+const (
+	// TypePercentIncoming moves Percent of every incoming transfer of at
+	// least MinAmount, e.g. to split a salary.
+	TypePercentIncoming = "percent_incoming"
+	// TypeRoundUpOutgoing rounds every outgoing transfer up to a multiple
+	// of RoundTo and moves the difference.
+	TypeRoundUpOutgoing = "round_up_outgoing"
+	// TypeSweep moves whatever is above Threshold every night.
+	TypeSweep = "sweep"
+
+	Incoming = "incoming"
+	Outgoing = "outgoing"
+
+	StatusApplied = "applied"
+	StatusSkipped = "skipped"
+)
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
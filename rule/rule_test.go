@@ -0,0 +1,189 @@
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/balance"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRuleAmount(t *testing.T) {
+	cases := []struct {
+		rule   Rule
+		amount float64
+		want   string
+	}{
+		{Rule{Type: TypePercentIncoming, Percent: 10}, 1234.56, "123.46"},
+		{Rule{Type: TypePercentIncoming, Percent: 10, MinAmount: 20000}, 1234.56, "0"},
+		{Rule{Type: TypePercentIncoming, Percent: 25, MinAmount: 20000}, 30000, "7500"},
+		{Rule{Type: TypeRoundUpOutgoing, RoundTo: 10}, 123, "7"},
+		{Rule{Type: TypeRoundUpOutgoing, RoundTo: 10}, 123.25, "6.75"},
+		{Rule{Type: TypeRoundUpOutgoing, RoundTo: 10}, 130, "0"},
+	}
+
+	for _, c := range cases {
+		got := ruleAmount(&c.rule, decimal.NewFromFloat(c.amount))
+		assert.Equal(t, c.want, got.String(), "%s on %v", c.rule.Type, c.amount)
+	}
+}
+
+func TestApply(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&balance.Account{}, &pocket.Pocket{}, &pocket.PocketTransfer{}, &Rule{}, &RuleExecution{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	account := balance.Account{Balance: 1000}
+	tx.Create(&account)
+	savings := pocket.Pocket{Title: "Savings", AccountID: account.ID}
+	tx.Create(&savings)
+	roundUps := pocket.Pocket{Title: "Round-ups", AccountID: account.ID}
+	tx.Create(&roundUps)
+
+	salary := Rule{AccountID: account.ID, PocketID: savings.ID, Type: TypePercentIncoming, Percent: 10, Active: true}
+	tx.Create(&salary)
+	roundUp := Rule{AccountID: account.ID, PocketID: roundUps.ID, Type: TypeRoundUpOutgoing, RoundTo: 10, Active: true}
+	tx.Create(&roundUp)
+
+	// Act
+	err = Apply(tx, Incoming, account.ID, 1, 500)
+	assert.NoError(t, err)
+	err = Apply(tx, Outgoing, account.ID, 2, 123)
+	assert.NoError(t, err)
+
+	// Assert
+	var updated balance.Account
+	tx.First(&updated, account.ID)
+	assert.Equal(t, 943.0, updated.Balance)
+
+	var updatedSavings, updatedRoundUps pocket.Pocket
+	tx.First(&updatedSavings, savings.ID)
+	assert.Equal(t, 50.0, updatedSavings.Balance)
+	tx.First(&updatedRoundUps, roundUps.ID)
+	assert.Equal(t, 7.0, updatedRoundUps.Balance)
+
+	var movements []pocket.PocketTransfer
+	tx.Where("type = ?", pocket.TransferTypeRule).Order("id").Find(&movements)
+	assert.Equal(t, 2, len(movements))
+	assert.Equal(t, savings.ID, movements[0].To)
+	assert.Equal(t, 50.0, movements[0].Amount)
+
+	// Act: not enough left for the round-up
+	tx.Model(&updated).Update("balance", 3)
+	err = Apply(tx, Outgoing, account.ID, 3, 121)
+	assert.NoError(t, err)
+
+	// Assert
+	var executions []RuleExecution
+	tx.Where("rule_id = ?", roundUp.ID).Order("id").Find(&executions)
+	assert.Equal(t, 2, len(executions))
+	assert.Equal(t, StatusApplied, executions[0].Status)
+	assert.Equal(t, uint(2), *executions[0].TransferID)
+	assert.Equal(t, StatusSkipped, executions[1].Status)
+	assert.Equal(t, "insufficient balance", executions[1].Note)
+	assert.Equal(t, 9.0, executions[1].Amount)
+}
+
+func TestSweep(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&balance.Account{}, &pocket.Pocket{}, &pocket.PocketTransfer{}, &Rule{}, &RuleExecution{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	rich := balance.Account{Balance: 80000}
+	tx.Create(&rich)
+	poor := balance.Account{Balance: 20000}
+	tx.Create(&poor)
+
+	richPocket := pocket.Pocket{Title: "Excess", AccountID: rich.ID}
+	tx.Create(&richPocket)
+	poorPocket := pocket.Pocket{Title: "Excess", AccountID: poor.ID}
+	tx.Create(&poorPocket)
+
+	tx.Create(&Rule{AccountID: rich.ID, PocketID: richPocket.ID, Type: TypeSweep, Threshold: 50000, Active: true})
+	tx.Create(&Rule{AccountID: poor.ID, PocketID: poorPocket.ID, Type: TypeSweep, Threshold: 50000, Active: true})
+
+	// Act
+	err = Sweep(tx, time.Now())
+
+	// Assert
+	assert.NoError(t, err)
+
+	var updatedRich, updatedPoor balance.Account
+	tx.First(&updatedRich, rich.ID)
+	assert.Equal(t, 50000.0, updatedRich.Balance)
+	tx.First(&updatedPoor, poor.ID)
+	assert.Equal(t, 20000.0, updatedPoor.Balance)
+
+	var swept pocket.Pocket
+	tx.First(&swept, richPocket.ID)
+	assert.Equal(t, 30000.0, swept.Balance)
+}
+
+func TestCreateRule(t *testing.T) {
+	// Arrange
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&pocket.Pocket{}, &Rule{})
+	assert.NoError(t, err)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", 1)
+		return c.Next()
+	})
+	handler := New(tx)
+	app.Post("/rules", handler.CreateRule)
+
+	mine := pocket.Pocket{Title: "Mine", AccountID: 1}
+	tx.Create(&mine)
+	theirs := pocket.Pocket{Title: "Theirs", AccountID: 2}
+	tx.Create(&theirs)
+
+	post := func(body RuleRequest) *http.Response {
+		reqBodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader(reqBodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// Act & Assert
+	resp := post(RuleRequest{PocketID: mine.ID, Type: TypePercentIncoming, Percent: 10})
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var created Rule
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	assert.NoError(t, err)
+	assert.Equal(t, mine.ID, created.PocketID)
+	assert.True(t, created.Active)
+
+	resp = post(RuleRequest{PocketID: mine.ID, Type: TypeRoundUpOutgoing})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp = post(RuleRequest{PocketID: theirs.ID, Type: TypeSweep, Threshold: 50000})
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
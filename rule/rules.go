@@ -0,0 +1,129 @@
+package rule
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// @Summary Create an automation rule
+// @Description Create a rule that moves money from the account into a pocket automatically
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param rule body rule.RuleRequest true "RuleRequest data"
+// @Success 201 {object} rule.Rule
+// @Security  Bearer
+// @Router /rules/ [post]
+func (h *handler) CreateRule(c *fiber.Ctx) error {
+	req := &RuleRequest{}
+	if err := c.BodyParser(req); err != nil {
+		return apperr.BadRequest("invalid request body")
+	}
+
+	if err := apperr.Validate(req); err != nil {
+		return err
+	}
+	switch {
+	case req.Type == TypePercentIncoming && req.Percent <= 0:
+		return apperr.BadRequest("payload invalid: percent is required")
+	case req.Type == TypeRoundUpOutgoing && req.RoundTo <= 0:
+		return apperr.BadRequest("payload invalid: round_to is required")
+	}
+
+	acc := c.Locals("account_id").(int)
+
+	p := &pocket.Pocket{}
+	if err := h.DB.Where("account_id = ?", acc).First(p, req.PocketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodePocketNotFound, "pocket not found")
+		}
+		return apperr.Internal(err)
+	}
+
+	r := &Rule{
+		AccountID: uint(acc),
+		PocketID:  p.ID,
+		Type:      req.Type,
+		Percent:   req.Percent,
+		MinAmount: req.MinAmount,
+		RoundTo:   req.RoundTo,
+		Threshold: req.Threshold,
+		Active:    true,
+	}
+	if err := h.DB.Create(r).Error; err != nil {
+		return apperr.Internal(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(r)
+}
+
+// @Summary Get all automation rules
+// @Description Get the automation rules of the account
+// @Tags rules
+// @Produce json
+// @Success 200 {array} rule.Rule
+// @Security  Bearer
+// @Router /rules/ [get]
+func (h *handler) GetAllRules(c *fiber.Ctx) error {
+	r := []Rule{}
+	acc := c.Locals("account_id").(int)
+	if err := h.DB.Where("account_id = ?", acc).Order("id").Find(&r).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(r)
+}
+
+// @Summary Delete an automation rule
+// @Description Delete an automation rule by ID
+// @Tags rules
+// @Param id path int true "Rule ID"
+// @Success 200 {object} rule.SuccessResponse
+// @Security  Bearer
+// @Router /rules/{id} [delete]
+func (h *handler) DeleteRule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+	acc := c.Locals("account_id").(int)
+
+	tx := h.DB.Where("account_id = ?", acc).Delete(&Rule{}, id)
+	if tx.Error != nil {
+		return apperr.Internal(tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		return apperr.NotFound(apperr.CodeNotFound, "rule not found")
+	}
+	return c.Status(fiber.StatusOK).JSON(SuccessResponse{Message: "delete rule success"})
+}
+
+// @Summary Get rule executions
+// @Description Get the log of every time a rule ran, newest first
+// @Tags rules
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {array} rule.RuleExecution
+// @Security  Bearer
+// @Router /rules/{id}/executions [get]
+func (h *handler) GetExecutions(c *fiber.Ctx) error {
+	acc := c.Locals("account_id").(int)
+
+	r := &Rule{}
+	if err := h.DB.Unscoped().Where("account_id = ?", acc).First(r, c.Params("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodeNotFound, "rule not found")
+		}
+		return apperr.Internal(err)
+	}
+
+	e := []RuleExecution{}
+	if err := h.DB.Where("rule_id = ?", r.ID).Order("id desc").Find(&e).Error; err != nil {
+		return apperr.Internal(err)
+	}
+	return c.Status(fiber.StatusOK).JSON(e)
+}
@@ -0,0 +1,213 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Camt053Namespace is the camt.053 version statements are written in.
+const Camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// Camt053 is the subset of an ISO 20022 camt.053 bank-to-customer
+// statement that statements are written with, one Stmt per ledger.
+type Camt053 struct {
+	XMLName xml.Name   `xml:"Document"`
+	Xmlns   string     `xml:"xmlns,attr"`
+	GrpHdr  CamtGrpHdr `xml:"BkToCstmrStmt>GrpHdr"`
+	Stmts   []CamtStmt `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type CamtGrpHdr struct {
+	MsgID   string `xml:"MsgId"`
+	CreDtTm string `xml:"CreDtTm"`
+}
+
+type CamtStmt struct {
+	ID        string        `xml:"Id"`
+	CreDtTm   string        `xml:"CreDtTm"`
+	FrDtTm    string        `xml:"FrToDt>FrDtTm"`
+	ToDtTm    string        `xml:"FrToDt>ToDtTm"`
+	Acct      CamtAcct      `xml:"Acct"`
+	Bals      []CamtBal     `xml:"Bal"`
+	TxsSummry CamtTxsSummry `xml:"TxsSummry"`
+	Ntries    []CamtNtry    `xml:"Ntry"`
+}
+
+type CamtAcct struct {
+	ID  string `xml:"Id>Othr>Id"`
+	Ccy string `xml:"Ccy"`
+	Nm  string `xml:"Nm,omitempty"`
+}
+
+type CamtAmt struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type CamtBal struct {
+	Cd        string  `xml:"Tp>CdOrPrtry>Cd"`
+	Amt       CamtAmt `xml:"Amt"`
+	CdtDbtInd string  `xml:"CdtDbtInd"`
+	Dt        string  `xml:"Dt>Dt"`
+}
+
+type CamtTxsSummry struct {
+	NbOfNtries    int    `xml:"TtlNtries>NbOfNtries"`
+	Sum           string `xml:"TtlNtries>Sum"`
+	TtlNetNtryAmt string `xml:"TtlNtries>TtlNetNtryAmt"`
+	CdtDbtInd     string `xml:"TtlNtries>CdtDbtInd"`
+	CdtNbOfNtries int    `xml:"TtlCdtNtries>NbOfNtries"`
+	CdtSum        string `xml:"TtlCdtNtries>Sum"`
+	DbtNbOfNtries int    `xml:"TtlDbtNtries>NbOfNtries"`
+	DbtSum        string `xml:"TtlDbtNtries>Sum"`
+}
+
+type CamtNtry struct {
+	NtryRef     string     `xml:"NtryRef"`
+	Amt         CamtAmt    `xml:"Amt"`
+	CdtDbtInd   string     `xml:"CdtDbtInd"`
+	Sts         string     `xml:"Sts"`
+	BookgDtTm   string     `xml:"BookgDt>DtTm"`
+	ValDt       string     `xml:"ValDt>Dt"`
+	AcctSvcrRef string     `xml:"AcctSvcrRef"`
+	BkTxCd      string     `xml:"BkTxCd>Prtry>Cd"`
+	TxDtls      CamtTxDtls `xml:"NtryDtls>TxDtls"`
+}
+
+type CamtTxDtls struct {
+	EndToEndID string         `xml:"Refs>EndToEndId"`
+	RltdPties  *CamtRltdPties `xml:"RltdPties"`
+	RmtInf     *CamtRmtInf    `xml:"RmtInf"`
+}
+
+// CamtRltdPties has the counterparty of an entry: the creditor account of
+// a debit and the debtor account of a credit.
+type CamtRltdPties struct {
+	DbtrAcct *CamtOthrID `xml:"DbtrAcct"`
+	CdtrAcct *CamtOthrID `xml:"CdtrAcct"`
+}
+
+type CamtOthrID struct {
+	ID string `xml:"Id>Othr>Id"`
+}
+
+type CamtRmtInf struct {
+	Ustrd string `xml:"Ustrd"`
+}
+
+const (
+	credit = "CRDT"
+	debit  = "DBIT"
+
+	// notProvided is the end-to-end reference of movements without one.
+	notProvided = "NOTPROVIDED"
+
+	dateLayout = "2006-01-02"
+)
+
+// Camt053 writes s as a camt.053 statement created at now.
+func (s *Statement) Camt053(now time.Time) ([]byte, error) {
+	doc := Camt053{
+		Xmlns: Camt053Namespace,
+		GrpHdr: CamtGrpHdr{
+			MsgID:   fmt.Sprintf("STMT-%s-%s", s.Account.AccountNumber, s.From.Format("20060102")),
+			CreDtTm: now.Format(time.RFC3339),
+		},
+	}
+	for _, l := range append([]Ledger{s.Main}, s.Pockets...) {
+		doc.Stmts = append(doc.Stmts, s.camtStmt(l, now))
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func (s *Statement) camtStmt(l Ledger, now time.Time) CamtStmt {
+	st := CamtStmt{
+		ID:      fmt.Sprintf("%s-%s", l.ID, s.From.Format("20060102")),
+		CreDtTm: now.Format(time.RFC3339),
+		FrDtTm:  s.From.Format(time.RFC3339),
+		ToDtTm:  s.To.Format(time.RFC3339),
+		Acct:    CamtAcct{ID: l.ID, Ccy: Currency, Nm: l.Name},
+		Bals: []CamtBal{
+			camtBal("OPBD", l.Opening, s.From),
+			// The closing balance is dated the last day the statement
+			// covers.
+			camtBal("CLBD", l.Closing, s.To.Add(-time.Nanosecond)),
+		},
+		Ntries: []CamtNtry{},
+	}
+
+	sum, total := decimal.Zero, decimal.Zero
+	cdt, dbt := decimal.Zero, decimal.Zero
+	for _, e := range l.Entries {
+		amount := decimal.NewFromFloat(e.Amount)
+		sum = sum.Add(amount)
+		total = total.Add(net(e))
+		if e.Credit {
+			st.TxsSummry.CdtNbOfNtries++
+			cdt = cdt.Add(amount)
+		} else {
+			st.TxsSummry.DbtNbOfNtries++
+			dbt = dbt.Add(amount)
+		}
+
+		n := CamtNtry{
+			NtryRef:     e.Ref,
+			Amt:         CamtAmt{Ccy: Currency, Value: amount.StringFixed(2)},
+			CdtDbtInd:   indicator(e.Credit),
+			Sts:         "BOOK",
+			BookgDtTm:   e.Time.Format(time.RFC3339),
+			ValDt:       e.Time.Format(dateLayout),
+			AcctSvcrRef: e.Ref,
+			BkTxCd:      e.Type,
+			TxDtls:      CamtTxDtls{EndToEndID: e.Reference},
+		}
+		if n.TxDtls.EndToEndID == "" {
+			n.TxDtls.EndToEndID = notProvided
+		}
+		if e.Counterparty != "" {
+			id := &CamtOthrID{ID: e.Counterparty}
+			if e.Credit {
+				n.TxDtls.RltdPties = &CamtRltdPties{DbtrAcct: id}
+			} else {
+				n.TxDtls.RltdPties = &CamtRltdPties{CdtrAcct: id}
+			}
+		}
+		if e.Memo != "" {
+			n.TxDtls.RmtInf = &CamtRmtInf{Ustrd: e.Memo}
+		}
+		st.Ntries = append(st.Ntries, n)
+	}
+
+	st.TxsSummry.NbOfNtries = len(l.Entries)
+	st.TxsSummry.Sum = sum.StringFixed(2)
+	st.TxsSummry.TtlNetNtryAmt = total.Abs().StringFixed(2)
+	st.TxsSummry.CdtDbtInd = indicator(!total.IsNegative())
+	st.TxsSummry.CdtSum = cdt.StringFixed(2)
+	st.TxsSummry.DbtSum = dbt.StringFixed(2)
+	return st
+}
+
+func camtBal(code string, amount float64, at time.Time) CamtBal {
+	d := decimal.NewFromFloat(amount)
+	return CamtBal{
+		Cd:        code,
+		Amt:       CamtAmt{Ccy: Currency, Value: d.Abs().StringFixed(2)},
+		CdtDbtInd: indicator(!d.IsNegative()),
+		Dt:        at.Format(dateLayout),
+	}
+}
+
+func indicator(isCredit bool) string {
+	if isCredit {
+		return credit
+	}
+	return debit
+}
@@ -0,0 +1,116 @@
+package statement
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/shopspring/decimal"
+)
+
+// MT940 writes s as SWIFT MT940 customer statements, one per ledger, each
+// ending with a "-" line. Lines end with CRLF as the format asks.
+func (s *Statement) MT940() []byte {
+	var b strings.Builder
+	for i, l := range append([]Ledger{s.Main}, s.Pockets...) {
+		line := func(tag, value string) {
+			b.WriteString(":" + tag + ":" + value + "\r\n")
+		}
+
+		line("20", fmt.Sprintf("ST%s-%d", s.From.Format("060102"), i+1))
+		line("25", l.ID)
+		line("28C", "00001")
+		line("60F", swiftBal(l.Opening, s.From.Format("060102")))
+		for _, e := range l.Entries {
+			mark := "D"
+			if e.Credit {
+				mark = "C"
+			}
+			ref := e.Reference
+			if ref == "" {
+				ref = "NONREF"
+			}
+			line("61", e.Time.Format("060102")+e.Time.Format("0102")+mark+
+				swiftAmount(decimal.NewFromFloat(e.Amount))+"N"+swiftCode(e.Type)+
+				swiftText(ref, 16)+"//"+swiftText(e.Ref, 16))
+
+			info := ""
+			if e.Reference != "" {
+				info += "/EREF/" + e.Reference
+			}
+			if e.Counterparty != "" {
+				info += "/CNTP/" + e.Counterparty
+			}
+			if e.Memo != "" {
+				info += "/REMI/" + e.Memo
+			}
+			if info != "" {
+				line("86", strings.Join(wrap(swiftText(info, 6*65), 65), "\r\n"))
+			}
+		}
+		// The closing balance is dated the last day the statement covers.
+		line("62F", swiftBal(l.Closing, s.To.AddDate(0, 0, -1).Format("060102")))
+		b.WriteString("-\r\n")
+	}
+	return []byte(b.String())
+}
+
+// swiftBal is a balance field: the credit or debit mark, the date, the
+// currency and the amount.
+func swiftBal(amount float64, date string) string {
+	d := decimal.NewFromFloat(amount)
+	mark := "C"
+	if d.IsNegative() {
+		mark = "D"
+	}
+	return mark + date + Currency + swiftAmount(d.Abs())
+}
+
+// swiftAmount writes d with a decimal comma, as in 1000,00.
+func swiftAmount(d decimal.Decimal) string {
+	return strings.Replace(d.StringFixed(2), ".", ",", 1)
+}
+
+// swiftCode is the SWIFT transaction type of a movement.
+func swiftCode(typ string) string {
+	switch typ {
+	case account.TransferTypeInterest, pocket.TransferTypeBonusInterest:
+		return "INT"
+	case account.TransferTypeWithholdingTax:
+		return "TAX"
+	case pocket.TransferTypePenalty:
+		return "CHG"
+	case account.TransferTypeAdjustment:
+		return "MSC"
+	}
+	return "TRF"
+}
+
+// swiftText cuts s to at most n characters of the SWIFT character set,
+// writing a dot for any other character.
+func swiftText(s string, n int) string {
+	out := []rune{}
+	for _, r := range s {
+		if len(out) == n {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			strings.ContainsRune("/-?:().,'+ ", r):
+			out = append(out, r)
+		default:
+			out = append(out, '.')
+		}
+	}
+	return string(out)
+}
+
+func wrap(s string, n int) []string {
+	lines := []string{}
+	for len(s) > n {
+		lines = append(lines, s[:n])
+		s = s[n:]
+	}
+	return append(lines, s)
+}
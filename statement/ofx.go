@@ -0,0 +1,136 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/shopspring/decimal"
+)
+
+// ofxHeader is the processing instruction that makes an XML document an
+// OFX 2.1.1 file.
+const ofxHeader = `<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+
+// BankID is the bank identifier OFX files give accounts.
+const BankID = "MAKEAPP"
+
+// OFX is the subset of an OFX 2.x bank statement response that statements
+// are written with, one STMTTRNRS per ledger.
+type OFX struct {
+	XMLName   xml.Name       `xml:"OFX"`
+	Status    OFXStatus      `xml:"SIGNONMSGSRSV1>SONRS>STATUS"`
+	DTServer  string         `xml:"SIGNONMSGSRSV1>SONRS>DTSERVER"`
+	Language  string         `xml:"SIGNONMSGSRSV1>SONRS>LANGUAGE"`
+	Responses []OFXStmtTrnRs `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type OFXStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type OFXStmtTrnRs struct {
+	TrnUID string    `xml:"TRNUID"`
+	Status OFXStatus `xml:"STATUS"`
+	StmtRs OFXStmtRs `xml:"STMTRS"`
+}
+
+type OFXStmtRs struct {
+	CurDef     string       `xml:"CURDEF"`
+	BankID     string       `xml:"BANKACCTFROM>BANKID"`
+	AcctID     string       `xml:"BANKACCTFROM>ACCTID"`
+	AcctType   string       `xml:"BANKACCTFROM>ACCTTYPE"`
+	DTStart    string       `xml:"BANKTRANLIST>DTSTART"`
+	DTEnd      string       `xml:"BANKTRANLIST>DTEND"`
+	Trns       []OFXStmtTrn `xml:"BANKTRANLIST>STMTTRN"`
+	LedgerBal  string       `xml:"LEDGERBAL>BALAMT"`
+	LedgerAsOf string       `xml:"LEDGERBAL>DTASOF"`
+}
+
+type OFXStmtTrn struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FITID    string `xml:"FITID"`
+	RefNum   string `xml:"REFNUM,omitempty"`
+	Name     string `xml:"NAME,omitempty"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+// OFX writes s as an OFX 2.1.1 bank statement response created at now.
+// Pockets are savings accounts of their own.
+func (s *Statement) OFX(now time.Time) ([]byte, error) {
+	ok := OFXStatus{Code: 0, Severity: "INFO"}
+	doc := OFX{Status: ok, DTServer: ofxTime(now), Language: "ENG"}
+	for i, l := range append([]Ledger{s.Main}, s.Pockets...) {
+		rs := OFXStmtRs{
+			CurDef:   Currency,
+			BankID:   BankID,
+			AcctID:   l.ID,
+			AcctType: "CHECKING",
+			DTStart:  ofxTime(s.From),
+			DTEnd:    ofxTime(s.To),
+			Trns:     []OFXStmtTrn{},
+			// The ledger balance is the one at the end of the statement.
+			LedgerBal:  decimal.NewFromFloat(l.Closing).StringFixed(2),
+			LedgerAsOf: ofxTime(s.To),
+		}
+		if l.PocketID != nil {
+			rs.AcctType = "SAVINGS"
+		}
+		for _, e := range l.Entries {
+			rs.Trns = append(rs.Trns, OFXStmtTrn{
+				TrnType:  ofxType(e, l.PocketID != nil),
+				DTPosted: ofxTime(e.Time),
+				TrnAmt:   net(e).StringFixed(2),
+				FITID:    e.Ref,
+				RefNum:   e.Reference,
+				Name:     cut(e.Counterparty, 32),
+				Memo:     cut(e.Memo, 255),
+			})
+		}
+		doc.Responses = append(doc.Responses, OFXStmtTrnRs{TrnUID: fmt.Sprint(i + 1), Status: ok, StmtRs: rs})
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header+ofxHeader), out...), nil
+}
+
+// ofxTime writes t in UTC as OFX dates are, with the time zone spelled out.
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+// ofxType is the OFX transaction type of e. Money moved between the main
+// balance and a pocket is a transfer.
+func ofxType(e Entry, inPocket bool) string {
+	switch e.Type {
+	case account.TransferTypeInterest, pocket.TransferTypeBonusInterest:
+		return "INT"
+	case pocket.TransferTypePenalty:
+		return "FEE"
+	case pocket.TransferTypeDeposit, pocket.TransferTypeWithdrawal, pocket.TransferTypeRule:
+		return "XFER"
+	}
+	if inPocket && e.Type == pocket.TransferTypeTransfer {
+		return "XFER"
+	}
+	if e.Credit {
+		return "CREDIT"
+	}
+	return "DEBIT"
+}
+
+// cut keeps the first n characters of s.
+func cut(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,223 @@
+package statement
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Currency is the currency every balance is kept in.
+const Currency = "THB"
+
+// Formats the history of an account can be exported in.
+const (
+	FormatMT940   = "mt940"
+	FormatOFX     = "ofx"
+	FormatCamt053 = "camt053"
+)
+
+// Entry is one movement on a ledger. Amount is always positive, Credit
+// tells which way it went.
+type Entry struct {
+	// Ref is unique across ledgers: AT<id> for an account transfer and
+	// PT<id> for a pocket movement.
+	Ref          string
+	Time         time.Time
+	Type         string
+	Amount       float64
+	Credit       bool
+	Counterparty string
+	Reference    string
+	Memo         string
+}
+
+// Ledger is the main balance of an account, or one of its pockets, over a
+// period. Opening and Closing are the balances at its start and end.
+type Ledger struct {
+	// ID is the account number, or the account number and pocket ID of a
+	// pocket (1234567890-P7), which statements treat as a sub-account.
+	ID       string
+	Name     string
+	PocketID *uint
+	Opening  float64
+	Closing  float64
+	Entries  []Entry
+}
+
+// Statement is everything that moved the balances of an account between
+// From and To.
+type Statement struct {
+	Account account.Account
+	From    time.Time
+	To      time.Time
+	Main    Ledger
+	Pockets []Ledger
+}
+
+// Build puts together the statement of account id from from to to. Balances
+// are worked out back from the current ones, so it holds as long as every
+// balance change is recorded as a movement.
+func Build(db *gorm.DB, id uint, from, to time.Time) (*Statement, error) {
+	acc := account.Account{}
+	if err := db.First(&acc, id).Error; err != nil {
+		return nil, err
+	}
+	s := &Statement{Account: acc, From: from, To: to}
+
+	entries, err := mainEntries(db, &acc, from)
+	if err != nil {
+		return nil, err
+	}
+	s.Main = ledger(acc.AccountNumber, acc.Email, acc.Balance, entries, to)
+
+	pockets := []pocket.Pocket{}
+	err = db.Unscoped().
+		Where("account_id = ? AND created_at < ? AND (deleted_at IS NULL OR deleted_at >= ?)", acc.ID, to, from).
+		Order("id").Find(&pockets).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range pockets {
+		p := &pockets[i]
+		entries, err := pocketEntries(db, p, from)
+		if err != nil {
+			return nil, err
+		}
+		current := p.Balance
+		if p.DeletedAt.Valid {
+			current = 0
+		}
+		l := ledger(SubAccount(acc.AccountNumber, p.ID), p.Title, current, entries, to)
+		l.PocketID = &p.ID
+		s.Pockets = append(s.Pockets, l)
+	}
+	return s, nil
+}
+
+// SubAccount is the account number statements give pocket id of account
+// number.
+func SubAccount(number string, id uint) string {
+	return fmt.Sprintf("%s-P%d", number, id)
+}
+
+// ledger keeps the entries before to and works the balances out back from
+// current, the balance after every entry.
+func ledger(id, name string, current float64, entries []Entry, to time.Time) Ledger {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+
+	closing := decimal.NewFromFloat(current)
+	l := Ledger{ID: id, Name: name, Entries: []Entry{}}
+	for _, e := range entries {
+		if !e.Time.Before(to) {
+			closing = closing.Sub(net(e))
+			continue
+		}
+		l.Entries = append(l.Entries, e)
+	}
+
+	opening := closing
+	for _, e := range l.Entries {
+		opening = opening.Sub(net(e))
+	}
+	l.Opening, _ = opening.Float64()
+	l.Closing, _ = closing.Float64()
+	return l
+}
+
+func net(e Entry) decimal.Decimal {
+	if e.Credit {
+		return decimal.NewFromFloat(e.Amount)
+	}
+	return decimal.NewFromFloat(e.Amount).Neg()
+}
+
+// mainEntries lists what moved the main balance of acc from from on: its
+// account transfers and the money it moved in and out of pockets.
+func mainEntries(db *gorm.DB, acc *account.Account, from time.Time) ([]Entry, error) {
+	transfers := []account.AccountTransfer{}
+	err := db.Where(db.Where(&account.AccountTransfer{From: acc.AccountNumber}).Or(&account.AccountTransfer{To: acc.AccountNumber})).
+		Where("status = ? AND created_at >= ?", account.TransferStatusCompleted, from).
+		Order("id").Find(&transfers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for _, t := range transfers {
+		e := Entry{
+			Ref:       fmt.Sprintf("AT%d", t.ID),
+			Time:      t.CreatedAt,
+			Type:      t.Type,
+			Amount:    t.Amount,
+			Credit:    t.To == acc.AccountNumber,
+			Reference: t.Reference,
+			Memo:      t.Memo,
+		}
+		if e.Credit {
+			e.Counterparty = t.From
+		} else {
+			e.Counterparty = t.To
+		}
+		entries = append(entries, e)
+	}
+
+	movements := []pocket.PocketTransfer{}
+	err = db.Where(&pocket.PocketTransfer{AccountID: acc.ID}).
+		Where("type IN ? AND created_at >= ?", []string{pocket.TransferTypeDeposit, pocket.TransferTypeRule, pocket.TransferTypeWithdrawal}, from).
+		Order("id").Find(&movements).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range movements {
+		e := Entry{Ref: fmt.Sprintf("PT%d", m.ID), Time: m.CreatedAt, Type: m.Type, Amount: m.Amount}
+		switch {
+		case m.Type == pocket.TransferTypeWithdrawal && m.To == 0:
+			e.Credit = true
+			e.Counterparty = SubAccount(acc.AccountNumber, m.From)
+		case m.Type != pocket.TransferTypeWithdrawal && m.From == 0:
+			e.Counterparty = SubAccount(acc.AccountNumber, m.To)
+		default:
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// pocketEntries lists every movement in and out of p from from on.
+func pocketEntries(db *gorm.DB, p *pocket.Pocket, from time.Time) ([]Entry, error) {
+	movements := []pocket.PocketTransfer{}
+	err := db.Where(db.Where(&pocket.PocketTransfer{From: p.ID}).Or(&pocket.PocketTransfer{To: p.ID})).
+		Where("created_at >= ?", from).
+		Order("id").Find(&movements).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for _, m := range movements {
+		e := Entry{Ref: fmt.Sprintf("PT%d", m.ID), Time: m.CreatedAt, Type: m.Type, Amount: m.Amount, Credit: m.To == p.ID}
+		other := m.From
+		if !e.Credit {
+			other = m.To
+		}
+		if other != 0 {
+			e.Counterparty = fmt.Sprintf("P%d", other)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
@@ -0,0 +1,181 @@
+package statement
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/pocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &account.AccountTransfer{}, &pocket.Pocket{}, &pocket.PocketTransfer{})
+	assert.NoError(t, err)
+	return db
+}
+
+// history creates an account with a pocket and a few days of movements
+// around 2026-01-15.
+func history(tx *gorm.DB) (*account.Account, *pocket.Pocket) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 1, day, hour, min, 0, 0, time.UTC)
+	}
+
+	acc := &account.Account{Email: "owner@test.com", AccountNumber: "6000000001", Balance: 550}
+	other := &account.Account{Email: "other@test.com", AccountNumber: "6000000002"}
+	tx.Create(acc)
+	tx.Create(other)
+
+	p := &pocket.Pocket{CreatedAt: at(10, 8, 0), Title: "Holiday", Balance: 301.5, AccountID: acc.ID}
+	tx.Create(p)
+
+	tx.Create(&account.AccountTransfer{CreatedAt: at(14, 10, 0), From: other.AccountNumber, To: acc.AccountNumber, Amount: 1000, Status: account.TransferStatusCompleted})
+	tx.Create(&account.AccountTransfer{CreatedAt: at(15, 9, 0), From: acc.AccountNumber, To: other.AccountNumber, Amount: 200, Status: account.TransferStatusCompleted, Memo: "rent", Reference: "INV-1"})
+	tx.Create(&pocket.PocketTransfer{CreatedAt: at(15, 12, 0), Type: pocket.TransferTypeDeposit, To: p.ID, Amount: 300, AccountID: acc.ID})
+	tx.Create(&pocket.PocketTransfer{CreatedAt: at(15, 23, 55), Type: pocket.TransferTypeInterest, To: p.ID, Amount: 1.5})
+	tx.Create(&account.AccountTransfer{CreatedAt: at(16, 8, 0), From: other.AccountNumber, To: acc.AccountNumber, Amount: 50, Status: account.TransferStatusCompleted})
+	return acc, p
+}
+
+func TestBuild(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+	acc, p := history(tx)
+
+	day := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	// Act
+	s, err := Build(tx, acc.ID, day, day.AddDate(0, 0, 1))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, acc.AccountNumber, s.Main.ID)
+	assert.Equal(t, 1000.0, s.Main.Opening)
+	assert.Equal(t, 500.0, s.Main.Closing)
+	assert.Equal(t, 2, len(s.Main.Entries))
+	assert.Equal(t, "INV-1", s.Main.Entries[0].Reference)
+	assert.False(t, s.Main.Entries[1].Credit)
+	assert.Equal(t, SubAccount(acc.AccountNumber, p.ID), s.Main.Entries[1].Counterparty)
+
+	assert.Equal(t, 1, len(s.Pockets))
+	assert.Equal(t, 0.0, s.Pockets[0].Opening)
+	assert.Equal(t, 301.5, s.Pockets[0].Closing)
+	assert.Equal(t, 2, len(s.Pockets[0].Entries))
+}
+
+func TestCamt053(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+	acc, _ := history(tx)
+
+	day := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	s, err := Build(tx, acc.ID, day, day.AddDate(0, 0, 1))
+	assert.NoError(t, err)
+
+	// Act
+	out, err := s.Camt053(time.Date(2026, 1, 16, 1, 0, 0, 0, time.UTC))
+
+	// Assert
+	assert.NoError(t, err)
+	want, err := os.ReadFile("testdata/camt053.xml")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(want)), string(out))
+}
+
+func TestMT940(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+	acc, _ := history(tx)
+
+	from := time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC)
+	s, err := Build(tx, acc.ID, from, from.AddDate(0, 0, 2))
+	assert.NoError(t, err)
+
+	// Act
+	out := s.MT940()
+
+	// Assert
+	want, err := os.ReadFile("testdata/mt940.sta")
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(out))
+}
+
+func TestOFX(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+	acc, _ := history(tx)
+
+	from := time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC)
+	s, err := Build(tx, acc.ID, from, from.AddDate(0, 0, 2))
+	assert.NoError(t, err)
+
+	// Act
+	out, err := s.OFX(time.Date(2026, 1, 16, 1, 0, 0, 0, time.UTC))
+
+	// Assert
+	assert.NoError(t, err)
+	want, err := os.ReadFile("testdata/statement.ofx")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(string(want)), string(out))
+}
+
+func TestGetExport(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+	acc, _ := history(tx)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(acc.ID))
+		return c.Next()
+	})
+	app.Get("/account/export", New(tx).GetExport)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		prefix string
+	}{
+		{"mt940", "?format=mt940&from=2026-01-14&to=2026-01-15", http.StatusOK, ":20:ST260114-1"},
+		{"ofx", "?format=ofx&from=2026-01-14&to=2026-01-15", http.StatusOK, "<?xml"},
+		{"unknown format", "?format=qif", http.StatusBadRequest, ""},
+		{"bad date", "?format=ofx&from=14/01/2026", http.StatusBadRequest, ""},
+		{"to before from", "?format=ofx&from=2026-01-15&to=2026-01-14", http.StatusBadRequest, ""},
+		{"over a year", "?format=ofx&from=2025-01-01&to=2026-01-15", http.StatusBadRequest, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/account/export"+tc.query, nil))
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			assert.True(t, strings.HasPrefix(string(body), tc.prefix))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// @Summary Get an end-of-day statement
+// @Description Get the ISO 20022 camt.053 statement of the caller's account and pockets for a day, yesterday by default. Pockets are statements of their own, with the account number and pocket ID as account
+// @Tags accounts
+// @Produce xml
+// @Param date query string false "Day of the statement (YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Security  Bearer
+// @Router /account/statement [get]
+func (h *handler) GetStatement(c *fiber.Ctx) error {
+	now := time.Now()
+	from, err := day(c.Query("date"), time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		return apperr.BadRequest("date must be YYYY-MM-DD")
+	}
+	to := from.AddDate(0, 0, 1)
+	if to.After(now) {
+		return apperr.BadRequest("the statement of a day is only ready once the day is over")
+	}
+
+	s, err := Build(h.DB, uint(c.Locals("account_id").(int)), from, to)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodeAccountNotFound, "account not found")
+		}
+		return apperr.Internal(err)
+	}
+
+	out, err := s.Camt053(now)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="camt053-%s-%s.xml"`, s.Account.AccountNumber, from.Format(dateLayout)))
+	return c.Status(fiber.StatusOK).Send(out)
+}
+
+// @Summary Export the account history
+// @Description Export the history of the caller's account and pockets between two days (the last 30 by default, up to a year) for accounting software, as SWIFT MT940 or OFX 2.1.1. Pockets are sub-accounts: separate MT940 statements, or OFX savings accounts
+// @Tags accounts
+// @Produce plain
+// @Param format query string true "mt940 or ofx"
+// @Param from query string false "First day (YYYY-MM-DD)"
+// @Param to query string false "Last day (YYYY-MM-DD), today by default"
+// @Success 200 {file} file
+// @Security  Bearer
+// @Router /account/export [get]
+func (h *handler) GetExport(c *fiber.Ctx) error {
+	format := c.Query("format")
+	if format != FormatMT940 && format != FormatOFX {
+		return apperr.BadRequest("format must be mt940 or ofx")
+	}
+
+	now := time.Now()
+	last, err := day(c.Query("to"), time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		return apperr.BadRequest("to must be YYYY-MM-DD")
+	}
+	from, err := day(c.Query("from"), last.AddDate(0, 0, -29))
+	if err != nil {
+		return apperr.BadRequest("from must be YYYY-MM-DD")
+	}
+	to := last.AddDate(0, 0, 1)
+	if !from.Before(to) || to.After(from.AddDate(1, 0, 1)) {
+		return apperr.BadRequest("from must be before to and at most a year apart")
+	}
+
+	s, err := Build(h.DB, uint(c.Locals("account_id").(int)), from, to)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.NotFound(apperr.CodeAccountNotFound, "account not found")
+		}
+		return apperr.Internal(err)
+	}
+
+	name := fmt.Sprintf("%s-%s-%s", s.Account.AccountNumber, from.Format("20060102"), last.Format("20060102"))
+	var out []byte
+	if format == FormatMT940 {
+		out = s.MT940()
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		name += ".sta"
+	} else {
+		if out, err = s.OFX(now); err != nil {
+			return apperr.Internal(err)
+		}
+		c.Set(fiber.HeaderContentType, "application/x-ofx")
+		name += ".ofx"
+	}
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, name))
+	return c.Status(fiber.StatusOK).Send(out)
+}
+
+// day reads a YYYY-MM-DD query value, or def when there is none.
+func day(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	return time.ParseInLocation(dateLayout, value, def.Location())
+}
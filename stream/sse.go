@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/event"
+	"github.com/gofiber/fiber/v2"
+)
+
+// @Summary Stream account updates
+// @Description Server-sent events of the caller's transfers in and out, pocket updates and the balance after each of them. Every event has an ID; reconnecting with it as Last-Event-ID replays the ones missed, as far back as the last 100.
+// @Tags accounts
+// @Produce text/event-stream
+// @Security Bearer
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {string} string "event stream"
+// @Failure 400 {object} apperr.Problem
+// @Router /account/stream [get]
+func (h *handler) GetStream(c *fiber.Ctx) error {
+	lastID, err := lastEventID(c.Get("Last-Event-ID"))
+	if err != nil {
+		return apperr.BadRequest("invalid Last-Event-ID")
+	}
+
+	acc := uint(c.Locals("account_id").(int))
+	missed, messages, unsubscribe := broker.Subscribe(acc, lastID)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		for _, m := range missed {
+			writeEvent(w, m)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		ping := time.NewTicker(keepAlive)
+		defer ping.Stop()
+		for {
+			select {
+			case m, ok := <-messages:
+				if !ok {
+					return
+				}
+				writeEvent(w, m)
+			case <-ping.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func writeEvent(w *bufio.Writer, m event.Message) {
+	data, err := json.Marshal(m.Event.Payload)
+	if err != nil {
+		data = []byte("null")
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", m.ID, m.Event.Type, data)
+}
+
+// lastEventID parses the ID a client last got, with none meaning nothing
+// is to be replayed.
+func lastEventID(value string) (uint64, error) {
+	if value == "" {
+		return ^uint64(0), nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/event"
+	"github.com/arthit666/make_app/pocket"
+	"gorm.io/gorm"
+)
+
+const (
+	TypeBalance          = "balance"
+	TypeTransferIncoming = "transfer.incoming"
+	TypeTransferOutgoing = "transfer.outgoing"
+)
+
+// bufferSize is how many updates of every account are kept for clients
+// reconnecting with the ID of the last one they got.
+const bufferSize = 100
+
+// keepAlive is how often an idle stream is pinged, so proxies keep it open
+// and a client gone away is noticed.
+const keepAlive = 15 * time.Second
+
+var broker = event.NewBroker(bufferSize)
+
+// Balance is the balance of an account and its pockets right after an
+// update.
+type Balance struct {
+	Balance float64         `json:"balance"`
+	Pockets []PocketBalance `json:"pockets"`
+}
+
+type PocketBalance struct {
+	ID      uint    `json:"id"`
+	Title   string  `json:"title"`
+	Balance float64 `json:"balance"`
+}
+
+// pocketEvents are the events about pockets passed on as they are.
+var pocketEvents = map[string]bool{
+	event.PocketDeposited:   true,
+	event.PocketWithdrawn:   true,
+	event.PocketGoalReached: true,
+	event.PocketClosed:      true,
+}
+
+// Listen passes every transfer and pocket update published on the event bus
+// to the streams of the accounts involved, each followed by their new
+// balance, until the returned function is called.
+func Listen(db *gorm.DB) func() {
+	accounts := account.NewStore(db).Accounts()
+
+	return event.Subscribe(func(e event.Event) {
+		ctx := context.Background()
+		for id, typ := range route(ctx, accounts, e) {
+			broker.Send(id, event.Event{Type: typ, AccountID: id, Payload: e.Payload, CreatedAt: e.CreatedAt})
+
+			acc, err := accounts.Get(ctx, id)
+			if err != nil {
+				log.Printf("stream: balance of account %d after %s: %s", id, e.Type, err)
+				continue
+			}
+			broker.Send(id, event.Event{Type: TypeBalance, AccountID: id, Payload: toBalance(acc), CreatedAt: e.CreatedAt})
+		}
+	})
+}
+
+// Close ends every open stream, so that the server can shut down.
+func Close() {
+	broker.Close()
+}
+
+// route tells which accounts e concerns, and the type of the update each of
+// them gets: both sides of a transfer, and the account that moved money in
+// or out of a pocket.
+func route(ctx context.Context, accounts account.AccountRepository, e event.Event) map[uint]string {
+	to := map[uint]string{}
+	switch t := e.Payload.(type) {
+	case account.AccountTransfer:
+		if e.Type != event.TransferSent {
+			break
+		}
+		to[e.AccountID] = TypeTransferOutgoing
+		recipient, err := accounts.GetByNumber(ctx, t.To)
+		if err != nil {
+			log.Printf("stream: recipient %s of transfer %d: %s", t.To, t.ID, err)
+			break
+		}
+		to[recipient.ID] = TypeTransferIncoming
+	case pocket.PocketTransfer, pocket.Pocket:
+		if pocketEvents[e.Type] {
+			to[e.AccountID] = e.Type
+		}
+	}
+	return to
+}
+
+func toBalance(acc *account.Account) Balance {
+	b := Balance{Balance: acc.Balance, Pockets: []PocketBalance{}}
+	for _, p := range acc.PocketList {
+		b.Pockets = append(b.Pockets, PocketBalance{ID: p.ID, Title: p.Title, Balance: p.Balance})
+	}
+	return b
+}
+
+type handler struct {
+	DB *gorm.DB
+}
+
+func New(db *gorm.DB) *handler {
+	return &handler{db}
+}
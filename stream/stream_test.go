@@ -0,0 +1,168 @@
+package stream
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/arthit666/make_app/account"
+	"github.com/arthit666/make_app/apperr"
+	"github.com/arthit666/make_app/event"
+	"github.com/arthit666/make_app/pocket"
+	fasthttpws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setup(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = db.AutoMigrate(&account.Account{}, &pocket.Pocket{})
+	assert.NoError(t, err)
+	return db
+}
+
+// serve runs the stream routes for account acc on a local port and returns
+// its address.
+func serve(t *testing.T, acc uint) string {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true, ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("account_id", int(acc))
+		return c.Next()
+	})
+	h := New(nil)
+	app.Get("/account/stream", h.GetStream)
+	app.Get("/account/stream/ws", h.Upgrade, h.GetSocket())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	return ln.Addr().String()
+}
+
+func TestListen(t *testing.T) {
+	// Arrange
+	db := setup(t)
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	from := &account.Account{Email: "from@test.com", AccountNumber: "8000000001", Balance: 300}
+	to := &account.Account{Email: "to@test.com", AccountNumber: "8000000002", Balance: 200}
+	tx.Create(from)
+	tx.Create(to)
+	tx.Create(&pocket.Pocket{Title: "Holiday", Balance: 50, AccountID: to.ID})
+
+	broker = event.NewBroker(bufferSize)
+	stop := Listen(tx)
+	defer stop()
+	_, messages, unsubscribe := broker.Subscribe(to.ID, 0)
+	defer unsubscribe()
+
+	// Act
+	event.Publish(event.Event{Type: event.TransferSent, AccountID: from.ID, Payload: account.AccountTransfer{From: from.AccountNumber, To: to.AccountNumber, Amount: 200}})
+	event.Publish(event.Event{Type: event.PocketDeposited, AccountID: from.ID, Payload: pocket.PocketTransfer{To: 99, Amount: 10}})
+
+	// Assert
+	incoming := <-messages
+	assert.Equal(t, TypeTransferIncoming, incoming.Event.Type)
+	assert.Equal(t, 200.0, incoming.Event.Payload.(account.AccountTransfer).Amount)
+
+	balance := <-messages
+	assert.Equal(t, TypeBalance, balance.Event.Type)
+	assert.Equal(t, 200.0, balance.Event.Payload.(Balance).Balance)
+	assert.Equal(t, 50.0, balance.Event.Payload.(Balance).Pockets[0].Balance)
+	assert.Len(t, messages, 0)
+
+	missed, _, stopFrom := broker.Subscribe(from.ID, 0)
+	defer stopFrom()
+	assert.Equal(t, 4, len(missed))
+	assert.Equal(t, TypeTransferOutgoing, missed[0].Event.Type)
+	assert.Equal(t, event.PocketDeposited, missed[2].Event.Type)
+
+	replayed, _, stopReplay := broker.Subscribe(from.ID, missed[1].ID)
+	defer stopReplay()
+	assert.Equal(t, missed[2:], replayed)
+}
+
+func TestGetStream(t *testing.T) {
+	// Arrange
+	acc := uint(9001)
+	broker.Send(acc, event.Event{Type: TypeBalance, Payload: Balance{Balance: 1}})
+	broker.Send(acc, event.Event{Type: TypeBalance, Payload: Balance{Balance: 2}})
+	missed, _, unsubscribe := broker.Subscribe(acc, 0)
+	unsubscribe()
+	addr := serve(t, acc)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/account/stream", nil)
+	req.Header.Set("Last-Event-ID", fmt.Sprint(missed[0].ID))
+
+	// Act
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	r := bufio.NewReader(res.Body)
+	replayed := readEvent(t, r)
+	broker.Send(acc, event.Event{Type: TypeTransferIncoming, Payload: account.AccountTransfer{Amount: 5}})
+	live := readEvent(t, r)
+
+	// Assert
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	assert.Equal(t, []string{fmt.Sprintf("id: %d", missed[1].ID), "event: balance", `data: {"balance":2,"pockets":null}`}, replayed)
+	assert.Equal(t, "event: transfer.incoming", live[1])
+	assert.Contains(t, live[2], `"amount":5`)
+
+	bad, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/account/stream", nil)
+	bad.Header.Set("Last-Event-ID", "nope")
+	res, err = http.DefaultClient.Do(bad)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+// readEvent reads the lines of the next server-sent event.
+func readEvent(t *testing.T, r *bufio.Reader) []string {
+	lines := []string{}
+	for {
+		line, err := r.ReadString('\n')
+		assert.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestGetSocket(t *testing.T) {
+	// Arrange
+	acc := uint(9002)
+	broker.Send(acc, event.Event{Type: TypeBalance, Payload: Balance{Balance: 1}})
+	missed, _, unsubscribe := broker.Subscribe(acc, 0)
+	unsubscribe()
+	addr := serve(t, acc)
+
+	// Act
+	conn, _, err := fasthttpws.DefaultDialer.Dial(fmt.Sprintf("ws://%s/account/stream/ws?last_event_id=%d", addr, missed[0].ID-1), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	replayed := SocketMessage{}
+	assert.NoError(t, conn.ReadJSON(&replayed))
+	broker.Send(acc, event.Event{Type: event.PocketWithdrawn, Payload: pocket.PocketTransfer{From: 3, Amount: 7}})
+	live := map[string]interface{}{}
+	assert.NoError(t, conn.ReadJSON(&live))
+
+	res, err := http.Get("http://" + addr + "/account/stream/ws")
+
+	// Assert
+	assert.Equal(t, missed[0].ID, replayed.ID)
+	assert.Equal(t, TypeBalance, replayed.Type)
+	assert.Equal(t, event.PocketWithdrawn, live["type"])
+	assert.Equal(t, 7.0, live["data"].(map[string]interface{})["amount"])
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUpgradeRequired, res.StatusCode)
+}
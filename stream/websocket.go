@@ -0,0 +1,85 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/arthit666/make_app/apperr"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SocketMessage is an update as sent over the WebSocket.
+type SocketMessage struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"create_at"`
+}
+
+// Upgrade only lets WebSocket handshakes through to GetSocket.
+func (h *handler) Upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return apperr.New(fiber.StatusUpgradeRequired, apperr.CodeUpgradeRequired, "websocket upgrade required")
+	}
+	if _, err := lastEventID(c.Query("last_event_id", c.Get("Last-Event-ID"))); err != nil {
+		return apperr.BadRequest("invalid last_event_id")
+	}
+	return c.Next()
+}
+
+// @Summary Stream account updates over a WebSocket
+// @Description The WebSocket equivalent of /account/stream. Every update is a JSON message with its ID, type, data and time; reconnecting with last_event_id (or a Last-Event-ID header) replays the ones missed, as far back as the last 100.
+// @Tags accounts
+// @Security Bearer
+// @Param last_event_id query string false "ID of the last message received"
+// @Success 101 {object} stream.SocketMessage
+// @Failure 400 {object} apperr.Problem
+// @Failure 426 {object} apperr.Problem
+// @Router /account/stream/ws [get]
+func (h *handler) GetSocket() fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		lastID, _ := lastEventID(conn.Query("last_event_id", conn.Headers("Last-Event-ID")))
+		acc := uint(conn.Locals("account_id").(int))
+		missed, messages, unsubscribe := broker.Subscribe(acc, lastID)
+		defer unsubscribe()
+
+		// Clients only ever close the connection, but reading is what
+		// notices it.
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for _, m := range missed {
+			if err := conn.WriteJSON(SocketMessage{m.ID, m.Event.Type, m.Event.Payload, m.Event.CreatedAt}); err != nil {
+				return
+			}
+		}
+
+		ping := time.NewTicker(keepAlive)
+		defer ping.Stop()
+		for {
+			var err error
+			select {
+			case <-gone:
+				return
+			case m, ok := <-messages:
+				if !ok {
+					conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+					return
+				}
+				err = conn.WriteJSON(SocketMessage{m.ID, m.Event.Type, m.Event.Payload, m.Event.CreatedAt})
+			case <-ping.C:
+				err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(keepAlive))
+			}
+			if err != nil {
+				return
+			}
+		}
+	})
+}